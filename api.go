@@ -0,0 +1,129 @@
+package gonymizer
+
+import "github.com/google/uuid"
+
+// This file contains the stable API of the gonymizer module. The types and functions below follow semantic versioning:
+// they are only changed in a backwards incompatible way in a new major version. Other exported symbols are used by the
+// gonymizer CLI and may change in any release.
+//
+// Processing uses package level state (the ProcessorCatalog and the consistency maps), so a single program must not
+// run more than one Pipeline at the same time.
+
+// ProcessorRegistry is the set of processors that can be used by the Processors of a map file.
+type ProcessorRegistry struct{}
+
+// Processors returns the ProcessorRegistry.
+func Processors() ProcessorRegistry {
+	return ProcessorRegistry{}
+}
+
+// Register adds a custom processor under name. Built-in processors can not be replaced. See RegisterProcessor.
+func (ProcessorRegistry) Register(name string, processor ProcessorFunc) error {
+	return RegisterProcessor(name, processor)
+}
+
+// Lookup returns the processor registered under name.
+func (ProcessorRegistry) Lookup(name string) (ProcessorFunc, bool) {
+	processor, ok := ProcessorCatalog[name]
+	return processor, ok
+}
+
+// Names returns the sorted names of every registered processor.
+func (ProcessorRegistry) Names() []string {
+	return RegisteredProcessors()
+}
+
+// ConsistencyStore contains the fake values that are reused for the same input (e.g. AlphaNumericScramble and
+// RandomUUID), which keeps values consistent between columns, dump files, and runs.
+type ConsistencyStore struct{}
+
+// Consistency returns the ConsistencyStore.
+func Consistency() ConsistencyStore {
+	return ConsistencyStore{}
+}
+
+// Save writes the store to path encrypted with the 256-bit key (see SaveMappings).
+func (ConsistencyStore) Save(path string, key []byte) error {
+	return SaveMappings(path, key)
+}
+
+// Load merges the store saved to path with the key into the current store.
+func (ConsistencyStore) Load(path string, key []byte) error {
+	return LoadMappings(path, key)
+}
+
+// Reset removes every value from the store.
+func (ConsistencyStore) Reset() {
+	AlphaNumericMap = map[string]map[string]string{}
+	UUIDMap = map[uuid.UUID]uuid.UUID{}
+}
+
+// Pipeline processes a PII dump file into an anonymized dump file.
+type Pipeline struct {
+	Mapper          *DBMapper
+	Options         ProcessOptions
+	PreProcessFile  string
+	PostProcessFile string
+	GenerateSeed    bool
+}
+
+// Run anonymizes the dump file src and writes the result to dst.
+func (p *Pipeline) Run(src, dst string) error {
+	return ProcessDumpFileWithOptions(p.Mapper, src, dst, p.PreProcessFile, p.PostProcessFile, p.GenerateSeed,
+		p.Options)
+}
+
+// Anonymizer anonymizes values and dump files using a map file.
+type Anonymizer struct {
+	mapper *DBMapper
+}
+
+// NewAnonymizer returns an Anonymizer for the map file found at mapFile.
+func NewAnonymizer(mapFile string) (*Anonymizer, error) {
+	mapper, err := LoadConfigSkeleton(mapFile)
+	if err != nil {
+		return nil, err
+	}
+	return &Anonymizer{mapper: mapper}, nil
+}
+
+// NewAnonymizerFromMap returns an Anonymizer for a map that was built in code.
+func NewAnonymizerFromMap(mapper *DBMapper) (*Anonymizer, error) {
+	if err := mapper.Validate(); err != nil {
+		return nil, err
+	}
+	return &Anonymizer{mapper: mapper}, nil
+}
+
+// Mapper returns the map used by the Anonymizer.
+func (a *Anonymizer) Mapper() *DBMapper {
+	return a.mapper
+}
+
+// Anonymize runs a single value through the processors of its column. Values of unmapped columns and NULL (\N) are
+// returned unchanged, unless the NullBehavior of the column is process. Processors that read other columns of the row
+// (e.g. CountryColumn) only see this value.
+func (a *Anonymizer) Anonymize(schema, table, column, value string) (string, error) {
+	cmap := a.mapper.ColumnMapper(schema, table, column)
+	if cmap == nil {
+		return value, nil
+	}
+	input, ok := cmap.processorInput(value)
+	if !ok {
+		return value, nil
+	}
+	if err := checkTimedOutCalls(); err != nil {
+		return "", err
+	}
+	currentRow = newRowContext([]string{column}, []string{value})
+	output, err := processValue(cmap, input)
+	if err != nil {
+		return "", err
+	}
+	return cmap.nullOutput(output), nil
+}
+
+// Pipeline returns a Pipeline that processes dump files with the map of the Anonymizer.
+func (a *Anonymizer) Pipeline(opts ProcessOptions) *Pipeline {
+	return &Pipeline{Mapper: a.mapper, Options: opts}
+}
@@ -0,0 +1,80 @@
+package gonymizer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorRegistry(t *testing.T) {
+	upper := func(cmap *ColumnMapper, input string) (string, error) { return "UPPER", nil }
+	defer delete(ProcessorCatalog, "TestUpper")
+
+	require.Nil(t, Processors().Register("TestUpper", upper))
+	require.NotNil(t, Processors().Register("TestUpper", upper))
+	require.NotNil(t, Processors().Register("Identity", upper))
+	require.NotNil(t, Processors().Register("", upper))
+
+	_, ok := Processors().Lookup("TestUpper")
+	require.True(t, ok)
+	_, ok = Processors().Lookup("Missing")
+	require.False(t, ok)
+	require.Contains(t, Processors().Names(), "TestUpper")
+	require.Len(t, Processors().Names(), len(ProcessorCatalog))
+}
+
+func TestAnonymizer(t *testing.T) {
+	_, err := NewAnonymizerFromMap(&DBMapper{})
+	require.NotNil(t, err)
+
+	anonymizer, err := NewAnonymizerFromMap(&DBMapper{
+		DBName: "pii_localtest",
+		ColumnMaps: []ColumnMapper{{
+			TableSchema: "public",
+			TableName:   "users",
+			ColumnName:  "password",
+			Processors:  []ProcessorDefinition{{Name: "ScrubString"}},
+		}},
+	})
+	require.Nil(t, err)
+	require.Equal(t, "pii_localtest", anonymizer.Mapper().DBName)
+
+	output, err := anonymizer.Anonymize("public", "users", "password", "secret")
+	require.Nil(t, err)
+	require.Equal(t, "******", output)
+
+	output, err = anonymizer.Anonymize("public", "users", "name", "Jane")
+	require.Nil(t, err)
+	require.Equal(t, "Jane", output)
+
+	pipeline := anonymizer.Pipeline(ProcessOptions{BatchSize: 10})
+	require.Equal(t, anonymizer.Mapper(), pipeline.Mapper)
+	require.Equal(t, 10, pipeline.Options.BatchSize)
+
+	_, err = NewAnonymizer("missing.json")
+	require.NotNil(t, err)
+}
+
+func TestConsistencyStore(t *testing.T) {
+	alphaNumericMap, uuidMap := AlphaNumericMap, UUIDMap
+	defer func() { AlphaNumericMap, UUIDMap = alphaNumericMap, uuidMap }()
+
+	dir, err := ioutil.TempDir("", "gonymizer-consistency")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mappings.bin")
+	key := bytes.Repeat([]byte{7}, 32)
+
+	Consistency().Reset()
+	AlphaNumericMap["public.users.id"] = map[string]string{"abc": "xyz"}
+	require.Nil(t, Consistency().Save(path, key))
+
+	Consistency().Reset()
+	require.Empty(t, AlphaNumericMap)
+	require.Nil(t, Consistency().Load(path, key))
+	require.Equal(t, "xyz", AlphaNumericMap["public.users.id"]["abc"])
+}
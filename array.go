@@ -0,0 +1,136 @@
+package gonymizer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// errInvalidArray is returned when a value is not a valid PostgreSQL array literal.
+var errInvalidArray = errors.New("Invalid array literal")
+
+// mapPGArray calls fn for every element of the PostgreSQL array literal ({a,"b c",NULL} or multi-dimensional arrays
+// such as {{1,2},{3,4}}) and returns the array with the elements replaced by the values fn returned. NULL elements are
+// kept as they are. Elements are unquoted before they are passed to fn and quoted again when needed.
+func mapPGArray(literal string, fn func(string) (string, error)) (string, error) {
+	var b strings.Builder
+
+	// Keep the dimension decoration of arrays that do not start at index 1 ([0:2]={a,b,c})
+	if strings.HasPrefix(literal, "[") {
+		equals := strings.IndexByte(literal, '=')
+		if equals < 0 {
+			return "", errInvalidArray
+		}
+		b.WriteString(literal[:equals+1])
+		literal = literal[equals+1:]
+	}
+
+	rest, err := mapPGArrayLevel(strings.TrimLeftFunc(literal, unicode.IsSpace), &b, fn)
+	if err != nil {
+		return "", err
+	}
+	if len(strings.TrimSpace(rest)) > 0 {
+		return "", errInvalidArray
+	}
+	return b.String(), nil
+}
+
+// mapPGArrayLevel maps the elements of the array (or sub-array) found at the start of literal and returns what is left
+// of literal after its closing brace.
+func mapPGArrayLevel(literal string, b *strings.Builder, fn func(string) (string, error)) (string, error) {
+	if !strings.HasPrefix(literal, "{") {
+		return "", errInvalidArray
+	}
+	b.WriteByte('{')
+	rest := strings.TrimLeftFunc(literal[1:], unicode.IsSpace)
+	if strings.HasPrefix(rest, "}") {
+		b.WriteByte('}')
+		return rest[1:], nil
+	}
+
+	for {
+		var err error
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		switch {
+		case strings.HasPrefix(rest, "{"):
+			if rest, err = mapPGArrayLevel(rest, b, fn); err != nil {
+				return "", err
+			}
+		default:
+			var (
+				element string
+				quoted  bool
+			)
+			if element, quoted, rest, err = readPGArrayElement(rest); err != nil {
+				return "", err
+			}
+			if !quoted && strings.EqualFold(element, "NULL") {
+				b.WriteString(element)
+				break
+			}
+			if element, err = fn(element); err != nil {
+				return "", err
+			}
+			b.WriteString(quotePGArrayElement(element))
+		}
+
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		switch {
+		case strings.HasPrefix(rest, ","):
+			b.WriteByte(',')
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "}"):
+			b.WriteByte('}')
+			return rest[1:], nil
+		default:
+			return "", errInvalidArray
+		}
+	}
+}
+
+// readPGArrayElement reads a quoted ("a \"b\"") or unquoted (abc) array element found at the start of literal and
+// returns the unescaped element, whether it was quoted, and the rest of literal.
+func readPGArrayElement(literal string) (string, bool, string, error) {
+	var b strings.Builder
+
+	quoted := strings.HasPrefix(literal, `"`)
+	if quoted {
+		literal = literal[1:]
+	}
+	for i := 0; i < len(literal); i++ {
+		c := literal[i]
+		switch {
+		case c == '\\':
+			if i++; i == len(literal) {
+				return "", false, "", errInvalidArray
+			}
+			b.WriteByte(literal[i])
+		case quoted && c == '"':
+			return b.String(), true, literal[i+1:], nil
+		case !quoted && (c == ',' || c == '}'):
+			return strings.TrimRightFunc(b.String(), unicode.IsSpace), false, literal[i:], nil
+		case !quoted && (c == '{' || c == '"'):
+			return "", false, "", errInvalidArray
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", false, "", errInvalidArray
+}
+
+// quotePGArrayElement returns the element quoted and escaped if it can not be written as an unquoted array element.
+func quotePGArrayElement(element string) string {
+	if len(element) > 0 && !strings.EqualFold(element, "NULL") && !strings.ContainsAny(element, "{}\",\\ \t\n\r\v\f") {
+		return element
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(element) + `"`
+}
+
+// validateArrayWrapper checks that an ArrayWrapper processor definition has inner processors.
+func (procDef ProcessorDefinition) validateArrayWrapper() error {
+	if procDef.Name == "ArrayWrapper" && len(procDef.Processors) == 0 {
+		return fmt.Errorf("Expected at least one inner processor for processor %s", procDef.Name)
+	}
+	return nil
+}
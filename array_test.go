@@ -0,0 +1,60 @@
+package gonymizer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapPGArray(t *testing.T) {
+	upper := func(element string) (string, error) { return strings.ToUpper(element), nil }
+
+	var arrays = map[string]string{
+		`{}`:                            `{}`,
+		`{a,b,c}`:                       `{A,B,C}`,
+		`{ a , b }`:                     `{A,B}`,
+		`{"a b","c\"d",NULL,"null"}`:    `{"A B","C\"D",NULL,"NULL"}`,
+		`{{a,b},{c,NULL}}`:              `{{A,B},{C,NULL}}`,
+		`[0:1]={x,y}`:                   `[0:1]={X,Y}`,
+		`{"back\\slash","{braces}",""}`: `{"BACK\\SLASH","{BRACES}",""}`,
+	}
+	for input, expected := range arrays {
+		output, err := mapPGArray(input, upper)
+		require.Nil(t, err, input)
+		require.Equal(t, expected, output, input)
+	}
+
+	for _, input := range []string{"", "abc", "{a,b", "{a}b", `{"a}`, "{a{b}}", "[1:2]{a,b}", `{a\`} {
+		_, err := mapPGArray(input, upper)
+		require.Equal(t, errInvalidArray, err, input)
+	}
+
+	require.Equal(t, `""`, quotePGArrayElement(""))
+	require.Equal(t, `"NULL"`, quotePGArrayElement("NULL"))
+	require.Equal(t, `"a,b"`, quotePGArrayElement("a,b"))
+	require.Equal(t, "abc", quotePGArrayElement("abc"))
+}
+
+func TestProcessorArrayWrapper(t *testing.T) {
+	arrayTest := ColumnMapper{
+		Processors: []ProcessorDefinition{{Name: "ArrayWrapper", Processors: []ProcessorDefinition{
+			{Name: "ScrubString"},
+		}}},
+	}
+
+	// COPY escaped: {"a\\b",secret,NULL}
+	output, err := ProcessorArrayWrapper(&arrayTest, `{"a\\\\b",secret,NULL}`)
+	require.Nil(t, err)
+	require.Equal(t, `{***,******,NULL}`, output)
+
+	output, err = ProcessorArrayWrapper(&arrayTest, `{"two words"}`)
+	require.Nil(t, err)
+	require.Equal(t, `{*********}`, output)
+
+	_, err = ProcessorArrayWrapper(&arrayTest, "not an array")
+	require.NotNil(t, err)
+
+	require.Nil(t, arrayTest.Processors[0].validateArrayWrapper())
+	require.NotNil(t, ProcessorDefinition{Name: "ArrayWrapper"}.validateArrayWrapper())
+}
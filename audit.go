@@ -0,0 +1,279 @@
+package gonymizer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// minAuditLength is the length below which unchanged values are not reported. Short values (booleans, single digits)
+// are often legitimately replaced by the same value.
+const minAuditLength = 3
+
+// maxAuditLines is the number of line numbers kept for every column in an AuditReport.
+const maxAuditLines = 10
+
+// reprocessAttempts is the number of times a value that was not changed by its processors is processed again. It is
+// set from ProcessOptions.ReprocessUnchanged for every call to ProcessDumpFileWithOptions.
+var reprocessAttempts int
+
+// addedIdentities are the mappings of a value to itself added to the mapping store or the processor cache since the
+// current value started processing. They are only kept while reprocessAttempts is set.
+var addedIdentities []identityMapping
+
+// identityMapping is a mapping of a value to itself in a namespace of the mapping store, or an entry of the processor
+// cache when namespace is empty.
+type identityMapping struct {
+	namespace string
+	key       string
+}
+
+// AuditColumn contains the number of values of an anonymized column that were checked and the number that were
+// written to the processed dump file unchanged. Lines contains the first line numbers (in the processed dump file) of
+// the unchanged values.
+type AuditColumn struct {
+	Column    string
+	Checked   int64
+	Unchanged int64
+	Lines     []int64
+}
+
+// AuditPII contains the number of values of a column that is not anonymized (Identity, no processors, or missing from
+// the map file) that match a PII pattern in the processed dump file. Lines contains the first line numbers of the
+// matching values.
+type AuditPII struct {
+	Column  string
+	Pattern string
+	Matches int64
+	Lines   []int64
+}
+
+// AuditReport is the result of checking a processed dump file for values that were not anonymized. IdentityMappings
+// lists the consistency store entries (AlphaNumericMap and UUIDMap) that map a value to itself. PII lists the columns
+// that are not anonymized but hold values matching PII patterns (see MatchPIIPatterns). SkippedTables lists the tables
+// whose rows are removed by a row filter or redaction, so their values could not be compared to the source dump file.
+type AuditReport struct {
+	Columns          []AuditColumn
+	Unchanged        int64
+	IdentityMappings []string
+	PII              []AuditPII `json:",omitempty"`
+	SkippedTables    []string   `json:",omitempty"`
+}
+
+// AuditDumpFiles compares the processed dump file to the source dump file and reports every value of an anonymized
+// column (not using the Identity processor) that was written unchanged, for example when a faker happens to pick the
+// original value. NULL values and values shorter than minAuditLength are skipped. Rows are matched by their position
+// in the table and columns by their name, so the processed dump file may be sampled (see ProcessOptions.SampleRows),
+// leave out tables or generated columns, but it must not be a shard split by row ranges (see ShardPlan). Tables with
+// a row filter or a dropping row redaction (see DBMapper.removesRows) are not compared and are listed in
+// SkippedTables. Values of columns that are not anonymized are matched against the PII patterns.
+func AuditDumpFiles(mapper *DBMapper, src, dst string) (*AuditReport, error) {
+	srcRows, err := openDumpRows(src)
+	if err != nil {
+		return nil, err
+	}
+	defer srcRows.close()
+	dstRows, err := openDumpRows(dst)
+	if err != nil {
+		return nil, err
+	}
+	defer dstRows.close()
+
+	columns := map[string]*AuditColumn{}
+	pii := map[string]*AuditPII{}
+	skipped := map[string]bool{}
+	report := new(AuditReport)
+	for {
+		dstValues, err := dstRows.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		table := fmt.Sprintf("%s.%s", dstRows.state.SchemaName, dstRows.state.TableName)
+		compare := !mapper.removesRows(dstRows.state.SchemaName, dstRows.state.TableName)
+		if !compare && !skipped[table] {
+			log.Warnf("Not comparing %s to the source dump file, its rows are removed by a row filter or redaction",
+				table)
+			skipped[table] = true
+			report.SkippedTables = append(report.SkippedTables, table)
+		}
+
+		// Find the same row in the source dump file
+		var srcValues []string
+		for compare && srcValues == nil || srcRows.state.SchemaName+"."+srcRows.state.TableName != table ||
+			srcRows.state.RowNumber != dstRows.state.RowNumber {
+			if srcValues, err = srcRows.next(); err == io.EOF {
+				return nil, fmt.Errorf("Row %d of %s (line %d of %s) was not found in %s", dstRows.state.RowNumber,
+					table, dstRows.state.LineNum, dst, src)
+			} else if err != nil {
+				return nil, err
+			}
+		}
+
+		for i, columnName := range dstRows.state.ColumnNames {
+			cmap := mapper.ColumnMapper(dstRows.state.SchemaName, dstRows.state.TableName, columnName)
+			if (cmap == nil || !anonymizes(*cmap)) && dstValues[i] != "\\N" {
+				for _, pattern := range MatchPIIPatterns(unescapeCopyValue(dstValues[i])) {
+					key := fmt.Sprintf("%s.%s: %s", table, columnName, pattern)
+					if pii[key] == nil {
+						pii[key] = &AuditPII{Column: fmt.Sprintf("%s.%s", table, columnName), Pattern: pattern}
+					}
+					pii[key].Matches++
+					if len(pii[key].Lines) < maxAuditLines {
+						pii[key].Lines = append(pii[key].Lines, dstRows.state.LineNum)
+					}
+				}
+			}
+			if !compare || cmap == nil || len(cmap.processorDefinition("Identity").Name) > 0 {
+				continue
+			}
+			srcValue, ok := srcRows.value(srcValues, columnName)
+			if !ok || srcValue == "\\N" || len(srcValue) < minAuditLength {
+				continue
+			}
+
+			key := fmt.Sprintf("%s.%s", table, columnName)
+			column, ok := columns[key]
+			if !ok {
+				column = &AuditColumn{Column: key}
+				columns[key] = column
+			}
+			column.Checked++
+			if dstValues[i] == srcValue {
+				column.Unchanged++
+				report.Unchanged++
+				if len(column.Lines) < maxAuditLines {
+					column.Lines = append(column.Lines, dstRows.state.LineNum)
+				}
+			}
+		}
+	}
+
+	for _, column := range columns {
+		report.Columns = append(report.Columns, *column)
+	}
+	sort.Slice(report.Columns, func(i, j int) bool { return report.Columns[i].Column < report.Columns[j].Column })
+	for _, match := range pii {
+		report.PII = append(report.PII, *match)
+	}
+	sort.Slice(report.PII, func(i, j int) bool {
+		if report.PII[i].Column != report.PII[j].Column {
+			return report.PII[i].Column < report.PII[j].Column
+		}
+		return report.PII[i].Pattern < report.PII[j].Pattern
+	})
+	sort.Strings(report.SkippedTables)
+	report.IdentityMappings = identityMappings()
+	return report, nil
+}
+
+// WriteAuditReport will save the audit report to filepath as JSON.
+func WriteAuditReport(report *AuditReport, filepath string) error {
+	return writeJSONFile(filepath, report)
+}
+
+// identityMappings returns the entries of the consistency stores that map a value to itself.
+func identityMappings() []string {
+	var mappings []string
+	for key, values := range AlphaNumericMap {
+		for input, output := range values {
+			if input == output && len(input) >= minAuditLength {
+				mappings = append(mappings, fmt.Sprintf("%s: %s", key, input))
+			}
+		}
+	}
+	for input, output := range UUIDMap {
+		if input == output {
+			mappings = append(mappings, fmt.Sprintf("UUID: %s", input))
+		}
+	}
+	sort.Strings(mappings)
+	return mappings
+}
+
+// unchangedValue returns true if the processors of cmap returned the input unchanged and the column is not
+// intentionally left as-is (Identity).
+func unchangedValue(cmap *ColumnMapper, input, output string) bool {
+	return output == input && input != "\\N" && len(input) >= minAuditLength &&
+		len(cmap.processorDefinition("Identity").Name) == 0
+}
+
+// forgetIdentityMappings removes the mappings of a value to itself added since the current value started processing
+// (see addedIdentities), so processing the value again does not find them and draws a new fake value.
+func forgetIdentityMappings() error {
+	defer func() { addedIdentities = addedIdentities[:0] }()
+	for _, mapping := range addedIdentities {
+		if len(mapping.namespace) == 0 {
+			processorCache.remove(mapping.key)
+			continue
+		}
+		if err := mappingStore.Delete(mapping.namespace, mapping.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dumpRows reads the rows of a dump file one at a time.
+type dumpRows struct {
+	file    *os.File
+	scanner *dumpScanner
+	state   *LineState
+	eof     bool
+}
+
+// openDumpRows opens the dump file found at path.
+func openDumpRows(path string) (*dumpRows, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &dumpRows{file: f, scanner: newDumpScanner(f), state: new(LineState)}, nil
+}
+
+// next returns the values of the next row. The table and row number of the row are found in state. io.EOF is returned
+// after the last row.
+func (r *dumpRows) next() ([]string, error) {
+	for !r.eof {
+		line, err := r.scanner.next()
+		if err == io.EOF {
+			r.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+		r.state.LineNum++
+
+		trimmed := strings.TrimLeft(string(line), " \t\r\n")
+		switch {
+		case !r.state.IsRow && strings.HasPrefix(trimmed, StateChangeTokenBeginCopy):
+			r.state.parseCopyLine(string(line))
+		case r.state.IsRow && strings.HasPrefix(trimmed, StateChangeTokenEndCopy):
+			r.state.Clear()
+		case r.state.IsRow && len(trimmed) > 0:
+			r.state.RowNumber++
+			return strings.Split(strings.TrimSuffix(string(line), "\n"), "\t"), nil
+		}
+	}
+	return nil, io.EOF
+}
+
+// value returns the value of the named column of the row values returned by next, and false if the table of the row
+// has no such column.
+func (r *dumpRows) value(values []string, column string) (string, bool) {
+	for i, name := range r.state.ColumnNames {
+		if name == column && i < len(values) {
+			return values[i], true
+		}
+	}
+	return "", false
+}
+
+// close closes the dump file.
+func (r *dumpRows) close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,155 @@
+package gonymizer
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestAuditFile writes contents to a temporary file and returns the path to the file.
+func writeTestAuditFile(t *testing.T, contents string) string {
+	f, err := ioutil.TempFile("", "gonymizer-audit-*.sql")
+	require.Nil(t, err)
+	_, err = f.WriteString(contents)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+	return f.Name()
+}
+
+func TestAuditDumpFiles(t *testing.T) {
+	mapper := &DBMapper{
+		DBName: "pii_localtest",
+		ColumnMaps: []ColumnMapper{
+			{
+				TableSchema: "public",
+				TableName:   "users",
+				ColumnName:  "name",
+				Processors:  []ProcessorDefinition{{Name: "FakeFirstName"}},
+			},
+			{
+				TableSchema: "public",
+				TableName:   "users",
+				ColumnName:  "country",
+				Processors:  []ProcessorDefinition{{Name: "Identity"}},
+			},
+		},
+	}
+
+	src := writeTestAuditFile(t, "COPY public.users (id, name, country) FROM stdin;\n"+
+		"1\tJane\tUS\n2\tJohn\tCanada\n3\t\\N\tMexico\n4\tAl\tPeru\n\\.\n")
+	defer os.Remove(src)
+	dst := writeTestAuditFile(t, "COPY public.users (id, name, country) FROM stdin;\n"+
+		"1\tMary\tUS\n2\tJohn\tCanada\n3\t\\N\tMexico\n4\tAl\tPeru\n\\.\n")
+	defer os.Remove(dst)
+
+	report, err := AuditDumpFiles(mapper, src, dst)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), report.Unchanged)
+	require.Equal(t, []AuditColumn{{Column: "public.users.name", Checked: 2, Unchanged: 1, Lines: []int64{3}}},
+		report.Columns)
+	require.Nil(t, report.PII)
+
+	// PII left in columns that are not anonymized
+	leaky := writeTestAuditFile(t, "COPY public.users (id, name, country) FROM stdin;\n"+
+		"1\tMary\tjane@example.com\n2\tJohn\tCanada\n3\t\\N\tMexico\n4\tAl\t123-45-6789\n\\.\n")
+	defer os.Remove(leaky)
+	report, err = AuditDumpFiles(mapper, src, leaky)
+	require.Nil(t, err)
+	require.Equal(t, []AuditPII{
+		{Column: "public.users.country", Pattern: "email", Matches: 1, Lines: []int64{2}},
+		{Column: "public.users.country", Pattern: "ssn_us", Matches: 1, Lines: []int64{5}},
+	}, report.PII)
+
+	// Sampled output
+	sampled := writeTestAuditFile(t, "COPY public.users (id, name, country) FROM stdin;\n1\tMary\tUS\n\\.\n")
+	defer os.Remove(sampled)
+	report, err = AuditDumpFiles(mapper, src, sampled)
+	require.Nil(t, err)
+	require.Equal(t, int64(0), report.Unchanged)
+
+	// Generated columns left out of the processed dump file
+	withGenerated := writeTestAuditFile(t, "COPY public.users (id, full_name, country, name) FROM stdin;\n"+
+		"1\tJane Doe\tUS\tJane\n2\tJohn Doe\tCanada\tJohn\n\\.\n")
+	defer os.Remove(withGenerated)
+	generated := writeTestAuditFile(t, "COPY public.users (id, country, name) FROM stdin;\n"+
+		"1\tUS\tMary\n2\tCanada\tJohn\n\\.\n")
+	defer os.Remove(generated)
+	report, err = AuditDumpFiles(mapper, withGenerated, generated)
+	require.Nil(t, err)
+	require.Equal(t, []AuditColumn{{Column: "public.users.name", Checked: 2, Unchanged: 1, Lines: []int64{3}}},
+		report.Columns)
+
+	// Tables with rows removed by a row filter
+	filtered := *mapper
+	filtered.RowFilters = []RowFilter{{TableSchema: "public", TableName: "users", SampleRate: 0.5}}
+	report, err = AuditDumpFiles(&filtered, src, dst)
+	require.Nil(t, err)
+	require.Equal(t, int64(0), report.Unchanged)
+	require.Nil(t, report.Columns)
+	require.Equal(t, []string{"public.users"}, report.SkippedTables)
+
+	// Tables missing from the source
+	other := writeTestAuditFile(t, "COPY public.orders (id) FROM stdin;\n1\n\\.\n")
+	defer os.Remove(other)
+	_, err = AuditDumpFiles(mapper, src, other)
+	require.NotNil(t, err)
+}
+
+func TestUnchangedValue(t *testing.T) {
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{{Name: "FakeFirstName"}}}
+	require.True(t, unchangedValue(cmap, "Jane", "Jane"))
+	require.False(t, unchangedValue(cmap, "Jane", "Mary"))
+	require.False(t, unchangedValue(cmap, "\\N", "\\N"))
+	require.False(t, unchangedValue(cmap, "Al", "Al"))
+
+	cmap.Processors[0].Name = "Identity"
+	require.False(t, unchangedValue(cmap, "Jane", "Jane"))
+}
+
+func TestReprocessUnchangedParent(t *testing.T) {
+	alphaNumericMap := AlphaNumericMap
+	defer func() { AlphaNumericMap = alphaNumericMap }()
+	Consistency().Reset()
+
+	// The first fake value drawn is the original value
+	calls := 0
+	ProcessorCatalog["TestFirstUnchanged"] = func(cmap *ColumnMapper, input string) (string, error) {
+		return consistentValue(cmap, input, func(input string) string {
+			calls++
+			if calls == 1 {
+				return input
+			}
+			return strings.ToUpper(input)
+		}), nil
+	}
+	defer delete(ProcessorCatalog, "TestFirstUnchanged")
+
+	mapper := &DBMapper{
+		ColumnMaps: []ColumnMapper{{
+			TableSchema:  "public",
+			TableName:    "orders",
+			ColumnName:   "user_name",
+			ParentSchema: "public",
+			ParentTable:  "users",
+			ParentColumn: "name",
+			Processors:   []ProcessorDefinition{{Name: "TestFirstUnchanged", Cache: true}},
+		}},
+	}
+	state := &LineState{IsRow: true, SchemaName: "public", TableName: "orders", ColumnNames: []string{"user_name"}}
+	processorCache = newLRUCache(10)
+	reprocessAttempts = 1
+	defer func() { processorCache, reprocessAttempts = nil, 0 }()
+
+	// The mapping of the value to itself is removed from the store and the processor cache before the retry
+	_, output, err := processRow(mapper, state, "jane\n")
+	require.Nil(t, err)
+	require.Equal(t, "JANE\n", output)
+	require.Equal(t, "JANE", AlphaNumericMap["public.users.name"]["jane"])
+	_, output, err = processRow(mapper, state, "jane\n")
+	require.Nil(t, err)
+	require.Equal(t, "JANE\n", output)
+	require.Equal(t, 2, calls)
+}
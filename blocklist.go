@@ -0,0 +1,90 @@
+package gonymizer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// nameBlocklist contains the normalized names of real people (executives, employees, known customers) that fake full
+// names must never match.
+var nameBlocklist = map[string]bool{}
+
+// nameAffixes are titles and suffixes that are ignored when comparing names.
+var nameAffixes = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "miss": true, "dr": true, "prof": true,
+	"jr": true, "sr": true, "ii": true, "iii": true, "iv": true, "md": true, "phd": true, "dds": true,
+}
+
+// LoadNameBlocklist will load a list of real names (one per line, "First Last" or "Last, First") that fake full names
+// must never match. Empty lines and lines starting with # are skipped. The names are added to the names that are
+// already loaded.
+func LoadNameBlocklist(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Error("Failure to open file: ", err)
+		log.Error("path: ", path)
+		return err
+	}
+	defer f.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if name := normalizeName(line); len(name) > 0 {
+			nameBlocklist[name] = true
+			count++
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+
+	log.Infof("Loaded %d blocked names from: %s", count, path)
+	return nil
+}
+
+// normalizeName returns the name in the form stored in the name blocklist: lower case first and last names separated by
+// a single space. "Last, First" is reordered, and punctuation, initials, titles, and suffixes are removed so "Dr. Jane
+// Q. Doe-Smith, PhD" and "doe smith, jane" match.
+func normalizeName(name string) string {
+	name = strings.ToLower(name)
+	if parts := strings.Split(name, ","); len(parts) == 2 && !nameAffixes[strings.Trim(strings.TrimSpace(parts[1]), ".")] {
+		name = parts[1] + " " + parts[0]
+	}
+
+	var words []string
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		word = strings.Trim(word, "'")
+		if len([]rune(word)) > 1 && !nameAffixes[word] {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// blockedName returns true if the name is on the name blocklist.
+func blockedName(name string) bool {
+	return len(nameBlocklist) > 0 && nameBlocklist[normalizeName(name)]
+}
+
+// notBlockedName calls generate until it returns a name that is not on the name blocklist.
+func notBlockedName(generate func() string) (string, error) {
+	for i := 0; i < maxSuppressionAttempts; i++ {
+		if name := generate(); !blockedName(name) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("Unable to generate a name that is not on the name blocklist after %d attempts",
+		maxSuppressionAttempts)
+}
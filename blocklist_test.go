@@ -0,0 +1,37 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadNameBlocklist(t *testing.T) {
+	defer func() { nameBlocklist = map[string]bool{} }()
+
+	require.Nil(t, LoadNameBlocklist(TestNameBlocklistFile))
+	require.Len(t, nameBlocklist, 3)
+	require.True(t, blockedName("JANE DOE"))
+	require.True(t, blockedName("Mr. John Smith Jr."))
+	require.True(t, blockedName("Lovelace Byron, Ada"))
+	require.False(t, blockedName("Jane Smith"))
+
+	require.NotNil(t, LoadNameBlocklist("testing/missing.txt"))
+}
+
+func TestNotBlockedName(t *testing.T) {
+	nameBlocklist = map[string]bool{"jane doe": true}
+	defer func() { nameBlocklist = map[string]bool{} }()
+
+	names := []string{"Jane Doe", "Mrs. Jane Doe", "Jane Roe"}
+	name, err := notBlockedName(func() string {
+		name := names[0]
+		names = names[1:]
+		return name
+	})
+	require.Nil(t, err)
+	require.Equal(t, "Jane Roe", name)
+
+	_, err = notBlockedName(func() string { return "Jane Doe" })
+	require.NotNil(t, err)
+}
@@ -0,0 +1,112 @@
+package gonymizer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errBucketOrder is returned when the Boundaries of a Bucket processor definition are not in ascending order.
+var errBucketOrder = errors.New("Expected the Boundaries of processor Bucket in ascending order")
+
+// bucket returns the lower bound of the bucket the number or ISO 8601 date (see timestampRegex) falls into, or its
+// label ([lower, upper) or lower+ for the last bucket) if label is set.
+func (procDef ProcessorDefinition) bucket(input string, label bool) (string, error) {
+	input = strings.TrimSpace(input)
+	if procDef.dateBuckets() {
+		match := timestampRegex.FindStringSubmatch(input)
+		if match == nil {
+			return "", fmt.Errorf("Date format is not ISO-8601: %q", input)
+		}
+		input = match[1]
+	}
+	return procDef.bucketBounds(input, label)
+}
+
+// dateBuckets returns true if the Boundaries of the processor definition are dates.
+func (procDef ProcessorDefinition) dateBuckets() bool {
+	if procDef.Width > 0 || len(procDef.Boundaries) == 0 {
+		return false
+	}
+	_, err := time.Parse("2006-01-02", procDef.Boundaries[0])
+	return err == nil
+}
+
+// parseBucketValue parses a value or boundary as a number, or as the Unix time of a date if the Boundaries are dates.
+func (procDef ProcessorDefinition) parseBucketValue(value string) (float64, error) {
+	if procDef.dateBuckets() {
+		date, err := time.Parse("2006-01-02", value)
+		return float64(date.Unix()), err
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// bucketBounds returns the lower bound (or label) of the bucket of the value. Buckets are Width wide starting at Min,
+// or start at every one of the Boundaries.
+func (procDef ProcessorDefinition) bucketBounds(value string, label bool) (string, error) {
+	number, err := procDef.parseBucketValue(value)
+	if err != nil {
+		return "", fmt.Errorf("Expected a number or an ISO-8601 date: %q", value)
+	}
+
+	if procDef.Width > 0 {
+		lower := procDef.Min + math.Floor((number-procDef.Min)/procDef.Width)*procDef.Width
+		if !label {
+			return formatBucketBound(lower), nil
+		}
+		return "[" + formatBucketBound(lower) + ", " + formatBucketBound(lower+procDef.Width) + ")", nil
+	}
+
+	i := -1
+	for j, boundary := range procDef.Boundaries {
+		bound, err := procDef.parseBucketValue(boundary)
+		if err != nil {
+			return "", fmt.Errorf("Invalid boundary %q: %s", boundary, err)
+		}
+		if number < bound {
+			break
+		}
+		i = j
+	}
+	switch {
+	case i < 0:
+		return "", fmt.Errorf("Value %s is below the first boundary %s", value, procDef.Boundaries[0])
+	case !label:
+		return procDef.Boundaries[i], nil
+	case i == len(procDef.Boundaries)-1:
+		return procDef.Boundaries[i] + "+", nil
+	}
+	return "[" + procDef.Boundaries[i] + ", " + procDef.Boundaries[i+1] + ")", nil
+}
+
+// formatBucketBound formats the bound of a bucket of Width without trailing zeros.
+func formatBucketBound(bound float64) string {
+	return strconv.FormatFloat(bound, 'f', -1, 64)
+}
+
+// validateBucket checks that a Bucket processor definition has a positive Width or ascending Boundaries that are all
+// numbers or all ISO 8601 dates.
+func (procDef ProcessorDefinition) validateBucket() error {
+	if procDef.Name != "Bucket" {
+		return nil
+	}
+	if procDef.Width < 0 || (procDef.Width > 0) == (len(procDef.Boundaries) > 0) {
+		return fmt.Errorf("Expected either a positive Width or Boundaries for processor %s", procDef.Name)
+	}
+
+	var previous float64
+	for i, boundary := range procDef.Boundaries {
+		bound, err := procDef.parseBucketValue(boundary)
+		if err != nil {
+			return fmt.Errorf("Invalid boundary %q for processor %s", boundary, procDef.Name)
+		}
+		if i > 0 && bound <= previous {
+			return errBucketOrder
+		}
+		previous = bound
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorBucket(t *testing.T) {
+	bucket := func(dataType string, procDef ProcessorDefinition) *ColumnMapper {
+		procDef.Name = "Bucket"
+		return &ColumnMapper{DataType: dataType, Processors: []ProcessorDefinition{procDef}}
+	}
+	ages := bucket("integer", ProcessorDefinition{Width: 5})
+	salaries := bucket("numeric", ProcessorDefinition{Width: 10000, Min: 5000})
+	groups := bucket("text", ProcessorDefinition{Boundaries: []string{"0", "18", "30", "65"}})
+	dates := bucket("date", ProcessorDefinition{Boundaries: []string{"2000-01-01", "2010-01-01", "2020-01-01"}})
+	periods := bucket("character varying", ProcessorDefinition{Boundaries: []string{"2000-01-01", "2010-01-01"}})
+
+	for _, tst := range []struct {
+		cmap          *ColumnMapper
+		input, output string
+	}{
+		{ages, "0", "0"},
+		{ages, "34", "30"},
+		{ages, "35", "35"},
+		{salaries, "72500.50", "65000"},
+		{salaries, "4999", "-5000"},
+		{groups, "17", "[0, 18)"},
+		{groups, "18", "[18, 30)"},
+		{groups, "97", "65+"},
+		{dates, "2009-12-31", "2000-01-01"},
+		{dates, "2015-06-01 13:45:00+02", "2010-01-01"},
+		{dates, "2024-02-29", "2020-01-01"},
+		{periods, "2005-05-05", "[2000-01-01, 2010-01-01)"},
+		{periods, "2011-01-01", "2010-01-01+"},
+		{bucket("text", ProcessorDefinition{Width: 2.5}), "6", "[5, 7.5)"},
+	} {
+		output, err := ProcessorBucket(tst.cmap, tst.input)
+		require.Nil(t, err)
+		require.Equal(t, tst.output, output, tst.input)
+	}
+
+	for _, tst := range []struct {
+		cmap  *ColumnMapper
+		input string
+	}{
+		{ages, "forty"},
+		{groups, "-1"},
+		{dates, "1999-12-31"},
+		{dates, "31/12/2005"},
+	} {
+		_, err := ProcessorBucket(tst.cmap, tst.input)
+		require.NotNil(t, err, tst.input)
+	}
+}
+
+func TestValidateBucket(t *testing.T) {
+	for _, procDef := range []ProcessorDefinition{
+		{Name: "Bucket", Width: 5},
+		{Name: "Bucket", Boundaries: []string{"0", "18.5", "65"}},
+		{Name: "Bucket", Boundaries: []string{"2000-01-01", "2010-01-01"}},
+		{Name: "Identity"},
+	} {
+		require.Nil(t, procDef.validateBucket())
+	}
+	for _, procDef := range []ProcessorDefinition{
+		{Name: "Bucket"},
+		{Name: "Bucket", Width: -5},
+		{Name: "Bucket", Width: 5, Boundaries: []string{"0"}},
+		{Name: "Bucket", Boundaries: []string{"0", "18", "18"}},
+		{Name: "Bucket", Boundaries: []string{"2010-01-01", "2000-01-01"}},
+		{Name: "Bucket", Boundaries: []string{"2000-01-01", "18"}},
+		{Name: "Bucket", Boundaries: []string{"young", "old"}},
+	} {
+		require.NotNil(t, procDef.validateBucket(), procDef.Boundaries)
+	}
+}
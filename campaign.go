@@ -0,0 +1,233 @@
+package gonymizer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Campaign processes a single PII dump file for several targets (staging, QA, analytics sandbox, ...) in one run. The
+// base map file is loaded once and every target may override columns of the base map, the seed (salt), the HMAC key,
+// and the sampling level. Targets with identical settings are only processed once and the processed file is copied.
+type Campaign struct {
+	DumpFile        string
+	MapFile         string
+	PreProcessFile  string `json:",omitempty"`
+	PostProcessFile string `json:",omitempty"`
+	BatchSize       int    `json:",omitempty"`
+	CacheSize       int    `json:",omitempty"`
+	Targets         []CampaignTarget
+}
+
+// CampaignTarget is a single output of a Campaign. MapFile is an optional map file whose columns override the columns
+// of the campaign's base map (see mergeMap). Seed overrides the seed of the map file. HMACKeyEnv is the environment
+// variable holding the secret key of the target's HMACScrambler and EmailDomainPreserving processors, which keeps the
+// key out of the campaign file. PreProcessFile and PostProcessFile override the campaign's files when set.
+type CampaignTarget struct {
+	Name            string
+	ProcessedFile   string
+	MapFile         string `json:",omitempty"`
+	Seed            int64  `json:",omitempty"`
+	HMACKeyEnv      string `json:",omitempty"`
+	SampleRows      int64  `json:",omitempty"`
+	PreProcessFile  string `json:",omitempty"`
+	PostProcessFile string `json:",omitempty"`
+}
+
+// LoadCampaign will load the campaign file found at givenPathToFile. Relative paths in the campaign are relative to the
+// directory of the campaign file.
+func LoadCampaign(givenPathToFile string) (*Campaign, error) {
+	f, err := os.Open(givenPathToFile)
+	if err != nil {
+		log.Error("Failure to open file: ", err)
+		log.Error("givenPathToFile: ", givenPathToFile)
+		return nil, err
+	}
+	defer f.Close()
+
+	campaign := new(Campaign)
+	if err = json.NewDecoder(f).Decode(campaign); err != nil {
+		log.Error(err)
+		log.Error("givenPathToFile: ", givenPathToFile)
+		return nil, err
+	}
+
+	dir := filepath.Dir(givenPathToFile)
+	for _, path := range []*string{&campaign.DumpFile, &campaign.MapFile, &campaign.PreProcessFile,
+		&campaign.PostProcessFile} {
+		*path = campaignPath(dir, *path)
+	}
+	for i := range campaign.Targets {
+		target := &campaign.Targets[i]
+		for _, path := range []*string{&target.ProcessedFile, &target.MapFile, &target.PreProcessFile,
+			&target.PostProcessFile} {
+			*path = campaignPath(dir, *path)
+		}
+	}
+
+	return campaign, campaign.Validate()
+}
+
+// Validate will check that the campaign has a dump file, a map file, and that every target has a unique name and
+// processed file.
+func (c *Campaign) Validate() error {
+	if len(c.DumpFile) == 0 {
+		return errors.New("Expected non-empty DumpFile")
+	}
+	if len(c.MapFile) == 0 {
+		return errors.New("Expected non-empty MapFile")
+	}
+	if len(c.Targets) == 0 {
+		return errors.New("Expected at least one campaign target")
+	}
+
+	names := map[string]bool{}
+	files := map[string]bool{}
+	for _, target := range c.Targets {
+		if len(target.Name) == 0 {
+			return errors.New("Expected non-empty target Name")
+		}
+		if len(target.ProcessedFile) == 0 {
+			return fmt.Errorf("Expected non-empty ProcessedFile for target: %s", target.Name)
+		}
+		if names[target.Name] {
+			return fmt.Errorf("Duplicate campaign target: %s", target.Name)
+		}
+		if files[target.ProcessedFile] {
+			return fmt.Errorf("Target %s uses the same ProcessedFile as another target: %s", target.Name,
+				target.ProcessedFile)
+		}
+		names[target.Name] = true
+		files[target.ProcessedFile] = true
+	}
+	return nil
+}
+
+// RunCampaign will process the campaign's dump file once for every target. When generateSeed is true targets without a
+// Seed use Go's crypto package to generate one. Targets which share the same map file, seed, HMAC key, sampling level,
+// and pre/post-process files as a previous target are copied from that target's processed file instead of being
+// processed again (unless the seed is generated). The consistent mappings are removed before every target is
+// processed, so the processed files of different targets cannot be joined on values mapped by a previous target.
+func RunCampaign(c *Campaign, generateSeed bool) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	log.Info("Loading base map file from: ", c.MapFile)
+	base, err := LoadConfigSkeleton(c.MapFile)
+	if err != nil {
+		return err
+	}
+
+	processed := map[CampaignTarget]string{}
+	for _, target := range c.Targets {
+		if len(target.PreProcessFile) == 0 {
+			target.PreProcessFile = c.PreProcessFile
+		}
+		if len(target.PostProcessFile) == 0 {
+			target.PostProcessFile = c.PostProcessFile
+		}
+
+		// Targets are equal when everything but their name and output file match
+		key := target
+		key.Name, key.ProcessedFile = "", ""
+		randomSeed := generateSeed && target.Seed == 0
+		if src, ok := processed[key]; ok && !randomSeed {
+			log.Infof("Target %s matches a previous target, copying %s to %s", target.Name, src, target.ProcessedFile)
+			if err = copyFile(src, target.ProcessedFile); err != nil {
+				return err
+			}
+			continue
+		}
+
+		mapper, err := campaignMapper(base, target)
+		if err != nil {
+			log.Error("Unable to create the map for target: ", target.Name)
+			return err
+		}
+
+		opts := ProcessOptions{
+			SampleRows: target.SampleRows,
+			BatchSize:  c.BatchSize,
+			CacheSize:  c.CacheSize,
+		}
+		if len(target.HMACKeyEnv) > 0 {
+			if opts.HMACKey = []byte(os.Getenv(target.HMACKeyEnv)); len(opts.HMACKey) == 0 {
+				err = fmt.Errorf("Expected the HMAC key of target %s in $%s", target.Name, target.HMACKeyEnv)
+				log.Error(err)
+				return err
+			}
+		}
+
+		log.Infof("Processing target %s: %s", target.Name, target.ProcessedFile)
+		SetMappingStore(nil)
+		Consistency().Reset()
+		err = ProcessDumpFileWithOptions(mapper, c.DumpFile, target.ProcessedFile, target.PreProcessFile,
+			target.PostProcessFile, randomSeed, opts)
+		if err != nil {
+			log.Error("Unable to process target: ", target.Name)
+			return err
+		}
+		processed[key] = target.ProcessedFile
+	}
+	return nil
+}
+
+// campaignMapper returns the map for the target. The target's map file (if any) is merged on top of the base map and
+// the target's seed replaces the seed of the map.
+func campaignMapper(base *DBMapper, target CampaignTarget) (*DBMapper, error) {
+	mapper := *base
+	if len(target.MapFile) > 0 {
+		log.Info("Loading map file overrides from: ", target.MapFile)
+		override, err := readMapFile(target.MapFile, map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+		override.mergeMap(base)
+		if err = override.Validate(); err != nil {
+			return nil, err
+		}
+		mapper = *override
+	}
+
+	if target.Seed != 0 {
+		mapper.Seed = target.Seed
+	}
+	return &mapper, nil
+}
+
+// campaignPath returns path relative to dir unless it is empty or absolute.
+func campaignPath(dir, path string) string {
+	if len(path) == 0 || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// copyFile will copy the file found at src to dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		log.Error(err)
+		log.Error("src: ", src)
+		return err
+	}
+	defer in.Close()
+
+	out, err := createPrivateFile(dst)
+	if err != nil {
+		log.Error(err)
+		log.Error("dst: ", dst)
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,84 @@
+package gonymizer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCampaign(t *testing.T) {
+	campaign, err := LoadCampaign(TestCampaignFile)
+	require.Nil(t, err)
+	require.Equal(t, filepath.Join("testing", "test_db.sql"), campaign.DumpFile)
+	require.Equal(t, filepath.Join("testing", "test_map.json"), campaign.MapFile)
+	require.Len(t, campaign.Targets, 3)
+	require.Equal(t, filepath.Join("testing", "test_map_include.json"), campaign.Targets[1].MapFile)
+	require.Equal(t, "", campaign.Targets[0].MapFile)
+
+	campaign.Targets[2].ProcessedFile = campaign.Targets[0].ProcessedFile
+	require.NotNil(t, campaign.Validate())
+	campaign.Targets[2].Name = campaign.Targets[0].Name
+	require.NotNil(t, campaign.Validate())
+	campaign.Targets = nil
+	require.NotNil(t, campaign.Validate())
+}
+
+func TestRunCampaign(t *testing.T) {
+	campaign, err := LoadCampaign(TestCampaignFile)
+	require.Nil(t, err)
+	for _, target := range campaign.Targets {
+		defer os.Remove(target.ProcessedFile)
+	}
+	require.Nil(t, RunCampaign(campaign, false))
+
+	staging, err := ioutil.ReadFile(campaign.Targets[0].ProcessedFile)
+	require.Nil(t, err)
+	analytics, err := ioutil.ReadFile(campaign.Targets[2].ProcessedFile)
+	require.Nil(t, err)
+	require.Equal(t, staging, analytics)
+
+	rowCounts := map[string]int{}
+	require.Nil(t, forEachDumpRow(campaign.Targets[1].ProcessedFile, func(state *LineState, values []string) error {
+		rowCounts[state.TableName]++
+		return nil
+	}))
+	require.Equal(t, map[string]int{"authors": 2, "books": 2, "distributors": 2, "purchasers": 2}, rowCounts)
+
+	// The qa overrides keep the purchasers' first names
+	mapper, err := campaignMapper(&DBMapper{Seed: 1}, campaign.Targets[1])
+	require.Nil(t, err)
+	require.Equal(t, int64(1234), mapper.Seed)
+	require.Equal(t, "Identity", mapper.ColumnMapper("public", "purchasers", "first_name").Processors[0].Name)
+}
+
+func TestRunCampaignTargetsIsolated(t *testing.T) {
+	campaign, err := LoadCampaign(TestCampaignFile)
+	require.Nil(t, err)
+	qa := campaign.Targets[1]
+	qa.SampleRows = 0
+	defer os.Remove(qa.ProcessedFile)
+	campaign.Targets = []CampaignTarget{qa}
+	require.Nil(t, RunCampaign(campaign, false))
+	alone, err := ioutil.ReadFile(qa.ProcessedFile)
+	require.Nil(t, err)
+
+	// The mappings of the staging target are not reused by the qa target
+	staging := CampaignTarget{Name: "staging", ProcessedFile: filepath.Join("testing", "output.TestCampaignIsolated.sql")}
+	defer os.Remove(staging.ProcessedFile)
+	campaign.Targets = []CampaignTarget{staging, qa}
+	require.Nil(t, RunCampaign(campaign, false))
+	after, err := ioutil.ReadFile(qa.ProcessedFile)
+	require.Nil(t, err)
+	require.Equal(t, string(alone), string(after))
+
+	// The HMAC key of a target is read from its environment variable
+	qa.HMACKeyEnv = "GONYMIZER_TEST_QA_HMAC_KEY"
+	campaign.Targets = []CampaignTarget{qa}
+	require.NotNil(t, RunCampaign(campaign, false))
+	require.Nil(t, os.Setenv(qa.HMACKeyEnv, "0123456789abcdef"))
+	defer os.Unsetenv(qa.HMACKeyEnv)
+	require.Nil(t, RunCampaign(campaign, false))
+}
@@ -0,0 +1,60 @@
+package gonymizer
+
+import (
+	"fmt"
+)
+
+// weightedCategory returns a random category of categories. Each category is picked with a probability proportional
+// to its weight of weights, or with the same probability if weights is empty.
+func weightedCategory(categories []string, weights []float64) string {
+	if len(weights) == 0 {
+		return categories[rng.Intn(len(categories))]
+	}
+
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	pick := rng.Float64() * total
+	for i, weight := range weights {
+		if pick < weight {
+			return categories[i]
+		}
+		pick -= weight
+	}
+	// Rounding may leave pick just above the last weight
+	for i := len(weights) - 1; i >= 0; i-- {
+		if weights[i] > 0 {
+			return categories[i]
+		}
+	}
+	return categories[len(categories)-1]
+}
+
+// validateCategorical checks that a Categorical processor definition has Categories and a positive Weight for each.
+func (procDef ProcessorDefinition) validateCategorical() error {
+	if procDef.Name != "Categorical" {
+		return nil
+	}
+	if len(procDef.Categories) == 0 {
+		return fmt.Errorf("Expected at least one category in Categories for processor %s", procDef.Name)
+	}
+	if len(procDef.Weights) == 0 {
+		return nil
+	}
+	if len(procDef.Weights) != len(procDef.Categories) {
+		return fmt.Errorf("Expected a weight for each of the %d Categories for processor %s, got %d",
+			len(procDef.Categories), procDef.Name, len(procDef.Weights))
+	}
+	total := 0.0
+	for _, weight := range procDef.Weights {
+		if weight < 0 {
+			return fmt.Errorf("Expected Weights of at least 0 for processor %s, got %g", procDef.Name, weight)
+		}
+		total += weight
+	}
+	if total == 0 {
+		return fmt.Errorf("Expected at least one positive weight for processor %s", procDef.Name)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorCategorical(t *testing.T) {
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{{Name: "Categorical"}}}
+	_, err := ProcessorCategorical(cmap, "F")
+	require.NotNil(t, err)
+
+	cmap.Processors[0].Categories = []string{"free", "pro", "enterprise"}
+	cmap.Processors[0].Weights = []float64{1, 0, 3}
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		output, err := ProcessorCategorical(cmap, "gold")
+		require.Nil(t, err)
+		counts[output]++
+	}
+	require.Equal(t, 0, counts["pro"])
+	require.Equal(t, 1000, counts["free"]+counts["enterprise"])
+	require.True(t, counts["enterprise"] > 2*counts["free"], counts)
+
+	// Without Weights every category is picked
+	cmap.Processors[0].Weights = nil
+	counts = map[string]int{}
+	for i := 0; i < 1000; i++ {
+		output, err := ProcessorCategorical(cmap, "gold")
+		require.Nil(t, err)
+		counts[output]++
+	}
+	require.Len(t, counts, 3)
+
+	// Columns with a parent map the same input to the same category
+	cmap.ParentSchema, cmap.ParentTable, cmap.ParentColumn = "public", "plans", "tier"
+	output, err := ProcessorCategorical(cmap, "gold")
+	require.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		again, err := ProcessorCategorical(cmap, "gold")
+		require.Nil(t, err)
+		require.Equal(t, output, again)
+	}
+}
+
+func TestValidateCategorical(t *testing.T) {
+	valid := ProcessorDefinition{Name: "Categorical", Categories: []string{"M", "F", "X"}}
+	require.Nil(t, valid.validateCategorical())
+	valid.Weights = []float64{49, 49, 2}
+	require.Nil(t, valid.validateCategorical())
+
+	require.NotNil(t, ProcessorDefinition{Name: "Categorical"}.validateCategorical())
+	require.NotNil(t, ProcessorDefinition{Name: "Categorical", Categories: []string{"M", "F"},
+		Weights: []float64{1}}.validateCategorical())
+	require.NotNil(t, ProcessorDefinition{Name: "Categorical", Categories: []string{"M", "F"},
+		Weights: []float64{1, -1}}.validateCategorical())
+	require.NotNil(t, ProcessorDefinition{Name: "Categorical", Categories: []string{"M", "F"},
+		Weights: []float64{0, 0}}.validateCategorical())
+	require.Nil(t, ProcessorDefinition{Name: "Identity"}.validateCategorical())
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/smithoss/gonymizer/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	campaignFile string
+
+	// CampaignCmd is the cobra.Command struct we use for the "campaign" command.
+	CampaignCmd = &cobra.Command{
+		Use:   "campaign",
+		Short: "Campaign will process a PostgreSQL dump file once for every target in the campaign file",
+		Run:   cliCommandCampaign,
+	}
+)
+
+// init initializes the campaign command for the application and adds application flags and options.
+func init() {
+	CampaignCmd.Flags().StringVar(
+		&campaignFile,
+		"campaign-file",
+		"",
+		"Campaign file location",
+	)
+	_ = viper.BindPFlag("campaign.campaign-file", CampaignCmd.Flags().Lookup("campaign-file"))
+
+	CampaignCmd.Flags().BoolVar(
+		&generateSeed,
+		"generate-seed",
+		false,
+		"Use Go's crypto package to generate seed values for targets that do not set a Seed",
+	)
+	_ = viper.BindPFlag("campaign.generate-seed", CampaignCmd.Flags().Lookup("generate-seed"))
+}
+
+// cliCommandCampaign is the initialization point for executing the Campaign command from the CLI and returns to the CLI
+// on exit.
+func cliCommandCampaign(cmd *cobra.Command, args []string) {
+	log.Info(aurora.Bold(aurora.Yellow(fmt.Sprint("Enabling log level: ",
+		strings.ToUpper(viper.GetString("log-level"))))))
+
+	log.Info("🚜 ", aurora.Bold(aurora.Green("Running campaign")), " 🚜")
+	err := campaign(viper.GetString("campaign.campaign-file"), viper.GetBool("campaign.generate-seed"))
+	if err != nil {
+		log.Error(err)
+		log.Error("❌ Gonymizer did not exit properly. See above for errors ❌")
+		os.Exit(1)
+	} else {
+		log.Info("🦄 ", aurora.Bold(aurora.Green("-- SUCCESS --")), " 🌈")
+	}
+}
+
+// campaign is the entry point for processing a dump file for every target in the campaign file.
+func campaign(campaignFile string, generateSeed bool) error {
+	log.Info("Loading campaign file from: ", campaignFile)
+	c, err := gonymizer.LoadCampaign(campaignFile)
+	if err != nil {
+		return err
+	}
+	return gonymizer.RunCampaign(c, generateSeed)
+}
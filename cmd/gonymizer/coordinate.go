@@ -0,0 +1,319 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/smithoss/gonymizer/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	listenAddress      string
+	shards             int
+	coordinatorTimeout time.Duration
+	reportFile         string
+	balanceShards      bool
+	coordinatorAddress string
+	workerName         string
+	useTLS             bool
+	workerMappingStore string
+	tlsCertFile        string
+	tlsKeyFile         string
+	tlsCAFile          string
+	workerLease        time.Duration
+	maxRetries         int
+
+	// CoordinateCmd is the cobra.Command struct we use for the "coordinate" command.
+	CoordinateCmd = &cobra.Command{
+		Use:   "coordinate",
+		Short: "Coordinate will split a PostgreSQL dump file into shards and assign them to workers",
+		Run:   cliCommandCoordinate,
+	}
+
+	// WorkCmd is the cobra.Command struct we use for the "work" command.
+	WorkCmd = &cobra.Command{
+		Use:   "work",
+		Short: "Work will process the shards assigned by a coordinator until there is no work left",
+		Run:   cliCommandWork,
+	}
+)
+
+// init initializes the coordinate and work commands for the application and adds application flags and options.
+func init() {
+	CoordinateCmd.Flags().StringVar(
+		&listenAddress,
+		"listen",
+		"127.0.0.1:7070",
+		"Address the coordinator listens on. Addresses other than loopback addresses require --tls-cert and --tls-key",
+	)
+	_ = viper.BindPFlag("coordinate.listen", CoordinateCmd.Flags().Lookup("listen"))
+
+	CoordinateCmd.Flags().StringVar(&tlsCertFile, "tls-cert", "", "TLS certificate file of the coordinator")
+	_ = viper.BindPFlag("coordinate.tls-cert", CoordinateCmd.Flags().Lookup("tls-cert"))
+
+	CoordinateCmd.Flags().StringVar(&tlsKeyFile, "tls-key", "", "TLS private key file of the coordinator")
+	_ = viper.BindPFlag("coordinate.tls-key", CoordinateCmd.Flags().Lookup("tls-key"))
+
+	CoordinateCmd.Flags().StringVar(&mapFile, "map-file", "", "Map file location (must be readable by the workers)")
+	_ = viper.BindPFlag("coordinate.map-file", CoordinateCmd.Flags().Lookup("map-file"))
+
+	CoordinateCmd.Flags().StringVar(
+		&dumpFile,
+		"dump-file",
+		"",
+		"Filename and location of the PII-PostgreSQL dump file (must be readable by the workers)",
+	)
+	_ = viper.BindPFlag("coordinate.dump-file", CoordinateCmd.Flags().Lookup("dump-file"))
+
+	CoordinateCmd.Flags().StringVar(
+		&processedFile,
+		"processed-file",
+		"",
+		"Filename and location of the processed dump file. Each shard is written to <name>.shard-N<ext>",
+	)
+	_ = viper.BindPFlag("coordinate.processed-file", CoordinateCmd.Flags().Lookup("processed-file"))
+
+	CoordinateCmd.Flags().StringVar(&preProcessFile, "pre-process-file", "", "SQL File to prepend to every shard")
+	_ = viper.BindPFlag("coordinate.pre-process-file", CoordinateCmd.Flags().Lookup("pre-process-file"))
+
+	CoordinateCmd.Flags().StringVar(&postProcessFile, "post-process-file", "", "SQL File to append to every shard")
+	_ = viper.BindPFlag("coordinate.post-process-file", CoordinateCmd.Flags().Lookup("post-process-file"))
+
+	CoordinateCmd.Flags().BoolVar(
+		&generateSeed,
+		"generate-seed",
+		false,
+		"Use Go's crypto package to generate seed values (instead of map file) for processors that require randomness",
+	)
+	_ = viper.BindPFlag("coordinate.generate-seed", CoordinateCmd.Flags().Lookup("generate-seed"))
+
+	CoordinateCmd.Flags().BoolVar(
+		&secureRandom,
+		"secure-random",
+		false,
+		"Use Go's crypto package (instead of a seeded generator) for processors that require randomness",
+	)
+	_ = viper.BindPFlag("coordinate.secure-random", CoordinateCmd.Flags().Lookup("secure-random"))
+
+	CoordinateCmd.Flags().StringVar(
+		&mappingStoreURI,
+		"mapping-store",
+		"",
+		"Store for the consistent mappings shared by the workers (required for more than one shard): redis://, postgres://",
+	)
+	_ = viper.BindPFlag("coordinate.mapping-store", CoordinateCmd.Flags().Lookup("mapping-store"))
+
+	CoordinateCmd.Flags().IntVar(&shards, "shards", 1, "Number of shards to split the tables of the dump file into")
+	_ = viper.BindPFlag("coordinate.shards", CoordinateCmd.Flags().Lookup("shards"))
+
+	CoordinateCmd.Flags().BoolVar(
+		&balanceShards,
+		"balance-shards",
+		false,
+		"Assign tables to shards by size and split large tables into row ranges instead of assigning them in order",
+	)
+	_ = viper.BindPFlag("coordinate.balance-shards", CoordinateCmd.Flags().Lookup("balance-shards"))
+
+	CoordinateCmd.Flags().DurationVar(
+		&coordinatorTimeout,
+		"timeout",
+		24*time.Hour,
+		"How long to wait for the workers to process every shard",
+	)
+	_ = viper.BindPFlag("coordinate.timeout", CoordinateCmd.Flags().Lookup("timeout"))
+
+	CoordinateCmd.Flags().DurationVar(
+		&workerLease,
+		"lease",
+		time.Minute,
+		"How long a worker may go without a heartbeat before its shard is assigned to another worker",
+	)
+	_ = viper.BindPFlag("coordinate.lease", CoordinateCmd.Flags().Lookup("lease"))
+
+	CoordinateCmd.Flags().IntVar(
+		&maxRetries,
+		"max-retries",
+		3,
+		"How many times a shard that failed or whose worker stopped is assigned again before the run fails",
+	)
+	_ = viper.BindPFlag("coordinate.max-retries", CoordinateCmd.Flags().Lookup("max-retries"))
+
+	CoordinateCmd.Flags().StringVar(&reportFile, "report-file", "", "Filename and location to store the merged report")
+	_ = viper.BindPFlag("coordinate.report-file", CoordinateCmd.Flags().Lookup("report-file"))
+
+	WorkCmd.Flags().StringVar(&coordinatorAddress, "coordinator", "", "Address (host:port) of the coordinator")
+	_ = viper.BindPFlag("work.coordinator", WorkCmd.Flags().Lookup("coordinator"))
+
+	WorkCmd.Flags().BoolVar(&useTLS, "tls", false, "Connect to the coordinator with TLS")
+	_ = viper.BindPFlag("work.tls", WorkCmd.Flags().Lookup("tls"))
+
+	WorkCmd.Flags().StringVar(
+		&tlsCAFile,
+		"tls-ca",
+		"",
+		"CA certificate file to verify the coordinator with (implies --tls). The system CAs are used by default",
+	)
+	_ = viper.BindPFlag("work.tls-ca", WorkCmd.Flags().Lookup("tls-ca"))
+
+	WorkCmd.Flags().StringVar(
+		&workerMappingStore,
+		"mapping-store",
+		"",
+		"Mapping store URI with credentials. Defaults to "+gonymizer.MappingStoreEnv+
+			" or the URI of the coordinator without credentials",
+	)
+	_ = viper.BindPFlag("work.mapping-store", WorkCmd.Flags().Lookup("mapping-store"))
+
+	hostname, _ := os.Hostname()
+	WorkCmd.Flags().StringVar(&workerName, "worker-name", hostname, "Name of this worker in the coordinator's logs")
+	_ = viper.BindPFlag("work.worker-name", WorkCmd.Flags().Lookup("worker-name"))
+}
+
+// cliCommandCoordinate is the initialization point for executing the Coordinate command from the CLI and returns to
+// the CLI on exit.
+func cliCommandCoordinate(cmd *cobra.Command, args []string) {
+	log.Info(aurora.Bold(aurora.Yellow(fmt.Sprint("Enabling log level: ",
+		strings.ToUpper(viper.GetString("log-level"))))))
+
+	log.Info("🚜 ", aurora.Bold(aurora.Green("Coordinating workers")), " 🚜")
+	var tlsConfig *tls.Config
+	if certFile := viper.GetString("coordinate.tls-cert"); len(certFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(certFile, viper.GetString("coordinate.tls-key"))
+		if err != nil {
+			exitOnError(err)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	}
+
+	err := coordinate(
+		viper.GetString("coordinate.listen"),
+		tlsConfig,
+		viper.GetDuration("coordinate.lease"),
+		viper.GetInt("coordinate.max-retries"),
+		viper.GetString("coordinate.report-file"),
+		viper.GetDuration("coordinate.timeout"),
+		gonymizer.CoordinatorJob{
+			DumpFile:        viper.GetString("coordinate.dump-file"),
+			MapFile:         viper.GetString("coordinate.map-file"),
+			ProcessedFile:   viper.GetString("coordinate.processed-file"),
+			PreProcessFile:  viper.GetString("coordinate.pre-process-file"),
+			PostProcessFile: viper.GetString("coordinate.post-process-file"),
+			GenerateSeed:    viper.GetBool("coordinate.generate-seed"),
+			SecureRandom:    viper.GetBool("coordinate.secure-random"),
+			MappingStore:    viper.GetString("coordinate.mapping-store"),
+			Shards:          viper.GetInt("coordinate.shards"),
+			BalanceShards:   viper.GetBool("coordinate.balance-shards"),
+		},
+	)
+	exitOnError(err)
+}
+
+// coordinate assigns the shards of the job to the workers that connect to listen and waits for them to finish. The
+// workers must present the token of CoordinatorTokenEnv.
+func coordinate(listen string, tlsConfig *tls.Config, lease time.Duration, maxRetries int, reportFile string,
+	timeout time.Duration, job gonymizer.CoordinatorJob) error {
+
+	token := os.Getenv(gonymizer.CoordinatorTokenEnv)
+	if len(token) == 0 {
+		return fmt.Errorf("Expected a token shared with the workers in %s", gonymizer.CoordinatorTokenEnv)
+	}
+	coordinator, err := gonymizer.NewCoordinator(job, gonymizer.CoordinatorOptions{
+		Token:      token,
+		Lease:      lease,
+		MaxRetries: maxRetries,
+	})
+	if err != nil {
+		return err
+	}
+
+	listener, err := gonymizer.ListenCoordinator(listen, tlsConfig)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	log.Infof("Waiting for workers on %s to process %d shard(s)", listener.Addr(), job.Shards)
+	go func() {
+		if err := coordinator.Serve(listener); err != nil {
+			log.Debug("Coordinator stopped: ", err)
+		}
+	}()
+
+	merged, err := coordinator.Wait(timeout)
+	if err != nil {
+		return err
+	}
+	log.Infof("Processed %d rows in %d tables across %d shard(s)", merged.Rows, len(merged.Tables), job.Shards)
+
+	if reportFile != "" {
+		log.Info("Writing merged report to: ", reportFile)
+		return gonymizer.WriteMergedShardReport(merged, reportFile)
+	}
+	return nil
+}
+
+// cliCommandWork is the initialization point for executing the Work command from the CLI and returns to the CLI on
+// exit.
+func cliCommandWork(cmd *cobra.Command, args []string) {
+	log.Info(aurora.Bold(aurora.Yellow(fmt.Sprint("Enabling log level: ",
+		strings.ToUpper(viper.GetString("log-level"))))))
+
+	log.Info("🚜 ", aurora.Bold(aurora.Green("Working for coordinator")), " 🚜")
+	opts, err := workerOptions(viper.GetBool("work.tls"), viper.GetString("work.tls-ca"),
+		viper.GetString("work.mapping-store"))
+	if err != nil {
+		exitOnError(err)
+	}
+	exitOnError(gonymizer.RunWorker(viper.GetString("work.coordinator"), viper.GetString("work.worker-name"), opts))
+}
+
+// workerOptions returns the options of a worker. The token is read from CoordinatorTokenEnv and the mapping store
+// defaults to MappingStoreEnv.
+func workerOptions(useTLS bool, caFile, mappingStore string) (gonymizer.WorkerOptions, error) {
+	opts := gonymizer.WorkerOptions{
+		Token:        os.Getenv(gonymizer.CoordinatorTokenEnv),
+		MappingStore: mappingStore,
+	}
+	if len(opts.Token) == 0 {
+		return opts, fmt.Errorf("Expected the token of the coordinator in %s", gonymizer.CoordinatorTokenEnv)
+	}
+	if len(opts.MappingStore) == 0 {
+		opts.MappingStore = os.Getenv(gonymizer.MappingStoreEnv)
+	}
+
+	if useTLS || len(caFile) > 0 {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if len(caFile) > 0 {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return opts, err
+		}
+		opts.TLSConfig.RootCAs = x509.NewCertPool()
+		if !opts.TLSConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return opts, errors.New("No certificates found in " + caFile)
+		}
+	}
+	return opts, nil
+}
+
+// exitOnError logs the result of a command and exits with a non-zero exit code if err is not nil.
+func exitOnError(err error) {
+	if err != nil {
+		log.Error(err)
+		log.Error("❌ Gonymizer did not exit properly. See above for errors ❌")
+		os.Exit(1)
+	}
+	log.Info("🦄 ", aurora.Bold(aurora.Green("-- SUCCESS --")), " 🌈")
+}
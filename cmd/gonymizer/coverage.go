@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/smithoss/gonymizer/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	coverageReport string
+
+	// CoverageCmd is the cobra.Command struct we use for the "coverage" command.
+	CoverageCmd = &cobra.Command{
+		Use:   "coverage",
+		Short: "Coverage will report the fraction of anonymized, Identity, and unmapped columns of every table",
+		Run:   cliCommandCoverage,
+	}
+)
+
+// init initializes the coverage command for the application and adds application flags and options.
+func init() {
+	CoverageCmd.Flags().StringVar(
+		&mapFile,
+		"map-file",
+		"",
+		"Map file location",
+	)
+	_ = viper.BindPFlag("coverage.map-file", CoverageCmd.Flags().Lookup("map-file"))
+
+	CoverageCmd.Flags().StringVar(
+		&dumpFile,
+		"dump-file",
+		"",
+		"Filename and location of the PII-PostgreSQL dump file used to find columns missing from the map file",
+	)
+	_ = viper.BindPFlag("coverage.dump-file", CoverageCmd.Flags().Lookup("dump-file"))
+
+	CoverageCmd.Flags().StringVar(
+		&coverageReport,
+		"report-file",
+		"coverage.html",
+		"Filename and location to store the report. Files ending in .html are written as a heatmap, others as JSON",
+	)
+	_ = viper.BindPFlag("coverage.report-file", CoverageCmd.Flags().Lookup("report-file"))
+}
+
+// cliCommandCoverage is the initialization point for executing the Coverage command from the CLI and returns to the
+// CLI on exit.
+func cliCommandCoverage(cmd *cobra.Command, args []string) {
+	log.Info(aurora.Bold(aurora.Yellow(fmt.Sprint("Enabling log level: ",
+		strings.ToUpper(viper.GetString("log-level"))))))
+
+	err := coverage(
+		viper.GetString("coverage.map-file"),
+		viper.GetString("coverage.dump-file"),
+		viper.GetString("coverage.report-file"),
+	)
+	if err != nil {
+		log.Error(err)
+		log.Error("❌ Gonymizer did not exit properly. See above for errors ❌")
+		os.Exit(1)
+	} else {
+		log.Info("🦄 ", aurora.Bold(aurora.Green("-- SUCCESS --")), " 🌈")
+	}
+}
+
+// coverage is the entry point for writing the map coverage report.
+func coverage(mapFile, dumpFile, reportFile string) error {
+	log.Info("Loading map file from: ", mapFile)
+	columnMap, err := gonymizer.LoadConfigSkeleton(mapFile)
+	if err != nil {
+		return err
+	}
+
+	report, err := gonymizer.MapCoverage(columnMap, dumpFile)
+	if err != nil {
+		return err
+	}
+	for _, table := range report.Tables {
+		if table.Anonymized == 0 && table.Unmapped > 0 {
+			log.Warnf("Table %s has %d unmapped column(s) and no anonymized columns", table.Table, table.Unmapped)
+		}
+	}
+	log.Info("Writing coverage report to: ", reportFile)
+	return gonymizer.WriteCoverageReport(report, reportFile)
+}
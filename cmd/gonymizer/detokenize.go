@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/smithoss/gonymizer/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	detokenizeToken  string
+	detokenizeUser   string
+	detokenizeReason string
+
+	// DetokenizeCmd is the cobra.Command struct we use for the "detokenize" command.
+	DetokenizeCmd = &cobra.Command{
+		Use:   "detokenize",
+		Short: "Detokenize will print the original value of a token created by the Tokenize processor",
+		Run:   cliCommandDetokenize,
+	}
+)
+
+// init initializes the detokenize command for the application and adds application flags and options.
+func init() {
+	DetokenizeCmd.Flags().StringVar(
+		&vaultFile,
+		"vault-file",
+		"",
+		"Vault storing the original values. The key is read from $"+gonymizer.VaultKeyEnv,
+	)
+	_ = viper.BindPFlag("detokenize.vault-file", DetokenizeCmd.Flags().Lookup("vault-file"))
+
+	DetokenizeCmd.Flags().StringVar(
+		&detokenizeToken,
+		"token",
+		"",
+		"Token to look up",
+	)
+	_ = viper.BindPFlag("detokenize.token", DetokenizeCmd.Flags().Lookup("token"))
+
+	DetokenizeCmd.Flags().StringVar(
+		&detokenizeUser,
+		"user",
+		os.Getenv("USER"),
+		"User requesting the value (recorded in the audit log)",
+	)
+	_ = viper.BindPFlag("detokenize.user", DetokenizeCmd.Flags().Lookup("user"))
+
+	DetokenizeCmd.Flags().StringVar(
+		&detokenizeReason,
+		"reason",
+		"",
+		"Reason the value is needed, e.g. a support ticket (recorded in the audit log)",
+	)
+	_ = viper.BindPFlag("detokenize.reason", DetokenizeCmd.Flags().Lookup("reason"))
+}
+
+// cliCommandDetokenize is the initialization point for executing the Detokenize command from the CLI and returns to
+// the CLI on exit. The value is printed to stdout.
+func cliCommandDetokenize(cmd *cobra.Command, args []string) {
+	value, err := detokenize(
+		viper.GetString("detokenize.vault-file"),
+		viper.GetString("detokenize.token"),
+		viper.GetString("detokenize.user"),
+		viper.GetString("detokenize.reason"),
+	)
+	if err != nil {
+		log.Error(err)
+		log.Error("❌ Gonymizer did not exit properly. See above for errors ❌")
+		os.Exit(1)
+	}
+	fmt.Println(value)
+}
+
+// detokenize is the entry point for looking up the original value of a token.
+func detokenize(vaultFile, token, user, reason string) (string, error) {
+	key, err := gonymizer.ParseVaultKey(os.Getenv(gonymizer.VaultKeyEnv))
+	if err != nil {
+		return "", err
+	}
+	if _, err = os.Stat(vaultFile); err != nil {
+		return "", err
+	}
+	vault, err := gonymizer.OpenVault(vaultFile, key)
+	if err != nil {
+		return "", err
+	}
+	defer vault.Close()
+	return vault.Detokenize(token, user, reason)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/smithoss/gonymizer/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	diffReport        string
+	diffMaxChangeRate float64
+
+	// DiffOutputCmd is the cobra.Command struct we use for the "diff-output" command.
+	DiffOutputCmd = &cobra.Command{
+		Use:   "diff-output a.sql b.sql",
+		Short: "Diff-output will compare the tables, row counts, and value changes of two processed dump files",
+		Args:  cobra.ExactArgs(2),
+		Run:   cliCommandDiffOutput,
+	}
+)
+
+// init initializes the diff-output command for the application and adds application flags and options.
+func init() {
+	DiffOutputCmd.Flags().StringVar(
+		&diffReport,
+		"report-file",
+		"",
+		"Filename and location to store the per column comparison as JSON",
+	)
+	_ = viper.BindPFlag("diff-output.report-file", DiffOutputCmd.Flags().Lookup("report-file"))
+
+	DiffOutputCmd.Flags().Float64Var(
+		&diffMaxChangeRate,
+		"max-change-rate",
+		1,
+		"Fail when the values of a column differ in more than this fraction (0 to 1) of the rows",
+	)
+	_ = viper.BindPFlag("diff-output.max-change-rate", DiffOutputCmd.Flags().Lookup("max-change-rate"))
+}
+
+// cliCommandDiffOutput is the initialization point for executing the DiffOutput command from the CLI and returns to
+// the CLI on exit.
+func cliCommandDiffOutput(cmd *cobra.Command, args []string) {
+	log.Info(aurora.Bold(aurora.Yellow(fmt.Sprint("Enabling log level: ",
+		strings.ToUpper(viper.GetString("log-level"))))))
+
+	err := diffOutput(
+		args[0],
+		args[1],
+		viper.GetString("diff-output.report-file"),
+		viper.GetFloat64("diff-output.max-change-rate"),
+	)
+	if err != nil {
+		log.Error(err)
+		log.Error("❌ Gonymizer did not exit properly. See above for errors ❌")
+		os.Exit(1)
+	} else {
+		log.Info("🦄 ", aurora.Bold(aurora.Green("-- SUCCESS --")), " 🌈")
+	}
+}
+
+// diffOutput is the entry point for comparing two processed dump files. An error is returned when the files differ.
+func diffOutput(fileA, fileB, reportFile string, maxChangeRate float64) error {
+	log.Infof("Comparing %s to %s", fileA, fileB)
+	diff, err := gonymizer.DiffOutputs(fileA, fileB)
+	if err != nil {
+		return err
+	}
+
+	if len(reportFile) > 0 {
+		log.Info("Writing diff report to: ", reportFile)
+		if err = gonymizer.WriteOutputDiff(diff, reportFile); err != nil {
+			return err
+		}
+	}
+
+	differences := diff.Differences(maxChangeRate)
+	for _, difference := range differences {
+		log.Warn(difference)
+	}
+	if len(differences) > 0 {
+		return fmt.Errorf("Found %d difference(s) between %s and %s", len(differences), fileA, fileB)
+	}
+	return nil
+}
@@ -13,7 +13,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/logrusorgru/aurora"
 	log "github.com/sirupsen/logrus"
-	"github.com/smithoss/gonymizer"
+	"github.com/smithoss/gonymizer/v2"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -189,6 +189,12 @@ func cliCommandDump(cmd *cobra.Command, args []string) {
 		viper.GetBool("dump.disable-ssl"),
 	)
 
+	started := time.Now()
+	details := map[string]string{
+		"Database": viper.GetString("dump.database"),
+		"DumpFile": viper.GetString("dump.dump-file"),
+	}
+
 	// Check to see if we need to complete row counts at the end of the dump process
 	if len(viper.GetString("dump.row-count-file")) > 1 {
 		excludeAllTables := append(
@@ -202,6 +208,7 @@ func cliCommandDump(cmd *cobra.Command, args []string) {
 			viper.GetString("dump.row-count-file"),
 			excludeAllTables)
 		if err != nil {
+			notifyRun("dump", started, err, details)
 			log.Error(err)
 			log.Error("❌ Gonymizer did not exit properly. See above for errors ❌")
 			os.Exit(1)
@@ -218,6 +225,7 @@ func cliCommandDump(cmd *cobra.Command, args []string) {
 		viper.GetStringSlice("dump.exclude-schema"),
 		viper.GetStringSlice("dump.schema"),
 	)
+	notifyRun("dump", started, err, details)
 
 	if err != nil {
 		log.Error(err)
@@ -0,0 +1,18 @@
+module github.com/smithoss/gonymizer/cmd/gonymizer/v2
+
+go 1.13
+
+require (
+	github.com/aws/aws-sdk-go v1.24.0
+	github.com/logrusorgru/aurora v0.0.0-20190428105938-cea283e61946
+	github.com/mattn/go-colorable v0.1.2 // indirect
+	github.com/mgutz/ansi v0.0.0-20170206155736-9520e82c474b // indirect
+	github.com/onsi/ginkgo v1.10.1 // indirect
+	github.com/onsi/gomega v1.7.0 // indirect
+	github.com/sirupsen/logrus v1.4.1
+	github.com/smithoss/gonymizer/v2 v2.0.0
+	github.com/spf13/cobra v0.0.5
+	github.com/spf13/viper v1.4.0
+	github.com/x-cray/logrus-prefixed-formatter v0.5.2
+	golang.org/x/crypto v0.0.0-20190506204251-e1dfcc566284
+)
@@ -9,7 +9,7 @@ import (
 
 	"github.com/logrusorgru/aurora"
 	log "github.com/sirupsen/logrus"
-	"github.com/smithoss/gonymizer"
+	"github.com/smithoss/gonymizer/v2"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -130,9 +130,16 @@ func cliCommandLoad(cmd *cobra.Command, args []string) {
 		viper.GetBool("load.disable-ssl"),
 	)
 
+	started := time.Now()
+	details := map[string]string{
+		"Database": viper.GetString("load.database"),
+		"LoadFile": viper.GetString("load.load-file"),
+	}
+
 	// Start the loading process
 	log.Info("🚜 ", aurora.Bold(aurora.Green("Loading the anonymized database")), " 🚜")
 	if err = load(dbConf, viper.GetString("load.load-file"), viper.GetString("load.s3-file-path")); err != nil {
+		notifyRun("load", started, err, details)
 		log.Error(err)
 		log.Error("❌ Gonymizer did not exit properly. See above for errors ❌")
 		os.Exit(1)
@@ -143,12 +150,14 @@ func cliCommandLoad(cmd *cobra.Command, args []string) {
 		log.Info("Loading row-counts CSV file from: ", viper.GetString("load.row-count-file"))
 		err = downloadRowCountFile(dbConf, viper.GetString("load.row-count-file"))
 		if err != nil {
+			notifyRun("load", started, err, details)
 			log.Error(err)
 			log.Error("❌ Gonymizer did not exit properly. See above for errors ❌")
 			os.Exit(1)
 		}
 	}
 
+	notifyRun("load", started, nil, details)
 	log.Info("🦄 ", aurora.Bold(aurora.Green("-- SUCCESS --")), " 🌈")
 }
 
@@ -14,7 +14,7 @@ import (
 	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/logrusorgru/aurora"
-	"github.com/smithoss/gonymizer"
+	"github.com/smithoss/gonymizer/v2"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -61,13 +61,18 @@ var (
 	excludeTable     []string
 	excludeTableData []string
 	generateSeed     bool
+	secureRandom     bool
 	loadFile         string
 	localFile        string
 	logFile          string
 	logFormat        string
 	logLevel         string
 	mapFile          string
+	mappingStoreURI  string
 	dumpFile         string
+	notifyOn         string
+	notifyTargets    []string
+	piiPatterns      string
 	postProcessFile  string
 	preProcessFile   string
 	procedures       bool
@@ -162,14 +167,48 @@ func init() {
 	)
 	_ = viper.BindPFlag("log-format", rootCmd.PersistentFlags().Lookup("log-format"))
 
+	rootCmd.PersistentFlags().StringSliceVar(
+		&notifyTargets,
+		"notify",
+		[]string{},
+		"Webhook URL, Slack webhook URL, or SNS topic ARN to send the run summary to (repeatable)",
+	)
+	_ = viper.BindPFlag("notify", rootCmd.PersistentFlags().Lookup("notify"))
+
+	rootCmd.PersistentFlags().StringVar(
+		&notifyOn,
+		"notify-on",
+		"always",
+		"When to send notifications, one of: always, failure",
+	)
+	_ = viper.BindPFlag("notify-on", rootCmd.PersistentFlags().Lookup("notify-on"))
+
+	rootCmd.PersistentFlags().StringVar(
+		&piiPatterns,
+		"pii-patterns",
+		"",
+		"JSON file of PII patterns to add to (or replace in) the built-in PII pattern library",
+	)
+	_ = viper.BindPFlag("pii-patterns", rootCmd.PersistentFlags().Lookup("pii-patterns"))
+
 	// Bind commands to root
 	rootCmd.AddCommand(
+		CampaignCmd,
+		CoordinateCmd,
+		CoverageCmd,
+		DetokenizeCmd,
+		DiffOutputCmd,
 		DumpCmd,
+		ExportMockCmd,
 		LoadCmd,
 		MapCmd,
 		ProcessCmd,
+		ReprocessCmd,
+		SimulateCmd,
+		SuggestCmd,
 		UploadCmd,
 		VersionCmd,
+		WorkCmd,
 	)
 }
 
@@ -220,6 +259,14 @@ func preRun(cmd *cobra.Command, args []string) {
 		gonymizer.BuildDate(),
 	)
 
+	// Load the PII patterns before the map file is validated, since ValueClass processors may refer to them
+	if path := viper.GetString("pii-patterns"); path != "" {
+		if err := gonymizer.LoadPIIPatterns(path); err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+	}
+
 	log.Debugf("Go (runtime: %v) (GOMAXPROCS: %d) (NumCPUs: %d)\n",
 		runtime.Version(),
 		runtime.GOMAXPROCS(-1),
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/smithoss/gonymizer/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	mockOutputFile string
+	mockResources  string
+	mockLimit      int
+
+	// ExportMockCmd is the cobra.Command struct we use for the "export-mock" command.
+	ExportMockCmd = &cobra.Command{
+		Use:   "export-mock",
+		Short: "Export-mock will write the rows of an anonymized dump file as JSON fixtures for API mocks",
+		Run:   cliCommandExportMock,
+	}
+)
+
+// init initializes the export-mock command for the application and adds application flags and options.
+func init() {
+	ExportMockCmd.Flags().StringVar(
+		&processedFile,
+		"processed-file",
+		"",
+		"Filename and location of the anonymized dump file (never the PII dump file)",
+	)
+	_ = viper.BindPFlag("export-mock.processed-file", ExportMockCmd.Flags().Lookup("processed-file"))
+
+	ExportMockCmd.Flags().StringVar(
+		&mockOutputFile,
+		"output-file",
+		"mock-data.json",
+		"Filename and location to store the JSON fixtures",
+	)
+	_ = viper.BindPFlag("export-mock.output-file", ExportMockCmd.Flags().Lookup("output-file"))
+
+	ExportMockCmd.Flags().StringVar(
+		&mockResources,
+		"resources",
+		"",
+		"JSON file mapping API resource names to a Table and its Fields (default: every table and column)",
+	)
+	_ = viper.BindPFlag("export-mock.resources", ExportMockCmd.Flags().Lookup("resources"))
+
+	ExportMockCmd.Flags().IntVar(
+		&mockLimit,
+		"limit",
+		100,
+		"Maximum number of rows exported for every table when no --resources are supplied (0 exports every row)",
+	)
+	_ = viper.BindPFlag("export-mock.limit", ExportMockCmd.Flags().Lookup("limit"))
+}
+
+// cliCommandExportMock is the initialization point for executing the ExportMock command from the CLI and returns to
+// the CLI on exit.
+func cliCommandExportMock(cmd *cobra.Command, args []string) {
+	log.Info(aurora.Bold(aurora.Yellow(fmt.Sprint("Enabling log level: ",
+		strings.ToUpper(viper.GetString("log-level"))))))
+
+	err := exportMock(
+		viper.GetString("export-mock.processed-file"),
+		viper.GetString("export-mock.output-file"),
+		viper.GetString("export-mock.resources"),
+		viper.GetInt("export-mock.limit"),
+	)
+	if err != nil {
+		log.Error(err)
+		log.Error("❌ Gonymizer did not exit properly. See above for errors ❌")
+		os.Exit(1)
+	} else {
+		log.Info("🦄 ", aurora.Bold(aurora.Green("-- SUCCESS --")), " 🌈")
+	}
+}
+
+// exportMock is the entry point for exporting an anonymized dump file as JSON fixtures.
+func exportMock(processedFile, outputFile, resourcesFile string, limit int) error {
+	var (
+		resources map[string]gonymizer.MockResource
+		err       error
+	)
+	if len(resourcesFile) > 0 {
+		log.Info("Loading resources from: ", resourcesFile)
+		if resources, err = gonymizer.LoadMockResources(resourcesFile); err != nil {
+			return err
+		}
+	}
+
+	log.Info("Exporting rows of: ", processedFile)
+	data, err := gonymizer.ExportMockData(processedFile, resources, limit)
+	if err != nil {
+		return err
+	}
+	log.Info("Writing mock data to: ", outputFile)
+	return gonymizer.WriteMockData(data, outputFile)
+}
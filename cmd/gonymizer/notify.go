@@ -0,0 +1,23 @@
+package main
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/smithoss/gonymizer/v2"
+	"github.com/spf13/viper"
+)
+
+// notifyRun sends the run summary to the targets given by --notify. Notification errors are logged and never change
+// the outcome of the run.
+func notifyRun(command string, started time.Time, err error, details map[string]string) {
+	targets := viper.GetStringSlice("notify")
+	if len(targets) == 0 || (err == nil && viper.GetString("notify-on") == "failure") {
+		return
+	}
+
+	log.Info("Sending run summary to ", len(targets), " notification target(s)")
+	if nErr := gonymizer.NotifyRun(targets, gonymizer.NewRunSummary(command, started, err, details)); nErr != nil {
+		log.Warn("Unable to send notification: ", nErr)
+	}
+}
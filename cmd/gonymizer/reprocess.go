@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/smithoss/gonymizer/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	reprocessOutputFile string
+	reprocessTables     []string
+	reprocessColumns    []string
+
+	// ReprocessCmd is the cobra.Command struct we use for the "reprocess" command.
+	ReprocessCmd = &cobra.Command{
+		Use:   "reprocess",
+		Short: "Reprocess will run only the selected tables/columns of a processed dump file or database again",
+		Run:   cliCommandReprocess,
+	}
+)
+
+// init initializes the reprocess command for the application and adds application flags and options.
+func init() {
+	ReprocessCmd.Flags().StringVar(
+		&mapFile,
+		"map-file",
+		"",
+		"Map file location",
+	)
+	_ = viper.BindPFlag("reprocess.map-file", ReprocessCmd.Flags().Lookup("map-file"))
+
+	ReprocessCmd.Flags().StringVar(
+		&processedFile,
+		"processed-file",
+		"",
+		"Filename and location of the processed PostgreSQL dump file to reprocess",
+	)
+	_ = viper.BindPFlag("reprocess.processed-file", ReprocessCmd.Flags().Lookup("processed-file"))
+
+	ReprocessCmd.Flags().StringVarP(
+		&dbName,
+		"database",
+		"d",
+		"",
+		"Name of the target database to update in place instead of a processed dump file",
+	)
+	_ = viper.BindPFlag("reprocess.database", ReprocessCmd.Flags().Lookup("database"))
+
+	ReprocessCmd.Flags().StringVarP(&dbHost, "host", "H", "", "Target database host address")
+	_ = viper.BindPFlag("reprocess.host", ReprocessCmd.Flags().Lookup("host"))
+
+	ReprocessCmd.Flags().Int32VarP(&dbPort, "port", "P", 5432, "Target database port")
+	_ = viper.BindPFlag("reprocess.port", ReprocessCmd.Flags().Lookup("port"))
+
+	ReprocessCmd.Flags().StringVarP(&dbUser, "username", "U", "", "Target database username")
+	_ = viper.BindPFlag("reprocess.username", ReprocessCmd.Flags().Lookup("username"))
+
+	ReprocessCmd.Flags().StringVarP(&dbPassword, "password", "p", "", "Target database password")
+	_ = viper.BindPFlag("reprocess.password", ReprocessCmd.Flags().Lookup("password"))
+
+	ReprocessCmd.Flags().BoolVarP(&dbDisableSSL, "disable-ssl", "S", false, "Disable SSL (Not-recommended)")
+	_ = viper.BindPFlag("reprocess.disable-ssl", ReprocessCmd.Flags().Lookup("disable-ssl"))
+
+	ReprocessCmd.Flags().StringVar(
+		&reprocessOutputFile,
+		"output-file",
+		"",
+		"Filename and location to store the reprocessed dump file (default is to replace --processed-file)",
+	)
+	_ = viper.BindPFlag("reprocess.output-file", ReprocessCmd.Flags().Lookup("output-file"))
+
+	ReprocessCmd.Flags().StringVar(
+		&dumpFile,
+		"dump-file",
+		"",
+		"Filename and location of the PII-PostgreSQL dump file. When set, the original values are reprocessed",
+	)
+	_ = viper.BindPFlag("reprocess.dump-file", ReprocessCmd.Flags().Lookup("dump-file"))
+
+	ReprocessCmd.Flags().StringSliceVar(
+		&reprocessTables,
+		"table",
+		[]string{},
+		"Table (schema.table or table) to reprocess. May be repeated",
+	)
+	_ = viper.BindPFlag("reprocess.table", ReprocessCmd.Flags().Lookup("table"))
+
+	ReprocessCmd.Flags().StringSliceVar(
+		&reprocessColumns,
+		"column",
+		[]string{},
+		"Column (column, table.column, or schema.table.column) to reprocess. May be repeated",
+	)
+	_ = viper.BindPFlag("reprocess.column", ReprocessCmd.Flags().Lookup("column"))
+
+	ReprocessCmd.Flags().StringVar(
+		&mappingsFile,
+		"mappings-file",
+		"",
+		"Encrypted mappings saved by 'process --mappings-file'. It is updated with any new mappings. The key is read "+
+			"from $"+gonymizer.MappingsKeyEnv,
+	)
+	_ = viper.BindPFlag("reprocess.mappings-file", ReprocessCmd.Flags().Lookup("mappings-file"))
+}
+
+// cliCommandReprocess is the initialization point for executing the Reprocess command from the CLI and returns to the
+// CLI on exit.
+func cliCommandReprocess(cmd *cobra.Command, args []string) {
+	log.Info(aurora.Bold(aurora.Yellow(fmt.Sprint("Enabling log level: ",
+		strings.ToUpper(viper.GetString("log-level"))))))
+
+	log.Info("🚜 ", aurora.Bold(aurora.Green("Reprocessing")), " 🚜")
+	opts := gonymizer.ReprocessOptions{
+		Tables:       viper.GetStringSlice("reprocess.table"),
+		Columns:      viper.GetStringSlice("reprocess.column"),
+		SourceFile:   viper.GetString("reprocess.dump-file"),
+		MappingsFile: viper.GetString("reprocess.mappings-file"),
+	}
+	if len(opts.MappingsFile) > 0 {
+		var err error
+		if opts.MappingsKey, err = gonymizer.ParseVaultKey(os.Getenv(gonymizer.MappingsKeyEnv)); err != nil {
+			log.Error(gonymizer.MappingsKeyEnv, ": ", err)
+			os.Exit(1)
+		}
+	}
+	var err error
+	if len(viper.GetString("reprocess.database")) > 0 {
+		// If no password was supplied grab from user input
+		if len(viper.GetString("reprocess.password")) < 1 {
+			log.Debug("Password is empty. Asking user for password")
+			viper.SetDefault("reprocess.password", GetPassword())
+		}
+		dbConf, db := GetDb(
+			viper.GetString("reprocess.host"),
+			viper.GetString("reprocess.username"),
+			viper.GetString("reprocess.password"),
+			viper.GetString("reprocess.database"),
+			viper.GetInt32("reprocess.port"),
+			viper.GetBool("reprocess.disable-ssl"),
+		)
+		db.Close()
+		err = reprocessDatabase(dbConf, viper.GetString("reprocess.map-file"), opts)
+	} else {
+		err = reprocess(
+			viper.GetString("reprocess.map-file"),
+			viper.GetString("reprocess.processed-file"),
+			viper.GetString("reprocess.output-file"),
+			opts,
+		)
+	}
+	if err != nil {
+		log.Error(err)
+		log.Error("❌ Gonymizer did not exit properly. See above for errors ❌")
+		os.Exit(1)
+	} else {
+		log.Info("🦄 ", aurora.Bold(aurora.Green("-- SUCCESS --")), " 🌈")
+	}
+}
+
+// reprocess is the entry point for reprocessing columns of a processed dump file. Without an output file the processed
+// dump file is replaced once reprocessing succeeds.
+func reprocess(mapFile, processedFile, outputFile string, opts gonymizer.ReprocessOptions) error {
+	log.Info("Loading map file from: ", mapFile)
+	columnMap, err := gonymizer.LoadConfigSkeleton(mapFile)
+	if err != nil {
+		return err
+	}
+
+	dst := outputFile
+	if len(dst) == 0 {
+		dst = processedFile + ".tmp"
+	}
+	log.Infof("Reprocessing tables %v and columns %v of: %s", opts.Tables, opts.Columns, processedFile)
+	if err = gonymizer.ReprocessDumpFile(columnMap, processedFile, dst, opts); err != nil {
+		return err
+	}
+	if len(outputFile) == 0 {
+		return os.Rename(dst, processedFile)
+	}
+	return nil
+}
+
+// reprocessDatabase is the entry point for reprocessing columns of a target database in place.
+func reprocessDatabase(dbConf gonymizer.PGConfig, mapFile string, opts gonymizer.ReprocessOptions) error {
+	log.Info("Loading map file from: ", mapFile)
+	columnMap, err := gonymizer.LoadConfigSkeleton(mapFile)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Reprocessing tables %v and columns %v of database: %s", opts.Tables, opts.Columns, dbConf.DefaultDBName)
+	return gonymizer.ReprocessDatabase(dbConf, columnMap, opts)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/smithoss/gonymizer/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	simulationReport string
+
+	// SimulateCmd is the cobra.Command struct we use for the "simulate" command.
+	SimulateCmd = &cobra.Command{
+		Use:   "simulate",
+		Short: "Simulate will replay the map file against a dump file and report values that fail or stay unchanged",
+		Run:   cliCommandSimulate,
+	}
+)
+
+// init initializes the simulate command for the application and adds application flags and options.
+func init() {
+	SimulateCmd.Flags().StringVar(
+		&mapFile,
+		"map-file",
+		"",
+		"Map file location",
+	)
+	_ = viper.BindPFlag("simulate.map-file", SimulateCmd.Flags().Lookup("map-file"))
+
+	SimulateCmd.Flags().StringVar(
+		&dumpFile,
+		"dump-file",
+		"",
+		"Filename and location of the (archived) PII-PostgreSQL dump file to replay the map file against",
+	)
+	_ = viper.BindPFlag("simulate.dump-file", SimulateCmd.Flags().Lookup("dump-file"))
+
+	SimulateCmd.Flags().StringVar(
+		&simulationReport,
+		"report-file",
+		"simulation.json",
+		"Filename and location to store the JSON report of values that failed or were left unchanged",
+	)
+	_ = viper.BindPFlag("simulate.report-file", SimulateCmd.Flags().Lookup("report-file"))
+
+	SimulateCmd.Flags().Int64Var(
+		&sampleRows,
+		"sample-rows",
+		0,
+		"Only replay the first N rows of every table (0 replays every row)",
+	)
+	_ = viper.BindPFlag("simulate.sample-rows", SimulateCmd.Flags().Lookup("sample-rows"))
+}
+
+// cliCommandSimulate is the initialization point for executing the Simulate command from the CLI and returns to the
+// CLI on exit.
+func cliCommandSimulate(cmd *cobra.Command, args []string) {
+	log.Info(aurora.Bold(aurora.Yellow(fmt.Sprint("Enabling log level: ",
+		strings.ToUpper(viper.GetString("log-level"))))))
+
+	err := simulate(
+		viper.GetString("simulate.map-file"),
+		viper.GetString("simulate.dump-file"),
+		viper.GetString("simulate.report-file"),
+		viper.GetInt64("simulate.sample-rows"),
+	)
+	if err != nil {
+		log.Error(err)
+		log.Error("❌ Gonymizer did not exit properly. See above for errors ❌")
+		os.Exit(1)
+	} else {
+		log.Info("🦄 ", aurora.Bold(aurora.Green("-- SUCCESS --")), " 🌈")
+	}
+}
+
+// simulate is the entry point for replaying the map file against a dump file.
+func simulate(mapFile, dumpFile, reportFile string, sampleRows int64) error {
+	log.Info("Loading map file from: ", mapFile)
+	columnMap, err := gonymizer.LoadConfigSkeleton(mapFile)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Replaying map file against: ", dumpFile)
+	report, err := gonymizer.SimulateDumpFile(columnMap, dumpFile, sampleRows)
+	if err != nil {
+		return err
+	}
+	for _, column := range report.Columns {
+		log.Warnf("Column %s: %d error(s) and %d unchanged value(s) in %d value(s)", column.Column, column.Errors,
+			column.Unchanged, column.Values)
+	}
+	log.Infof("Replayed %d value(s) in %d row(s): %d error(s), %d unchanged", report.Values, report.Rows,
+		report.Errors, report.Unchanged)
+
+	log.Info("Writing simulation report to: ", reportFile)
+	return gonymizer.WriteSimulationReport(report, reportFile)
+}
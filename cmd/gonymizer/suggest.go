@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/smithoss/gonymizer/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	suggestionReport string
+
+	// SuggestCmd is the cobra.Command struct we use for the "suggest" command.
+	SuggestCmd = &cobra.Command{
+		Use:   "suggest",
+		Short: "Suggest will sample the dump file and suggest processors for columns that are not anonymized",
+		Run:   cliCommandSuggest,
+	}
+)
+
+// init initializes the suggest command for the application and adds application flags and options.
+func init() {
+	SuggestCmd.Flags().StringVar(
+		&mapFile,
+		"map-file",
+		"",
+		"Map file location. Columns anonymized by the map file are skipped. Without a map file every column is sampled",
+	)
+	_ = viper.BindPFlag("suggest.map-file", SuggestCmd.Flags().Lookup("map-file"))
+
+	SuggestCmd.Flags().StringVar(
+		&dumpFile,
+		"dump-file",
+		"",
+		"Filename and location of the PII-PostgreSQL dump file to sample",
+	)
+	_ = viper.BindPFlag("suggest.dump-file", SuggestCmd.Flags().Lookup("dump-file"))
+
+	SuggestCmd.Flags().StringVar(
+		&suggestionReport,
+		"report-file",
+		"suggestions.json",
+		"Filename and location to store the suggested map entries (JSON)",
+	)
+	_ = viper.BindPFlag("suggest.report-file", SuggestCmd.Flags().Lookup("report-file"))
+}
+
+// cliCommandSuggest is the initialization point for executing the Suggest command from the CLI and returns to the CLI
+// on exit.
+func cliCommandSuggest(cmd *cobra.Command, args []string) {
+	log.Info(aurora.Bold(aurora.Yellow(fmt.Sprint("Enabling log level: ",
+		strings.ToUpper(viper.GetString("log-level"))))))
+
+	err := suggest(
+		viper.GetString("suggest.map-file"),
+		viper.GetString("suggest.dump-file"),
+		viper.GetString("suggest.report-file"),
+	)
+	if err != nil {
+		log.Error(err)
+		log.Error("❌ Gonymizer did not exit properly. See above for errors ❌")
+		os.Exit(1)
+	} else {
+		log.Info("🦄 ", aurora.Bold(aurora.Green("-- SUCCESS --")), " 🌈")
+	}
+}
+
+// suggest is the entry point for writing the processor suggestions.
+func suggest(mapFile, dumpFile, reportFile string) error {
+	var columnMap *gonymizer.DBMapper
+	if len(mapFile) > 0 {
+		log.Info("Loading map file from: ", mapFile)
+		var err error
+		if columnMap, err = gonymizer.LoadConfigSkeleton(mapFile); err != nil {
+			return err
+		}
+	}
+
+	log.Info("Sampling dump file: ", dumpFile)
+	report, err := gonymizer.SuggestProcessors(columnMap, dumpFile)
+	if err != nil {
+		return err
+	}
+	for _, suggestion := range report.Suggestions {
+		log.Infof("%s.%s.%s: %s (%s)", suggestion.Entry.TableSchema, suggestion.Entry.TableName,
+			suggestion.Entry.ColumnName, suggestion.Entry.Processors[0].Name, suggestion.Reason)
+	}
+	log.Info("Writing suggestions to: ", reportFile)
+	return gonymizer.WriteSuggestionReport(report, reportFile)
+}
@@ -0,0 +1,83 @@
+package gonymizer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ProcessorCondition is a predicate on the value of another column of the row. A processor with a condition (see
+// ProcessorDefinition.When) is only run for the rows that match it, the value is passed on unchanged for all other
+// rows.
+//
+// Example map file definitions:
+// {"Name": "ScrubString", "When": {"Column": "country", "In": ["DE", "FR", "NL"]}}
+// {"Name": "FakeEmailAddress", "When": {"Column": "is_test_account", "NotIn": ["t"]}}
+type ProcessorCondition struct {
+	// Column is the name of the column of the same row the condition tests
+	Column string
+
+	// In matches rows where the value of Column is one of the values, NotIn rows where it is none of them. Regex
+	// matches rows where the value matches the regular expression. All conditions that are set must match.
+	In    []string `json:",omitempty"`
+	NotIn []string `json:",omitempty"`
+	Regex string   `json:",omitempty"`
+}
+
+// matches returns true if the row matches the condition. A NULL value (or a missing column) matches NotIn, but never
+// In or Regex.
+func (cond *ProcessorCondition) matches(row *rowContext) (bool, error) {
+	value, ok := row.value(cond.Column)
+	value = unescapeCopyValue(value)
+
+	if len(cond.In) > 0 && (!ok || !containsString(cond.In, value)) {
+		return false, nil
+	}
+	if len(cond.NotIn) > 0 && ok && containsString(cond.NotIn, value) {
+		return false, nil
+	}
+	if len(cond.Regex) > 0 {
+		re, err := compileRegexReplace(cond.Regex)
+		if err != nil {
+			return false, err
+		}
+		if !ok || !re.MatchString(value) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// runsFor returns true if the processor is run for the row: it has no When condition or the row matches it.
+func (procDef ProcessorDefinition) runsFor(row *rowContext) (bool, error) {
+	if procDef.When == nil {
+		return true, nil
+	}
+	return procDef.When.matches(row)
+}
+
+// validateCondition checks the When condition of a processor definition.
+func (procDef ProcessorDefinition) validateCondition() error {
+	if procDef.When == nil {
+		return nil
+	}
+	if err := procDef.When.validate(); err != nil {
+		return fmt.Errorf("When condition of processor %s: %s", procDef.Name, err)
+	}
+	return nil
+}
+
+// validate checks that the condition has a Column and at least one predicate, and that its Regex compiles.
+func (cond *ProcessorCondition) validate() error {
+	if len(cond.Column) == 0 {
+		return errors.New("Expected a Column")
+	}
+	if len(cond.In) == 0 && len(cond.NotIn) == 0 && len(cond.Regex) == 0 {
+		return errors.New("Expected In, NotIn, or Regex")
+	}
+	if len(cond.Regex) > 0 {
+		if _, err := compileRegexReplace(cond.Regex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
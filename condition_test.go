@@ -0,0 +1,70 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorConditionMatches(t *testing.T) {
+	row := newRowContext([]string{"country", "is_test_account", "note", "deleted_at"},
+		[]string{"DE", "t", "line\\tbreak", "\\N"})
+
+	for _, test := range []struct {
+		cond    ProcessorCondition
+		matches bool
+	}{
+		{ProcessorCondition{Column: "country", In: []string{"DE", "FR"}}, true},
+		{ProcessorCondition{Column: "country", In: []string{"US"}}, false},
+		{ProcessorCondition{Column: "is_test_account", NotIn: []string{"t", "true"}}, false},
+		{ProcessorCondition{Column: "is_test_account", NotIn: []string{"f"}}, true},
+		{ProcessorCondition{Column: "note", Regex: "^line\tb"}, true},
+		{ProcessorCondition{Column: "note", In: []string{"line\tbreak"}, Regex: "^x"}, false},
+		{ProcessorCondition{Column: "deleted_at", In: []string{"\\N"}}, false},
+		{ProcessorCondition{Column: "deleted_at", NotIn: []string{""}}, true},
+		{ProcessorCondition{Column: "missing", Regex: ".*"}, false},
+	} {
+		matches, err := test.cond.matches(row)
+		require.Nil(t, err)
+		require.Equal(t, test.matches, matches, test.cond)
+	}
+}
+
+func TestProcessValueCondition(t *testing.T) {
+	defer func() { currentRow = newRowContext(nil, nil) }()
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{
+		{Name: "ScrubString", When: &ProcessorCondition{Column: "country", In: []string{"DE"}}},
+		{Name: "Uppercase"},
+	}}
+
+	currentRow = newRowContext([]string{"country"}, []string{"DE"})
+	output, err := processValue(cmap, "secret")
+	require.Nil(t, err)
+	require.Equal(t, "******", output)
+
+	currentRow = newRowContext([]string{"country"}, []string{"US"})
+	output, err = processValue(cmap, "secret")
+	require.Nil(t, err)
+	require.Equal(t, "SECRET", output)
+
+	// Batches only process the cells of matching rows
+	cells := []Cell{
+		{Column: cmap, Value: "a", row: newRowContext([]string{"country"}, []string{"DE"})},
+		{Column: cmap, Value: "b", row: newRowContext([]string{"country"}, []string{"US"})},
+	}
+	outputs, err := processBatch(cmap, cells)
+	require.Nil(t, err)
+	require.Equal(t, []string{"*", "B"}, outputs)
+}
+
+func TestValidateCondition(t *testing.T) {
+	require.Nil(t, ProcessorDefinition{Name: "ScrubString"}.validateCondition())
+	require.Nil(t, ProcessorDefinition{Name: "ScrubString",
+		When: &ProcessorCondition{Column: "country", Regex: "^(DE|FR)$"}}.validateCondition())
+	require.NotNil(t, ProcessorDefinition{Name: "ScrubString",
+		When: &ProcessorCondition{In: []string{"DE"}}}.validateCondition())
+	require.NotNil(t, ProcessorDefinition{Name: "ScrubString",
+		When: &ProcessorCondition{Column: "country"}}.validateCondition())
+	require.NotNil(t, ProcessorDefinition{Name: "ScrubString",
+		When: &ProcessorCondition{Column: "country", Regex: "("}}.validateCondition())
+}
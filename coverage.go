@@ -0,0 +1,250 @@
+package gonymizer
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxPIIDetectionRows is the number of rows of every table that are scanned for PII by MapCoverage.
+const maxPIIDetectionRows = 1000
+
+// TableCoverage contains the number of columns of a table that are anonymized (mapped to a processor other than
+// Identity), left as-is (Identity or no processors), or missing from the map file. PIIColumns lists the columns that
+// are not anonymized but hold values matching PII patterns, with the names of the patterns ("notes: email, phone_us").
+type TableCoverage struct {
+	Table      string
+	Anonymized int
+	Identity   int
+	Unmapped   int
+
+	IdentityColumns []string `json:",omitempty"`
+	UnmappedColumns []string `json:",omitempty"`
+	PIIColumns      []string `json:",omitempty"`
+}
+
+// CoverageReport contains the map coverage of every table. Tables are sorted riskiest first (lowest fraction of
+// anonymized columns).
+type CoverageReport struct {
+	Tables []TableCoverage
+}
+
+// Total returns the number of columns in the table.
+func (c TableCoverage) Total() int {
+	return c.Anonymized + c.Identity + c.Unmapped
+}
+
+// AnonymizedFraction returns the fraction (0 to 1) of the columns of the table that are anonymized.
+func (c TableCoverage) AnonymizedFraction() float64 {
+	if c.Total() == 0 {
+		return 0
+	}
+	return float64(c.Anonymized) / float64(c.Total())
+}
+
+// MapCoverage reports how much of every table is covered by the map file. The columns of every table are read from the
+// COPY statements of the dump file so columns missing from the map file are found. Without a dump file only the
+// columns in the map file are reported. The values of the first maxPIIDetectionRows rows of every table are matched
+// against the PII patterns (see MatchPIIPatterns) to find columns that hold personal data but are not anonymized.
+func MapCoverage(mapper *DBMapper, dumpFile string) (*CoverageReport, error) {
+	tables := map[string]*TableCoverage{}
+	table := func(name string) *TableCoverage {
+		if tables[name] == nil {
+			tables[name] = &TableCoverage{Table: name}
+		}
+		return tables[name]
+	}
+
+	for _, cmap := range mapper.ColumnMaps {
+		coverage := table(unquoteIdentifier(cmap.TableSchema) + "." + unquoteIdentifier(cmap.TableName))
+		if anonymizes(cmap) {
+			coverage.Anonymized++
+		} else {
+			coverage.Identity++
+			coverage.IdentityColumns = append(coverage.IdentityColumns, unquoteIdentifier(cmap.ColumnName))
+		}
+	}
+
+	if len(dumpFile) > 0 {
+		err := forEachCopyStatement(dumpFile, func(state *LineState) {
+			coverage := table(state.SchemaName + "." + state.TableName)
+			for _, columnName := range state.ColumnNames {
+				if mapper.ColumnMapper(state.SchemaName, state.TableName, columnName) == nil {
+					coverage.Unmapped++
+					coverage.UnmappedColumns = append(coverage.UnmappedColumns, columnName)
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err = detectPII(mapper, dumpFile, table); err != nil {
+			return nil, err
+		}
+	}
+
+	report := new(CoverageReport)
+	for _, coverage := range tables {
+		report.Tables = append(report.Tables, *coverage)
+	}
+	sort.Slice(report.Tables, func(i, j int) bool {
+		a, b := report.Tables[i], report.Tables[j]
+		if a.AnonymizedFraction() != b.AnonymizedFraction() {
+			return a.AnonymizedFraction() < b.AnonymizedFraction()
+		}
+		return a.Table < b.Table
+	})
+	return report, nil
+}
+
+// anonymizes returns true if the column has a processor other than Identity.
+func anonymizes(cmap ColumnMapper) bool {
+	for _, procDef := range cmap.Processors {
+		if procDef.Name != "Identity" {
+			return true
+		}
+	}
+	return false
+}
+
+// detectPII matches the values of the columns of the dump file found at path that are not anonymized against the PII
+// patterns and adds the columns with matching values to the PIIColumns of their table.
+func detectPII(mapper *DBMapper, path string, table func(name string) *TableCoverage) error {
+	matches := map[string]map[string][]string{}
+	rows := map[string]int{}
+	err := forEachDumpRow(path, func(state *LineState, values []string) error {
+		name := state.SchemaName + "." + state.TableName
+		if rows[name]++; rows[name] > maxPIIDetectionRows {
+			return nil
+		}
+		if matches[name] == nil {
+			matches[name] = map[string][]string{}
+		}
+		for i, columnName := range state.ColumnNames {
+			cmap := mapper.ColumnMapper(state.SchemaName, state.TableName, columnName)
+			if (cmap != nil && anonymizes(*cmap)) || values[i] == "\\N" {
+				continue
+			}
+			for _, pattern := range MatchPIIPatterns(unescapeCopyValue(values[i])) {
+				if !containsString(matches[name][columnName], pattern) {
+					matches[name][columnName] = append(matches[name][columnName], pattern)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error("Unable to scan dump file for PII: ", err)
+		return err
+	}
+
+	for name, columns := range matches {
+		if len(columns) == 0 {
+			continue
+		}
+		coverage := table(name)
+		for columnName, patterns := range columns {
+			coverage.PIIColumns = append(coverage.PIIColumns, columnName+": "+strings.Join(patterns, ", "))
+		}
+		sort.Strings(coverage.PIIColumns)
+	}
+	return nil
+}
+
+// forEachCopyStatement calls fn for every COPY statement of the dump file found at path.
+func forEachCopyStatement(path string, fn func(state *LineState)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := newDumpScanner(f)
+	state := new(LineState)
+	for {
+		line, err := scanner.next()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		state.LineNum++
+
+		// Rows are never COPY statements, so only lines outside of COPY blocks need to be converted
+		if !state.IsRow {
+			if trimmed := strings.TrimLeftFunc(string(line), unicode.IsSpace); strings.HasPrefix(trimmed,
+				StateChangeTokenBeginCopy) {
+				state.parseCopyLine(string(line))
+				fn(state)
+			}
+		} else if len(line) >= 2 && line[0] == '\\' && line[1] == '.' {
+			state.Clear()
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// WriteCoverageReport will save the coverage report to filepath. Files ending in .html are written as a heatmap for
+// privacy reviews, everything else as JSON.
+func WriteCoverageReport(report *CoverageReport, filepath string) error {
+	if !strings.HasSuffix(strings.ToLower(filepath), ".html") {
+		return writeJSONFile(filepath, report)
+	}
+
+	f, err := createPrivateFile(filepath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err = coverageTemplate.Execute(f, report); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// coverageColor returns the background color of a table in the heatmap: red for tables without anonymized columns to
+// green for fully anonymized tables.
+func coverageColor(c TableCoverage) template.CSS {
+	return template.CSS(fmt.Sprintf("hsl(%.0f, 70%%, 80%%)", 120*c.AnonymizedFraction()))
+}
+
+// coverageTemplate is the HTML heatmap written by WriteCoverageReport.
+var coverageTemplate = template.Must(template.New("coverage").Funcs(template.FuncMap{
+	"color":   coverageColor,
+	"percent": func(c TableCoverage) string { return fmt.Sprintf("%.0f%%", 100*c.AnonymizedFraction()) },
+	"join":    func(columns []string) string { return strings.Join(columns, ", ") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Gonymizer map coverage</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+td.number { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Map coverage</h1>
+<table>
+<tr><th>Table</th><th>Coverage</th><th>Anonymized</th><th>Identity</th><th>Unmapped</th>
+<th>Identity columns</th><th>Unmapped columns</th><th>Detected PII</th></tr>
+{{- range .Tables}}
+<tr style="background-color: {{color .}}">
+<td>{{.Table}}</td><td class="number">{{percent .}}</td><td class="number">{{.Anonymized}}</td>
+<td class="number">{{.Identity}}</td><td class="number">{{.Unmapped}}</td>
+<td>{{join .IdentityColumns}}</td><td>{{join .UnmappedColumns}}</td><td>{{join .PIIColumns}}</td>
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
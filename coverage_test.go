@@ -0,0 +1,51 @@
+package gonymizer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapCoverage(t *testing.T) {
+	columnMap, err := LoadConfigSkeleton(TestMapFile)
+	require.Nil(t, err)
+
+	// Drop purchasers.email from the map file
+	for i, cmap := range columnMap.ColumnMaps {
+		if cmap.TableName == "purchasers" && cmap.ColumnName == "email" {
+			columnMap.ColumnMaps = append(columnMap.ColumnMaps[:i], columnMap.ColumnMaps[i+1:]...)
+			break
+		}
+	}
+
+	report, err := MapCoverage(columnMap, TestDbFile)
+	require.Nil(t, err)
+	require.Len(t, report.Tables, 4)
+	require.Equal(t, TableCoverage{
+		Table:           "public.purchasers",
+		Identity:        3,
+		Unmapped:        1,
+		IdentityColumns: []string{"id", "first_name", "last_name"},
+		UnmappedColumns: []string{"email"},
+		PIIColumns:      []string{"email: email"},
+	}, report.Tables[0])
+	require.Equal(t, "public.distributors", report.Tables[1].Table)
+	require.Equal(t, 0.2, report.Tables[1].AnonymizedFraction())
+
+	f, err := ioutil.TempFile("", "gonymizer-coverage-*.html")
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+	defer os.Remove(f.Name())
+	require.Nil(t, os.Chmod(f.Name(), 0644))
+	require.Nil(t, WriteCoverageReport(report, f.Name()))
+	info, err := os.Stat(f.Name())
+	require.Nil(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	html, err := ioutil.ReadFile(f.Name())
+	require.Nil(t, err)
+	require.Contains(t, string(html), "<td>public.purchasers</td><td class=\"number\">0%</td>")
+	require.Contains(t, string(html), "hsl(0, 70%, 80%)")
+	require.Contains(t, string(html), "<td>email: email</td>")
+}
@@ -0,0 +1,192 @@
+package gonymizer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// base58Alphabet is the alphabet used by Bitcoin for Base58Check encoded addresses.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// bech32Alphabet is the alphabet used by BIP-173 (bech32) and BIP-350 (bech32m) encoded addresses.
+const bech32Alphabet = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32 checksum constants for witness version 0 (bech32) and witness version 1+ (bech32m).
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+// randomBytes returns n random bytes from the package random number generator.
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(rng.Intn(256))
+	}
+	return b
+}
+
+// fakeBitcoinAddress returns a random, checksum valid, Bitcoin address of the same type and network as the input.
+// Supported types are P2PKH (1..., m/n...), P2SH (3..., 2...), and segwit (bc1q..., bc1p..., tb1...). If the type of
+// the input cannot be detected a mainnet P2PKH address is returned.
+func fakeBitcoinAddress(input string) string {
+	lower := strings.ToLower(input)
+	for _, hrp := range []string{"bc", "tb", "bcrt"} {
+		if strings.HasPrefix(lower, hrp+"1") && len(lower) > len(hrp)+2 {
+			// Taproot (witness v1) programs are 32 bytes, v0 programs are 20 bytes (P2WPKH) or 32 bytes (P2WSH)
+			version := strings.IndexByte(bech32Alphabet, lower[len(hrp)+1])
+			length := 20
+			if version != 0 || len(lower) > 50 {
+				length = 32
+			}
+			if version < 0 {
+				version = 0
+			}
+			return bech32Encode(hrp, version, randomBytes(length))
+		}
+	}
+
+	var version byte
+	switch {
+	case strings.HasPrefix(input, "3"):
+		version = 0x05
+	case strings.HasPrefix(input, "2"):
+		version = 0xc4
+	case strings.HasPrefix(input, "m"), strings.HasPrefix(input, "n"):
+		version = 0x6f
+	default:
+		version = 0x00
+	}
+	return base58CheckEncode(version, randomBytes(20))
+}
+
+// fakeEthereumAddress returns a random Ethereum address using the EIP-55 mixed case checksum.
+func fakeEthereumAddress() string {
+	return "0x" + eip55Checksum(hex.EncodeToString(randomBytes(20)))
+}
+
+// base58CheckEncode encodes the version and payload using Base58Check (double SHA-256 checksum).
+func base58CheckEncode(version byte, payload []byte) string {
+	data := append([]byte{version}, payload...)
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	data = append(data, second[:4]...)
+
+	var encoded []byte
+	num := new(big.Int).SetBytes(data)
+	base := big.NewInt(58)
+	mod := new(big.Int)
+	for num.Sign() > 0 {
+		num.DivMod(num, base, mod)
+		encoded = append(encoded, base58Alphabet[mod.Int64()])
+	}
+
+	// Leading zero bytes are encoded as leading 1's
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		encoded = append(encoded, base58Alphabet[0])
+	}
+
+	// Reverse since we built the string from the least significant digit
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
+
+// bech32Encode encodes a segwit address using bech32 (witness v0) or bech32m (witness v1+).
+func bech32Encode(hrp string, version int, program []byte) string {
+	data := append([]int{version}, convertBits(program, 8, 5)...)
+
+	constant := bech32Const
+	if version > 0 {
+		constant = bech32mConst
+	}
+
+	values := append(bech32HRPExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ constant
+
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteByte('1')
+	for _, d := range data {
+		b.WriteByte(bech32Alphabet[d])
+	}
+	for i := 0; i < 6; i++ {
+		b.WriteByte(bech32Alphabet[(polymod>>uint(5*(5-i)))&31])
+	}
+	return b.String()
+}
+
+// bech32Polymod calculates the BCH checksum used by bech32 and bech32m.
+func bech32Polymod(values []int) int {
+	generator := []int{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+	chk := 1
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ v
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+// bech32HRPExpand expands the human readable part of a bech32 string for use in the checksum calculation.
+func bech32HRPExpand(hrp string) []int {
+	expanded := make([]int, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, int(hrp[i]>>5))
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, int(hrp[i]&31))
+	}
+	return expanded
+}
+
+// convertBits regroups a slice of bytes from fromBits sized groups to toBits sized groups (with padding).
+func convertBits(data []byte, fromBits, toBits uint) []int {
+	var (
+		acc    int
+		bits   uint
+		result []int
+	)
+	maxv := (1 << toBits) - 1
+	for _, value := range data {
+		acc = acc<<fromBits | int(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			result = append(result, (acc>>bits)&maxv)
+		}
+	}
+	if bits > 0 {
+		result = append(result, (acc<<(toBits-bits))&maxv)
+	}
+	return result
+}
+
+// eip55Checksum applies the EIP-55 mixed case checksum to a lowercase hex encoded Ethereum address (without 0x).
+func eip55Checksum(address string) string {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(address))
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	result := []byte(address)
+	for i, c := range result {
+		if c >= 'a' && c <= 'f' && digest[i] >= '8' {
+			result[i] = c - 32
+		}
+	}
+	return string(result)
+}
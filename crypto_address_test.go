@@ -0,0 +1,41 @@
+package gonymizer
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBase58CheckEncode(t *testing.T) {
+	require.Equal(t, "1111111111111111111114oLvT2", base58CheckEncode(0x00, make([]byte, 20)))
+}
+
+func TestBech32Encode(t *testing.T) {
+	// Test vectors from BIP-173 and BIP-350
+	program, err := hex.DecodeString("751e76e8199196d454941c45d1b3a323f1433bd6")
+	require.Nil(t, err)
+	require.Equal(t, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", bech32Encode("bc", 0, program))
+
+	program, err = hex.DecodeString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	require.Nil(t, err)
+	require.Equal(t, "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", bech32Encode("bc", 1, program))
+}
+
+func TestEIP55Checksum(t *testing.T) {
+	// Test vector from EIP-55
+	require.Equal(t, "5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		eip55Checksum("5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"))
+}
+
+func TestFakeBitcoinAddress(t *testing.T) {
+	var formats = map[string]string{
+		"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2":                             `^1[1-9A-HJ-NP-Za-km-z]{25,33}$`,
+		"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy":                             `^3[1-9A-HJ-NP-Za-km-z]{25,33}$`,
+		"bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq":                     `^bc1q[02-9ac-hj-np-z]{38}$`,
+		"bc1p5d7rjq7g6rdk2yhzks9smlaqtedr4dekq08ge8ztwac72sfr9rusxg3297": `^bc1p[02-9ac-hj-np-z]{58}$`,
+	}
+	for input, format := range formats {
+		require.Regexp(t, format, fakeBitcoinAddress(input))
+	}
+}
@@ -0,0 +1,44 @@
+package gonymizer
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// defaultAmountVariance is the variance used by the RandomAmount processor when the processor definition does not set
+// one.
+const defaultAmountVariance = 0.1
+
+// currencyMinorUnits are the ISO 4217 minor units (number of decimal places) of currencies that do not use 2 decimal
+// places.
+var currencyMinorUnits = map[string]int{
+	// no minor unit
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0,
+	"UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	// thousandths
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	// ten thousandths
+	"CLF": 4, "UYW": 4,
+}
+
+// currencyDecimals returns the number of decimal places used by the currency (ISO 4217 code). Unknown currencies use 2.
+func currencyDecimals(currency string) int {
+	if decimals, ok := currencyMinorUnits[strings.ToUpper(strings.TrimSpace(currency))]; ok {
+		return decimals
+	}
+	return 2
+}
+
+// perturbAmount will move amount by a random percentage of up to +/- variance and round the result to the given
+// number of decimal places.
+func perturbAmount(amount, variance float64, decimals int) string {
+	amount *= 1 + (rng.Float64()*2-1)*variance
+
+	scale := math.Pow10(decimals)
+	amount = math.Round(amount*scale) / scale
+	if amount == 0 {
+		amount = 0 // avoid -0
+	}
+	return strconv.FormatFloat(amount, 'f', decimals, 64)
+}
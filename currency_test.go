@@ -0,0 +1,50 @@
+package gonymizer
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorRandomAmount(t *testing.T) {
+	cmap := &ColumnMapper{
+		Processors: []ProcessorDefinition{{Name: "RandomAmount", CurrencyColumn: "currency", Variance: 0.2}},
+	}
+
+	for currency, decimals := range map[string]int{"JPY": 0, "KWD": 3, "usd": 2, "XYZ": 2} {
+		currentRow = newRowContext([]string{"amount", "currency"}, []string{"1000", currency})
+		output, err := ProcessorRandomAmount(cmap, "1000")
+		require.Nil(t, err)
+
+		if decimals == 0 {
+			require.NotContains(t, output, ".")
+		} else {
+			require.Len(t, output[strings.Index(output, ".")+1:], decimals, output)
+		}
+		amount, err := strconv.ParseFloat(output, 64)
+		require.Nil(t, err)
+		require.InDelta(t, 1000, amount, 200)
+	}
+
+	// Fixed currency when the currency column is NULL
+	cmap.Processors[0].Currency = "BHD"
+	currentRow = newRowContext([]string{"amount", "currency"}, []string{"-5.5", "\\N"})
+	output, err := ProcessorRandomAmount(cmap, "-5.5")
+	require.Nil(t, err)
+	require.True(t, strings.HasPrefix(output, "-"), output)
+	require.Len(t, output[strings.Index(output, ".")+1:], 3, output)
+
+	_, err = ProcessorRandomAmount(cmap, "ten dollars")
+	require.NotNil(t, err)
+}
+
+func TestCurrencyDecimals(t *testing.T) {
+	require.Equal(t, 0, currencyDecimals("JPY"))
+	require.Equal(t, 0, currencyDecimals(" krw "))
+	require.Equal(t, 3, currencyDecimals("KWD"))
+	require.Equal(t, 4, currencyDecimals("CLF"))
+	require.Equal(t, 2, currencyDecimals("EUR"))
+	require.Equal(t, 2, currencyDecimals(""))
+}
@@ -0,0 +1,414 @@
+package gonymizer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// dashboardSampleInterval is the interval at which the throughput of the run is sampled for the dashboard graph.
+	dashboardSampleInterval = 5 * time.Second
+
+	// maxDashboardSamples is the number of throughput samples shown in the dashboard graph (30 minutes).
+	maxDashboardSamples = 360
+
+	// maxDashboardErrors is the number of recent errors and warnings shown on the dashboard.
+	maxDashboardErrors = 20
+
+	// dashboardStoreInterval is the number of lines after which the sizes of the consistency stores are read again.
+	dashboardStoreInterval = 10000
+)
+
+// DashboardTable is the progress of a table shown on the dashboard. ExpectedRows is only known when a row count file
+// is supplied (see ProcessOptions.RowCountFile).
+type DashboardTable struct {
+	Table        string
+	Rows         int64
+	ExpectedRows int64 `json:",omitempty"`
+	Done         bool
+}
+
+// DashboardSample is the number of lines processed per second at a point in time.
+type DashboardSample struct {
+	Time           time.Time
+	LinesPerSecond float64
+}
+
+// DashboardStatus is the state of a run served as JSON by the dashboard (/status).
+type DashboardStatus struct {
+	Source            string
+	Started           time.Time
+	Lines             int64
+	BytesRead         int64
+	TotalBytes        int64
+	CurrentTable      string
+	Tables            []DashboardTable
+	Throughput        []DashboardSample
+	Errors            []string
+	ConsistencyStores map[string]int
+}
+
+// dashboard serves the progress of ProcessDumpFileWithOptions as a web page so the health of a long run can be
+// checked at a glance. The progress is recorded by the processing goroutine (see tick) and read by the HTTP handlers,
+// so every field is guarded by mu. A nil dashboard does nothing.
+type dashboard struct {
+	mu        sync.Mutex
+	status    DashboardStatus
+	tables    map[string]*DashboardTable
+	expected  map[string]int64
+	lastTable string
+
+	server *http.Server
+	addr   net.Addr
+	hooks  log.LevelHooks
+	stop   chan struct{}
+}
+
+// newDashboard starts serving the dashboard of the run processing src on address (e.g. 127.0.0.1:8080). rowCountFile
+// is an optional row count CSV file (schema, table, count) with the expected number of rows of every table.
+func newDashboard(address, src, rowCountFile string) (*dashboard, error) {
+	d := &dashboard{
+		status: DashboardStatus{
+			Source:            src,
+			Started:           time.Now(),
+			ConsistencyStores: map[string]int{},
+		},
+		tables: map[string]*DashboardTable{},
+		stop:   make(chan struct{}),
+	}
+	if info, err := os.Stat(src); err == nil {
+		d.status.TotalBytes = info.Size()
+	}
+	if len(rowCountFile) > 0 {
+		expected, err := loadExpectedRows(rowCountFile)
+		if err != nil {
+			return nil, err
+		}
+		d.expected = expected
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Error("Unable to start dashboard: ", err)
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.serveIndex)
+	mux.HandleFunc("/status", d.serveStatus)
+	d.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	d.addr = listener.Addr()
+	go func() {
+		if err := d.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error("Dashboard stopped: ", err)
+		}
+	}()
+
+	// Keep the recent errors and warnings, and restore the hooks of the logger when the dashboard is closed
+	d.hooks = make(log.LevelHooks)
+	for level, hooks := range log.StandardLogger().Hooks {
+		d.hooks[level] = append([]log.Hook(nil), hooks...)
+	}
+	log.AddHook(d)
+
+	go d.sample()
+	log.Infof("Serving the progress dashboard at http://%s/", d.addr)
+	return d, nil
+}
+
+// loadExpectedRows reads the row count CSV file (schema, table, count) written by the dump command.
+func loadExpectedRows(path string) (map[string]int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Error("Failure to open file: ", err)
+		log.Error("path: ", path)
+		return nil, err
+	}
+	defer f.Close()
+
+	expected := map[string]int64{}
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = 3
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return expected, nil
+		} else if err != nil {
+			log.Error("Unable to read row count file: ", err)
+			return nil, err
+		}
+		count, err := strconv.ParseInt(record[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid row count %q for %s.%s", record[2], record[0], record[1])
+		}
+		expected[record[0]+"."+record[1]] = count
+	}
+}
+
+// tick records that the line of the table in state has been read.
+func (d *dashboard) tick(state *LineState, line []byte) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.status.Lines++
+	d.status.BytesRead += int64(len(line))
+	if state.IsRow && !bytes.HasPrefix(line, []byte(StateChangeTokenEndCopy)) {
+		table := state.SchemaName + "." + state.TableName
+		if table != d.lastTable {
+			d.startTable(table)
+		}
+		d.tables[table].Rows++
+	} else if len(d.lastTable) > 0 {
+		d.tables[d.lastTable].Done = true
+		d.lastTable = ""
+		d.status.CurrentTable = ""
+	}
+
+	// The consistency stores are only written by this goroutine, so their sizes are read here
+	if d.status.Lines%dashboardStoreInterval == 0 {
+		d.readStoreSizes()
+	}
+}
+
+// startTable adds the table to the dashboard and makes it the current table.
+func (d *dashboard) startTable(table string) {
+	if d.tables[table] == nil {
+		d.status.Tables = append(d.status.Tables, DashboardTable{Table: table, ExpectedRows: d.expected[table]})
+		d.tables = map[string]*DashboardTable{}
+		for i := range d.status.Tables {
+			d.tables[d.status.Tables[i].Table] = &d.status.Tables[i]
+		}
+	}
+	d.tables[table].Done = false
+	d.lastTable = table
+	d.status.CurrentTable = table
+}
+
+// readStoreSizes reads the number of entries of every consistency store.
+func (d *dashboard) readStoreSizes() {
+	for key := range d.status.ConsistencyStores {
+		delete(d.status.ConsistencyStores, key)
+	}
+	for key, values := range AlphaNumericMap {
+		d.status.ConsistencyStores[key] = len(values)
+	}
+	d.status.ConsistencyStores["UUID"] = len(UUIDMap)
+}
+
+// sample records the throughput of the run every dashboardSampleInterval until the dashboard is closed.
+func (d *dashboard) sample() {
+	ticker := time.NewTicker(dashboardSampleInterval)
+	defer ticker.Stop()
+
+	var lastLines int64
+	for {
+		select {
+		case <-d.stop:
+			return
+		case now := <-ticker.C:
+			d.mu.Lock()
+			d.status.Throughput = append(d.status.Throughput, DashboardSample{
+				Time:           now,
+				LinesPerSecond: float64(d.status.Lines-lastLines) / dashboardSampleInterval.Seconds(),
+			})
+			if len(d.status.Throughput) > maxDashboardSamples {
+				d.status.Throughput = d.status.Throughput[len(d.status.Throughput)-maxDashboardSamples:]
+			}
+			lastLines = d.status.Lines
+			d.mu.Unlock()
+		}
+	}
+}
+
+// Levels returns the log levels recorded as recent errors (see Fire).
+func (d *dashboard) Levels() []log.Level {
+	return []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel, log.WarnLevel}
+}
+
+// Fire records a log entry as a recent error.
+func (d *dashboard) Fire(entry *log.Entry) error {
+	message := fmt.Sprintf("%s %s: %s", entry.Time.Format("15:04:05"), entry.Level, entry.Message)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status.Errors = append(d.status.Errors, message)
+	if len(d.status.Errors) > maxDashboardErrors {
+		d.status.Errors = d.status.Errors[len(d.status.Errors)-maxDashboardErrors:]
+	}
+	return nil
+}
+
+// snapshot returns a copy of the status of the run.
+func (d *dashboard) snapshot() DashboardStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status := d.status
+	status.Tables = append([]DashboardTable(nil), d.status.Tables...)
+	status.Throughput = append([]DashboardSample(nil), d.status.Throughput...)
+	status.Errors = append([]string(nil), d.status.Errors...)
+	status.ConsistencyStores = map[string]int{}
+	for key, size := range d.status.ConsistencyStores {
+		status.ConsistencyStores[key] = size
+	}
+	return status
+}
+
+// serveStatus writes the status of the run as JSON.
+func (d *dashboard) serveStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(d.snapshot()); err != nil {
+		log.Debug("Unable to write dashboard status: ", err)
+	}
+}
+
+// serveIndex writes the dashboard page. The page reloads the status every few seconds.
+func (d *dashboard) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, d.snapshot()); err != nil {
+		log.Debug("Unable to write dashboard: ", err)
+	}
+}
+
+// close stops the dashboard server and removes its log hook.
+func (d *dashboard) close() {
+	if d == nil {
+		return
+	}
+	close(d.stop)
+	log.StandardLogger().ReplaceHooks(d.hooks)
+	if err := d.server.Close(); err != nil {
+		log.Debug("Unable to stop dashboard: ", err)
+	}
+}
+
+// dashboardPercent returns the progress of a table (0 to 100) or -1 if the expected number of rows is not known.
+func dashboardPercent(table DashboardTable) int64 {
+	switch {
+	case table.Done:
+		return 100
+	case table.ExpectedRows <= 0:
+		return -1
+	case table.Rows >= table.ExpectedRows:
+		return 99
+	}
+	return 100 * table.Rows / table.ExpectedRows
+}
+
+// dashboardGraph returns the points of an SVG polyline (600x100) of the throughput samples.
+func dashboardGraph(samples []DashboardSample) string {
+	var peak float64
+	for _, sample := range samples {
+		if sample.LinesPerSecond > peak {
+			peak = sample.LinesPerSecond
+		}
+	}
+	var points bytes.Buffer
+	for i, sample := range samples {
+		y := 100.0
+		if peak > 0 {
+			y = 100 - 100*sample.LinesPerSecond/peak
+		}
+		fmt.Fprintf(&points, "%.1f,%.1f ", float64(i)*600/maxDashboardSamples, y)
+	}
+	return points.String()
+}
+
+// dashboardTemplate is the page served by the dashboard.
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"percent": dashboardPercent,
+	"graph":   dashboardGraph,
+	"overall": func(s DashboardStatus) int64 {
+		if s.TotalBytes == 0 {
+			return 0
+		}
+		return 100 * s.BytesRead / s.TotalBytes
+	},
+	"elapsed": func(s DashboardStatus) time.Duration { return time.Since(s.Started).Round(time.Second) },
+	"stores": func(stores map[string]int) []string {
+		keys := make([]string, 0, len(stores))
+		for key := range stores {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys
+	},
+	"last": func(samples []DashboardSample) float64 {
+		if len(samples) == 0 {
+			return 0
+		}
+		return samples[len(samples)-1].LinesPerSecond
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="5">
+<title>Gonymizer progress</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+td.number { text-align: right; }
+.bar { width: 300px; background: #eee; }
+.bar div { height: 12px; background: hsl(120, 50%, 50%); }
+pre { background: #fee; padding: 8px; }
+</style>
+</head>
+<body>
+<h1>Processing {{.Source}}</h1>
+<p>{{overall .}}% of {{.TotalBytes}} bytes, {{.Lines}} lines in {{elapsed .}}
+({{printf "%.0f" (last .Throughput)}} lines/s).
+Current table: {{if .CurrentTable}}{{.CurrentTable}}{{else}}none{{end}}</p>
+<div class="bar"><div style="width: {{overall .}}%"></div></div>
+
+<h2>Throughput</h2>
+<svg width="600" height="100" style="border: 1px solid #ccc">
+<polyline fill="none" stroke="steelblue" points="{{graph .Throughput}}"/>
+</svg>
+
+<h2>Tables</h2>
+<table>
+<tr><th>Table</th><th>Rows</th><th>Progress</th></tr>
+{{- range .Tables}}
+<tr><td>{{.Table}}</td><td class="number">{{.Rows}}{{if .ExpectedRows}} / {{.ExpectedRows}}{{end}}</td>
+<td>{{$p := percent .}}{{if ge $p 0}}<div class="bar"><div style="width: {{$p}}%"></div></div>
+{{- else}}in progress{{end}}</td>
+</tr>
+{{- end}}
+</table>
+
+<h2>Consistency stores</h2>
+<table>
+<tr><th>Store</th><th>Entries</th></tr>
+{{- $stores := .ConsistencyStores}}
+{{- range stores $stores}}
+<tr><td>{{.}}</td><td class="number">{{index $stores .}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Recent errors</h2>
+{{if .Errors}}<pre>{{range .Errors}}{{.}}
+{{end}}</pre>{{else}}<p>None</p>{{end}}
+</body>
+</html>
+`))
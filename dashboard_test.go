@@ -0,0 +1,82 @@
+package gonymizer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboard(t *testing.T) {
+	rowCounts := writeTestAuditFile(t, "public,users,4\npublic,orders,10\n")
+	defer os.Remove(rowCounts)
+	src := writeTestAuditFile(t, "COPY public.users (id) FROM stdin;\n1\n2\n\\.\nCOPY public.orders (id) FROM stdin;\n1\n")
+	defer os.Remove(src)
+
+	d, err := newDashboard("127.0.0.1:0", src, rowCounts)
+	require.Nil(t, err)
+	defer d.close()
+
+	f, err := os.Open(src)
+	require.Nil(t, err)
+	defer f.Close()
+	scanner := newDumpScanner(f)
+	state := new(LineState)
+	for {
+		line, err := scanner.next()
+		if len(line) > 0 {
+			d.tick(state, line)
+			switch {
+			case strings.HasPrefix(string(line), StateChangeTokenBeginCopy):
+				state.parseCopyLine(string(line))
+			case strings.HasPrefix(string(line), StateChangeTokenEndCopy):
+				state.Clear()
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	// Hooks only receive the entries of enabled levels
+	defer log.SetLevel(log.GetLevel())
+	log.SetLevel(log.WarnLevel)
+	log.Warn("Something is slow")
+
+	resp, err := http.Get("http://" + d.addr.String() + "/status")
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	var status DashboardStatus
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&status))
+	require.Equal(t, int64(6), status.Lines)
+	require.Equal(t, status.TotalBytes, status.BytesRead)
+	require.Equal(t, "public.orders", status.CurrentTable)
+	require.Equal(t, []DashboardTable{
+		{Table: "public.users", Rows: 2, ExpectedRows: 4, Done: true},
+		{Table: "public.orders", Rows: 1, ExpectedRows: 10},
+	}, status.Tables)
+	require.Len(t, status.Errors, 1)
+	require.Contains(t, status.Errors[0], "warning: Something is slow")
+
+	resp, err = http.Get("http://" + d.addr.String() + "/")
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	page, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+	require.Contains(t, string(page), "<td>public.orders</td><td class=\"number\">1 / 10</td>")
+	require.Contains(t, string(page), "width: 10%")
+
+	_, err = newDashboard("127.0.0.1:0", src, "/does/not/exist.csv")
+	require.NotNil(t, err)
+}
+
+func TestDashboardPercent(t *testing.T) {
+	require.Equal(t, int64(100), dashboardPercent(DashboardTable{Rows: 3, Done: true}))
+	require.Equal(t, int64(-1), dashboardPercent(DashboardTable{Rows: 3}))
+	require.Equal(t, int64(30), dashboardPercent(DashboardTable{Rows: 3, ExpectedRows: 10}))
+	require.Equal(t, int64(99), dashboardPercent(DashboardTable{Rows: 12, ExpectedRows: 10}))
+}
@@ -0,0 +1,204 @@
+package gonymizer
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/icrowley/fake"
+	log "github.com/sirupsen/logrus"
+)
+
+// Data pack files (categories) that replace the vocabulary of the fake library. Country specific files are stored in
+// a sub directory named after the alpha-2 country code (GB/cities).
+const (
+	DataPackFirstNames = "first_names"
+	DataPackLastNames  = "last_names"
+	DataPackStreets    = "streets"
+	DataPackCities     = "cities"
+	DataPackStates     = "states"
+	DataPackCompanies  = "companies"
+)
+
+// dataPackChecksumFile is the (optional) file of a data pack containing the SHA-256 checksum of every file in the
+// format written by sha256sum.
+const dataPackChecksumFile = "SHA256SUMS"
+
+// dataPackCategories are the files read from a data pack (and from each country directory).
+var dataPackCategories = []string{
+	DataPackFirstNames, DataPackLastNames, DataPackStreets, DataPackCities, DataPackStates, DataPackCompanies,
+}
+
+// dataPack contains the values of the loaded data pack by file (first_names, GB/cities, ...).
+var dataPack = map[string][]string{}
+
+// LoadDataPack will load the fake vocabularies found in dir. Each file contains one value per line, empty lines and
+// lines starting with # are skipped. Files are named after the category they replace (see DataPackFirstNames, ...) and
+// country specific cities and streets are read from sub directories named after the alpha-2 country code (GB/cities).
+// Categories without a file keep using the fake library. If dir contains a SHA256SUMS file every loaded file must be
+// listed in it with a matching checksum so the exact vocabulary used can be pinned and audited.
+func LoadDataPack(dir string) error {
+	checksums, err := loadDataPackChecksums(dir)
+	if err != nil {
+		return err
+	}
+
+	// files maps the data pack keys (GB/cities) to the path of the file relative to dir (gb/cities)
+	files := map[string]string{}
+	for _, category := range dataPackCategories {
+		files[category] = category
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Error("Failure to read data pack: ", err)
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && len(entry.Name()) == 2 {
+			for _, category := range []string{DataPackCities, DataPackStreets} {
+				files[strings.ToUpper(entry.Name())+"/"+category] = entry.Name() + "/" + category
+			}
+		}
+	}
+
+	pack := map[string][]string{}
+	for key, name := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		digest := sha256.Sum256(data)
+		checksum := hex.EncodeToString(digest[:])
+		if checksums != nil && checksums[name] != checksum {
+			return fmt.Errorf("Checksum of data pack file %s is missing from or does not match %s", path,
+				dataPackChecksumFile)
+		}
+
+		values, err := readDataPackValues(data)
+		if err != nil {
+			return err
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("Data pack file %s is empty", path)
+		}
+		pack[key] = values
+		log.Infof("Loaded %d values from data pack file %s (sha256: %s)", len(values), path, checksum)
+	}
+	dataPack = pack
+	return nil
+}
+
+// loadDataPackChecksums reads the SHA256SUMS file of the data pack in dir. Nil is returned if there is no such file.
+func loadDataPackChecksums(dir string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, dataPackChecksumFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	checksums := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		checksums[filepath.ToSlash(strings.TrimPrefix(fields[1], "*"))] = strings.ToLower(fields[0])
+	}
+	return checksums, nil
+}
+
+// readDataPackValues returns the values of a data pack file.
+func readDataPackValues(data []byte) ([]string, error) {
+	var values []string
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) > 0 && !strings.HasPrefix(line, "#") {
+			values = append(values, line)
+		}
+	}
+	return values, scanner.Err()
+}
+
+// DataPackFiles returns the names of the loaded data pack files.
+func DataPackFiles() []string {
+	var files []string
+	for name := range dataPack {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// dataPackValue returns a random value of the loaded data pack file name or the result of faker when the file was not
+// loaded.
+func dataPackValue(name string, faker func() string) string {
+	if values := dataPack[name]; len(values) > 0 {
+		return values[rng.Intn(len(values))]
+	}
+	return faker()
+}
+
+// fakeFirstName returns a fake first name.
+func fakeFirstName() string {
+	return dataPackValue(DataPackFirstNames, fake.FirstName)
+}
+
+// fakeLastName returns a fake last name.
+func fakeLastName() string {
+	return dataPackValue(DataPackLastNames, fake.LastName)
+}
+
+// fakeFullName returns a fake full name.
+func fakeFullName() string {
+	if len(dataPack[DataPackFirstNames]) == 0 && len(dataPack[DataPackLastNames]) == 0 {
+		return fake.FullName()
+	}
+	return fakeFirstName() + " " + fakeLastName()
+}
+
+// fakeCity returns a fake city.
+func fakeCity() string {
+	return dataPackValue(DataPackCities, fake.City)
+}
+
+// fakeState returns a fake state.
+func fakeState() string {
+	return dataPackValue(DataPackStates, fake.State)
+}
+
+// fakeCompany returns a fake company name.
+func fakeCompany() string {
+	return dataPackValue(DataPackCompanies, fake.Company)
+}
+
+// fakeStreet returns a fake street name. Country specific streets are used when countryCode is set and the data pack
+// contains them.
+func fakeStreet(countryCode string) string {
+	if len(countryCode) > 0 {
+		if values := dataPack[countryCode+"/"+DataPackStreets]; len(values) > 0 {
+			return values[rng.Intn(len(values))]
+		}
+	}
+	return dataPackValue(DataPackStreets, fake.Street)
+}
+
+// fakeStreetAddress returns a fake street address (house number and street).
+func fakeStreetAddress() string {
+	if len(dataPack[DataPackStreets]) == 0 {
+		return fake.StreetAddress()
+	}
+	return strconv.Itoa(rng.Intn(9999)+1) + " " + fakeStreet("")
+}
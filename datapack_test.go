@@ -0,0 +1,49 @@
+package gonymizer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDataPack(t *testing.T) {
+	defer func() { dataPack = map[string][]string{} }()
+
+	dir, err := ioutil.TempDir("", "gonymizer-datapack")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"first_names": "# Approved first names\nAlda\n\nBrin\n",
+		"last_names":  "Quill\n",
+		"gb/cities":   "Little Snoring\n",
+	}
+	var checksums strings.Builder
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+		digest := sha256.Sum256([]byte(contents))
+		checksums.WriteString(hex.EncodeToString(digest[:]) + "  " + name + "\n")
+	}
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, dataPackChecksumFile), []byte(checksums.String()), 0644))
+
+	require.Nil(t, LoadDataPack(dir))
+	require.Equal(t, []string{"GB/cities", "first_names", "last_names"}, DataPackFiles())
+	require.Contains(t, []string{"Alda", "Brin"}, fakeFirstName())
+	require.Regexp(t, `^(Alda|Brin) Quill$`, fakeFullName())
+	require.Contains(t, fakeCountryAddress("GB"), "Little Snoring")
+
+	// Categories without a file use the fake library
+	require.NotEmpty(t, fakeCity())
+
+	// Files must match the checksums
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "last_names"), []byte("Changed\n"), 0644))
+	require.NotNil(t, LoadDataPack(dir))
+}
@@ -0,0 +1,73 @@
+package gonymizer
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// defaultDateShiftDays is the maximum number of days the DateShift processor moves dates when the processor definition
+// does not set Max.
+const defaultDateShiftDays = 365
+
+// dateShiftMapKey is the key in the AlphaNumericMap used to store the offset of every subject of the DateShift
+// processor. The Group of the processor definition is added to keep the offsets of different kinds of subjects apart.
+const dateShiftMapKey = "date-shift"
+
+// dateShiftOffset returns the number of days the dates of the subject are moved by: a random number between -maxDays
+// and maxDays, never 0. The offset is stored so every date of the subject (in any column or table using the same
+// Group) is moved by the same number of days. Subjects without a value get a new offset for every date.
+func dateShiftOffset(group, subject string, ok bool, maxDays int) int {
+	random := func() int {
+		offset := 1 + rng.Intn(maxDays)
+		if rng.Intn(2) == 0 {
+			return -offset
+		}
+		return offset
+	}
+	if !ok {
+		return random()
+	}
+
+	key := dateShiftMapKey
+	if len(group) > 0 {
+		key += ":" + group
+	}
+	stored := mappedValue(key, subject, func() string {
+		return strconv.Itoa(random())
+	})
+	offset, err := strconv.Atoi(stored)
+	if err != nil {
+		offset = random()
+		setMappedValue(key, subject, strconv.Itoa(offset))
+	}
+	return offset
+}
+
+// shiftDate moves the date of an ISO 8601 date or timestamp (see timestampRegex) by the number of days. The time of
+// day and time zone offset are kept.
+func shiftDate(input string, days int) (string, error) {
+	match := timestampRegex.FindStringSubmatch(input)
+	if match == nil {
+		return "", fmt.Errorf("Date format is not ISO-8601: %q", input)
+	}
+	date, err := time.Parse("2006-01-02", match[1])
+	if err != nil {
+		return "", err
+	}
+	return date.AddDate(0, 0, days).Format("2006-01-02") + match[2] + match[3], nil
+}
+
+// validateDateShift checks that a DateShift processor definition names the column of the subject and a positive Max.
+func (procDef ProcessorDefinition) validateDateShift() error {
+	if procDef.Name != "DateShift" {
+		return nil
+	}
+	if len(procDef.SubjectColumn) == 0 {
+		return fmt.Errorf("Expected a SubjectColumn for processor %s", procDef.Name)
+	}
+	if procDef.Max < 0 || (procDef.Max > 0 && procDef.Max < 1) {
+		return fmt.Errorf("Expected a Max of at least 1 day for processor %s, got %g", procDef.Name, procDef.Max)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package gonymizer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorDateShift(t *testing.T) {
+	defer delete(AlphaNumericMap, dateShiftMapKey+":patient")
+	defer func() { currentRow = newRowContext(nil, nil) }()
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{
+		{Name: "DateShift", SubjectColumn: "patient_id", Group: "patient", Max: 30},
+	}}
+
+	days := func(a, b string) float64 {
+		x, err := time.Parse("2006-01-02", a[:10])
+		require.Nil(t, err)
+		y, err := time.Parse("2006-01-02", b[:10])
+		require.Nil(t, err)
+		return y.Sub(x).Hours() / 24
+	}
+
+	// Every date of a subject is moved by the same number of days
+	currentRow = newRowContext([]string{"id", "patient_id"}, []string{"1", "42"})
+	admitted, err := ProcessorDateShift(cmap, "2020-02-27")
+	require.Nil(t, err)
+	shift := days("2020-02-27", admitted)
+	require.True(t, shift != 0 && shift >= -30 && shift <= 30, admitted)
+
+	currentRow = newRowContext([]string{"id", "patient_id"}, []string{"2", "42"})
+	discharged, err := ProcessorDateShift(cmap, "2020-03-02 13:45:00.5+02")
+	require.Nil(t, err)
+	require.Equal(t, shift, days("2020-03-02", discharged))
+	require.Equal(t, float64(4), days(admitted, discharged))
+	require.Equal(t, " 13:45:00.5+02", discharged[10:])
+
+	_, err = ProcessorDateShift(cmap, "02/27/2020")
+	require.NotNil(t, err)
+}
+
+func TestValidateDateShift(t *testing.T) {
+	procDef := ProcessorDefinition{Name: "DateShift", SubjectColumn: "patient_id"}
+	require.Nil(t, procDef.validateDateShift())
+	procDef.Max = 0.5
+	require.NotNil(t, procDef.validateDateShift())
+	procDef.Max = 10
+	procDef.SubjectColumn = ""
+	require.NotNil(t, procDef.validateDateShift())
+}
@@ -70,12 +70,18 @@ func GetAllProceduresInSchema(conf PGConfig, schema string) ([]string, error) {
 func GetAllSchemaColumns(db *sql.DB) (*sql.Rows, error) {
 	query := `
 			SELECT table_catalog, table_schema, table_name, column_name, data_type, ordinal_position,
+			CASE
+			    WHEN data_type IN ('character varying', 'character') THEN
+			        COALESCE(character_maximum_length, 0)
+			    ELSE 0
+			END AS max_length,
 			CASE
 			    WHEN is_nullable = 'YES' THEN
 			        TRUE
           WHEN is_nullable = 'NO' THEN
               FALSE
-					END AS is_nullable
+					END AS is_nullable,
+			COALESCE(is_generated = 'ALWAYS', FALSE) AS is_generated
 			FROM information_schema.columns
 			WHERE table_schema NOT IN ('information_schema', 'pg_catalog')
 			ORDER BY table_schema, table_name, ordinal_position
@@ -188,13 +194,19 @@ func GetSchemasInDatabase(conf PGConfig, excludeSchemas []string) ([]string, err
 // the provided schema (using the SQL equals operator).
 func GetSchemaColumnEquals(db *sql.DB, schema string) (*sql.Rows, error) {
 	rows, err := db.Query(`
-	SELECT table_catalog, table_schema, table_name, column_name, data_type, ordinal_position, 
+	SELECT table_catalog, table_schema, table_name, column_name, data_type, ordinal_position,
+			CASE
+			    WHEN data_type IN ('character varying', 'character') THEN
+			        COALESCE(character_maximum_length, 0)
+			    ELSE 0
+			END AS max_length,
 			CASE
 			    WHEN is_nullable = 'YES' THEN
 			        TRUE
           WHEN is_nullable = 'NO' THEN
               FALSE
-					END AS is_nullable
+					END AS is_nullable,
+			COALESCE(is_generated = 'ALWAYS', FALSE) AS is_generated
 	FROM information_schema.columns
 	WHERE table_schema = $1
 	ORDER BY table_schema, table_name, ordinal_position`, schema)
@@ -227,13 +239,19 @@ func GetSchemaColumnsLike(db *sql.DB, schemaPrefix string) (*sql.Rows, error) {
 
 	// Now grab all the columns from this schema
 	rows, err := db.Query(`
-			SELECT table_catalog, table_schema, table_name, column_name, data_type, ordinal_position, 
+			SELECT table_catalog, table_schema, table_name, column_name, data_type, ordinal_position,
+			CASE
+			    WHEN data_type IN ('character varying', 'character') THEN
+			        COALESCE(character_maximum_length, 0)
+			    ELSE 0
+			END AS max_length,
 			CASE
 			    WHEN is_nullable = 'YES' THEN
 			        TRUE
           WHEN is_nullable = 'NO' THEN
               FALSE
-					END AS is_nullable
+					END AS is_nullable,
+			COALESCE(is_generated = 'ALWAYS', FALSE) AS is_generated
 			FROM information_schema.columns
 			WHERE table_schema = $1
 			ORDER BY table_schema, table_name, ordinal_position`, selectedSchema)
@@ -322,7 +340,8 @@ func GetTableRowCountsInDB(conf PGConfig, schemaPrefix string, excludeTable []st
 	// Luckily Postgres is smart and does not blow away cache for a
 	// simple Count(*). See -> https://stackoverflow.com/questions/37097736/understanding-postgres-caching
 	for _, row := range dbRowCounts {
-		query := fmt.Sprintf("SELECT COUNT(*) FROM %s.%s;", *row.SchemaName, *row.TableName)
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s.%s;", quoteIdentifier(*row.SchemaName),
+			quoteIdentifier(*row.TableName))
 		if err := db.QueryRow(query).Scan(row.Count); err != nil {
 			log.Error(err)
 		}
@@ -350,7 +369,7 @@ func KillDatabaseConnections(db *sql.DB, dbName string) (err error) {
 
 // RenameDatabase will rename a database using the fromName to the toName.
 func RenameDatabase(db *sql.DB, fromName, toName string) (err error) {
-	_, err = db.Exec(fmt.Sprintf("ALTER DATABASE %s RENAME TO %s", fromName, toName))
+	_, err = db.Exec(fmt.Sprintf("ALTER DATABASE %s RENAME TO %s", quoteIdentifier(fromName), quoteIdentifier(toName)))
 	if err != nil {
 		log.Errorf("Unable to rename database '%s' -> '%s'", fromName, toName)
 		log.Error(err)
@@ -25,7 +25,7 @@ func DropDatabase(conf PGConfig) error {
 		dburl,
 		"-v", "ON_ERROR_STOP=1",
 		"-c", // run a command
-		"DROP DATABASE IF EXISTS " + conf.DefaultDBName + ";",
+		"DROP DATABASE IF EXISTS " + quoteIdentifier(conf.DefaultDBName) + ";",
 	}
 
 	err := ExecPostgresCmd(cmd, args...)
@@ -47,7 +47,8 @@ func DropPublicTables(conf PGConfig) error {
 
 	dropStatements := []string{}
 	for _, tablename := range tablenames {
-		dropStatements = append(dropStatements, fmt.Sprintf("DROP TABLE IF EXISTS \"%s\" CASCADE;", tablename))
+		dropStatements = append(dropStatements, fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE;",
+			quoteIdentifier(tablename)))
 	}
 
 	dropAll := strings.Join(dropStatements, " ")
@@ -83,7 +84,7 @@ func CreateDatabase(conf PGConfig) error {
 		dburl,
 		"-v", "ON_ERROR_STOP=1",
 		"-c", // run a command
-		"CREATE DATABASE " + conf.DefaultDBName + ";",
+		"CREATE DATABASE " + quoteIdentifier(conf.DefaultDBName) + ";",
 	}
 
 	err := ExecPostgresCmd(cmd, args...)
@@ -162,7 +163,7 @@ func ExecPostgresCommandOutErr(stdOut, stdErr io.Writer, name string, arg ...str
 		name = filepath.Join(pgBinDir, name)
 	}
 	cmd := exec.Command(name, arg...)
-	cmd.Env = append(os.Environ())
+	cmd.Env = os.Environ()
 
 	// we use buffers cause we want the output to go to two places...
 	var outBuffer bytes.Buffer
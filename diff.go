@@ -0,0 +1,264 @@
+package gonymizer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ColumnDiff contains the number of rows of a column that were compared between two processed dump files and the
+// number of rows whose value was different.
+type ColumnDiff struct {
+	Column     string
+	Compared   int64
+	Changed    int64
+	ChangeRate float64
+}
+
+// TableDiff contains the structure of a table in two processed dump files (A and B) and the value changes of the
+// columns found in both.
+type TableDiff struct {
+	Table          string
+	RowsA          int64
+	RowsB          int64
+	ColumnsOnlyInA []string `json:",omitempty"`
+	ColumnsOnlyInB []string `json:",omitempty"`
+	Columns        []ColumnDiff
+}
+
+// OutputDiff is the result of comparing two processed dump files, for example the output of two gonymizer versions or
+// of two versions of a map file.
+type OutputDiff struct {
+	FileA       string
+	FileB       string
+	TablesOnlyA []string `json:",omitempty"`
+	TablesOnlyB []string `json:",omitempty"`
+	Tables      []TableDiff
+}
+
+// DiffOutputs compares two processed dump files. Tables are matched by name and rows by their position in the table,
+// so both files must list the rows of a table in the same order (as pg_dump does for the same source database).
+func DiffOutputs(fileA, fileB string) (*OutputDiff, error) {
+	// First pass: the tables, columns, and row counts of both files
+	structureA, err := dumpStructure(fileA)
+	if err != nil {
+		return nil, err
+	}
+	structureB, err := dumpStructure(fileB)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &OutputDiff{FileA: fileA, FileB: fileB}
+	tables := map[string]*TableDiff{}
+	for table, a := range structureA {
+		b, ok := structureB[table]
+		if !ok {
+			diff.TablesOnlyA = append(diff.TablesOnlyA, table)
+			continue
+		}
+		tableDiff := &TableDiff{
+			Table:          table,
+			RowsA:          a.rows,
+			RowsB:          b.rows,
+			ColumnsOnlyInA: missingColumns(a.columns, b.columns),
+			ColumnsOnlyInB: missingColumns(b.columns, a.columns),
+		}
+		for _, column := range a.columns {
+			if !containsString(b.columns, column) {
+				continue
+			}
+			tableDiff.Columns = append(tableDiff.Columns, ColumnDiff{Column: column})
+		}
+		tables[table] = tableDiff
+	}
+	for table := range structureB {
+		if _, ok := structureA[table]; !ok {
+			diff.TablesOnlyB = append(diff.TablesOnlyB, table)
+		}
+	}
+
+	// Second pass: compare the values of the rows found in both files
+	if err := compareDumpRows(fileA, fileB, structureA, structureB, tables); err != nil {
+		return nil, err
+	}
+
+	for _, tableDiff := range tables {
+		for i := range tableDiff.Columns {
+			column := &tableDiff.Columns[i]
+			if column.Compared > 0 {
+				column.ChangeRate = float64(column.Changed) / float64(column.Compared)
+			}
+		}
+		diff.Tables = append(diff.Tables, *tableDiff)
+	}
+	sort.Slice(diff.Tables, func(i, j int) bool { return diff.Tables[i].Table < diff.Tables[j].Table })
+	sort.Strings(diff.TablesOnlyA)
+	sort.Strings(diff.TablesOnlyB)
+	return diff, nil
+}
+
+// Differences returns a description of every structural difference (tables, columns, and row counts) between the two
+// files and of every column whose values changed in more than maxChangeRate (0 to 1) of the compared rows.
+func (diff *OutputDiff) Differences(maxChangeRate float64) []string {
+	var differences []string
+	for _, table := range diff.TablesOnlyA {
+		differences = append(differences, fmt.Sprintf("Table %s is only found in %s", table, diff.FileA))
+	}
+	for _, table := range diff.TablesOnlyB {
+		differences = append(differences, fmt.Sprintf("Table %s is only found in %s", table, diff.FileB))
+	}
+	for _, table := range diff.Tables {
+		if table.RowsA != table.RowsB {
+			differences = append(differences, fmt.Sprintf("Table %s has %d row(s) in %s and %d row(s) in %s",
+				table.Table, table.RowsA, diff.FileA, table.RowsB, diff.FileB))
+		}
+		for _, column := range table.ColumnsOnlyInA {
+			differences = append(differences, fmt.Sprintf("Column %s.%s is only found in %s", table.Table, column,
+				diff.FileA))
+		}
+		for _, column := range table.ColumnsOnlyInB {
+			differences = append(differences, fmt.Sprintf("Column %s.%s is only found in %s", table.Table, column,
+				diff.FileB))
+		}
+		for _, column := range table.Columns {
+			if column.ChangeRate > maxChangeRate {
+				differences = append(differences, fmt.Sprintf("Column %s.%s changed in %.1f%% of %d row(s)",
+					table.Table, column.Column, column.ChangeRate*100, column.Compared))
+			}
+		}
+	}
+	return differences
+}
+
+// WriteOutputDiff will save the diff to filepath as JSON.
+func WriteOutputDiff(diff *OutputDiff, filepath string) error {
+	return writeJSONFile(filepath, diff)
+}
+
+// tableStructure contains the columns and number of rows of a table in a dump file.
+type tableStructure struct {
+	columns []string
+	rows    int64
+}
+
+// dumpStructure returns the structure of every table with a COPY statement in the dump file.
+func dumpStructure(path string) (map[string]*tableStructure, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	structure := map[string]*tableStructure{}
+	scanner := newDumpScanner(f)
+	state := new(LineState)
+	var table *tableStructure
+	for {
+		line, err := scanner.next()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		trimmed := strings.TrimLeft(string(line), " \t\r\n")
+		switch {
+		case !state.IsRow && strings.HasPrefix(trimmed, StateChangeTokenBeginCopy):
+			state.parseCopyLine(string(line))
+			table = &tableStructure{columns: append([]string(nil), state.ColumnNames...)}
+			structure[state.SchemaName+"."+state.TableName] = table
+		case state.IsRow && strings.HasPrefix(trimmed, StateChangeTokenEndCopy):
+			state.Clear()
+		case state.IsRow && len(trimmed) > 0:
+			table.rows++
+		}
+
+		if err == io.EOF {
+			return structure, nil
+		}
+	}
+}
+
+// compareDumpRows reads both dump files side by side and counts the changed values of the columns in tables. Rows of
+// a table that is missing from the other file, or beyond the row count of the table in the other file, are skipped.
+func compareDumpRows(fileA, fileB string, structureA, structureB map[string]*tableStructure,
+	tables map[string]*TableDiff) error {
+	rowsA, err := openDumpRows(fileA)
+	if err != nil {
+		return err
+	}
+	defer rowsA.close()
+	rowsB, err := openDumpRows(fileB)
+	if err != nil {
+		return err
+	}
+	defer rowsB.close()
+
+	valuesA, errA := rowsA.next()
+	valuesB, errB := rowsB.next()
+	for errA == nil && errB == nil {
+		tableA := rowsA.state.SchemaName + "." + rowsA.state.TableName
+		tableB := rowsB.state.SchemaName + "." + rowsB.state.TableName
+
+		if tableA == tableB && rowsA.state.RowNumber == rowsB.state.RowNumber {
+			compareRow(tables[tableA], rowsA.state.ColumnNames, valuesA, rowsB.state.ColumnNames, valuesB)
+			valuesA, errA = rowsA.next()
+			valuesB, errB = rowsB.next()
+			continue
+		}
+
+		// Skip the rows that can not be matched until both files are at the same row again
+		if structureB[tableA] == nil || rowsA.state.RowNumber > structureB[tableA].rows {
+			valuesA, errA = rowsA.next()
+		} else if structureA[tableB] == nil || rowsB.state.RowNumber > structureA[tableB].rows {
+			valuesB, errB = rowsB.next()
+		} else {
+			// The tables are in a different order, the rows of table A are not compared
+			valuesA, errA = rowsA.next()
+		}
+	}
+	if errA != nil && errA != io.EOF {
+		return errA
+	}
+	if errB != nil && errB != io.EOF {
+		return errB
+	}
+	return nil
+}
+
+// compareRow counts the compared and changed values of every column of tableDiff for a row found in both files.
+func compareRow(tableDiff *TableDiff, columnsA, valuesA, columnsB, valuesB []string) {
+	for i := range tableDiff.Columns {
+		column := &tableDiff.Columns[i]
+		a, b := indexOf(columnsA, column.Column), indexOf(columnsB, column.Column)
+		if a < 0 || b < 0 || a >= len(valuesA) || b >= len(valuesB) {
+			continue
+		}
+		column.Compared++
+		if valuesA[a] != valuesB[b] {
+			column.Changed++
+		}
+	}
+}
+
+// missingColumns returns the columns that are not found in others.
+func missingColumns(columns, others []string) []string {
+	var missing []string
+	for _, column := range columns {
+		if !containsString(others, column) {
+			missing = append(missing, column)
+		}
+	}
+	return missing
+}
+
+// indexOf returns the index of value in values or -1 if it is not found.
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
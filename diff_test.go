@@ -0,0 +1,59 @@
+package gonymizer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffOutputs(t *testing.T) {
+	a := writeTestAuditFile(t, "COPY public.users (id, name, country) FROM stdin;\n"+
+		"1\tJane\tUS\n2\tJohn\tCanada\n3\tMary\tPeru\n\\.\n"+
+		"COPY public.accounts (id) FROM stdin;\n1\n\\.\n"+
+		"COPY public.orders (id, total) FROM stdin;\n1\t10\n2\t20\n\\.\n")
+	defer os.Remove(a)
+	b := writeTestAuditFile(t, "COPY public.users (id, name, email) FROM stdin;\n"+
+		"1\tJane\tj@example.com\n2\tJack\tk@example.com\n\\.\n"+
+		"COPY public.orders (id, total) FROM stdin;\n1\t10\n2\t25\n\\.\n"+
+		"COPY public.events (id) FROM stdin;\n\\.\n")
+	defer os.Remove(b)
+
+	diff, err := DiffOutputs(a, b)
+	require.Nil(t, err)
+	require.Equal(t, []string{"public.accounts"}, diff.TablesOnlyA)
+	require.Equal(t, []string{"public.events"}, diff.TablesOnlyB)
+	require.Equal(t, []TableDiff{
+		{
+			Table: "public.orders",
+			RowsA: 2,
+			RowsB: 2,
+			Columns: []ColumnDiff{
+				{Column: "id", Compared: 2},
+				{Column: "total", Compared: 2, Changed: 1, ChangeRate: 0.5},
+			},
+		},
+		{
+			Table:          "public.users",
+			RowsA:          3,
+			RowsB:          2,
+			ColumnsOnlyInA: []string{"country"},
+			ColumnsOnlyInB: []string{"email"},
+			Columns: []ColumnDiff{
+				{Column: "id", Compared: 2},
+				{Column: "name", Compared: 2, Changed: 1, ChangeRate: 0.5},
+			},
+		},
+	}, diff.Tables)
+
+	require.Len(t, diff.Differences(1), 5)
+	require.Len(t, diff.Differences(0.25), 7)
+
+	// The same file has no differences
+	diff, err = DiffOutputs(a, a)
+	require.Nil(t, err)
+	require.Empty(t, diff.Differences(0))
+
+	_, err = DiffOutputs(a, "missing.sql")
+	require.NotNil(t, err)
+}
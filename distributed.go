@@ -0,0 +1,549 @@
+package gonymizer
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// CoordinatorTokenEnv is the environment variable the CLI reads the token shared by the coordinator and its workers
+// from (see CoordinatorOptions).
+const CoordinatorTokenEnv = "GONYMIZER_COORDINATOR_TOKEN"
+
+// MappingStoreEnv is the environment variable the CLI of a worker reads the URI of the mapping store from, including
+// the credentials the coordinator does not send (see WorkerOptions).
+const MappingStoreEnv = "GONYMIZER_MAPPING_STORE"
+
+// CoordinatorJob describes the dump file that is split across the workers of a Coordinator. The dump, map, and
+// pre/post-process files must be readable by every worker using the same paths (e.g. a shared volume). Every shard is
+// written to its own processed file (see ShardFileName). When BalanceShards is set the coordinator plans the shards by
+// the size of the tables (see PlanShards) before handing them out. SecureRandom is passed to the ProcessOptions of the
+// workers, and every worker opens the MappingStore URI (see OpenMappingStore) so they share the consistent mappings.
+// Workers process their shards at the same time, so a job with more than one shard requires a shared mapping store
+// (see IsSharedMappingStore). The user name and password of the MappingStore URI are never sent to the workers, which
+// read them from their own configuration (see WorkerOptions).
+type CoordinatorJob struct {
+	DumpFile        string
+	MapFile         string
+	ProcessedFile   string
+	PreProcessFile  string
+	PostProcessFile string
+	GenerateSeed    bool
+	SecureRandom    bool
+	MappingStore    string
+	Shards          int
+	BalanceShards   bool
+	ShardPlan       *ShardPlan
+}
+
+// defaultLease is how long a worker may go without a heartbeat when CoordinatorOptions.Lease is not set.
+const defaultLease = time.Minute
+
+// CoordinatorOptions configures a Coordinator. Token is the secret shared with the workers (see WorkerOptions). Calls
+// from workers that do not present it are refused. A worker holds the lease of its shard by sending a heartbeat
+// several times per Lease (default one minute); the shard of a worker that stops sending them is assigned again.
+// MaxRetries is how many times a shard that failed or whose lease expired is assigned again before the run fails.
+type CoordinatorOptions struct {
+	Token      string
+	Lease      time.Duration
+	MaxRetries int
+}
+
+// WorkerOptions configures a worker (see RunWorker). Token must match the token of the coordinator. MappingStore is the
+// URI of the mapping store including its credentials; when empty the URI sent by the coordinator (without credentials)
+// is used. TLSConfig encrypts the connection to a coordinator that listens with TLS (see ListenCoordinator).
+type WorkerOptions struct {
+	Token        string
+	MappingStore string
+	TLSConfig    *tls.Config
+}
+
+// WorkRequest is sent by a worker asking for an assignment.
+type WorkRequest struct {
+	Worker string
+	Token  string
+}
+
+// WorkAssignment is the shard of the job assigned to a worker, who must send a heartbeat (see WorkHeartbeat) several
+// times per Lease. Done is set when there is no work left. Idle is set when every remaining shard is held by another
+// worker, which may still fail: the worker asks again after a while.
+type WorkAssignment struct {
+	CoordinatorJob
+	Shard int
+	Lease time.Duration
+	Done  bool
+	Idle  bool
+}
+
+// WorkHeartbeat is sent by a worker while it processes its assignment to renew the lease of its shard.
+type WorkHeartbeat struct {
+	Worker string
+	Token  string
+	Shard  int
+}
+
+// WorkResult is sent by a worker once it has finished (or failed) its assignment.
+type WorkResult struct {
+	Worker string
+	Token  string
+	Shard  int
+	Error  string
+	Report ShardReport
+}
+
+// ConsistencyState contains the global mappings (AlphaNumericMap and UUIDMap) that keep anonymized values consistent
+// across tables. UUIDs are stored as strings.
+type ConsistencyState struct {
+	AlphaNumeric map[string]map[string]string
+	UUIDs        map[string]string
+}
+
+// Coordinator assigns the shards of a CoordinatorJob to workers (see RunWorker) over RPC (Go's net/rpc) and aggregates
+// their reports. The workers share the consistent mappings through the mapping store of the job. Shards of workers that
+// report a failure or whose lease expires are assigned again (see CoordinatorOptions).
+type Coordinator struct {
+	job        CoordinatorJob
+	token      string
+	lease      time.Duration
+	maxRetries int
+	mu         sync.Mutex
+	pending    []int
+	running    map[int]*shardLease
+	retries    map[int]int
+	reports    []ShardReport
+	err        error
+	finished   bool
+	done       chan struct{}
+}
+
+// shardLease is held by the worker processing a shard until it expires.
+type shardLease struct {
+	worker  string
+	expires time.Time
+}
+
+// coordinatorRPC contains the methods of the Coordinator that are exposed over RPC.
+type coordinatorRPC struct {
+	c *Coordinator
+}
+
+// NewCoordinator returns a Coordinator for the job.
+func NewCoordinator(job CoordinatorJob, opts CoordinatorOptions) (*Coordinator, error) {
+	if len(opts.Token) == 0 {
+		return nil, errors.New("Expected a token shared with the workers")
+	}
+	if job.Shards < 1 {
+		return nil, errors.New("Expected at least one shard")
+	}
+	if len(job.DumpFile) == 0 || len(job.MapFile) == 0 || len(job.ProcessedFile) == 0 {
+		return nil, errors.New("Expected a dump file, map file, and processed file")
+	}
+	if job.Shards > 1 && !IsSharedMappingStore(job.MappingStore) {
+		return nil, errors.New("Expected a shared mapping store (redis:// or postgres://) for more than one shard")
+	}
+	mappingStore, err := withoutCredentials(job.MappingStore)
+	if err != nil {
+		return nil, err
+	}
+	job.MappingStore = mappingStore
+	if job.BalanceShards && job.Shards > 1 {
+		plan, err := PlanShards(job.DumpFile, job.Shards)
+		if err != nil {
+			return nil, err
+		}
+		job.ShardPlan = plan
+	}
+
+	c := &Coordinator{
+		job:        job,
+		token:      opts.Token,
+		lease:      opts.Lease,
+		maxRetries: opts.MaxRetries,
+		running:    map[int]*shardLease{},
+		retries:    map[int]int{},
+		done:       make(chan struct{}),
+	}
+	if c.lease <= 0 {
+		c.lease = defaultLease
+	}
+	for shard := 0; shard < job.Shards; shard++ {
+		c.pending = append(c.pending, shard)
+	}
+	return c, nil
+}
+
+// ListenCoordinator returns a listener on address for Serve. The coordinator hands out the paths of PII dump files, so
+// unless address is a loopback address (e.g. 127.0.0.1:7070) the connections must be encrypted with tlsConfig.
+func ListenCoordinator(address string, tlsConfig *tls.Config) (net.Listener, error) {
+	if tlsConfig == nil {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, fmt.Errorf("Expected a TLS certificate to listen on %s, which is not a loopback address", address)
+		}
+		return net.Listen("tcp", address)
+	}
+	return tls.Listen("tcp", address, tlsConfig)
+}
+
+// Serve accepts worker connections on listener until the listener is closed.
+func (c *Coordinator) Serve(listener net.Listener) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName("Coordinator", &coordinatorRPC{c: c}); err != nil {
+		return err
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go server.ServeConn(conn)
+	}
+}
+
+// Wait blocks until every shard has been processed (or timeout is reached) and returns the merged report. Leases that
+// expire while waiting are released so their shards are assigned again. Returns an error if a shard failed more than
+// MaxRetries times (see CoordinatorOptions).
+func (c *Coordinator) Wait(timeout time.Duration) (*MergedShardReport, error) {
+	ticker := time.NewTicker(c.lease / 4)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+	for waiting := true; waiting; {
+		select {
+		case <-c.done:
+			waiting = false
+		case <-ticker.C:
+			c.mu.Lock()
+			c.expireLeases(time.Now())
+			c.mu.Unlock()
+		case <-deadline:
+			return nil, errors.New("Timed out waiting for the workers to finish")
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	return mergeShardReports(c.job.Shards, append([]ShardReport(nil), c.reports...)), nil
+}
+
+// authenticate returns an error if token does not match the token of the coordinator.
+func (c *Coordinator) authenticate(worker, token string) error {
+	if subtle.ConstantTimeCompare([]byte(token), []byte(c.token)) != 1 {
+		log.Warnf("Refusing worker %s with an invalid token", worker)
+		return errors.New("Invalid coordinator token")
+	}
+	return nil
+}
+
+// expireLeases assigns the shards whose lease expired before now again. The caller must hold the lock.
+func (c *Coordinator) expireLeases(now time.Time) {
+	for shard, lease := range c.running {
+		if now.After(lease.expires) {
+			delete(c.running, shard)
+			c.retry(shard, fmt.Sprintf("worker %s stopped sending heartbeats", lease.worker))
+		}
+	}
+}
+
+// retry assigns the shard to the next worker that asks, or fails the run if the shard was already retried MaxRetries
+// times. The caller must hold the lock.
+func (c *Coordinator) retry(shard int, reason string) {
+	c.retries[shard]++
+	if c.retries[shard] > c.maxRetries {
+		c.finish(fmt.Errorf("Shard %d failed %d time(s), last because %s", shard, c.retries[shard], reason))
+		return
+	}
+	log.Warnf("Assigning shard %d again (retry %d/%d) because %s", shard, c.retries[shard], c.maxRetries, reason)
+	c.pending = append(c.pending, shard)
+}
+
+// finish ends the run with err (nil on success) and releases Wait. The caller must hold the lock.
+func (c *Coordinator) finish(err error) {
+	if c.finished {
+		return
+	}
+	c.finished = true
+	c.err = err
+	close(c.done)
+}
+
+// Assign hands the next pending shard to the worker. If the run is finished Done is set, and if every remaining shard
+// is held by another worker Idle is set.
+func (r *coordinatorRPC) Assign(request WorkRequest, assignment *WorkAssignment) error {
+	c := r.c
+	if err := c.authenticate(request.Worker, request.Token); err != nil {
+		return err
+	}
+	worker := request.Worker
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expireLeases(time.Now())
+	if c.finished {
+		*assignment = WorkAssignment{Done: true}
+		return nil
+	}
+	if len(c.pending) == 0 {
+		*assignment = WorkAssignment{Lease: c.lease, Idle: true}
+		return nil
+	}
+	shard := c.pending[0]
+	c.pending = c.pending[1:]
+	c.running[shard] = &shardLease{worker: worker, expires: time.Now().Add(c.lease)}
+
+	log.Infof("Assigning shard %d/%d to worker %s", shard, c.job.Shards, worker)
+	*assignment = WorkAssignment{CoordinatorJob: c.job, Shard: shard, Lease: c.lease}
+	return nil
+}
+
+// Heartbeat renews the lease of the worker's shard. Returns an error if the shard is no longer held by the worker.
+func (r *coordinatorRPC) Heartbeat(heartbeat WorkHeartbeat, ack *bool) error {
+	c := r.c
+	if err := c.authenticate(heartbeat.Worker, heartbeat.Token); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lease := c.running[heartbeat.Shard]
+	if lease == nil || lease.worker != heartbeat.Worker {
+		return fmt.Errorf("Shard %d is not assigned to worker %s", heartbeat.Shard, heartbeat.Worker)
+	}
+	lease.expires = time.Now().Add(c.lease)
+	*ack = true
+	return nil
+}
+
+// Complete records the result of a worker's assignment. Failed shards are assigned to the next worker that asks.
+func (r *coordinatorRPC) Complete(result WorkResult, ack *bool) error {
+	c := r.c
+	if err := c.authenticate(result.Worker, result.Token); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if lease := c.running[result.Shard]; lease == nil || lease.worker != result.Worker {
+		return fmt.Errorf("Shard %d is not assigned to worker %s", result.Shard, result.Worker)
+	}
+	delete(c.running, result.Shard)
+
+	if len(result.Error) > 0 {
+		log.Errorf("Worker %s failed shard %d: %s", result.Worker, result.Shard, result.Error)
+		c.retry(result.Shard, fmt.Sprintf("worker %s failed it", result.Worker))
+		*ack = true
+		return nil
+	}
+
+	log.Infof("Worker %s finished shard %d (%d rows)", result.Worker, result.Shard, result.Report.Rows)
+	c.reports = append(c.reports, result.Report)
+	if len(c.reports) == c.job.Shards {
+		c.finish(nil)
+	}
+	*ack = true
+	return nil
+}
+
+// merge adds the mappings of other that are not already in the state. The first mapping of a value wins.
+func (state *ConsistencyState) merge(other ConsistencyState) {
+	for key, values := range other.AlphaNumeric {
+		if state.AlphaNumeric[key] == nil {
+			state.AlphaNumeric[key] = map[string]string{}
+		}
+		for input, output := range values {
+			if _, ok := state.AlphaNumeric[key][input]; !ok {
+				state.AlphaNumeric[key][input] = output
+			}
+		}
+	}
+	for input, output := range other.UUIDs {
+		if _, ok := state.UUIDs[input]; !ok {
+			state.UUIDs[input] = output
+		}
+	}
+}
+
+// RunWorker connects to the coordinator at address and processes the shards assigned to it until there is no work
+// left, sending heartbeats to hold the lease of each shard. Worker is the name of the worker used in the coordinator's
+// logs.
+func RunWorker(address, worker string, opts WorkerOptions) error {
+	client, err := dialCoordinator(address, opts.TLSConfig)
+	if err != nil {
+		log.Error(err)
+		log.Error("address: ", address)
+		return err
+	}
+	defer client.Close()
+
+	for {
+		var assignment WorkAssignment
+		request := WorkRequest{Worker: worker, Token: opts.Token}
+		if err = client.Call("Coordinator.Assign", request, &assignment); err != nil {
+			return err
+		}
+		if assignment.Done {
+			log.Info("No work left for worker: ", worker)
+			return nil
+		}
+		if assignment.Idle {
+			time.Sleep(assignment.Lease / 3)
+			continue
+		}
+
+		if len(opts.MappingStore) > 0 {
+			assignment.MappingStore = opts.MappingStore
+		}
+		result := WorkResult{Worker: worker, Token: opts.Token, Shard: assignment.Shard}
+		stop := make(chan struct{})
+		go sendHeartbeats(client, WorkHeartbeat{Worker: worker, Token: opts.Token, Shard: assignment.Shard},
+			assignment.Lease/3, stop)
+		report, err := processAssignment(assignment)
+		close(stop)
+		if err != nil {
+			log.Errorf("Unable to process shard %d: %s", assignment.Shard, err)
+			result.Error = err.Error()
+		} else {
+			result.Report = *report
+		}
+
+		var ack bool
+		if err = client.Call("Coordinator.Complete", result, &ack); err != nil {
+			if _, ok := err.(rpc.ServerError); !ok {
+				return err
+			}
+			// The lease expired and the shard was assigned to another worker
+			log.Warnf("Result of shard %d was refused: %s", assignment.Shard, err)
+		}
+	}
+}
+
+// sendHeartbeats sends heartbeat to the coordinator every interval until stop is closed.
+func sendHeartbeats(client *rpc.Client, heartbeat WorkHeartbeat, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			var ack bool
+			if err := client.Call("Coordinator.Heartbeat", heartbeat, &ack); err != nil {
+				log.Warnf("Unable to renew the lease of shard %d: %s", heartbeat.Shard, err)
+			}
+		}
+	}
+}
+
+// dialCoordinator connects to the coordinator at address, using TLS if tlsConfig is set.
+func dialCoordinator(address string, tlsConfig *tls.Config) (*rpc.Client, error) {
+	if tlsConfig == nil {
+		return rpc.Dial("tcp", address)
+	}
+	conn, err := tls.Dial("tcp", address, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.NewClient(conn), nil
+}
+
+// withoutCredentials removes the user name and password from the mapping store URI (see OpenMappingStore).
+func withoutCredentials(uri string) (string, error) {
+	if !IsSharedMappingStore(uri) {
+		return uri, nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		// The error repeats the URI and its password
+		return "", errors.New("Unable to parse the mapping store URI")
+	}
+	u.User = nil
+	query := u.Query()
+	query.Del("user")
+	query.Del("password")
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// processAssignment processes the worker's shard of the dump file.
+func processAssignment(assignment WorkAssignment) (*ShardReport, error) {
+	mapper, err := LoadConfigSkeleton(assignment.MapFile)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := OpenMappingStore(assignment.MappingStore)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	started := time.Now()
+	opts := ProcessOptions{ShardCount: assignment.Shards, ShardIndex: assignment.Shard, ShardPlan: assignment.ShardPlan,
+		SecureRandom: assignment.SecureRandom, MappingStore: store}
+	processedFile := ShardFileName(assignment.ProcessedFile, assignment.Shard)
+	err = ProcessDumpFileWithOptions(mapper, assignment.DumpFile, processedFile, assignment.PreProcessFile,
+		assignment.PostProcessFile, assignment.GenerateSeed, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewShardReport(processedFile, opts, started)
+}
+
+// ShardFileName returns the processed file of the shard: processed.sql becomes processed.shard-3.sql.
+func ShardFileName(processedFile string, shard int) string {
+	ext := filepath.Ext(processedFile)
+	return fmt.Sprintf("%s.shard-%d%s", strings.TrimSuffix(processedFile, ext), shard, ext)
+}
+
+// loadConsistencyState adds the mappings of state to AlphaNumericMap and UUIDMap.
+func loadConsistencyState(state ConsistencyState) {
+	for key, values := range state.AlphaNumeric {
+		if AlphaNumericMap[key] == nil {
+			AlphaNumericMap[key] = map[string]string{}
+		}
+		for input, output := range values {
+			if _, ok := AlphaNumericMap[key][input]; !ok {
+				AlphaNumericMap[key][input] = output
+			}
+		}
+	}
+	for input, output := range state.UUIDs {
+		in, err := uuid.Parse(input)
+		if err != nil {
+			continue
+		}
+		out, err := uuid.Parse(output)
+		if err != nil {
+			continue
+		}
+		if _, ok := UUIDMap[in]; !ok {
+			UUIDMap[in] = out
+		}
+	}
+}
+
+// currentConsistencyState returns a copy of AlphaNumericMap and UUIDMap.
+func currentConsistencyState() ConsistencyState {
+	state := ConsistencyState{AlphaNumeric: map[string]map[string]string{}, UUIDs: map[string]string{}}
+	state.merge(ConsistencyState{AlphaNumeric: AlphaNumericMap})
+	for input, output := range UUIDMap {
+		state.UUIDs[input.String()] = output.String()
+	}
+	return state
+}
@@ -0,0 +1,133 @@
+package gonymizer
+
+import (
+	"net/rpc"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoordinator(t *testing.T) {
+	job := CoordinatorJob{
+		DumpFile:      TestDbFile,
+		MapFile:       TestMapFile,
+		ProcessedFile: TestDistributedDumpFile,
+		Shards:        3,
+	}
+	opts := CoordinatorOptions{Token: "secret"}
+	_, err := NewCoordinator(job, opts)
+	require.NotNil(t, err)
+
+	server := newFakeRedisServer(t, "")
+	defer server.Close()
+	job.MappingStore = "redis://" + server.Addr().String()
+	_, err = NewCoordinator(job, CoordinatorOptions{})
+	require.NotNil(t, err)
+	coordinator, err := NewCoordinator(job, opts)
+	require.Nil(t, err)
+
+	_, err = ListenCoordinator(":0", nil)
+	require.NotNil(t, err)
+	listener, err := ListenCoordinator("127.0.0.1:0", nil)
+	require.Nil(t, err)
+	defer listener.Close()
+	go coordinator.Serve(listener)
+
+	// Workers with another token are refused
+	require.NotNil(t, RunWorker(listener.Addr().String(), "worker-x", WorkerOptions{Token: "guess"}))
+
+	// Workers share the processors' global state so only one can run per process
+	workerOpts := WorkerOptions{Token: "secret", MappingStore: job.MappingStore}
+	require.Nil(t, RunWorker(listener.Addr().String(), "worker-a", workerOpts))
+	require.Nil(t, RunWorker(listener.Addr().String(), "worker-b", workerOpts))
+
+	merged, err := coordinator.Wait(time.Second)
+	require.Nil(t, err)
+	require.Len(t, merged.Shards, 3)
+	require.Len(t, merged.Tables, 4)
+	for shard := 0; shard < 3; shard++ {
+		require.Equal(t, shard, merged.Shards[shard].Shard)
+		require.Nil(t, os.Remove(ShardFileName(TestDistributedDumpFile, shard)))
+	}
+
+	_, err = NewCoordinator(CoordinatorJob{DumpFile: TestDbFile, MapFile: TestMapFile, ProcessedFile: "out.sql"}, opts)
+	require.NotNil(t, err)
+}
+
+func TestCoordinatorLease(t *testing.T) {
+	job := CoordinatorJob{DumpFile: TestDbFile, MapFile: TestMapFile, ProcessedFile: TestDistributedDumpFile, Shards: 1}
+	opts := CoordinatorOptions{Token: "secret", Lease: 40 * time.Millisecond, MaxRetries: 1}
+
+	// assignToDeadWorker assigns the shard to a worker that never sends a heartbeat
+	assignToDeadWorker := func(address string) {
+		client, err := rpc.Dial("tcp", address)
+		require.Nil(t, err)
+		defer client.Close()
+		var assignment WorkAssignment
+		require.Nil(t, client.Call("Coordinator.Assign", WorkRequest{Worker: "dead", Token: "secret"}, &assignment))
+		require.Equal(t, opts.Lease, assignment.Lease)
+		require.False(t, assignment.Done || assignment.Idle)
+	}
+
+	// The shard of a worker that stops sending heartbeats is assigned again
+	coordinator, err := NewCoordinator(job, opts)
+	require.Nil(t, err)
+	listener, err := ListenCoordinator("127.0.0.1:0", nil)
+	require.Nil(t, err)
+	defer listener.Close()
+	go coordinator.Serve(listener)
+
+	assignToDeadWorker(listener.Addr().String())
+	require.Nil(t, RunWorker(listener.Addr().String(), "worker-a", WorkerOptions{Token: "secret"}))
+	merged, err := coordinator.Wait(time.Second)
+	require.Nil(t, err)
+	require.Len(t, merged.Shards, 1)
+	require.Nil(t, os.Remove(ShardFileName(TestDistributedDumpFile, 0)))
+
+	// The run fails once a shard was retried MaxRetries times
+	coordinator, err = NewCoordinator(job, opts)
+	require.Nil(t, err)
+	listener, err = ListenCoordinator("127.0.0.1:0", nil)
+	require.Nil(t, err)
+	defer listener.Close()
+	go coordinator.Serve(listener)
+
+	assignToDeadWorker(listener.Addr().String())
+	time.Sleep(2 * opts.Lease)
+	assignToDeadWorker(listener.Addr().String())
+	_, err = coordinator.Wait(time.Second)
+	require.NotNil(t, err)
+}
+
+func TestConsistencyStateMerge(t *testing.T) {
+	state := ConsistencyState{
+		AlphaNumeric: map[string]map[string]string{"public.users.code": {"abc": "xyz"}},
+		UUIDs:        map[string]string{},
+	}
+	state.merge(ConsistencyState{
+		AlphaNumeric: map[string]map[string]string{"public.users.code": {"abc": "qqq", "def": "uvw"}},
+		UUIDs:        map[string]string{"a": "b"},
+	})
+	require.Equal(t, map[string]string{"abc": "xyz", "def": "uvw"}, state.AlphaNumeric["public.users.code"])
+	require.Equal(t, "b", state.UUIDs["a"])
+
+	require.Equal(t, "testing/processed.shard-2.sql", ShardFileName("testing/processed.sql", 2))
+
+	require.True(t, IsSharedMappingStore("redis://localhost:6379/0"))
+	require.True(t, IsSharedMappingStore("postgres://localhost/mappings"))
+	require.False(t, IsSharedMappingStore("bolt:///tmp/mappings.db"))
+	require.False(t, IsSharedMappingStore(""))
+
+	for input, expected := range map[string]string{
+		"redis://:pass@redis:6379/0":                   "redis://redis:6379/0",
+		"postgres://user:pass@db/maps?sslmode=require": "postgres://db/maps?sslmode=require",
+		"postgres://db/maps?password=pass&user=user":   "postgres://db/maps",
+		"bolt:///tmp/mappings.db":                      "bolt:///tmp/mappings.db",
+	} {
+		uri, err := withoutCredentials(input)
+		require.Nil(t, err)
+		require.Equal(t, expected, uri)
+	}
+}
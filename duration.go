@@ -0,0 +1,105 @@
+package gonymizer
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultDurationVariance is the variance used by the RandomDuration processor when the processor definition does not
+// set one.
+const defaultDurationVariance = 0.1
+
+// intervalRegex matches PostgreSQL intervals in the default (postgres) output style: 1 year 2 mons 3 days 04:05:06.789.
+// The groups are the years, months, days, sign of the time, hours, minutes, seconds, and fraction of a second.
+var intervalRegex = regexp.MustCompile(
+	`^(?:(-?\d+) years? ?)?(?:(-?\d+) mons? ?)?(?:(-?\d+) days? ?)?(?:([+-])?(\d+):(\d{2}):(\d{2})(?:\.(\d+))?)?$`)
+
+// perturbDuration will multiply a duration by a random factor between 1 - variance and 1 + variance. Durations are a
+// number (of seconds, milliseconds, ...) or a PostgreSQL interval. Numbers keep their number of decimal places and
+// every part of an interval (months, days, and time) is multiplied by the same factor, so the sign and rough magnitude
+// are kept.
+func perturbDuration(input string, variance float64) (string, error) {
+	if variance <= 0 || variance >= 1 {
+		return "", fmt.Errorf("Expected a Variance between 0 and 1, got %g", variance)
+	}
+
+	if number, err := strconv.ParseFloat(input, 64); err == nil {
+		decimals := 0
+		if dot := strings.Index(input, "."); dot >= 0 {
+			decimals = len(input) - dot - 1
+		}
+		return perturbAmount(number, variance, decimals), nil
+	}
+
+	match := intervalRegex.FindStringSubmatch(input)
+	if match == nil || len(strings.TrimSpace(input)) == 0 {
+		return "", fmt.Errorf("Unable to parse duration: %q", input)
+	}
+	factor := 1 + (rng.Float64()*2-1)*variance
+	scale := func(value string) int64 {
+		n, _ := strconv.ParseInt(value, 10, 64)
+		return int64(math.Round(float64(n) * factor))
+	}
+
+	var parts []string
+	if len(match[1]) > 0 || len(match[2]) > 0 {
+		years, _ := strconv.ParseInt(match[1], 10, 64)
+		months, _ := strconv.ParseInt(match[2], 10, 64)
+		months = int64(math.Round(float64(years*12+months) * factor))
+		parts = appendIntervalPart(parts, months/12, "year")
+		parts = appendIntervalPart(parts, months%12, "mon")
+	}
+	if len(match[3]) > 0 {
+		parts = appendIntervalPart(parts, scale(match[3]), "day")
+	}
+	if len(match[5]) > 0 {
+		parts = append(parts, perturbIntervalTime(match[4:], factor))
+	}
+	if len(parts) == 0 {
+		return "00:00:00", nil
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// appendIntervalPart appends "n unit(s)" to parts unless n is 0.
+func appendIntervalPart(parts []string, n int64, unit string) []string {
+	switch n {
+	case 0:
+		return parts
+	case 1, -1:
+		return append(parts, fmt.Sprintf("%d %s", n, unit))
+	}
+	return append(parts, fmt.Sprintf("%d %ss", n, unit))
+}
+
+// perturbIntervalTime multiplies the time of an interval (sign, hours, minutes, seconds, and fraction) by factor. The
+// result has the same number of decimal places as the input.
+func perturbIntervalTime(time []string, factor float64) string {
+	hours, _ := strconv.ParseInt(time[1], 10, 64)
+	minutes, _ := strconv.ParseInt(time[2], 10, 64)
+	seconds, _ := strconv.ParseInt(time[3], 10, 64)
+	fraction, decimals := 0.0, len(time[4])
+	if decimals > 0 {
+		fraction, _ = strconv.ParseFloat("0."+time[4], 64)
+	}
+
+	unit := math.Pow10(decimals)
+	total := math.Round((float64(hours*3600+minutes*60+seconds)+fraction)*factor*unit) / unit
+	sign := time[0]
+	if sign == "+" {
+		sign = ""
+	}
+	if total == 0 {
+		sign = ""
+	}
+
+	whole := int64(total)
+	output := fmt.Sprintf("%s%02d:%02d:%02d", sign, whole/3600, whole/60%60, whole%60)
+	if decimals > 0 {
+		output += strconv.FormatFloat(total-float64(whole), 'f', decimals, 64)[1:]
+	}
+	return output
+}
@@ -0,0 +1,58 @@
+package gonymizer
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorRandomDuration(t *testing.T) {
+	cmap := &ColumnMapper{
+		Processors: []ProcessorDefinition{{Name: "RandomDuration", Variance: 0.2}},
+	}
+
+	// Seconds
+	for _, input := range []string{"3600", "-12.50"} {
+		output, err := ProcessorRandomDuration(cmap, input)
+		require.Nil(t, err)
+		require.Equal(t, strings.HasPrefix(input, "-"), strings.HasPrefix(output, "-"), output)
+		require.Equal(t, strings.Contains(input, "."), strings.Contains(output, "."), output)
+
+		before, _ := strconv.ParseFloat(input, 64)
+		after, err := strconv.ParseFloat(output, 64)
+		require.Nil(t, err)
+		require.InDelta(t, before, after, math.Abs(before)*0.2+0.01)
+	}
+
+	// Intervals
+	output, err := ProcessorRandomDuration(cmap, "02:00:00")
+	require.Nil(t, err)
+	duration, err := time.ParseDuration(strings.Replace(strings.Replace(output, ":", "h", 1), ":", "m", 1) + "s")
+	require.Nil(t, err)
+	require.InDelta(t, 2*time.Hour, duration, float64(24*time.Minute))
+
+	for _, input := range []string{"1 year 2 mons 3 days 04:05:06.5", "-3 days -00:00:05", "10 days"} {
+		output, err = ProcessorRandomDuration(cmap, input)
+		require.Nil(t, err)
+		require.Regexp(t, intervalRegex, output)
+		require.Equal(t, strings.Contains(input, "-"), strings.Contains(output, "-"), output)
+		require.Equal(t, strings.Contains(input, "."), strings.Contains(output, "."), output)
+	}
+
+	_, err = ProcessorRandomDuration(cmap, "@ 1 hour")
+	require.NotNil(t, err)
+
+	cmap.Processors[0].Variance = 1
+	_, err = ProcessorRandomDuration(cmap, "3600")
+	require.NotNil(t, err)
+}
+
+func TestPerturbIntervalTime(t *testing.T) {
+	require.Equal(t, "27:46:40", perturbIntervalTime([]string{"", "20", "00", "00", ""}, 5.0/3.6))
+	require.Equal(t, "-00:00:06.000", perturbIntervalTime([]string{"-", "00", "00", "05", "000"}, 1.2))
+	require.Equal(t, "00:00:00", perturbIntervalTime([]string{"-", "00", "00", "00", ""}, 1.1))
+}
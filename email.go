@@ -0,0 +1,77 @@
+package gonymizer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// emailMapKey is the key in the AlphaNumericMap used to store the consistent mapping of e-mail addresses to local part
+// pseudonyms when no HMAC key is set.
+const emailMapKey = "email"
+
+// emailDomainWildcard is the key of the Domains map that matches every domain not listed in the map.
+const emailDomainWildcard = "*"
+
+// splitEmailAddress returns the local part and the domain of an e-mail address. The domain is found after the last @,
+// since quoted local parts may contain @ themselves. Returns false if the address has no @.
+func splitEmailAddress(address string) (string, string, bool) {
+	at := strings.LastIndexByte(address, '@')
+	if at < 0 {
+		return "", "", false
+	}
+	return address[:at], address[at+1:], true
+}
+
+// mapEmailDomain returns the domain the domain is mapped to in domains (compared case-insensitively), the target of the
+// * wildcard for domains that are not listed, or the domain itself.
+func mapEmailDomain(domain string, domains map[string]string) string {
+	for source, target := range domains {
+		if strings.EqualFold(source, domain) {
+			return target
+		}
+	}
+	if target, ok := domains[emailDomainWildcard]; ok {
+		return target
+	}
+	return domain
+}
+
+// emailPseudonym returns the pseudonym of the local part of the e-mail address. The local part is scrambled (see
+// hmacScramble) with the HMAC key when one is set, so the same address gets the same pseudonym in every run. Otherwise
+// it is scrambled randomly and stored in the mapping store (see MappingStore) so it is consistent within the run (and
+// across runs that share a consistency state file). Addresses are compared case-insensitively. attempt is added to the
+// input of the HMAC to draw a different pseudonym when the first one is on the suppression list.
+func emailPseudonym(local, domain string, attempt int) string {
+	local = strings.ToLower(local)
+	address := local + "@" + strings.ToLower(domain)
+	if len(hmacKey) > 0 {
+		if attempt > 0 {
+			address += "#" + strconv.Itoa(attempt)
+		}
+		// The scrambled characters of the local part depend on the whole address
+		return hmacScramble(hmacKey, address)[:len(local)]
+	}
+
+	if attempt == 0 {
+		return mappedValue(emailMapKey, address, func() string {
+			return scrambleString(local)
+		})
+	}
+	pseudonym := generateMapped(emailMapKey, address+"#"+strconv.Itoa(attempt), func() string {
+		return scrambleString(local)
+	})
+	setMappedValue(emailMapKey, address, pseudonym)
+	return pseudonym
+}
+
+// validateEmailDomains checks that the Domains of an EmailDomainPreserving processor definition map domains to
+// domains.
+func (procDef ProcessorDefinition) validateEmailDomains() error {
+	for source, target := range procDef.Domains {
+		if len(source) == 0 || len(target) == 0 || strings.Contains(source, "@") || strings.Contains(target, "@") {
+			return fmt.Errorf("Invalid domain mapping %q: %q for processor %s", source, target, procDef.Name)
+		}
+	}
+	return nil
+}
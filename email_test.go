@@ -0,0 +1,66 @@
+package gonymizer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorEmailDomainPreserving(t *testing.T) {
+	defer delete(AlphaNumericMap, emailMapKey)
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{{Name: "EmailDomainPreserving"}}}
+
+	output, err := ProcessorEmailDomainPreserving(cmap, "Jane.Smith+news@Example.com")
+	require.Nil(t, err)
+	require.Regexp(t, `^[a-z]{4}\.[a-z]{5}\+[a-z]{4}@Example\.com$`, output)
+	require.NotEqual(t, "jane.smith+news@Example.com", output)
+
+	// Consistent within the run, case-insensitive
+	again, err := ProcessorEmailDomainPreserving(cmap, "jane.smith+news@example.com")
+	require.Nil(t, err)
+	require.Equal(t, strings.ToLower(output), again)
+
+	// Mapped domains
+	cmap.Processors[0].Domains = map[string]string{"customer.com": "customer.test", "*": "example.com"}
+	output, err = ProcessorEmailDomainPreserving(cmap, "bob@Customer.com")
+	require.Nil(t, err)
+	require.True(t, strings.HasSuffix(output, "@customer.test"), output)
+	output, err = ProcessorEmailDomainPreserving(cmap, "bob@gmail.com")
+	require.Nil(t, err)
+	require.True(t, strings.HasSuffix(output, "@example.com"), output)
+
+	// Deterministic across runs with an HMAC key
+	hmacKey = []byte("0123456789abcdef")
+	defer func() { hmacKey = nil }()
+	cmap.Processors[0].Domains = nil
+	output, err = ProcessorEmailDomainPreserving(cmap, "jane@example.com")
+	require.Nil(t, err)
+	delete(AlphaNumericMap, emailMapKey)
+	again, err = ProcessorEmailDomainPreserving(cmap, "jane@example.com")
+	require.Nil(t, err)
+	require.Equal(t, output, again)
+	require.Equal(t, hmacScramble(hmacKey, "jane@example.com")[:4]+"@example.com", output)
+
+	// Suppressed pseudonyms are drawn again
+	suppressionList[normalizeSuppressionEntry(output)] = true
+	defer delete(suppressionList, normalizeSuppressionEntry(output))
+	again, err = ProcessorEmailDomainPreserving(cmap, "jane@example.com")
+	require.Nil(t, err)
+	require.NotEqual(t, output, again)
+	require.True(t, strings.HasSuffix(again, "@example.com"), again)
+
+	// Not an e-mail address
+	output, err = ProcessorEmailDomainPreserving(cmap, "jane")
+	require.Nil(t, err)
+	require.Len(t, output, 4)
+}
+
+func TestValidateEmailDomains(t *testing.T) {
+	procDef := ProcessorDefinition{Name: "EmailDomainPreserving", Domains: map[string]string{"a.com": "a.test"}}
+	require.Nil(t, procDef.validateEmailDomains())
+	procDef.Domains["b.com"] = ""
+	require.NotNil(t, procDef.validateEmailDomains())
+	procDef.Domains = map[string]string{"a.com": "jane@a.test"}
+	require.NotNil(t, procDef.validateEmailDomains())
+}
@@ -0,0 +1,185 @@
+package gonymizer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/icrowley/fake"
+)
+
+// freeTextEntityRegex matches the URLs, e-mail addresses, SSNs, and phone numbers (US or E.164) found in free text.
+// Alternatives are tried in order so the e-mail address in a URL is part of the URL.
+var freeTextEntityRegex = regexp.MustCompile(
+	`(?P<url>\bhttps?://[^\s<>"']+|\bwww\.[^\s<>"']+)` +
+		`|(?P<email>[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,})` +
+		`|(?P<ssn>\b\d{3}-\d{2}-\d{4}\b)` +
+		`|(?P<phone>(?:\+?1[ .-]?)?(?:\(\d{3}\) ?|\b\d{3}[ .-])\d{3}[ .-]\d{4}\b|\+[1-9]\d{7,14}\b)`)
+
+// freeTextWordRegex matches the words of free text, including names with hyphens and apostrophes (Mary-Jane, O'Brien).
+var freeTextWordRegex = regexp.MustCompile(`[A-Za-z]+(?:['-][A-Za-z]+)*`)
+
+// freeTextHonorifics are the titles (matched case-insensitively, with or without a period) followed by a name.
+var freeTextHonorifics = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "miss": true, "mx": true, "dr": true, "prof": true,
+}
+
+// freeTextFirstNames are common first names recognized in free text. Names that are also common words (Will, May,
+// Mark, Grace) are left out to avoid replacing them. The first names of a loaded data pack (see LoadDataPack) are
+// recognized as well.
+var freeTextFirstNames = map[string]bool{}
+
+// dataPackFirstNameSet contains the lower case first names of the data pack file dataPackFirstNameSource.
+var (
+	dataPackFirstNameSet    map[string]bool
+	dataPackFirstNameSource []string
+)
+
+func init() {
+	for _, name := range strings.Fields(`aaron adam alan albert alice amanda amy andrea andrew angela anna anthony
+		ashley barbara benjamin betty brandon brenda brian carol carolyn catherine charles christina christine
+		christopher cynthia daniel david deborah dennis diana donald donna dorothy douglas edward elizabeth emily emma
+		eric frank gary george gregory hannah harold heather helen henry jacob james jason jeffrey jennifer jeremy
+		jessica john jonathan joseph joshua joyce judith julie justin karen katherine kathleen kelly kenneth kevin
+		kimberly larry laura linda lisa margaret maria marie mary matthew melissa michael michelle nancy nicholas
+		nicole olivia pamela patricia patrick paul peter rachel raymond rebecca richard robert ronald ryan samantha
+		samuel sandra sarah scott sharon shirley sophia stephanie stephen steven susan thomas timothy tyler victoria
+		walter william`) {
+		freeTextFirstNames[name] = true
+	}
+}
+
+// freeText replaces the personal data found in free text with fake values. URLs get a fake host name and a scrambled
+// path, e-mail addresses are replaced with fake addresses, SSNs with fake SSNs, and phone numbers are scrambled in
+// their format. Names are found after an honorific (Dr. Smith) or by a known first name (see freeTextFirstNames),
+// together with the capitalized word that follows it as the last name, and replaced with fake names of the same case.
+// The same entity is replaced with the same value everywhere in the text. The text is unescaped from (and escaped
+// back to) the COPY format, so escaped new lines separate words.
+func freeText(input string) (string, error) {
+	text := unescapeCopyValue(input)
+	seen := map[string]string{}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range freeTextEntityRegex.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(freeTextNames(text[last:match[0]], seen))
+		last = match[1]
+
+		entity := text[match[0]:match[1]]
+		output, ok := seen[entity]
+		if !ok {
+			var err error
+			if output, err = fakeFreeTextEntity(entity, match); err != nil {
+				return "", err
+			}
+			seen[entity] = output
+		}
+		b.WriteString(output)
+	}
+	b.WriteString(freeTextNames(text[last:], seen))
+	return escapeCopyValue(b.String()), nil
+}
+
+// fakeFreeTextEntity returns the fake value of the entity matched by freeTextEntityRegex.
+func fakeFreeTextEntity(entity string, match []int) (string, error) {
+	switch {
+	case match[2] >= 0:
+		return fakeFreeTextURL(entity), nil
+	case match[4] >= 0:
+		return notSuppressed(fake.EmailAddress)
+	case match[6] >= 0:
+		if !validSSN(entity) {
+			return entity, nil
+		}
+		return fakeSSN(entity, 0), nil
+	}
+	return scrambleString(entity), nil
+}
+
+// fakeFreeTextURL replaces the URL with a fake URL (see fakeURL). Punctuation ending the URL (the period ending a
+// sentence) is kept.
+func fakeFreeTextURL(url string) string {
+	trimmed := strings.TrimRight(url, ".,;:!?)")
+	return fakeURL(trimmed, nil) + url[len(trimmed):]
+}
+
+// freeTextNames replaces the names found in the text (see freeText) with fake names. Every word of a name is mapped
+// to the same fake word everywhere in the text using seen.
+func freeTextNames(text string, seen map[string]string) string {
+	words := freeTextWordRegex.FindAllStringIndex(text, -1)
+	isName := make([]bool, len(words))
+	isLast := make([]bool, len(words))
+
+	// follows returns true if word i is capitalized and separated from word i-1 by spaces, after a period if period
+	// is set (Dr. Smith).
+	follows := func(i int, period bool) bool {
+		if i >= len(words) || text[words[i][0]] < 'A' || text[words[i][0]] > 'Z' {
+			return false
+		}
+		separator := text[words[i-1][1]:words[i][0]]
+		if period {
+			separator = strings.TrimPrefix(separator, ".")
+		}
+		return len(separator) > 0 && strings.TrimLeft(separator, " ") == ""
+	}
+	for i := range words {
+		word := strings.ToLower(text[words[i][0]:words[i][1]])
+		switch {
+		case freeTextHonorifics[word] && follows(i+1, true):
+			// Dr. Smith is a last name, Dr. Jane Smith a first and last name
+			if follows(i+2, false) {
+				isName[i+1], isName[i+2], isLast[i+2] = true, true, true
+			} else {
+				isName[i+1], isLast[i+1] = true, true
+			}
+		case !isName[i] && text[words[i][0]] >= 'A' && text[words[i][0]] <= 'Z' && freeTextIsFirstName(word):
+			isName[i] = true
+			if follows(i+1, false) {
+				isName[i+1], isLast[i+1] = true, true
+			}
+		}
+	}
+
+	var b strings.Builder
+	last := 0
+	for i, word := range words {
+		if !isName[i] {
+			continue
+		}
+		name := text[word[0]:word[1]]
+		b.WriteString(text[last:word[0]])
+		last = word[1]
+
+		output, ok := seen[name]
+		if !ok {
+			faker := fakeFirstName
+			if isLast[i] {
+				faker = fakeLastName
+			}
+			output = matchCase(name, faker())
+			seen[name] = output
+		}
+		b.WriteString(output)
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// freeTextIsFirstName returns true if the lower case word is a known first name.
+func freeTextIsFirstName(word string) bool {
+	if freeTextFirstNames[word] {
+		return true
+	}
+
+	names := dataPack[DataPackFirstNames]
+	if len(names) == 0 {
+		return false
+	}
+	if len(names) != len(dataPackFirstNameSource) || &names[0] != &dataPackFirstNameSource[0] {
+		dataPackFirstNameSet = map[string]bool{}
+		for _, name := range names {
+			dataPackFirstNameSet[strings.ToLower(name)] = true
+		}
+		dataPackFirstNameSource = names
+	}
+	return dataPackFirstNameSet[word]
+}
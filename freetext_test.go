@@ -0,0 +1,52 @@
+package gonymizer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorFreeText(t *testing.T) {
+	input := "Spoke with John Smith (john.smith@example.com, 555-123-4567) about SSN 123-45-6789.\\n" +
+		"See https://portal.example.com/patients/4821. John will call Dr. Jones back at +14155550123."
+	output, err := ProcessorFreeText(&ColumnMapper{}, input)
+	require.Nil(t, err)
+	for _, pii := range []string{"john.smith@example.com", "555-123-4567", "123-45-6789", "portal.example.com", "4821",
+		"+14155550123"} {
+		require.NotContains(t, output, pii)
+	}
+	require.Regexp(t, `^Spoke with [A-Z][A-Za-z'-]+ [A-Z][A-Za-z'-]+ \(\S+@\S+, \d{3}-\d{3}-\d{4}\) about SSN `+
+		`\d{3}-\d{2}-\d{4}\.\\nSee https://[a-z.]+/[a-z]+/\d{4}\. [A-Z][A-Za-z'-]+ will call Dr\. [A-Z][A-Za-z'-]+ `+
+		`back at \+\d{11}\.$`, output)
+
+	// Text without personal data is kept
+	output, err = ProcessorFreeText(&ColumnMapper{}, "Follow up next week. Will may bring the forms.")
+	require.Nil(t, err)
+	require.Equal(t, "Follow up next week. Will may bring the forms.", output)
+}
+
+func TestFreeTextNames(t *testing.T) {
+	seen := map[string]string{}
+	output := freeTextNames("Mary met MARY and Ms. Lopez. Then Mr Adam Price left.", seen)
+	require.Len(t, seen, 5)
+	require.Equal(t, seen["Mary"]+" met "+seen["MARY"]+" and Ms. "+seen["Lopez"]+". Then Mr "+seen["Adam"]+" "+
+		seen["Price"]+" left.", output)
+	require.Equal(t, strings.ToUpper(seen["MARY"]), seen["MARY"])
+
+	// A sentence following a name does not start a last name
+	seen = map[string]string{}
+	output = freeTextNames("Ask David. Tomorrow works.", seen)
+	require.Len(t, seen, 1)
+	require.Equal(t, "Ask "+seen["David"]+". Tomorrow works.", output)
+}
+
+func TestFreeTextDataPackNames(t *testing.T) {
+	defer func(pack map[string][]string) { dataPack = pack }(dataPack)
+	dataPack = map[string][]string{DataPackFirstNames: {"Zyra"}}
+
+	seen := map[string]string{}
+	output := freeTextNames("Zyra Quill called", seen)
+	require.Len(t, seen, 2)
+	require.Equal(t, "Zyra "+seen["Quill"]+" called", output)
+}
@@ -0,0 +1,174 @@
+package gonymizer
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+const (
+	// fromRegexMaxRepeat is the maximum number of repetitions (beyond the minimum) of *, +, and {n,} in the values
+	// generated by the FromRegex processor.
+	fromRegexMaxRepeat = 8
+
+	// fromRegexAttempts is the number of values generated for a regular expression before giving up on finding one
+	// that matches it (only possible for expressions with word boundaries or that cannot match anything).
+	fromRegexAttempts = 10
+)
+
+// fromRegexTrees caches the parsed syntax tree of every regular expression used by the FromRegex processor.
+var fromRegexTrees = map[string]*syntax.Regexp{}
+
+// fromRegex returns a random value matching the regular expression. Characters of character classes are picked from
+// printable ASCII if the class contains any, . is replaced by an ASCII letter or digit, and unbounded repeats are
+// repeated at most fromRegexMaxRepeat more times than their minimum. The value is escaped for the COPY format.
+//
+// Example:
+// "KQ482913" = fromRegex("^[A-Z]{2}[0-9]{6}$")
+func fromRegex(expr string) (string, error) {
+	tree, ok := fromRegexTrees[expr]
+	if !ok {
+		parsed, err := syntax.Parse(expr, syntax.Perl)
+		if err != nil {
+			return "", fmt.Errorf("Invalid Regex %q: %s", expr, err)
+		}
+		tree = parsed.Simplify()
+		fromRegexTrees[expr] = tree
+	}
+	re, err := compileRegexReplace(expr)
+	if err != nil {
+		return "", err
+	}
+
+	for attempt := 0; attempt < fromRegexAttempts; attempt++ {
+		var b strings.Builder
+		writeFromRegex(&b, tree)
+		if value := b.String(); re.MatchString(value) {
+			return escapeCopyValue(value), nil
+		}
+	}
+	return "", fmt.Errorf("Unable to generate a value matching Regex %q", expr)
+}
+
+// writeFromRegex writes a random string matching the syntax tree to the builder. Anchors and word boundaries match
+// the empty string.
+func writeFromRegex(b *strings.Builder, re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && rng.Intn(2) == 0 {
+				r = unicode.SimpleFold(r)
+			}
+			b.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		b.WriteRune(randomClassRune(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		set := lowercaseSet + uppercaseSet + numericSet
+		b.WriteByte(set[rng.Intn(len(set))])
+	case syntax.OpCapture:
+		writeFromRegex(b, re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			writeFromRegex(b, sub)
+		}
+	case syntax.OpAlternate:
+		writeFromRegex(b, re.Sub[rng.Intn(len(re.Sub))])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := re.Min, re.Max
+		switch re.Op {
+		case syntax.OpStar:
+			min, max = 0, -1
+		case syntax.OpPlus:
+			min, max = 1, -1
+		case syntax.OpQuest:
+			min, max = 0, 1
+		}
+		if max < 0 {
+			max = min + fromRegexMaxRepeat
+		}
+		for count := min + rng.Intn(max-min+1); count > 0; count-- {
+			writeFromRegex(b, re.Sub[0])
+		}
+	}
+}
+
+// randomClassRune returns a random rune of the character class given as pairs of inclusive rune ranges. Printable
+// ASCII runes of the class are preferred.
+func randomClassRune(ranges []rune) rune {
+	pick := func(low, high rune) (rune, bool) {
+		total := 0
+		for i := 0; i+1 < len(ranges); i += 2 {
+			if lo, hi := maxRune(ranges[i], low), minRune(ranges[i+1], high); lo <= hi {
+				total += int(hi-lo) + 1
+			}
+		}
+		if total == 0 {
+			return 0, false
+		}
+		n := rng.Intn(total)
+		for i := 0; i+1 < len(ranges); i += 2 {
+			if lo, hi := maxRune(ranges[i], low), minRune(ranges[i+1], high); lo <= hi {
+				if n <= int(hi-lo) {
+					return lo + rune(n), true
+				}
+				n -= int(hi-lo) + 1
+			}
+		}
+		return 0, false
+	}
+
+	if r, ok := pick(' ', '~'); ok {
+		return r
+	}
+	r, _ := pick(0, unicode.MaxRune)
+	return r
+}
+
+// minRune returns the smaller of the runes.
+func minRune(a, b rune) rune {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxRune returns the larger of the runes.
+func maxRune(a, b rune) rune {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// fromRegexExpr returns the Regex of the FromRegex processor definition, or the Pattern of the column if it has none.
+func fromRegexExpr(cmap *ColumnMapper, procDef ProcessorDefinition) string {
+	if len(procDef.Regex) > 0 {
+		return procDef.Regex
+	}
+	return cmap.Pattern
+}
+
+// validatePattern checks that the Pattern of the column is a valid regular expression and that its FromRegex
+// processors have a Regex or the Pattern to generate values from.
+func (cmap ColumnMapper) validatePattern() error {
+	if len(cmap.Pattern) > 0 {
+		if _, err := compileRegexReplace(cmap.Pattern); err != nil {
+			return err
+		}
+	}
+	for _, procDef := range cmap.Processors {
+		if procDef.Name != "FromRegex" {
+			continue
+		}
+		expr := fromRegexExpr(&cmap, procDef)
+		if len(expr) == 0 {
+			return fmt.Errorf("Expected a Regex or the Pattern of the column for processor %s", procDef.Name)
+		}
+		if _, err := fromRegex(expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package gonymizer
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromRegex(t *testing.T) {
+	for _, expr := range []string{
+		`^[A-Z]{2}[0-9]{6}$`,
+		`^\d{3}-\d{2}-\d{4}$`,
+		`^(?i)ab[c-e]+x?$`,
+		`^(foo|bar|baz)_\w{3,}$`,
+		`^[^a-z]{4}$`,
+		`^.{2,5}\.(pdf|txt)$`,
+		`^\p{Greek}{3}$`,
+		`^[A-Z]+(-[0-9]+)*$`,
+	} {
+		re := regexp.MustCompile(expr)
+		for i := 0; i < 50; i++ {
+			output, err := fromRegex(expr)
+			require.Nil(t, err, expr)
+			require.Regexp(t, re, unescapeCopyValue(output))
+		}
+	}
+
+	// Generated values are escaped for the COPY format
+	output, err := fromRegex(`^a\tb$`)
+	require.Nil(t, err)
+	require.Equal(t, `a\tb`, output)
+
+	_, err = fromRegex(`^[A-Z`)
+	require.NotNil(t, err)
+	_, err = fromRegex(`^a\bb$`)
+	require.NotNil(t, err)
+}
+
+func TestProcessorFromRegex(t *testing.T) {
+	cmap := ColumnMapper{Pattern: `^[A-Z]{2}[0-9]{6}$`, Processors: []ProcessorDefinition{{Name: "FromRegex"}}}
+	output, err := ProcessorFromRegex(&cmap, "XY123456")
+	require.Nil(t, err)
+	require.Regexp(t, `^[A-Z]{2}[0-9]{6}$`, output)
+
+	// The Regex of the processor definition overrides the Pattern of the column
+	cmap.Processors[0].Regex = `^P-[0-9]{4}$`
+	output, err = ProcessorFromRegex(&cmap, "XY123456")
+	require.Nil(t, err)
+	require.Regexp(t, `^P-[0-9]{4}$`, output)
+
+	// Columns with a parent get the same value for the same input
+	cmap.ParentSchema, cmap.ParentTable, cmap.ParentColumn = "public", "passports", "number"
+	first, err := ProcessorFromRegex(&cmap, "XY123456")
+	require.Nil(t, err)
+	second, err := ProcessorFromRegex(&cmap, "XY123456")
+	require.Nil(t, err)
+	require.Equal(t, first, second)
+
+	_, err = ProcessorFromRegex(&ColumnMapper{}, "XY123456")
+	require.NotNil(t, err)
+}
+
+func TestValidatePattern(t *testing.T) {
+	require.Nil(t, ColumnMapper{}.validatePattern())
+	require.Nil(t, ColumnMapper{Pattern: `^[A-Z]{2}$`}.validatePattern())
+	require.Nil(t, ColumnMapper{Pattern: `^[A-Z]{2}$`, Processors: []ProcessorDefinition{{Name: "FromRegex"}}}.
+		validatePattern())
+	require.Nil(t, ColumnMapper{Processors: []ProcessorDefinition{{Name: "FromRegex", Regex: `^\d+$`}}}.
+		validatePattern())
+	require.NotNil(t, ColumnMapper{Pattern: `^[A-Z`}.validatePattern())
+	require.NotNil(t, ColumnMapper{Processors: []ProcessorDefinition{{Name: "FromRegex"}}}.validatePattern())
+	require.NotNil(t, ColumnMapper{Processors: []ProcessorDefinition{{Name: "FromRegex", Regex: `a\bb`}}}.
+		validatePattern())
+}
@@ -0,0 +1,86 @@
+package gonymizer
+
+import (
+	"strings"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// dropGeneratedColumns removes the columns marked IsGenerated in the map file from the column names and column maps of
+// the current COPY block. PostgreSQL refuses to COPY into GENERATED ALWAYS columns and computes them itself when the
+// processed dump file is loaded. The indexes of the removed columns are kept in DroppedColumns so they can be removed
+// from every row (see dropValues). Returns true if any column was removed.
+func (curLine *LineState) dropGeneratedColumns() bool {
+	curLine.DroppedColumns = nil
+	for i, cmap := range curLine.ColumnMaps {
+		if cmap != nil && cmap.IsGenerated {
+			curLine.DroppedColumns = append(curLine.DroppedColumns, i)
+		}
+	}
+	if len(curLine.DroppedColumns) == 0 {
+		return false
+	}
+
+	columnNames := make([]string, 0, len(curLine.ColumnNames)-len(curLine.DroppedColumns))
+	columnMaps := make([]*ColumnMapper, 0, cap(columnNames))
+	curLine.Mapped = false
+	for i, columnName := range curLine.ColumnNames {
+		if containsInt(curLine.DroppedColumns, i) {
+			log.Debugf("Leaving generated column %s.%s.%s out of the processed dump file", curLine.SchemaName,
+				curLine.TableName, columnName)
+			continue
+		}
+		columnNames = append(columnNames, columnName)
+		columnMaps = append(columnMaps, curLine.ColumnMaps[i])
+		curLine.Mapped = curLine.Mapped || curLine.ColumnMaps[i] != nil
+	}
+	curLine.ColumnNames = columnNames
+	curLine.ColumnMaps = columnMaps
+	return true
+}
+
+// copyStatement returns the COPY statement of the current COPY block listing only the remaining column names (see
+// dropGeneratedColumns). The table name is kept as it was found in inputLine.
+func (curLine *LineState) copyStatement(inputLine string) string {
+	copyLine := strings.TrimLeftFunc(strings.TrimPrefix(strings.TrimSpace(inputLine), StateChangeTokenBeginCopy),
+		unicode.IsSpace)
+	nameEnd := indexOutsideQuotes(copyLine, " (")
+	if nameEnd < 0 {
+		nameEnd = len(copyLine)
+	}
+
+	columns := make([]string, len(curLine.ColumnNames))
+	for i, columnName := range curLine.ColumnNames {
+		columns[i] = quoteIdentifier(columnName)
+	}
+	return StateChangeTokenBeginCopy + " " + copyLine[:nameEnd] + " (" + strings.Join(columns, ", ") + ") FROM stdin;\n"
+}
+
+// dropValues removes the values found at the dropped column indexes from a COPY row.
+func dropValues(inputLine string, dropped []int) string {
+	newLine := ""
+	if strings.HasSuffix(inputLine, "\n") {
+		newLine = "\n"
+		inputLine = strings.TrimSuffix(inputLine, "\n")
+	}
+
+	values := strings.Split(inputLine, "\t")
+	kept := make([]string, 0, len(values))
+	for i, value := range values {
+		if !containsInt(dropped, i) {
+			kept = append(kept, value)
+		}
+	}
+	return strings.Join(kept, "\t") + newLine
+}
+
+// containsInt returns true if value is found in list.
+func containsInt(list []int, value int) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package gonymizer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDropGeneratedColumns(t *testing.T) {
+	mapper := &DBMapper{
+		DBName: "pii_localtest",
+		ColumnMaps: []ColumnMapper{
+			{
+				TableSchema: "public",
+				TableName:   "Order Items",
+				ColumnName:  "total",
+				IsGenerated: true,
+				Processors:  []ProcessorDefinition{{Name: "Identity"}},
+			},
+			{
+				TableSchema: "public",
+				TableName:   "Order Items",
+				ColumnName:  "note",
+				Processors:  []ProcessorDefinition{{Name: "ScrubString"}},
+			},
+		},
+	}
+
+	state := new(LineState)
+	copyLine := "COPY public.\"Order Items\" (id, price, total, note) FROM stdin;\n"
+	state, output, err := processLine(mapper, state, copyLine, ProcessOptions{})
+	require.Nil(t, err)
+	require.Equal(t, "COPY public.\"Order Items\" (\"id\", \"price\", \"note\") FROM stdin;\n", output)
+	require.Equal(t, []string{"id", "price", "note"}, state.ColumnNames)
+	require.Equal(t, []int{2}, state.DroppedColumns)
+
+	state, output, err = processLine(mapper, state, "1\t9.99\t19.98\tsecret\n", ProcessOptions{})
+	require.Nil(t, err)
+	require.Equal(t, "1\t9.99\t******\n", output)
+
+	state, _, err = processLine(mapper, state, "\\.\n", ProcessOptions{})
+	require.Nil(t, err)
+	require.Nil(t, state.DroppedColumns)
+
+	// Tables without generated columns are written as they are
+	state, output, err = processLine(mapper, state, "COPY public.users (id, total) FROM stdin;\n", ProcessOptions{})
+	require.Nil(t, err)
+	require.Equal(t, "COPY public.users (id, total) FROM stdin;\n", output)
+	require.Nil(t, state.DroppedColumns)
+
+	require.Equal(t, "a\tc", dropValues("a\tb\tc", []int{1}))
+	require.Equal(t, "b\n", dropValues("a\tb\tc\n", []int{0, 2}))
+}
+
+func TestProcessDumpFileGeneratedColumns(t *testing.T) {
+	mapper := &DBMapper{
+		DBName: "pii_localtest",
+		Seed:   42,
+		ColumnMaps: []ColumnMapper{
+			{TableSchema: "public", TableName: "orders", ColumnName: "total", IsGenerated: true},
+		},
+	}
+
+	src := writeTestAuditFile(t, "CREATE TABLE public.orders (id integer, price numeric, "+
+		"total numeric GENERATED ALWAYS AS (price * 2) STORED);\n\n"+
+		"COPY public.orders (id, price, total) FROM stdin;\n1\t2.50\t5.00\n2\t\\N\t\\N\n\\.\n")
+	defer os.Remove(src)
+	dst := src + ".processed"
+	defer os.Remove(dst)
+
+	require.Nil(t, ProcessDumpFileWithOptions(mapper, src, dst, "", "", false, ProcessOptions{}))
+	output, err := ioutil.ReadFile(dst)
+	require.Nil(t, err)
+	require.Contains(t, string(output), "COPY public.orders (\"id\", \"price\") FROM stdin;\n1\t2.50\n2\t\\N\n\\.\n")
+}
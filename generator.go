@@ -8,9 +8,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	mathRand "math/rand"
 	"os"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/spf13/viper"
@@ -34,14 +34,259 @@ type LineState struct {
 	SchemaName  string
 	TableName   string
 	ColumnNames []string
+	RowCount    int64
+	RowNumber   int64
+	Batched     bool
+	Batch       []string
+	CopyCount   int64
+	Skipped     bool
+	Mapped      bool
+	ColumnMaps  []*ColumnMapper
+
+	// row filter and row redaction of the current table or nil (see RowFilter and RowRedaction)
+	RowFilter    *RowFilter
+	RowRedaction *RowRedaction
+
+	// indexes of the generated columns removed from the COPY block (see dropGeneratedColumns)
+	DroppedColumns []int
 }
 
+// ProcessorError is returned when a processor fails for a value of the dump file. It tells where the value is, while
+// the error of the processor (Err) may contain the value itself, so it must never be sent outside of the log (see
+// NewRunSummary).
+type ProcessorError struct {
+	Table  string
+	Column string
+	Line   int64
+	Err    error
+}
+
+// Error returns the error message.
+func (e *ProcessorError) Error() string {
+	return fmt.Sprintf("Line %d, column %s of %s: %s", e.Line, e.Column, e.Table, e.Err)
+}
+
+// Unwrap returns the error of the processor.
+func (e *ProcessorError) Unwrap() error {
+	return e.Err
+}
+
+// processorError returns a ProcessorError for the error of a processor on the current line of the column.
+func (curLine *LineState) processorError(columnName string, err error) error {
+	return &ProcessorError{
+		Table:  curLine.SchemaName + "." + curLine.TableName,
+		Column: columnName,
+		Line:   curLine.LineNum,
+		Err:    err,
+	}
+}
+
+// rowContext contains the state shared by all columns of the row that is currently being processed.
+type rowContext struct {
+	// Groups contains the fake records of correlated column groups keyed by group name (see groupValue)
+	Groups map[string]map[string]string
+
+	// Values contains the original (unprocessed) values of the row keyed by column name
+	Values map[string]string
+}
+
+// currentRow is the row that is currently being processed. Processors use it to read the values of sibling columns and
+// to share records between the columns of a row.
+var currentRow = newRowContext(nil, nil)
+
+// newRowContext returns a new row context for the row values.
+func newRowContext(columnNames, values []string) *rowContext {
+	row := &rowContext{
+		Groups: map[string]map[string]string{},
+		Values: map[string]string{},
+	}
+	for i, columnName := range columnNames {
+		if i < len(values) {
+			row.Values[strings.Trim(columnName, "\"")] = strings.TrimSuffix(values[i], "\n")
+		}
+	}
+	return row
+}
+
+// value returns the original value of the column in the row. False is returned if the column does not exist or is NULL.
+func (row *rowContext) value(columnName string) (string, bool) {
+	value, ok := row.Values[columnName]
+	if !ok || value == "\\N" {
+		return "", false
+	}
+	return value, true
+}
+
+// ProcessOptions contains the optional settings used when processing a dump file. The zero value processes every row.
+type ProcessOptions struct {
+	// SampleRows limits the number of rows written for each table. The schema is always written in full. Rows beyond
+	// the limit are dropped before any processors are run. A value of 0 writes every row.
+	SampleRows int64
+
+	// BatchSize is the number of rows that are buffered for tables with columns using a BatchProcessor. A value of 0
+	// uses defaultBatchSize.
+	BatchSize int
+
+	// Strict aborts processing when the dump file contains a table or column that is not in the map file instead of
+	// writing its values unmodified, so columns added since the map file was last updated can not leak into the
+	// processed dump file. The table is checked before any of its rows are written.
+	Strict bool
+
+	// PipelineDepth is the number of buffers (of 1 MiB) the dump file is read ahead of and the processed dump file is
+	// written behind the processing of the rows. Reads and writes run in their own goroutines, so slow storage does not
+	// stall CPU bound processors and vice versa, and block when PipelineDepth buffers are waiting (backpressure). A value
+	// of 0 uses defaultPipelineDepth and a negative value reads and writes in the processing goroutine.
+	PipelineDepth int
+
+	// CacheSize is the maximum number of results kept in the processor cache used by processor definitions with Cache
+	// set. A value of 0 uses defaultCacheSize.
+	CacheSize int
+
+	// ShardCount splits the tables of the dump file across ShardCount processes (e.g. the pods of a Kubernetes Job).
+	// COPY blocks are assigned to shards in the order they appear in the dump file and only the blocks assigned to
+	// ShardIndex are written. The schema is only written by shard 0. A value of 0 or 1 disables sharding. Columns
+	// mapped from a parent column require a MappingStore shared by every shard (see isSharedStore).
+	ShardCount int
+	ShardIndex int
+
+	// ShardPlan assigns the COPY blocks (or row ranges of large COPY blocks) to shards by size instead of by the order
+	// they appear in the dump file. See PlanShards.
+	ShardPlan *ShardPlan
+
+	// HeartbeatInterval logs the number of lines processed so far at this interval. A value of 0 disables it.
+	HeartbeatInterval time.Duration
+
+	// StallTimeout logs the stack of every goroutine when no line has been processed for this long and calls OnStall
+	// (if set) with an error describing the stall. A value of 0 disables stall detection.
+	StallTimeout time.Duration
+	OnStall      func(error)
+
+	// DashboardAddress serves a web page with the progress of the run (per-table progress, throughput, recent errors,
+	// and consistency store sizes) on this address (e.g. 127.0.0.1:8080) while the dump file is processed. RowCountFile
+	// is the optional row count file written by the dump command, used to show the progress of every table.
+	DashboardAddress string
+	RowCountFile     string
+
+	// SuppressionList is the path to a suppression (do not contact) list. Fake e-mail addresses and phone numbers never
+	// use a value on the list. See LoadSuppressionList.
+	SuppressionList string
+
+	// ReprocessUnchanged is the number of times a value is processed again when its processors return the input
+	// unchanged (e.g. a faker picking the original value). Identity columns are never reprocessed. See AuditDumpFiles.
+	ReprocessUnchanged int
+
+	// DataPack is the path to a directory of fake vocabularies (names, streets, cities, ...) used instead of the ones
+	// built into the fake library. See LoadDataPack.
+	DataPack string
+
+	// NameBlocklist is the path to a list of real names (VIPs, employees, known customers) that fake full names never
+	// match. See LoadNameBlocklist.
+	NameBlocklist string
+
+	// VaultFile is the path to the vault that stores the original values replaced by the Tokenize processor. It is
+	// encrypted with VaultKey (256 bits). See OpenVault.
+	VaultFile string
+	VaultKey  []byte
+
+	// HMACKey is the secret key (at least 16 bytes) of the HMACScrambler and EmailDomainPreserving processors. The
+	// same key must be used for every run that has to produce the same output.
+	HMACKey []byte
+
+	// MappingsFile is the path to the encrypted consistent mappings of earlier runs. It is loaded (if it exists) before
+	// and saved after processing with MappingsKey (256 bits). See SaveMappings.
+	MappingsFile string
+	MappingsKey  []byte
+
+	// SecureRandom makes the processors use crypto/rand instead of a seeded math/rand generator, so their output
+	// cannot be predicted. The Seed of the map file is ignored. See SetSecureRandom.
+	SecureRandom bool
+
+	// MappingStore keeps the consistent mappings of the processors instead of the in-memory maps. It is not closed
+	// after processing. MappingsFile requires the in-memory store. See OpenMappingStore.
+	MappingStore MappingStore
+
+	// MaxMappings bounds the number of consistent mappings kept in memory. The least recently used mappings are
+	// evicted and generated again with the same value when they are seen again. See NewBoundedMappingStore.
+	MaxMappings int
+}
+
+// writesSchema returns true if the schema (everything outside of the COPY blocks) is written by this process.
+func (opts ProcessOptions) writesSchema() bool {
+	return opts.ShardCount <= 1 || opts.ShardIndex == 0
+}
+
+// ownsTable returns true if the copyCount'th COPY block (starting at 1) of the dump file is assigned to this process.
+func (opts ProcessOptions) ownsTable(copyCount int64) bool {
+	if opts.ShardCount <= 1 {
+		return true
+	}
+	if opts.ShardPlan != nil {
+		return opts.ShardPlan.owns(opts.ShardIndex, copyCount, 0)
+	}
+	return (copyCount-1)%int64(opts.ShardCount) == int64(opts.ShardIndex)
+}
+
+// ownsRow returns true if the rowNumber'th row (starting at 1) of the copyCount'th COPY block is assigned to this
+// process. Rows are only split between processes by a ShardPlan.
+func (opts ProcessOptions) ownsRow(copyCount, rowNumber int64) bool {
+	return opts.ShardCount <= 1 || opts.ShardPlan == nil || opts.ShardPlan.owns(opts.ShardIndex, copyCount, rowNumber)
+}
+
+// defaultBatchSize is the number of rows processed at a time when ProcessOptions.BatchSize is not set.
+const defaultBatchSize = 1000
+
+// defaultCacheSize is the size of the processor cache when ProcessOptions.CacheSize is not set.
+const defaultCacheSize = 100000
+
+// processorCache contains the results of processor definitions that have Cache set. It is created for every call to
+// ProcessDumpFileWithOptions.
+var processorCache *lruCache
+
 // Clear will clear out all known line stat for the current LineState object.
 func (curLine *LineState) Clear() {
 	curLine.IsRow = false
 	curLine.SchemaName = ""
 	curLine.TableName = ""
 	curLine.ColumnNames = nil
+	curLine.RowCount = 0
+	curLine.RowNumber = 0
+	curLine.Batched = false
+	curLine.Batch = nil
+	curLine.Skipped = false
+	curLine.Mapped = false
+	curLine.ColumnMaps = nil
+	curLine.DroppedColumns = nil
+	curLine.RowFilter = nil
+	curLine.RowRedaction = nil
+}
+
+// redactsRow returns true if the values of the row are replaced by the row redaction of the table (see RowRedaction).
+func (curLine *LineState) redactsRow(row *rowContext) (bool, error) {
+	if curLine.RowRedaction == nil || curLine.RowRedaction.Action == RedactDrop {
+		return false, nil
+	}
+	return curLine.RowRedaction.matches(row)
+}
+
+// mapColumns looks up the column map of every column of the current COPY block so it is only done once per table.
+func (curLine *LineState) mapColumns(mapper *DBMapper) {
+	curLine.Mapped = false
+	curLine.ColumnMaps = make([]*ColumnMapper, len(curLine.ColumnNames))
+	for i, columnName := range curLine.ColumnNames {
+		curLine.ColumnMaps[i] = mapper.ColumnMapper(curLine.SchemaName, curLine.TableName, columnName)
+		curLine.Mapped = curLine.Mapped || curLine.ColumnMaps[i] != nil
+	}
+}
+
+// unmappedColumns returns the columns of the current COPY block that are not in the map file.
+func (curLine *LineState) unmappedColumns() []string {
+	var unmapped []string
+	for i, cmap := range curLine.ColumnMaps {
+		if cmap == nil {
+			unmapped = append(unmapped, curLine.ColumnNames[i])
+		}
+	}
+	return unmapped
 }
 
 // CreateDumpFile will create a PostgreSQL dump file from the specified PGConfig to the location, and with
@@ -123,19 +368,113 @@ func ProcessDumpFile(mapper *DBMapper,
 	postProcessFile string,
 	generateSeed bool,
 ) error {
+	return ProcessDumpFileWithOptions(mapper, src, dst, preProcessFile, postProcessFile, generateSeed, ProcessOptions{})
+}
+
+// ProcessDumpFileWithOptions is the same as ProcessDumpFile but allows the caller to supply optional settings. See
+// ProcessOptions for details.
+func ProcessDumpFileWithOptions(mapper *DBMapper,
+	src,
+	dst,
+	preProcessFile,
+	postProcessFile string,
+	generateSeed bool,
+	opts ProcessOptions,
+) error {
 
 	var (
 		inputLine  string
 		outputLine string
 	)
-	if generateSeed {
+	if err := checkTimedOutCalls(); err != nil {
+		return err
+	}
+	if opts.ShardCount > 1 && (opts.ShardIndex < 0 || opts.ShardIndex >= opts.ShardCount) {
+		return fmt.Errorf("Expected ShardIndex between 0 and %d, got %d", opts.ShardCount-1, opts.ShardIndex)
+	}
+	if opts.ShardPlan != nil && opts.ShardPlan.ShardCount != opts.ShardCount {
+		return fmt.Errorf("Shard plan is for %d shards, expected %d", opts.ShardPlan.ShardCount, opts.ShardCount)
+	}
+	if opts.ShardCount > 1 && !isSharedStore(opts.MappingStore) {
+		// Every shard would map the values of the parent column and its children differently
+		for _, cmap := range mapper.ColumnMaps {
+			if cmap.ParentSchema != "" && cmap.ParentTable != "" && cmap.ParentColumn != "" {
+				err := fmt.Errorf("Column %s.%s.%s is mapped from a parent column, which requires a shared "+
+					"mapping store for more than one shard", cmap.TableSchema, cmap.TableName, cmap.ColumnName)
+				log.Error(err)
+				return err
+			}
+		}
+	}
+	if len(opts.SuppressionList) > 0 {
+		if err := LoadSuppressionList(opts.SuppressionList); err != nil {
+			return err
+		}
+	}
+	if len(opts.NameBlocklist) > 0 {
+		if err := LoadNameBlocklist(opts.NameBlocklist); err != nil {
+			return err
+		}
+	}
+	if len(opts.VaultFile) > 0 {
+		vault, err := OpenVault(opts.VaultFile, opts.VaultKey)
+		if err != nil {
+			return err
+		}
+		tokenVault = vault
+		defer func() {
+			vault.Close()
+			tokenVault = nil
+		}()
+	}
+	if len(opts.DataPack) > 0 {
+		if err := LoadDataPack(opts.DataPack); err != nil {
+			return err
+		}
+	}
+	if opts.MaxMappings > 0 && (opts.MappingStore != nil || len(opts.MappingsFile) > 0) {
+		err := errors.New("MaxMappings cannot be combined with a mapping store or mappings file")
+		log.Error(err)
+		return err
+	}
+	if opts.MappingStore != nil {
+		if _, memory := opts.MappingStore.(memoryStore); !memory && len(opts.MappingsFile) > 0 {
+			err := errors.New("A mappings file can only be used with the in-memory mapping store")
+			log.Error(err)
+			return err
+		}
+		SetMappingStore(opts.MappingStore)
+		defer SetMappingStore(nil)
+	}
+	if len(opts.MappingsFile) > 0 {
+		if err := LoadMappings(opts.MappingsFile, opts.MappingsKey); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	hmacKey = nil
+	if len(opts.HMACKey) > 0 {
+		if err := validateHMACKey(opts.HMACKey); err != nil {
+			return err
+		}
+		hmacKey = opts.HMACKey
+	}
+	histograms, err := collectLengthHistograms(mapper, src)
+	if err != nil {
+		return err
+	}
+	lengthHistograms = histograms
+	defer func() { lengthHistograms = nil }()
+	SetSecureRandom(opts.SecureRandom)
+	if opts.SecureRandom {
+		log.Debug("Using crypto/rand for processors that require randomness")
+	} else if generateSeed {
 		for {
 			randVal, err := generateRandomInt64()
 			if err != nil {
 				log.Error(err)
 			} else {
-				log.Debugf("Using internal number generator for seed value: %d", randVal)
-				mathRand.Seed(randVal)
+				log.Infof("Using internal number generator for seed value: %d", randVal)
+				SetSeed(randVal)
 				break
 			}
 		}
@@ -145,7 +484,12 @@ func ProcessDumpFile(mapper *DBMapper,
 			return errors.New("Expected non-zero Seed")
 		}
 		log.Debugf("Using map file for seed value: %d", randVal)
-		mathRand.Seed(mapper.Seed)
+		SetSeed(mapper.Seed)
+	}
+	if opts.MaxMappings > 0 {
+		// The secret of the store is drawn from the seeded generator when there is no HMAC key
+		SetMappingStore(NewBoundedMappingStore(opts.MaxMappings, opts.HMACKey))
+		defer SetMappingStore(nil)
 	}
 
 	srcFile, err := os.Open(src)
@@ -157,8 +501,6 @@ func ProcessDumpFile(mapper *DBMapper,
 	}
 	defer srcFile.Close()
 
-	fileReader := bufio.NewReader(srcFile)
-
 	dstFile, err := os.Create(dst)
 	if err != nil {
 		log.Error(err)
@@ -168,27 +510,72 @@ func ProcessDumpFile(mapper *DBMapper,
 	}
 	defer dstFile.Close()
 
+	var (
+		reader io.Reader = srcFile
+		writer io.Writer = dstFile
+	)
+	depth := opts.PipelineDepth
+	if depth == 0 {
+		depth = defaultPipelineDepth
+	}
+	if depth > 0 {
+		ra := newReadAhead(srcFile, depth)
+		defer ra.Close()
+		wb := newWriteBehind(dstFile, depth)
+		defer wb.Close()
+		defer func() {
+			log.Debugf("Pipeline: waited %s for the dump file and %s for the processed dump file", ra.waited, wb.waited)
+		}()
+		reader, writer = ra, wb
+	}
+	scanner := newDumpScanner(reader)
+	dstWriter := bufio.NewWriterSize(writer, dumpBufferSize)
+
 	// Call fileInjector to write any required configuration settings to the top of the
 	// processed dump file
 	if len(preProcessFile) > 0 {
-		if err = fileInjector(preProcessFile, dstFile); err != nil {
+		if err = fileInjector(preProcessFile, dstWriter); err != nil {
 			log.Error("Unable to run preProcessor")
 			return err
 		}
 	}
 
 	// Always make sure we are in replication mode so we can import tables without constraints
-	if _, err := dstFile.WriteString("SET session_replication_role = 'replica';\n"); err != nil {
+	if _, err := dstWriter.WriteString("SET session_replication_role = 'replica';\n"); err != nil {
 		return err
 	}
 
+	cacheSize := opts.CacheSize
+	if cacheSize <= 0 {
+		cacheSize = defaultCacheSize
+	}
+	processorCache = newLRUCache(cacheSize)
+	reprocessAttempts = opts.ReprocessUnchanged
+	defer func() { reprocessAttempts = 0 }()
+	defer func() {
+		log.Debugf("Processor cache: %d hits, %d misses, %d entries", processorCache.Hits, processorCache.Misses,
+			processorCache.len())
+		processorCache = nil
+	}()
+
 	allDone := false
 	state := new(LineState)
 
+	watchdog := newWatchdog(opts)
+	defer watchdog.close()
+
+	var dash *dashboard
+	if len(opts.DashboardAddress) > 0 {
+		if dash, err = newDashboard(opts.DashboardAddress, src, opts.RowCountFile); err != nil {
+			return err
+		}
+		defer dash.close()
+	}
+
 	for {
 		lineCount++
 		state.LineNum = lineCount
-		inputLine, err = fileReader.ReadString('\n')
+		line, err := scanner.next()
 		if err != nil {
 			if err == io.EOF {
 				// readline will fail if it doesn't encounter our delimiter (\n)
@@ -201,12 +588,30 @@ func ProcessDumpFile(mapper *DBMapper,
 				log.Debug("src: ", src)
 				log.Debug("dst: ", dst)
 				log.Debug("lineCount: ", lineCount)
-				log.Debug("inputLine: ", inputLine)
+				log.Debug("inputLine: ", string(line))
 				return err
 			}
 		}
 
-		state, outputLine, err = processLine(mapper, state, inputLine)
+		if lineCount%100000 == 0 {
+			log.Info("Processing line number: ", lineCount)
+		}
+		watchdog.tick(state)
+		dash.tick(state, line)
+
+		// Most lines are written unmodified, only convert the lines that need processing to a string
+		if passThrough(state, opts, line) {
+			if _, err = dstWriter.Write(line); err != nil {
+				return err
+			}
+			if allDone {
+				break
+			}
+			continue
+		}
+		inputLine = string(line)
+
+		state, outputLine, err = processLine(mapper, state, inputLine, opts)
 
 		if err != nil {
 			log.Error("processLine failure: ", err)
@@ -218,7 +623,7 @@ func ProcessDumpFile(mapper *DBMapper,
 			return err
 		}
 
-		bytesWritten, err := dstFile.WriteString(outputLine)
+		bytesWritten, err := dstWriter.WriteString(outputLine)
 		if err != nil {
 			log.Error(err)
 			log.Debug("src: ", src)
@@ -230,12 +635,16 @@ func ProcessDumpFile(mapper *DBMapper,
 		}
 
 		if allDone {
+			// Dump files always end a COPY with "\.", but make sure no buffered rows are lost
+			outputLine, err = flushBatch(mapper, state)
+			if err != nil {
+				return err
+			}
+			if _, err = dstWriter.WriteString(outputLine); err != nil {
+				return err
+			}
 			break
 		}
-
-		if lineCount%100000 == 0 {
-			log.Info("Processing line number: ", lineCount)
-		}
 	}
 	if strings.ToLower(viper.GetString("log-level")) == "debug" {
 		err = writeDebugMap()
@@ -245,15 +654,27 @@ func ProcessDumpFile(mapper *DBMapper,
 	}
 	// Add in SQL at the end of the dump file
 	if len(postProcessFile) > 0 {
-		if err = fileInjector(postProcessFile, dstFile); err != nil {
+		if err = fileInjector(postProcessFile, dstWriter); err != nil {
 			return err
 		}
 	}
 
 	// Enable constraints (they were disabled earlier)
-	if _, err := dstFile.WriteString("SET session_replication_role = 'origin';\n"); err != nil {
+	if _, err := dstWriter.WriteString("SET session_replication_role = 'origin';\n"); err != nil {
+		return err
+	}
+	if err = dstWriter.Flush(); err != nil {
 		return err
 	}
+	if wb, ok := writer.(*writeBehind); ok {
+		if err = wb.Close(); err != nil {
+			return err
+		}
+	}
+	if len(opts.MappingsFile) > 0 {
+		log.Info("Saving mappings to: ", opts.MappingsFile)
+		return SaveMappings(opts.MappingsFile, opts.MappingsKey)
+	}
 	return nil
 }
 
@@ -298,10 +719,21 @@ func generateSchemaSQL(conf PGConfig, outputFile *os.File, excludeCreateSchemas
 
 // processLine will process the current line in the dump file by deciding which state the processor should be in
 // based on reading in the content of the current line in the dump file and analyzing it.
-func processLine(mapper *DBMapper, state *LineState, inputLine string) (*LineState, string, error) {
+func processLine(mapper *DBMapper, state *LineState, inputLine string, opts ProcessOptions) (*LineState, string,
+	error) {
 
 	outputLine := inputLine
+	if !state.IsRow && !opts.writesSchema() {
+		outputLine = ""
+	}
 	trimmedInput := strings.TrimLeftFunc(inputLine, unicode.IsSpace)
+	if state.Skipped {
+		if strings.HasPrefix(trimmedInput, StateChangeTokenEndCopy) {
+			state.Clear()
+		}
+		return state, "", nil
+	}
+
 	if len(trimmedInput) == 0 {
 		return state, outputLine, nil
 	}
@@ -312,26 +744,187 @@ func processLine(mapper *DBMapper, state *LineState, inputLine string) (*LineSta
 
 	if strings.HasPrefix(trimmedInput, StateChangeTokenBeginCopy) {
 		state.parseCopyLine(inputLine)
-		return state, outputLine, nil
+		state.CopyCount++
+		if !opts.ownsTable(state.CopyCount) {
+			// Another shard processes this table
+			state.Skipped = true
+			return state, "", nil
+		}
+		state.Batched = usesBatchProcessor(mapper, state)
+		state.RowFilter = mapper.rowFilter(state.SchemaName, state.TableName)
+		state.RowRedaction = mapper.rowRedaction(state.SchemaName, state.TableName)
+		state.mapColumns(mapper)
+		if unmapped := state.unmappedColumns(); opts.Strict && len(unmapped) > 0 {
+			return state, "", fmt.Errorf("Strict mode: column(s) of %s.%s not found in the map file: %s",
+				state.SchemaName, state.TableName, strings.Join(unmapped, ", "))
+		}
+		if state.dropGeneratedColumns() {
+			return state, state.copyStatement(inputLine), nil
+		}
+		return state, inputLine, nil
 	}
 
 	if strings.HasPrefix(trimmedInput, StateChangeTokenEndCopy) {
+		batchOutput, err := flushBatch(mapper, state)
+		if err != nil {
+			return state, "", err
+		}
 		state.Clear()
-		return state, outputLine, nil
+		return state, batchOutput + outputLine, nil
 	}
 
 	if state.IsRow {
+		state.RowNumber++
+		if !opts.ownsRow(state.CopyCount, state.RowNumber) {
+			return state, "", nil
+		}
+		if len(state.DroppedColumns) > 0 {
+			inputLine = dropValues(inputLine, state.DroppedColumns)
+		}
+		if state.RowFilter != nil {
+			keep, err := state.RowFilter.keeps(state.ColumnNames, strings.Split(inputLine, "\t"))
+			if err != nil {
+				return state, "", err
+			} else if !keep {
+				// Filtered rows are dropped before processing and do not count towards SampleRows
+				return state, "", nil
+			}
+		}
+		if state.RowRedaction != nil && state.RowRedaction.Action == RedactDrop {
+			drop, err := state.RowRedaction.matches(newRowContext(state.ColumnNames, strings.Split(inputLine, "\t")))
+			if err != nil || drop {
+				return state, "", err
+			}
+		}
+		state.RowCount++
+		if opts.SampleRows > 0 && state.RowCount > opts.SampleRows {
+			// Drop the row before processing so unused PII never reaches the processors
+			return state, "", nil
+		}
+		if state.Batched {
+			state.Batch = append(state.Batch, inputLine)
+			if len(state.Batch) < batchSize(opts) {
+				return state, "", nil
+			}
+			outputLine, err := flushBatch(mapper, state)
+			return state, outputLine, err
+		}
 		return processRow(mapper, state, inputLine)
 	}
 
 	return state, outputLine, nil
 }
 
+// batchSize returns the number of rows to buffer for tables using a BatchProcessor.
+func batchSize(opts ProcessOptions) int {
+	if opts.BatchSize > 0 {
+		return opts.BatchSize
+	}
+	return defaultBatchSize
+}
+
+// usesBatchProcessor returns true if any column of the current table uses a processor found in the
+// BatchProcessorCatalog.
+func usesBatchProcessor(mapper *DBMapper, state *LineState) bool {
+	for _, columnName := range state.ColumnNames {
+		cmap := mapper.ColumnMapper(state.SchemaName, state.TableName, columnName)
+		if cmap == nil {
+			continue
+		}
+		for _, procDef := range cmap.Processors {
+			if _, ok := BatchProcessorCatalog[procDef.Name]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flushBatch will process all rows buffered in state.Batch and return the processed rows.
+func flushBatch(mapper *DBMapper, state *LineState) (string, error) {
+	if len(state.Batch) == 0 {
+		return "", nil
+	}
+
+	rows := make([][]string, len(state.Batch))
+	for j, inputLine := range state.Batch {
+		rows[j] = strings.Split(strings.TrimSuffix(inputLine, "\n"), "\t")
+		if len(rows[j]) != len(state.ColumnNames) {
+			return "", fmt.Errorf("Expected %d columns in %s.%s, found %d", len(state.ColumnNames), state.SchemaName,
+				state.TableName, len(rows[j]))
+		}
+	}
+	state.Batch = state.Batch[:0]
+
+	// Every row needs its own row context
+	contexts := make([]*rowContext, len(rows))
+	redacted := make([]bool, len(rows))
+	for j, row := range rows {
+		contexts[j] = newRowContext(state.ColumnNames, row)
+		var err error
+		if redacted[j], err = state.redactsRow(contexts[j]); err != nil {
+			return "", err
+		}
+	}
+
+	for i, columnName := range state.ColumnNames {
+		cmap := mapper.ColumnMapper(state.SchemaName, state.TableName, columnName)
+		if cmap == nil {
+			continue
+		}
+
+		// NULL values are only processed if the column says so (see NullBehavior)
+		var (
+			cells []Cell
+			index []int
+		)
+		for j, row := range rows {
+			if redacted[j] {
+				if output, ok := state.RowRedaction.redact(columnName, cmap, row[i]); ok {
+					row[i] = output
+					continue
+				}
+			}
+			if input, ok := cmap.processorInput(row[i]); ok {
+				cells = append(cells, Cell{Column: cmap, Value: input, row: contexts[j]})
+				index = append(index, j)
+			}
+		}
+
+		outputs, err := processBatch(cmap, cells)
+		if err != nil {
+			log.Error(err)
+			log.Debug("columnName: ", columnName)
+			return "", state.processorError(columnName, err)
+		}
+		for k, j := range index {
+			rows[j][i] = cmap.nullOutput(outputs[k])
+		}
+	}
+
+	var b strings.Builder
+	for _, row := range rows {
+		b.WriteString(strings.Join(row, "\t"))
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
 // processRow will process the line in the dump file IFF it is a SQL-line (eventual row in the database after import).
 func processRow(mapper *DBMapper, state *LineState, inputLine string) (*LineState, string, error) {
 
 	rowVals := strings.Split(inputLine, "\t")
 	outputVals := make([]string, 0, len(rowVals))
+	if len(state.ColumnMaps) != len(state.ColumnNames) {
+		state.mapColumns(mapper)
+	}
+
+	// Every row starts with a new row context
+	currentRow = newRowContext(state.ColumnNames, rowVals)
+	redacted, err := state.redactsRow(currentRow)
+	if err != nil {
+		return state, "", err
+	}
 
 	for i, columnName := range state.ColumnNames {
 		var (
@@ -340,7 +933,7 @@ func processRow(mapper *DBMapper, state *LineState, inputLine string) (*LineStat
 			output     string
 		)
 
-		cmap := mapper.ColumnMapper(state.SchemaName, state.TableName, columnName)
+		cmap := state.ColumnMaps[i]
 		val := rowVals[i]
 
 		// Check to see if the column has an escape char at the end of it.
@@ -353,17 +946,38 @@ func processRow(mapper *DBMapper, state *LineState, inputLine string) (*LineStat
 			val = strings.Replace(val, "\t", "", -1)
 		}
 
-		// If column value is nil or if this column is not mapped, keep the value and continue on
-		if val == "\\N" || cmap == nil {
+		// Redacted values never reach the processors
+		if redacted {
+			if output, ok := state.RowRedaction.redact(columnName, cmap, val); ok {
+				outputVals = append(outputVals, output+escapeChar)
+				continue
+			}
+		}
+
+		// If column value is nil (see NullBehavior) or if this column is not mapped, keep the value and continue on
+		input, process := val, cmap != nil
+		if process {
+			input, process = cmap.processorInput(val)
+		}
+		if !process {
 			output = val
 		} else {
-			output, err = processValue(cmap, val)
+			addedIdentities = addedIdentities[:0]
+			output, err = processValue(cmap, input)
+			for attempt := 0; err == nil && attempt < reprocessAttempts &&
+				unchangedValue(cmap, input, output); attempt++ {
+				// The value mapped to itself must not be found again
+				if err = forgetIdentityMappings(); err == nil {
+					output, err = processValue(cmap, input)
+				}
+			}
 			if err != nil {
 				log.Error(err)
 				log.Debug("i: ", i)
 				log.Debug("columnName: ", columnName)
-				return state, "****************** PROCESS ROW ERROR ******************", err
+				return state, "****************** PROCESS ROW ERROR ******************", state.processorError(columnName, err)
 			}
+			output = cmap.nullOutput(output)
 		}
 		// Add escape character back to column
 		output += escapeChar
@@ -377,7 +991,9 @@ func processRow(mapper *DBMapper, state *LineState, inputLine string) (*LineStat
 	return state, outputLine, nil
 }
 
-// processValue will anonymize or ignore the current value for a given column in the dump file
+// processValue will anonymize or ignore the current value for a given column in the dump file. The processors of the
+// column are run in order, each on the output of the one before it (e.g. TrimWhitespace, FakeEmailAddress, Lowercase).
+// Processors with a When condition are skipped for the rows that do not match it.
 func processValue(cmap *ColumnMapper, input string) (string, error) {
 	var err error
 
@@ -398,7 +1014,15 @@ func processValue(cmap *ColumnMapper, input string) (string, error) {
 
 		}
 
-		output, err = pfunc(cmap, input)
+		runs, err := procDef.runsFor(currentRow)
+		if err != nil {
+			return "", err
+		} else if !runs {
+			continue
+		}
+
+		// Each processor gets the output of the one before it, so processors can be chained
+		output, err = runProcessor(cmap, procDef, pfunc, output)
 		if err != nil {
 			log.Error(err)
 			log.Debug("i: ", i)
@@ -410,24 +1034,159 @@ func processValue(cmap *ColumnMapper, input string) (string, error) {
 	return output, nil
 }
 
-// parseCopyLine will parse the /copy line in a PostgreSQL dump file
+// processBatch is the batch version of processValue. Processors found in the BatchProcessorCatalog are called once for
+// all cells, every other processor is called once per cell. Processors with a When condition only get the cells of the
+// rows that match it.
+func processBatch(cmap *ColumnMapper, cells []Cell) ([]string, error) {
+	outputs := make([]string, len(cells))
+	for i, cell := range cells {
+		outputs[i] = cell.Value
+	}
+	if len(cells) == 0 {
+		return outputs, nil
+	}
+
+	for _, procDef := range cmap.Processors {
+		// Only the cells of rows matching the When condition of the processor are processed
+		var indexes []int
+		for i, cell := range cells {
+			row := currentRow
+			if cell.row != nil {
+				row = cell.row
+			}
+			runs, err := procDef.runsFor(row)
+			if err != nil {
+				return nil, err
+			} else if runs {
+				indexes = append(indexes, i)
+			}
+		}
+
+		if batchProcessor, ok := BatchProcessorCatalog[procDef.Name]; ok {
+			if len(indexes) == 0 {
+				continue
+			}
+			// Pass the outputs of the processors before this one
+			inputs := make([]Cell, len(indexes))
+			for j, i := range indexes {
+				inputs[j] = cells[i]
+				inputs[j].Value = outputs[i]
+			}
+			batchOutputs, err := batchProcessor.ProcessBatch(inputs)
+			if err != nil {
+				return nil, err
+			}
+			if len(batchOutputs) != len(inputs) {
+				return nil, fmt.Errorf("Batch processor %s returned %d values, expected %d", procDef.Name,
+					len(batchOutputs), len(inputs))
+			}
+			for j, i := range indexes {
+				outputs[i] = truncateMaxLength(cmap, batchOutputs[j])
+			}
+			continue
+		}
+
+		pfunc := ProcessorCatalog[procDef.Name]
+		if pfunc == nil {
+			return nil, fmt.Errorf("Unknown Processor Name: %s", procDef.Name)
+		}
+		for _, i := range indexes {
+			if cells[i].row != nil {
+				currentRow = cells[i].row
+			}
+			output, err := runProcessor(cmap, procDef, pfunc, outputs[i])
+			if err != nil {
+				return nil, err
+			}
+			outputs[i] = output
+		}
+	}
+	return outputs, nil
+}
+
+// runProcessor will call pfunc for the input. If the processor definition has Cache set, the result is looked up in
+// (and stored to) the processor cache keyed by the column, the processor definition (name and options), and the input.
+// When the processor takes longer than the definition's Timeout a ProcessorTimeoutError is returned (see
+// callProcessor). With LengthHistogram set the output length is sampled from the column (see sampledLength). Output
+// longer than the MaxLength of the column is regenerated or truncated (see maxLengthOutput). The processor is called
+// with a copy of the column listing only procDef, so every step of a chain gets its own options even when several steps
+// use the same processor (see ColumnMapper.processorDefinition).
+func runProcessor(cmap *ColumnMapper, procDef ProcessorDefinition, pfunc ProcessorFunc, input string) (string, error) {
+	reseedSecureFake()
+	if len(cmap.Processors) > 1 {
+		step := *cmap
+		step.Processors = []ProcessorDefinition{procDef}
+		cmap = &step
+	}
+	output, err := runCachedProcessor(cmap, procDef, pfunc, input)
+	if err == nil && procDef.LengthHistogram {
+		output, err = sampledLength(cmap, procDef, pfunc, input, output)
+	}
+	if err == nil {
+		output, err = maxLengthOutput(cmap, procDef, pfunc, input, output)
+	}
+	// Errors of the mapping store are kept by the processors (see mappedValue)
+	if storeErr := mappingStoreError(); storeErr != nil && err == nil {
+		return "", storeErr
+	}
+	return output, err
+}
+
+// runCachedProcessor will return the cached output of the processor for the input if the processor definition has
+// Cache set. Otherwise the processor is run (see callProcessor). Outputs are cached by column, as processors depend on
+// the parent column, MaxLength, and DataType of the column.
+func runCachedProcessor(cmap *ColumnMapper, procDef ProcessorDefinition, pfunc ProcessorFunc, input string) (string,
+	error) {
+	if !procDef.Cache || processorCache == nil {
+		return callProcessor(cmap, procDef, pfunc, input)
+	}
+
+	key := fmt.Sprintf("%s.%s.%s\x00%+v\x00%s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, procDef, input)
+	if output, ok := processorCache.get(key); ok {
+		return output, nil
+	}
+	output, err := callProcessor(cmap, procDef, pfunc, input)
+	if err != nil {
+		return "", err
+	}
+	processorCache.add(key, output)
+	if output == input && reprocessAttempts > 0 {
+		addedIdentities = append(addedIdentities, identityMapping{key: key})
+	}
+	return output, nil
+}
+
+// parseCopyLine will parse the /copy line in a PostgreSQL dump file. Quoted identifiers (public."Order Items" ("Id",
+// "Unit.Price")) are unquoted, so the names match the map file.
 func (curLine *LineState) parseCopyLine(inputLine string) {
+	copyLine := strings.TrimLeftFunc(strings.TrimPrefix(strings.TrimSpace(inputLine), StateChangeTokenBeginCopy),
+		unicode.IsSpace)
 
-	spaceSplts := strings.Split(inputLine, " ")
-	schemaTableSplt := strings.Split(spaceSplts[1], ".")
+	nameEnd := indexOutsideQuotes(copyLine, " (")
+	if nameEnd < 0 {
+		nameEnd = len(copyLine)
+	}
+	schemaTable := splitIdentifiers(copyLine[:nameEnd], '.')
 
 	curLine.IsRow = true
-	curLine.SchemaName = schemaTableSplt[0]
-	curLine.TableName = schemaTableSplt[1]
-
-	openSplts := strings.Split(inputLine, "(")
-	parensContent := openSplts[1]
-	closeSplits := strings.Split(parensContent, ")")
-	parensContent = closeSplits[0]
-	curLine.ColumnNames = strings.Split(parensContent, ",")
+	curLine.RowCount = 0
+	curLine.RowNumber = 0
+	if len(schemaTable) > 1 {
+		curLine.SchemaName = schemaTable[0]
+		curLine.TableName = schemaTable[1]
+	} else {
+		// pg_dump always qualifies the table name, default to the public schema for hand written dump files
+		curLine.SchemaName = "public"
+		curLine.TableName = schemaTable[0]
+	}
 
-	for i, v := range curLine.ColumnNames {
-		curLine.ColumnNames[i] = strings.TrimSpace(v)
+	curLine.ColumnNames = nil
+	if open := indexOutsideQuotes(copyLine, "("); open >= 0 {
+		columns := copyLine[open+1:]
+		if end := indexOutsideQuotes(columns, ")"); end >= 0 {
+			columns = columns[:end]
+		}
+		curLine.ColumnNames = splitIdentifiers(columns, ',')
 	}
 
 	debugLine := fmt.Sprintf(`
@@ -441,8 +1200,50 @@ func (curLine *LineState) parseCopyLine(inputLine string) {
 	log.Debug(debugLine)
 }
 
+// forEachDumpRow reads the dump file found at path and calls fn for every SQL-COPY row in the file. The row values are
+// split into columns (in the same order as state.ColumnNames) with the trailing new line removed.
+func forEachDumpRow(path string, fn func(state *LineState, values []string) error) error {
+	srcFile, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	reader := bufio.NewReader(srcFile)
+	state := new(LineState)
+
+	for lineNum := int64(1); ; lineNum++ {
+		inputLine, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		state.LineNum = lineNum
+
+		trimmedInput := strings.TrimLeftFunc(inputLine, unicode.IsSpace)
+		switch {
+		case strings.HasPrefix(trimmedInput, StateChangeTokenBeginCopy):
+			state.parseCopyLine(inputLine)
+		case strings.HasPrefix(trimmedInput, StateChangeTokenEndCopy):
+			state.Clear()
+		case state.IsRow && len(trimmedInput) > 0:
+			values := strings.Split(strings.TrimSuffix(inputLine, "\n"), "\t")
+			if len(values) != len(state.ColumnNames) {
+				return fmt.Errorf("Line %d of %s has %d columns, expected %d", lineNum, path, len(values),
+					len(state.ColumnNames))
+			}
+			if err := fn(state, values); err != nil {
+				return err
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
 // fileInjector writes data to the current position in the destination file from the source file
-func fileInjector(srcFileName string, dstFile *os.File) error {
+func fileInjector(srcFileName string, dstFile io.StringWriter) error {
 	srcFile, err := os.Open(srcFileName)
 	if err != nil {
 		return err
@@ -4,8 +4,10 @@ import (
 	"errors"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/icrowley/fake"
 	"github.com/stretchr/testify/require"
 )
 
@@ -72,6 +74,192 @@ func TestProcessDumpFile(t *testing.T) {
 	require.Nil(t, SQLCommandFile(conf, TestProcessDumpfile, true)) //Must ignore errors
 }
 
+func TestProcessDumpFileSampleRows(t *testing.T) {
+	columnMap, err := LoadConfigSkeleton(TestMapFile)
+	require.Nil(t, err)
+	require.Nil(t, ProcessDumpFileWithOptions(
+		columnMap,
+		TestDbFile,
+		TestSampleDumpFile,
+		"",
+		"",
+		true,
+		ProcessOptions{SampleRows: 2}))
+
+	rowCounts := map[string]int{}
+	require.Nil(t, forEachDumpRow(TestSampleDumpFile, func(state *LineState, values []string) error {
+		rowCounts[state.TableName]++
+		return nil
+	}))
+	require.Equal(t, map[string]int{"authors": 2, "books": 2, "distributors": 2, "purchasers": 2}, rowCounts)
+
+	// The schema must be written in full
+	output, err := ioutil.ReadFile(TestSampleDumpFile)
+	require.Nil(t, err)
+	require.Contains(t, string(output), "CREATE OR REPLACE FUNCTION create_distributor")
+	require.Nil(t, os.Remove(TestSampleDumpFile))
+}
+
+func TestProcessDumpFileStrict(t *testing.T) {
+	columnMap, err := LoadConfigSkeleton(TestMapFile)
+	require.Nil(t, err)
+	defer os.Remove(TestSampleDumpFile)
+	require.Nil(t, ProcessDumpFileWithOptions(columnMap, TestDbFile, TestSampleDumpFile, "", "", true,
+		ProcessOptions{Strict: true}))
+
+	// Remove a column from the map file
+	var columnMaps []ColumnMapper
+	for _, cmap := range columnMap.ColumnMaps {
+		if cmap.TableName != "purchasers" || cmap.ColumnName != "email" {
+			columnMaps = append(columnMaps, cmap)
+		}
+	}
+	require.True(t, len(columnMaps) < len(columnMap.ColumnMaps))
+	columnMap.ColumnMaps = columnMaps
+	require.Nil(t, ProcessDumpFileWithOptions(columnMap, TestDbFile, TestSampleDumpFile, "", "", true,
+		ProcessOptions{}))
+	err = ProcessDumpFileWithOptions(columnMap, TestDbFile, TestSampleDumpFile, "", "", true,
+		ProcessOptions{Strict: true})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "public.purchasers not found in the map file: email")
+}
+
+func TestProcessDumpFileBatch(t *testing.T) {
+	calls := 0
+	BatchProcessorCatalog["TestUpperCase"] = BatchProcessorFunc(func(cells []Cell) ([]string, error) {
+		calls++
+		outputs := make([]string, len(cells))
+		for i, cell := range cells {
+			outputs[i] = strings.ToUpper(cell.Value)
+		}
+		return outputs, nil
+	})
+	defer delete(BatchProcessorCatalog, "TestUpperCase")
+
+	columnMap := &DBMapper{
+		DBName: "pii_localtest",
+		ColumnMaps: []ColumnMapper{
+			{
+				TableSchema: "public",
+				TableName:   "purchasers",
+				ColumnName:  "first_name",
+				Processors:  []ProcessorDefinition{{Name: "TestUpperCase"}},
+			},
+			{
+				TableSchema: "public",
+				TableName:   "purchasers",
+				ColumnName:  "last_name",
+				Processors:  []ProcessorDefinition{{Name: "ScrubString"}},
+			},
+		},
+	}
+	require.Nil(t, ProcessDumpFileWithOptions(
+		columnMap,
+		TestDbFile,
+		TestBatchDumpFile,
+		"",
+		"",
+		true,
+		ProcessOptions{BatchSize: 3}))
+
+	// 4 purchasers in batches of 3
+	require.Equal(t, 2, calls)
+
+	var firstNames, lastNames []string
+	require.Nil(t, forEachDumpRow(TestBatchDumpFile, func(state *LineState, values []string) error {
+		if state.TableName == "purchasers" {
+			firstNames = append(firstNames, values[1])
+			lastNames = append(lastNames, values[2])
+		}
+		return nil
+	}))
+	require.Equal(t, []string{"NORMAN", "JANET", "STEPHANIE", "COLUMBO"}, firstNames)
+	require.Equal(t, []string{"*****", "*******", "****", "********"}, lastNames)
+	require.Nil(t, os.Remove(TestBatchDumpFile))
+}
+
+func TestProcessValueChain(t *testing.T) {
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{
+		{Name: "TrimWhitespace"},
+		{Name: "MaskPartial", SuffixLength: 4},
+		{Name: "Lowercase"},
+	}}
+	output, err := processValue(cmap, "  ABCD-1234XY\n")
+	require.Nil(t, err)
+	require.Equal(t, "*******34xy", output)
+
+	// Every step gets its own options when steps share a processor
+	cmap = &ColumnMapper{Processors: []ProcessorDefinition{
+		{Name: "RegexReplace", Regex: "a", Replacement: "b"},
+		{Name: "RegexReplace", Regex: "b", Replacement: "c"},
+	}}
+	output, err = processValue(cmap, "aaa")
+	require.Nil(t, err)
+	require.Equal(t, "ccc", output)
+}
+
+func TestProcessBatchChain(t *testing.T) {
+	BatchProcessorCatalog["TestReverse"] = BatchProcessorFunc(func(cells []Cell) ([]string, error) {
+		outputs := make([]string, len(cells))
+		for i, cell := range cells {
+			runes := []rune(cell.Value)
+			for j, k := 0, len(runes)-1; j < k; j, k = j+1, k-1 {
+				runes[j], runes[k] = runes[k], runes[j]
+			}
+			outputs[i] = string(runes)
+		}
+		return outputs, nil
+	})
+	defer delete(BatchProcessorCatalog, "TestReverse")
+
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{
+		{Name: "TrimWhitespace"}, {Name: "TestReverse"}, {Name: "Uppercase"},
+	}}
+	outputs, err := processBatch(cmap, []Cell{{Column: cmap, Value: " abc"}, {Column: cmap, Value: "de "}})
+	require.Nil(t, err)
+	require.Equal(t, []string{"CBA", "ED"}, outputs)
+}
+
+func TestRunProcessorCache(t *testing.T) {
+	processorCache = newLRUCache(10)
+	defer func() { processorCache = nil }()
+
+	calls := 0
+	pfunc := func(cmap *ColumnMapper, input string) (string, error) {
+		calls++
+		return fake.FirstName(), nil
+	}
+
+	procDef := ProcessorDefinition{Name: "FakeFirstName", Cache: true}
+	first, err := runProcessor(&cMap, procDef, pfunc, "Jane")
+	require.Nil(t, err)
+	second, err := runProcessor(&cMap, procDef, pfunc, "Jane")
+	require.Nil(t, err)
+	require.Equal(t, first, second)
+	require.Equal(t, 1, calls)
+
+	// Different options or input are cached separately
+	_, err = runProcessor(&cMap, ProcessorDefinition{Name: "FakeFirstName", Cache: true, PreserveCase: true}, pfunc,
+		"Jane")
+	require.Nil(t, err)
+	_, err = runProcessor(&cMap, procDef, pfunc, "John")
+	require.Nil(t, err)
+	require.Equal(t, 3, calls)
+
+	// Other columns are cached separately
+	other := cMap
+	other.ColumnName, other.MaxLength = "nickname", 4
+	_, err = runProcessor(&other, procDef, pfunc, "Jane")
+	require.Nil(t, err)
+	require.Equal(t, 4, calls)
+
+	// Definitions without Cache are never cached
+	procDef.Cache = false
+	_, err = runProcessor(&cMap, procDef, pfunc, "Jane")
+	require.Nil(t, err)
+	require.Equal(t, 5, calls)
+}
+
 func TestGenerateRandomInt64(t *testing.T) {
 	var test int64
 	num, err := generateRandomInt64()
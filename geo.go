@@ -0,0 +1,246 @@
+package gonymizer
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultGeoRadius is the radius in km coordinates are moved within by the GeoFuzz processor when Max is not set.
+	defaultGeoRadius = 5.0
+
+	// earthRadius is the mean radius of the earth in km.
+	earthRadius = 6371.0
+
+	// ewkbSRIDFlag is set in the geometry type of PostGIS extended WKB values that contain an SRID.
+	ewkbSRIDFlag = 0x20000000
+)
+
+// kmPerDegree is the length in km of a degree of latitude (and of longitude at the equator).
+var kmPerDegree = earthRadius * math.Pi / 180
+
+var (
+	// geoPointRegex matches a PostgreSQL point: (x,y) where x is the longitude and y the latitude.
+	geoPointRegex = regexp.MustCompile(`^\(\s*(-?[0-9.]+)\s*,\s*(-?[0-9.]+)\s*\)$`)
+
+	// geoWKTRegex matches a point in (extended) well-known text: POINT(x y) or SRID=4326;POINT(x y).
+	geoWKTRegex = regexp.MustCompile(`^(?i)((?:SRID=\d+;)?\s*POINT\s*\(\s*)(-?[0-9.]+)(\s+)(-?[0-9.]+)(\s*\))$`)
+
+	// geoEWKBRegex matches a point geometry in the hex (extended) well-known binary format written by pg_dump for
+	// PostGIS geometry and geography columns.
+	geoEWKBRegex = regexp.MustCompile(`^(?i)[0-9a-f]{42}([0-9a-f]{8})?$`)
+)
+
+// geoValue is a coordinate value of a column: a latitude or longitude (see ProcessorDefinition.Field), a point, or a
+// PostGIS point geometry. format returns the value with the coordinates replaced.
+type geoValue struct {
+	lat, lon float64
+	format   func(lat, lon float64) string
+}
+
+// parseGeoValue parses a coordinate value of a GeoFuzz or GeoSnap column. A latitude or longitude column (Field) reads
+// the other coordinate from CoordinateColumn of the same row, the coordinate is 0 if it is not set.
+func parseGeoValue(procDef ProcessorDefinition, input string) (*geoValue, error) {
+	input = strings.TrimSpace(input)
+	if len(procDef.Field) > 0 {
+		number, decimals, err := parseCoordinate(input)
+		if err != nil {
+			return nil, err
+		}
+		var other float64
+		if value, ok := currentRow.value(procDef.CoordinateColumn); ok {
+			if other, _, err = parseCoordinate(value); err != nil {
+				return nil, fmt.Errorf("Invalid coordinate in column %s: %s", procDef.CoordinateColumn, err)
+			}
+		}
+		if procDef.Field == "latitude" {
+			return &geoValue{lat: number, lon: other, format: func(lat, lon float64) string {
+				return formatCoordinate(lat, decimals)
+			}}, nil
+		}
+		return &geoValue{lat: other, lon: number, format: func(lat, lon float64) string {
+			return formatCoordinate(lon, decimals)
+		}}, nil
+	}
+
+	if match := geoPointRegex.FindStringSubmatch(input); match != nil {
+		lon, lonDecimals, err := parseCoordinate(match[1])
+		if err != nil {
+			return nil, err
+		}
+		lat, latDecimals, err := parseCoordinate(match[2])
+		if err != nil {
+			return nil, err
+		}
+		return &geoValue{lat: lat, lon: lon, format: func(lat, lon float64) string {
+			return "(" + formatCoordinate(lon, lonDecimals) + "," + formatCoordinate(lat, latDecimals) + ")"
+		}}, nil
+	}
+
+	if match := geoWKTRegex.FindStringSubmatch(input); match != nil {
+		lon, lonDecimals, err := parseCoordinate(match[2])
+		if err != nil {
+			return nil, err
+		}
+		lat, latDecimals, err := parseCoordinate(match[4])
+		if err != nil {
+			return nil, err
+		}
+		return &geoValue{lat: lat, lon: lon, format: func(lat, lon float64) string {
+			return match[1] + formatCoordinate(lon, lonDecimals) + match[3] + formatCoordinate(lat, latDecimals) +
+				match[5]
+		}}, nil
+	}
+
+	if geoEWKBRegex.MatchString(input) {
+		return parseEWKBPoint(input)
+	}
+	return nil, fmt.Errorf("Expected a coordinate, point, or point geometry: %q", input)
+}
+
+// parseEWKBPoint parses a point geometry in the hex (extended) well-known binary format.
+func parseEWKBPoint(input string) (*geoValue, error) {
+	data, err := hex.DecodeString(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if data[0] == 1 {
+		order = binary.LittleEndian
+	}
+	geometryType := order.Uint32(data[1:5])
+	offset := 5
+	if geometryType&ewkbSRIDFlag != 0 {
+		offset += 4
+	}
+	if geometryType&^ewkbSRIDFlag != 1 || len(data) != offset+16 {
+		return nil, fmt.Errorf("Only 2D point geometries are supported: %q", input)
+	}
+
+	lon := math.Float64frombits(order.Uint64(data[offset:]))
+	lat := math.Float64frombits(order.Uint64(data[offset+8:]))
+	return &geoValue{lat: lat, lon: lon, format: func(lat, lon float64) string {
+		output := append([]byte(nil), data...)
+		order.PutUint64(output[offset:], math.Float64bits(lon))
+		order.PutUint64(output[offset+8:], math.Float64bits(lat))
+		// pg_dump writes upper case hex
+		return strings.ToUpper(hex.EncodeToString(output))
+	}}, nil
+}
+
+// parseCoordinate parses a latitude or longitude and returns it with its number of decimals.
+func parseCoordinate(value string) (float64, int, error) {
+	value = strings.TrimSpace(value)
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid coordinate %q", value)
+	}
+	decimals := 0
+	if dot := strings.IndexByte(value, '.'); dot >= 0 {
+		decimals = len(value) - dot - 1
+	}
+	return number, decimals, nil
+}
+
+// formatCoordinate formats a latitude or longitude with the number of decimals.
+func formatCoordinate(value float64, decimals int) string {
+	return strconv.FormatFloat(value, 'f', decimals, 64)
+}
+
+// geoFuzz moves the coordinates of the value by a random distance between Min and Max km (default defaultGeoRadius)
+// in a random direction. All GeoFuzz columns of a row with the same Group are moved by the same offset, so separate
+// latitude and longitude columns stay a pair.
+func geoFuzz(procDef ProcessorDefinition, input string) (string, error) {
+	value, err := parseGeoValue(procDef, input)
+	if err != nil {
+		return "", err
+	}
+
+	radius := procDef.Max
+	if radius == 0 {
+		radius = defaultGeoRadius
+	}
+	offset := func() map[string]string {
+		// Uniformly distributed over the area of the ring between Min and Max
+		distance := math.Sqrt(procDef.Min*procDef.Min + rng.Float64()*(radius*radius-procDef.Min*procDef.Min))
+		bearing := 2 * math.Pi * rng.Float64()
+		return map[string]string{
+			"north": strconv.FormatFloat(distance*math.Cos(bearing), 'g', -1, 64),
+			"east":  strconv.FormatFloat(distance*math.Sin(bearing), 'g', -1, 64),
+		}
+	}
+	north, err := groupValue("GeoFuzz:"+procDef.Group, "north", offset)
+	if err != nil {
+		return "", err
+	}
+	east, err := groupValue("GeoFuzz:"+procDef.Group, "east", offset)
+	if err != nil {
+		return "", err
+	}
+	northKm, _ := strconv.ParseFloat(north, 64)
+	eastKm, _ := strconv.ParseFloat(east, 64)
+
+	lat := math.Max(-90, math.Min(90, value.lat+northKm/kmPerDegree))
+	lon := value.lon + eastKm/(kmPerDegree*math.Max(math.Cos(value.lat*math.Pi/180), 0.01))
+	lon = math.Mod(lon+540, 360) - 180
+	return value.format(lat, lon), nil
+}
+
+// geoSnap replaces the coordinates of the value with the coordinates of the nearest city of the FakeLocation processor
+// (see locations).
+func geoSnap(procDef ProcessorDefinition, input string) (string, error) {
+	value, err := parseGeoValue(procDef, input)
+	if err != nil {
+		return "", err
+	}
+
+	nearest, shortest := locations[0], math.Inf(1)
+	for _, loc := range locations {
+		if distance := haversine(value.lat, value.lon, loc.Latitude, loc.Longitude); distance < shortest {
+			nearest, shortest = loc, distance
+		}
+	}
+	return value.format(nearest.Latitude, nearest.Longitude), nil
+}
+
+// haversine returns the great circle distance in km between two coordinates.
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLon := (lon2 - lon1) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// validateGeo checks the Field, CoordinateColumn, and radius of a GeoFuzz or GeoSnap processor definition.
+func (procDef ProcessorDefinition) validateGeo() error {
+	if procDef.Name != "GeoFuzz" && procDef.Name != "GeoSnap" {
+		return nil
+	}
+	if len(procDef.Field) > 0 && procDef.Field != "latitude" && procDef.Field != "longitude" {
+		return fmt.Errorf("Expected Field latitude or longitude for processor %s, got %q", procDef.Name,
+			procDef.Field)
+	}
+	if len(procDef.CoordinateColumn) > 0 && len(procDef.Field) == 0 {
+		return fmt.Errorf("CoordinateColumn requires Field latitude or longitude for processor %s", procDef.Name)
+	}
+	if procDef.Name == "GeoSnap" && len(procDef.Field) > 0 && len(procDef.CoordinateColumn) == 0 {
+		return fmt.Errorf("Expected the CoordinateColumn holding the other coordinate for processor %s",
+			procDef.Name)
+	}
+	radius := procDef.Max
+	if radius == 0 {
+		radius = defaultGeoRadius
+	}
+	if procDef.Name == "GeoFuzz" && (procDef.Min < 0 || procDef.Max < 0 || procDef.Min > radius) {
+		return fmt.Errorf("Expected 0 <= Min <= Max (default %g km) for processor %s", defaultGeoRadius,
+			procDef.Name)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorGeoFuzz(t *testing.T) {
+	currentRow = newRowContext([]string{"lat", "lon"}, []string{"40.712800", "-74.006000"})
+	latMap := &ColumnMapper{Processors: []ProcessorDefinition{{Name: "GeoFuzz", Max: 5, Min: 1, Field: "latitude"}}}
+	lonMap := &ColumnMapper{Processors: []ProcessorDefinition{
+		{Name: "GeoFuzz", Max: 5, Min: 1, Field: "longitude", CoordinateColumn: "lat"},
+	}}
+
+	lat, err := ProcessorGeoFuzz(latMap, "40.712800")
+	require.Nil(t, err)
+	lon, err := ProcessorGeoFuzz(lonMap, "-74.006000")
+	require.Nil(t, err)
+	require.Regexp(t, `^-?\d+\.\d{6}$`, lat)
+	require.Regexp(t, `^-?\d+\.\d{6}$`, lon)
+
+	// The separate columns are moved together by Min to Max km
+	newLat, _, err := parseCoordinate(lat)
+	require.Nil(t, err)
+	newLon, _, err := parseCoordinate(lon)
+	require.Nil(t, err)
+	distance := haversine(40.7128, -74.006, newLat, newLon)
+	require.True(t, distance >= 0.99 && distance <= 5.01, distance)
+
+	// Points and PostGIS geometries
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{{Name: "GeoFuzz"}}}
+	output, err := ProcessorGeoFuzz(cmap, "(-74.0060,40.7128)")
+	require.Nil(t, err)
+	require.Regexp(t, `^\(-7\d\.\d{4},4\d\.\d{4}\)$`, output)
+
+	output, err = ProcessorGeoFuzz(cmap, "SRID=4326;POINT(-74.0060 40.7128)")
+	require.Nil(t, err)
+	require.Regexp(t, `^SRID=4326;POINT\(-7\d\.\d{4} 4\d\.\d{4}\)$`, output)
+
+	ewkb := "0101000020E6100000AAF1D24D628052C05E4BC8073D5B4440"
+	output, err = ProcessorGeoFuzz(cmap, ewkb)
+	require.Nil(t, err)
+	require.Len(t, output, len(ewkb))
+	require.Equal(t, ewkb[:18], output[:18])
+	value, err := parseEWKBPoint(output)
+	require.Nil(t, err)
+	require.True(t, haversine(40.7128, -74.006, value.lat, value.lon) <= 5.01)
+
+	_, err = ProcessorGeoFuzz(cmap, "LINESTRING(0 0, 1 1)")
+	require.NotNil(t, err)
+}
+
+func TestProcessorGeoSnap(t *testing.T) {
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{{Name: "GeoSnap"}}}
+	output, err := ProcessorGeoSnap(cmap, "POINT(-73.9857 40.7484)")
+	require.Nil(t, err)
+	require.Equal(t, "POINT(-74.0060 40.7128)", output)
+
+	currentRow = newRowContext([]string{"lat", "lon"}, []string{"51.5033", "-0.1196"})
+	cmap.Processors[0] = ProcessorDefinition{Name: "GeoSnap", Field: "longitude", CoordinateColumn: "lat"}
+	output, err = ProcessorGeoSnap(cmap, "-0.1196")
+	require.Nil(t, err)
+	require.Equal(t, "-0.1278", output)
+}
+
+func TestValidateGeo(t *testing.T) {
+	require.Nil(t, ProcessorDefinition{Name: "GeoFuzz", Max: 10, Min: 2}.validateGeo())
+	require.Nil(t, ProcessorDefinition{Name: "GeoSnap", Field: "latitude", CoordinateColumn: "lon"}.validateGeo())
+	require.NotNil(t, ProcessorDefinition{Name: "GeoFuzz", Min: 10}.validateGeo())
+	require.NotNil(t, ProcessorDefinition{Name: "GeoFuzz", Field: "altitude"}.validateGeo())
+	require.NotNil(t, ProcessorDefinition{Name: "GeoSnap", Field: "latitude"}.validateGeo())
+	require.NotNil(t, ProcessorDefinition{Name: "GeoFuzz", CoordinateColumn: "lon"}.validateGeo())
+}
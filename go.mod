@@ -1,4 +1,4 @@
-module github.com/smithoss/gonymizer
+module github.com/smithoss/gonymizer/v2
 
 go 1.13
 
@@ -8,15 +8,9 @@ require (
 	github.com/google/uuid v1.1.1
 	github.com/icrowley/fake v0.0.0-20180203215853-4178557ae428
 	github.com/lib/pq v1.1.1
-	github.com/logrusorgru/aurora v0.0.0-20190428105938-cea283e61946
-	github.com/mattn/go-colorable v0.1.2 // indirect
-	github.com/mgutz/ansi v0.0.0-20170206155736-9520e82c474b // indirect
-	github.com/onsi/ginkgo v1.10.1 // indirect
-	github.com/onsi/gomega v1.7.0 // indirect
 	github.com/sirupsen/logrus v1.4.1
-	github.com/spf13/cobra v0.0.5
 	github.com/spf13/viper v1.4.0
 	github.com/stretchr/testify v1.3.0
-	github.com/x-cray/logrus-prefixed-formatter v0.5.2
+	go.etcd.io/bbolt v1.3.6
 	golang.org/x/crypto v0.0.0-20190506204251-e1dfcc566284
 )
@@ -0,0 +1,54 @@
+package gonymizer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// formatWildcard is the key of the Formats of a processor definition used for rows without a format of their own.
+const formatWildcard = "*"
+
+// formatTemplate returns the format template (see randomFormat) for the current row: the template of Formats keyed by
+// the value of FormatColumn (matched case-insensitively, e.g. a country or state code), or the template keyed by *.
+// False is returned if the processor definition has no template for the row.
+func (procDef ProcessorDefinition) formatTemplate() (string, bool) {
+	if len(procDef.FormatColumn) > 0 {
+		if key, ok := currentRow.value(procDef.FormatColumn); ok {
+			for name, template := range procDef.Formats {
+				if strings.EqualFold(name, strings.TrimSpace(key)) {
+					return template, true
+				}
+			}
+		}
+	}
+	template, ok := procDef.Formats[formatWildcard]
+	return template, ok
+}
+
+// fakeGovernmentID returns a fake identifier (passport, driver's license, or national ID number) in the format template
+// of the processor definition for the row (see formatTemplate). Without a template the format of the input is kept
+// (letters are replaced with random upper case letters and digits with random digits), and empty inputs get a random
+// identifier in the format fallback.
+func fakeGovernmentID(procDef ProcessorDefinition, input, fallback string) string {
+	if template, ok := procDef.formatTemplate(); ok {
+		return randomFormat(template)
+	}
+	if len(input) == 0 {
+		return randomFormat(fallback)
+	}
+	return strings.ToUpper(scrambleString(input))
+}
+
+// validateFormats checks that the format templates of a processor definition are not empty and that FormatColumn is
+// only set with Formats.
+func (procDef ProcessorDefinition) validateFormats() error {
+	if len(procDef.FormatColumn) > 0 && len(procDef.Formats) == 0 {
+		return fmt.Errorf("FormatColumn requires Formats for processor %s", procDef.Name)
+	}
+	for name, template := range procDef.Formats {
+		if len(template) == 0 {
+			return fmt.Errorf("Empty format %q for processor %s", name, procDef.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandomFormat(t *testing.T) {
+	require.Regexp(t, `^A-\d{3}-[A-Z0-9][A-Z]$`, randomFormat("\\A-999-XA"))
+	require.Equal(t, "", randomFormat(""))
+}
+
+func TestProcessorDriversLicense(t *testing.T) {
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{{
+		Name:         "FakeDriversLicense",
+		FormatColumn: "state",
+		Formats:      map[string]string{"CA": "A9999999", "NY": "999 999 999", "*": "\\D99999999"},
+	}}}
+
+	currentRow = newRowContext([]string{"license", "state"}, []string{"B1234567", "ca"})
+	output, err := ProcessorDriversLicense(cmap, "B1234567")
+	require.Nil(t, err)
+	require.Regexp(t, `^[A-Z]\d{7}$`, output)
+
+	currentRow = newRowContext([]string{"license", "state"}, []string{"123 456 789", "NY"})
+	output, err = ProcessorDriversLicense(cmap, "123 456 789")
+	require.Nil(t, err)
+	require.Regexp(t, `^\d{3} \d{3} \d{3}$`, output)
+
+	currentRow = newRowContext([]string{"license", "state"}, []string{"X", "\\N"})
+	output, err = ProcessorDriversLicense(cmap, "X")
+	require.Nil(t, err)
+	require.Regexp(t, `^D\d{8}$`, output)
+
+	// Without a template the format of the input is kept
+	output, err = ProcessorDriversLicense(&cMap, "wdl-1234")
+	require.Nil(t, err)
+	require.Regexp(t, `^[A-Z]{3}-\d{4}$`, output)
+
+	output, err = ProcessorDriversLicense(&cMap, "")
+	require.Nil(t, err)
+	require.Regexp(t, `^[A-Z]\d{7}$`, output)
+}
+
+func TestValidateFormats(t *testing.T) {
+	require.Nil(t, ProcessorDefinition{Formats: map[string]string{"*": "A9"}}.validateFormats())
+	require.NotNil(t, ProcessorDefinition{FormatColumn: "state"}.validateFormats())
+	require.NotNil(t, ProcessorDefinition{Formats: map[string]string{"CA": ""}}.validateFormats())
+}
@@ -0,0 +1,56 @@
+package gonymizer
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	// hipaaMaxAge is the oldest age HIPAA Safe Harbor (45 CFR 164.514(b)(2)(i)(C)) allows to be disclosed. Older ages
+	// are aggregated into a single category of 90 or older.
+	hipaaMaxAge = 89
+
+	// hipaaAgeBucket replaces ages over hipaaMaxAge in character columns.
+	hipaaAgeBucket = "90+"
+)
+
+// hipaaNow returns the date ages are computed at.
+var hipaaNow = time.Now
+
+// capAge returns the age, or the 90 or older category if it is over hipaaMaxAge.
+func capAge(cmap *ColumnMapper, age int) string {
+	if age <= hipaaMaxAge {
+		return strconv.Itoa(age)
+	}
+	if isCharacterType(cmap.DataType) {
+		return hipaaAgeBucket
+	}
+	return strconv.Itoa(hipaaMaxAge + 1)
+}
+
+// ageAt returns the age in whole years of a person born on birth at the date now.
+func ageAt(birth, now time.Time) int {
+	age := now.Year() - birth.Year()
+	if now.Month() < birth.Month() || (now.Month() == birth.Month() && now.Day() < birth.Day()) {
+		age--
+	}
+	return age
+}
+
+// isNumericType returns true if the PostgreSQL data type (as found in information_schema.columns) holds numbers.
+func isNumericType(dataType string) bool {
+	switch dataType {
+	case "smallint", "integer", "bigint", "numeric", "real", "double precision":
+		return true
+	}
+	return false
+}
+
+// isCharacterType returns true if the PostgreSQL data type (as found in information_schema.columns) holds text.
+func isCharacterType(dataType string) bool {
+	switch dataType {
+	case "text", "character varying", "character", "varchar", "char":
+		return true
+	}
+	return false
+}
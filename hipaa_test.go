@@ -0,0 +1,50 @@
+package gonymizer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorHIPAAAge(t *testing.T) {
+	defer func() { hipaaNow = time.Now }()
+	hipaaNow = func() time.Time { return time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC) }
+
+	ages := &ColumnMapper{DataType: "integer"}
+	text := &ColumnMapper{DataType: "text"}
+	dates := &ColumnMapper{DataType: "date"}
+
+	for _, tst := range []struct {
+		cmap          *ColumnMapper
+		input, output string
+	}{
+		{ages, "42", "42"},
+		{ages, "89", "89"},
+		{ages, "90", "90"},
+		{ages, "104", "90"},
+		{text, "104", "90+"},
+		{dates, "1980-03-01", "1980-03-01"},
+		{dates, "1936-06-16", "1936-06-16"}, // 89 until tomorrow
+		{dates, "1930-02-11", "1936-02-11"},
+		{dates, "1920-06-16 08:30:00+02", "1935-06-16 08:30:00+02"},
+		{dates, "1916-02-29", "1936-02-29"},
+		{dates, "1915-02-28", "1936-02-28"},
+		{ages, "1930-02-11", "90"},
+		{text, "1930-02-11", "90+"},
+		{text, "1980-07-01", "45"},
+	} {
+		output, err := ProcessorHIPAAAge(tst.cmap, tst.input)
+		require.Nil(t, err)
+		require.Equal(t, tst.output, output, tst.input)
+	}
+
+	// 29 February in a year that is not a leap year
+	hipaaNow = func() time.Time { return time.Date(2027, 6, 15, 12, 0, 0, 0, time.UTC) }
+	output, err := ProcessorHIPAAAge(dates, "1916-02-29")
+	require.Nil(t, err)
+	require.Equal(t, "1937-02-28", output)
+
+	_, err = ProcessorHIPAAAge(ages, "ninety")
+	require.NotNil(t, err)
+}
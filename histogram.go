@@ -0,0 +1,169 @@
+package gonymizer
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxLengthAttempts is the number of times a processor is run again to produce a value of the sampled length before
+// the closest value is truncated or padded to it (see sampledLength).
+const maxLengthAttempts = 10
+
+// lengthHistograms contains the value length distribution of every column with a LengthHistogram processor
+// definition, keyed by the column of the map file (see histogramKey). It is collected by ProcessDumpFileWithOptions
+// before processing.
+var lengthHistograms map[string]*lengthHistogram
+
+// lengthHistogram counts the values of a column by their length in characters.
+type lengthHistogram struct {
+	counts  map[int]int64
+	lengths []int
+	total   int64
+}
+
+// newLengthHistogram returns an empty length histogram.
+func newLengthHistogram() *lengthHistogram {
+	return &lengthHistogram{counts: map[int]int64{}}
+}
+
+// add counts a value of the given length.
+func (h *lengthHistogram) add(length int) {
+	if h.counts[length] == 0 {
+		h.lengths = append(h.lengths, length)
+		sort.Ints(h.lengths)
+	}
+	h.counts[length]++
+	h.total++
+}
+
+// sample returns a length drawn from the histogram. The draw is derived from the input value, so the same input always
+// gets the same length and consistently mapped columns stay consistent.
+func (h *lengthHistogram) sample(input string) int {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(input))
+	n := int64(hash.Sum64() % uint64(h.total))
+
+	for _, length := range h.lengths {
+		if n < h.counts[length] {
+			return length
+		}
+		n -= h.counts[length]
+	}
+	return h.lengths[len(h.lengths)-1]
+}
+
+// histogramKey returns the key of the column in lengthHistograms.
+func (cmap *ColumnMapper) histogramKey() string {
+	return fmt.Sprintf("%s.%s.%s", cmap.TableSchema, cmap.TableName, cmap.ColumnName)
+}
+
+// usesLengthHistogram returns true if any processor definition of the column has LengthHistogram set.
+func (cmap *ColumnMapper) usesLengthHistogram() bool {
+	for _, procDef := range cmap.Processors {
+		if procDef.LengthHistogram {
+			return true
+		}
+	}
+	return false
+}
+
+// collectLengthHistograms reads the dump file found at path and returns the value length histogram of every column
+// that uses a LengthHistogram processor definition. NULL values are not counted. Returns nil when no column uses one.
+func collectLengthHistograms(mapper *DBMapper, path string) (map[string]*lengthHistogram, error) {
+	var columns int
+	for i := range mapper.ColumnMaps {
+		if mapper.ColumnMaps[i].usesLengthHistogram() {
+			columns++
+		}
+	}
+	if columns == 0 {
+		return nil, nil
+	}
+
+	log.Infof("Collecting value length histograms of %d column(s) from: %s", columns, path)
+	histograms := map[string]*lengthHistogram{}
+	err := forEachDumpRow(path, func(state *LineState, values []string) error {
+		if len(state.ColumnMaps) != len(state.ColumnNames) {
+			state.mapColumns(mapper)
+		}
+		for i, cmap := range state.ColumnMaps {
+			if cmap == nil || values[i] == "\\N" || !cmap.usesLengthHistogram() {
+				continue
+			}
+			key := cmap.histogramKey()
+			if histograms[key] == nil {
+				histograms[key] = newLengthHistogram()
+			}
+			histograms[key].add(utf8.RuneCountInString(values[i]))
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error("Unable to collect value length histograms: ", err)
+		return nil, err
+	}
+	for key, histogram := range histograms {
+		log.Debugf("Length histogram of %s: %d values, %d distinct lengths", key, histogram.total,
+			len(histogram.lengths))
+	}
+	return histograms, nil
+}
+
+// sampledLength returns a value of the processor with a length sampled from the length histogram of the column. The
+// processor is run again (up to maxLengthAttempts times) until it returns a value of that length, otherwise the value
+// closest in length is truncated or padded (see fitLength). The output is returned unchanged when the column has no
+// histogram.
+func sampledLength(cmap *ColumnMapper, procDef ProcessorDefinition, pfunc ProcessorFunc, input, output string) (string,
+	error) {
+	histogram := lengthHistograms[cmap.histogramKey()]
+	if histogram == nil || histogram.total == 0 {
+		return output, nil
+	}
+
+	length := histogram.sample(input)
+	for attempt := 0; attempt < maxLengthAttempts && utf8.RuneCountInString(output) != length; attempt++ {
+		next, err := runCachedProcessor(cmap, procDef, pfunc, input)
+		if err != nil {
+			return "", err
+		}
+		if next == output {
+			// The processor is deterministic for this input
+			break
+		}
+		if lengthDistance(next, length) < lengthDistance(output, length) {
+			output = next
+		}
+	}
+	return fitLength(output, length), nil
+}
+
+// lengthDistance returns how many characters the value is away from the length.
+func lengthDistance(value string, length int) int {
+	distance := utf8.RuneCountInString(value) - length
+	if distance < 0 {
+		return -distance
+	}
+	return distance
+}
+
+// fitLength truncates the value to length characters or pads it by repeating its own characters. Empty values are
+// returned as they are.
+func fitLength(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) == 0 || len(runes) == length {
+		return value
+	}
+	if len(runes) > length {
+		return string(runes[:length])
+	}
+
+	padded := make([]rune, length)
+	for i := range padded {
+		padded[i] = runes[i%len(runes)]
+	}
+	return string(padded)
+}
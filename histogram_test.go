@@ -0,0 +1,82 @@
+package gonymizer
+
+import (
+	"os"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLengthHistogram(t *testing.T) {
+	histogram := newLengthHistogram()
+	for _, length := range []int{5, 5, 5, 12, 3} {
+		histogram.add(length)
+	}
+	require.Equal(t, []int{3, 5, 12}, histogram.lengths)
+	require.Equal(t, int64(5), histogram.total)
+
+	counts := map[int]int{}
+	for _, input := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p"} {
+		length := histogram.sample(input)
+		require.Contains(t, histogram.lengths, length)
+		require.Equal(t, length, histogram.sample(input))
+		counts[length]++
+	}
+	require.True(t, counts[5] > counts[3])
+}
+
+func TestCollectLengthHistograms(t *testing.T) {
+	mapper := &DBMapper{
+		DBName: "pii_localtest",
+		ColumnMaps: []ColumnMapper{
+			{
+				TableSchema: "public",
+				TableName:   "users",
+				ColumnName:  "first_name",
+				Processors:  []ProcessorDefinition{{Name: "FakeFirstName", LengthHistogram: true}},
+			},
+			{
+				TableSchema: "public",
+				TableName:   "users",
+				ColumnName:  "last_name",
+				Processors:  []ProcessorDefinition{{Name: "FakeLastName"}},
+			},
+		},
+	}
+
+	histograms, err := collectLengthHistograms(&DBMapper{ColumnMaps: mapper.ColumnMaps[1:]}, "does-not-exist.sql")
+	require.Nil(t, err)
+	require.Nil(t, histograms)
+
+	dumpFile := writeTestAuditFile(t, "COPY public.users (id, first_name, last_name) FROM stdin;\n"+
+		"1\tAnn\tSmith\n"+
+		"2\tBartholomew\tJones\n"+
+		"3\t\\N\tBrown\n"+
+		"4\tJosé\tGarcía\n\\.\n")
+	defer os.Remove(dumpFile)
+
+	histograms, err = collectLengthHistograms(mapper, dumpFile)
+	require.Nil(t, err)
+	require.Len(t, histograms, 1)
+	histogram := histograms["public.users.first_name"]
+	require.Equal(t, int64(3), histogram.total)
+	require.Equal(t, []int{3, 4, 11}, histogram.lengths)
+
+	lengthHistograms = histograms
+	defer func() { lengthHistograms = nil }()
+	cmap := mapper.ColumnMapper("public", "users", "first_name")
+	for _, input := range []string{"Ann", "Bartholomew", "José", "Zoe"} {
+		output, err := processValue(cmap, input)
+		require.Nil(t, err)
+		require.Contains(t, histogram.lengths, utf8.RuneCountInString(output))
+	}
+}
+
+func TestFitLength(t *testing.T) {
+	require.Equal(t, "Smi", fitLength("Smith", 3))
+	require.Equal(t, "SmithSmi", fitLength("Smith", 8))
+	require.Equal(t, "Jos", fitLength("José", 3))
+	require.Equal(t, "", fitLength("", 4))
+	require.Equal(t, 2, lengthDistance("José", 6))
+}
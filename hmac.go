@@ -0,0 +1,160 @@
+package gonymizer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// HMACKeyEnv is the environment variable the CLI reads the secret key of the HMACScrambler processor from.
+const HMACKeyEnv = "GONYMIZER_HMAC_KEY"
+
+// minHMACKeyLength is the minimum length in bytes of the secret key of the HMACScrambler processor.
+const minHMACKeyLength = 16
+
+// hmacKey is the secret key used by the HMACScrambler and EmailDomainPreserving processors. It is set from
+// ProcessOptions.HMACKey for every call to ProcessDumpFileWithOptions.
+var hmacKey []byte
+
+// validateHMACKey checks that key is long enough to keep the output of the HMACScrambler processor from being guessed.
+func validateHMACKey(key []byte) error {
+	if len(key) < minHMACKeyLength {
+		return fmt.Errorf("Expected an HMAC key of at least %d bytes, got %d", minHMACKeyLength, len(key))
+	}
+	return nil
+}
+
+// hmacScramble replaces the letters and digits of input like scrambleString, but uses HMAC-SHA256 of the input with
+// key as the source of randomness. The same input is always scrambled to the same output for the same key, across
+// columns and runs.
+func hmacScramble(key []byte, input string) string {
+	output := make([]byte, len(input))
+
+	// Every 32 byte block of random values is the HMAC of the block number and the input
+	var (
+		block   [32]byte
+		counter uint32
+		offset  = len(block)
+	)
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		set := scrambleSets[scrambleClasses[c]]
+		if len(set) == 0 {
+			output[i] = c
+			continue
+		}
+		if offset == len(block) {
+			mac := hmac.New(sha256.New, key)
+			_ = binary.Write(mac, binary.BigEndian, counter)
+			mac.Write([]byte(input))
+			copy(block[:], mac.Sum(nil))
+			counter++
+			offset = 0
+		}
+		// Map the 16-bit value onto the set (multiply and shift instead of modulo)
+		random := uint32(binary.BigEndian.Uint16(block[offset:]))
+		output[i] = set[random*uint32(len(set))>>16]
+		offset += 2
+	}
+
+	return string(output)
+}
+
+// defaultHashTokenLength is the length of the tokens of the HashToken processor when Length is not set.
+const defaultHashTokenLength = 16
+
+// base62Alphabet is the alphabet of base62 encoded hash tokens.
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// hashTokenEncodings are the encodings of the HashToken processor and the length of an encoded SHA-256 hash.
+var hashTokenEncodings = map[string]int{
+	"hex":    64,
+	"base32": 52,
+	"base62": 43,
+}
+
+// hashToken returns the SHA-256 hash of the Salt and the input, encoded with Encoding (default hex) and truncated to
+// Length characters (default defaultHashTokenLength). If ProcessOptions.HMACKey is set the hash is an HMAC-SHA256
+// keyed with it, so tokens can not be recomputed from a guessed input without the key.
+//
+// Example:
+// "17e4b67b77871550" = hashToken(ProcessorDefinition{Salt: "users"}, "jane@example.com")
+func hashToken(procDef ProcessorDefinition, input string) (string, error) {
+	if len(procDef.Salt) == 0 && len(hmacKey) == 0 {
+		return "", errors.New("HashToken requires a Salt or a secret key, see $" + HMACKeyEnv)
+	}
+
+	// The zero byte separates the salt from the input, so salt "ab" and input "c" differ from salt "a" and input "bc"
+	message := []byte(procDef.Salt + "\x00" + input)
+	var sum []byte
+	if len(hmacKey) > 0 {
+		mac := hmac.New(sha256.New, hmacKey)
+		mac.Write(message)
+		sum = mac.Sum(nil)
+	} else {
+		hash := sha256.Sum256(message)
+		sum = hash[:]
+	}
+
+	var token string
+	switch procDef.Encoding {
+	case "", "hex":
+		token = hex.EncodeToString(sum)
+	case "base32":
+		token = strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(sum))
+	case "base62":
+		token = base62Encode(sum, hashTokenEncodings["base62"])
+	default:
+		return "", fmt.Errorf("Unknown HashToken encoding %q", procDef.Encoding)
+	}
+
+	length := procDef.Length
+	if length == 0 {
+		length = defaultHashTokenLength
+	}
+	if length > len(token) {
+		length = len(token)
+	}
+	return token[:length], nil
+}
+
+// base62Encode encodes data as a base62 number padded with leading zeros to length characters.
+func base62Encode(data []byte, length int) string {
+	number := new(big.Int).SetBytes(data)
+	base := big.NewInt(int64(len(base62Alphabet)))
+	digit := new(big.Int)
+
+	output := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		number.DivMod(number, base, digit)
+		output[i] = base62Alphabet[digit.Int64()]
+	}
+	return string(output)
+}
+
+// validateHashToken checks the Encoding and Length of a HashToken processor definition.
+func (procDef ProcessorDefinition) validateHashToken() error {
+	if procDef.Name != "HashToken" {
+		return nil
+	}
+	encoding := procDef.Encoding
+	if len(encoding) == 0 {
+		encoding = "hex"
+	}
+	maxLength, ok := hashTokenEncodings[encoding]
+	if !ok {
+		return fmt.Errorf("Expected Encoding hex, base32, or base62 for processor %s, got %q", procDef.Name,
+			procDef.Encoding)
+	}
+	if procDef.Length < 0 || procDef.Length > maxLength {
+		return fmt.Errorf("Expected a Length of at most %d for %s encoded tokens, got %d", maxLength, encoding,
+			procDef.Length)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorHMACScrambler(t *testing.T) {
+	defer func() { hmacKey = nil }()
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{{Name: "HMACScrambler"}}}
+
+	hmacKey = nil
+	_, err := ProcessorHMACScrambler(cmap, "ABC-1a2bC")
+	require.NotNil(t, err)
+
+	hmacKey = []byte("0123456789abcdef")
+	output, err := ProcessorHMACScrambler(cmap, "ABC-1a2bC")
+	require.Nil(t, err)
+	require.Regexp(t, "^[A-Z]{3}-[0-9][a-z][0-9][a-z][A-Z]$", output)
+	require.NotEqual(t, "ABC-1a2bC", output)
+
+	// The output only depends on the key and the input
+	other := &ColumnMapper{ColumnName: "other"}
+	again, err := ProcessorHMACScrambler(other, "ABC-1a2bC")
+	require.Nil(t, err)
+	require.Equal(t, output, again)
+
+	hmacKey = []byte("fedcba9876543210")
+	again, err = ProcessorHMACScrambler(cmap, "ABC-1a2bC")
+	require.Nil(t, err)
+	require.NotEqual(t, output, again)
+
+	// Values longer than a single HMAC block
+	long := "abcdefghijklmnopqrstuvwxyz0123456789"
+	output = hmacScramble(hmacKey, long)
+	require.Regexp(t, "^[a-z]{26}[0-9]{10}$", output)
+	require.Equal(t, output, hmacScramble(hmacKey, long))
+}
+
+func TestValidateHMACKey(t *testing.T) {
+	require.NotNil(t, validateHMACKey(nil))
+	require.NotNil(t, validateHMACKey([]byte("short")))
+	require.Nil(t, validateHMACKey([]byte("0123456789abcdef")))
+}
+
+func TestProcessorHashToken(t *testing.T) {
+	defer func() { hmacKey = nil }()
+	hmacKey = nil
+
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{{Name: "HashToken"}}}
+	_, err := ProcessorHashToken(cmap, "jane@example.com")
+	require.NotNil(t, err)
+
+	cmap.Processors[0].Salt = "users"
+	output, err := ProcessorHashToken(cmap, "jane@example.com")
+	require.Nil(t, err)
+	require.Equal(t, "17e4b67b77871550", output)
+
+	// Other columns with the same salt get the same token, other salts do not
+	other := &ColumnMapper{ColumnName: "other", Processors: []ProcessorDefinition{{Name: "HashToken", Salt: "users"}}}
+	again, err := ProcessorHashToken(other, "jane@example.com")
+	require.Nil(t, err)
+	require.Equal(t, output, again)
+	other.Processors[0].Salt = "orders"
+	again, err = ProcessorHashToken(other, "jane@example.com")
+	require.Nil(t, err)
+	require.NotEqual(t, output, again)
+
+	hmacKey = []byte("0123456789abcdef")
+	again, err = ProcessorHashToken(cmap, "jane@example.com")
+	require.Nil(t, err)
+	require.NotEqual(t, output, again)
+
+	for encoding, pattern := range map[string]string{
+		"hex":    "^[0-9a-f]{64}$",
+		"base32": "^[a-z2-7]{52}$",
+		"base62": "^[0-9A-Za-z]{43}$",
+	} {
+		cmap.Processors[0].Encoding = encoding
+		cmap.Processors[0].Length = 100
+		output, err = ProcessorHashToken(cmap, "jane@example.com")
+		require.Nil(t, err)
+		require.Regexp(t, pattern, output)
+
+		cmap.Processors[0].Length = 10
+		short, err := ProcessorHashToken(cmap, "jane@example.com")
+		require.Nil(t, err)
+		require.Equal(t, output[:10], short)
+	}
+}
+
+func TestValidateHashToken(t *testing.T) {
+	require.Nil(t, ProcessorDefinition{Name: "HashToken"}.validateHashToken())
+	require.Nil(t, ProcessorDefinition{Name: "HashToken", Encoding: "base62", Length: 43}.validateHashToken())
+	require.NotNil(t, ProcessorDefinition{Name: "HashToken", Encoding: "base64"}.validateHashToken())
+	require.NotNil(t, ProcessorDefinition{Name: "HashToken", Encoding: "base62", Length: 44}.validateHashToken())
+	require.NotNil(t, ProcessorDefinition{Name: "HashToken", Length: -1}.validateHashToken())
+	require.Nil(t, ProcessorDefinition{Name: "FakeEmailAddress", Encoding: "base64"}.validateHashToken())
+}
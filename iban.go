@@ -0,0 +1,149 @@
+package gonymizer
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ibanFormats are the formats of the BBAN (the part of an IBAN after the country code and check digits) used to create
+// IBANs for inputs that are not IBANs. Every 9 is replaced by a random digit, every A by a random upper case
+// letter, and every X by a random upper case letter or digit.
+var ibanFormats = map[string]string{
+	"AT": "9999999999999999",
+	"BE": "999999999999",
+	"CH": "99999XXXXXXXXXXXX",
+	"DE": "999999999999999999",
+	"DK": "99999999999999",
+	"ES": "99999999999999999999",
+	"FI": "99999999999999",
+	"FR": "9999999999XXXXXXXXXXX99",
+	"GB": "AAAA99999999999999",
+	"IE": "AAAA99999999999999",
+	"IT": "A9999999999XXXXXXXXXXXX",
+	"LU": "999XXXXXXXXXXXXX",
+	"NL": "AAAA9999999999",
+	"NO": "99999999999",
+	"PL": "999999999999999999999999",
+	"PT": "999999999999999999999",
+	"SE": "99999999999999999999",
+}
+
+// ibanCountries returns the sorted country codes of ibanFormats.
+func ibanCountries() []string {
+	countries := make([]string, 0, len(ibanFormats))
+	for country := range ibanFormats {
+		countries = append(countries, country)
+	}
+	sort.Strings(countries)
+	return countries
+}
+
+// fakeIBAN returns a fake IBAN with valid check digits. The country code of the input is kept and the BBAN is
+// scrambled in its format, keeping the first prefixLength characters (the bank code) of the BBAN. Inputs that do not
+// start with a country code and check digits get an IBAN of a random country in ibanFormats. If the input is grouped in
+// blocks of four characters the output is as well. National check digits within the BBAN are not recalculated.
+//
+// Example:
+// "DE44 5001 0517 5407 3249 31" = fakeIBAN("DE89 3704 0044 0532 0130 00", 0)
+func fakeIBAN(input string, prefixLength int) string {
+	iban := strings.ToUpper(strings.Replace(strings.TrimSpace(input), " ", "", -1))
+
+	var country, bban string
+	if len(iban) >= 15 && isUpperAlpha(iban[0]) && isUpperAlpha(iban[1]) && len(digitsOnly(iban[2:4])) == 2 {
+		country = iban[:2]
+		if prefixLength > len(iban)-4 {
+			prefixLength = len(iban) - 4
+		}
+		bban = iban[4:4+prefixLength] + scrambleString(iban[4+prefixLength:])
+	} else {
+		countries := ibanCountries()
+		country = countries[rng.Intn(len(countries))]
+		bban = randomFormat(ibanFormats[country])
+	}
+
+	output := country + ibanCheckDigits(country, bban) + bban
+	if !strings.Contains(strings.TrimSpace(input), " ") {
+		return output
+	}
+	var blocks []string
+	for i := 0; i < len(output); i += 4 {
+		end := i + 4
+		if end > len(output) {
+			end = len(output)
+		}
+		blocks = append(blocks, output[i:end])
+	}
+	return strings.Join(blocks, " ")
+}
+
+// ibanCheckDigits returns the two ISO 13616 check digits of the IBAN with the country code and BBAN.
+func ibanCheckDigits(country, bban string) string {
+	// Move the country code and 00 check digits to the end and replace letters by numbers (A = 10, ..., Z = 35)
+	remainder := 0
+	for _, c := range bban + country + "00" {
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			remainder = (remainder*100 + int(c-'A'+10)) % 97
+		}
+	}
+	check := strconv.Itoa(98 - remainder)
+	if len(check) == 1 {
+		check = "0" + check
+	}
+	return check
+}
+
+// fakeBIC returns a fake BIC (SWIFT code) with a random bank and location code. The country code and length (8 or 11
+// characters) of the input are kept, as is the XXX branch code of a primary office. Inputs that are not a BIC get an 8
+// character BIC of a random country in ibanFormats.
+//
+// Example:
+// "QWPLDEFFXXX" = fakeBIC("DEUTDEFFXXX")
+func fakeBIC(input string) string {
+	bic := strings.ToUpper(strings.TrimSpace(input))
+	if (len(bic) != 8 && len(bic) != 11) || !isUpperAlpha(bic[4]) || !isUpperAlpha(bic[5]) {
+		countries := ibanCountries()
+		bic = "AAAA" + countries[rng.Intn(len(countries))] + "AA"
+	}
+
+	// The second character of the location code is a letter, a 0 marks test BICs
+	output := randomFormat("AAAA") + bic[4:6] + randomFormat("XA")
+	if len(bic) == 11 {
+		if bic[8:] == "XXX" {
+			output += "XXX"
+		} else {
+			output += randomFormat("XXX")
+		}
+	}
+	return output
+}
+
+// randomFormat replaces every 9 of the format with a random digit, every A with a random upper case letter, and every X
+// with a random upper case letter or digit. All other characters are kept, a backslash keeps the next character
+// (\A is a literal A).
+//
+// Example:
+// "A-482-K7" = randomFormat("\\A-999-AX")
+func randomFormat(format string) string {
+	output := make([]byte, 0, len(format))
+	for i := 0; i < len(format); i++ {
+		switch c := format[i]; {
+		case c == '\\' && i+1 < len(format):
+			i++
+			output = append(output, format[i])
+		case c == '9':
+			output = append(output, numericSet[rng.Intn(numericSetLen)])
+		case c == 'A':
+			output = append(output, uppercaseSet[rng.Intn(uppercaseSetLen)])
+		case c == 'X':
+			set := uppercaseSet + numericSet
+			output = append(output, set[rng.Intn(len(set))])
+		default:
+			output = append(output, c)
+		}
+	}
+	return string(output)
+}
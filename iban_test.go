@@ -0,0 +1,52 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeIBAN(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		output := fakeIBAN("DE89 3704 0044 0532 0130 00", 0)
+		require.Regexp(t, `^DE\d{2}( \d{4}){4} \d{2}$`, output)
+		require.True(t, validIBAN(output), output)
+
+		output = fakeIBAN("GB29NWBK60161331926819", 4)
+		require.Regexp(t, `^GB\d{2}NWBK\d{14}$`, output)
+		require.True(t, validIBAN(output), output)
+
+		output = fakeIBAN("not an iban", 0)
+		require.True(t, validIBAN(output), output)
+	}
+	require.Equal(t, "89", ibanCheckDigits("DE", "370400440532013000"))
+	require.Equal(t, "29", ibanCheckDigits("GB", "NWBK60161331926819"))
+}
+
+func TestFakeBIC(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		require.Regexp(t, `^[A-Z]{4}DE[A-Z0-9][A-Z]XXX$`, fakeBIC("DEUTDEFFXXX"))
+		require.Regexp(t, `^[A-Z]{4}FR[A-Z0-9][A-Z]$`, fakeBIC("BNPAFRPP"))
+		require.Regexp(t, `^[A-Z]{4}GB[A-Z0-9][A-Z][A-Z0-9]{3}$`, fakeBIC("NWBKGB2L123"))
+		require.Regexp(t, `^[A-Z]{6}[A-Z0-9]{2}$`, fakeBIC(""))
+	}
+}
+
+func TestProcessorIBAN(t *testing.T) {
+	cmap := &ColumnMapper{
+		Processors:   []ProcessorDefinition{{Name: "FakeIBAN", PrefixLength: 8}},
+		ParentSchema: "public", ParentTable: "accounts", ParentColumn: "iban",
+	}
+	output, err := ProcessorIBAN(cmap, "DE89370400440532013000")
+	require.Nil(t, err)
+	require.Regexp(t, `^DE\d{2}37040044\d{10}$`, output)
+	require.True(t, validIBAN(output))
+
+	again, err := ProcessorIBAN(cmap, "DE89370400440532013000")
+	require.Nil(t, err)
+	require.Equal(t, output, again)
+
+	output, err = ProcessorBIC(&cMap, "DEUTDEFF")
+	require.Nil(t, err)
+	require.Regexp(t, `^[A-Z]{4}DE[A-Z0-9][A-Z]$`, output)
+}
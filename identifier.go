@@ -0,0 +1,54 @@
+package gonymizer
+
+import "strings"
+
+// quoteIdentifier returns name as a quoted PostgreSQL identifier ("My Table"), so mixed-case names, names with spaces
+// or dots, reserved words, and non-ASCII names can be used in SQL statements.
+func quoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// unquoteIdentifier returns the name of a (possibly) quoted PostgreSQL identifier: "Order ""Items""" becomes
+// Order "Items". Unquoted identifiers are returned as-is.
+func unquoteIdentifier(identifier string) string {
+	if len(identifier) < 2 || identifier[0] != '"' || identifier[len(identifier)-1] != '"' {
+		return identifier
+	}
+	return strings.Replace(identifier[1:len(identifier)-1], `""`, `"`, -1)
+}
+
+// splitIdentifiers splits a list of (possibly quoted) identifiers on sep, ignoring separators inside quotes, and
+// returns the unquoted names. For example `public."odd.name"` split on '.' returns public and odd.name.
+func splitIdentifiers(list string, sep byte) []string {
+	var (
+		names    []string
+		inQuotes bool
+		start    int
+	)
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '"':
+			// An escaped quote ("") toggles twice
+			inQuotes = !inQuotes
+		case sep:
+			if !inQuotes {
+				names = append(names, unquoteIdentifier(strings.TrimSpace(list[start:i])))
+				start = i + 1
+			}
+		}
+	}
+	return append(names, unquoteIdentifier(strings.TrimSpace(list[start:])))
+}
+
+// indexOutsideQuotes returns the index of the first of chars in s that is not inside a quoted identifier, or -1.
+func indexOutsideQuotes(s string, chars string) int {
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' {
+			inQuotes = !inQuotes
+		} else if !inQuotes && strings.IndexByte(chars, s[i]) >= 0 {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,63 @@
+package gonymizer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	require.Equal(t, `"UserAccounts"`, quoteIdentifier("UserAccounts"))
+	require.Equal(t, `"Order ""Items"""`, quoteIdentifier(`Order "Items"`))
+
+	for _, name := range []string{"users", "UserAccounts", "odd.name", "with space", `Order "Items"`, "Kundenüberblick"} {
+		require.Equal(t, name, unquoteIdentifier(quoteIdentifier(name)))
+	}
+	require.Equal(t, "users", unquoteIdentifier("users"))
+	require.Equal(t, `"`, unquoteIdentifier(`"`))
+}
+
+func TestParseCopyLineIdentifiers(t *testing.T) {
+	state := new(LineState)
+	state.parseCopyLine(`COPY "Legacy Schema"."Order.Items" ("Id", "Unit Price", "a,b", "x(y)", "say ""hi""", ` +
+		`größe) FROM stdin;` + "\n")
+	require.Equal(t, "Legacy Schema", state.SchemaName)
+	require.Equal(t, "Order.Items", state.TableName)
+	require.Equal(t, []string{"Id", "Unit Price", "a,b", "x(y)", `say "hi"`, "größe"}, state.ColumnNames)
+	require.True(t, state.IsRow)
+
+	state.parseCopyLine("COPY public.users (id, email) FROM stdin;\n")
+	require.Equal(t, "public", state.SchemaName)
+	require.Equal(t, "users", state.TableName)
+	require.Equal(t, []string{"id", "email"}, state.ColumnNames)
+}
+
+func TestColumnMapperIdentifiers(t *testing.T) {
+	dbmap := DBMapper{
+		ColumnMaps: []ColumnMapper{
+			{TableSchema: "public", TableName: "UserAccounts", ColumnName: "FirstName"},
+			{TableSchema: "public", TableName: `"Order Items"`, ColumnName: `"Unit.Price"`},
+		},
+	}
+	require.NotNil(t, dbmap.ColumnMapper("public", `"UserAccounts"`, `"FirstName"`))
+	require.NotNil(t, dbmap.ColumnMapper("public", "UserAccounts", "FirstName"))
+	require.Nil(t, dbmap.ColumnMapper("public", "useraccounts", "firstname"))
+	require.NotNil(t, dbmap.ColumnMapper("public", "Order Items", "Unit.Price"))
+
+	// End-to-end: the columns of a dump file with quoted names are found in the map
+	dumpFile := writeTestAuditFile(t, `COPY public."UserAccounts" ("Id", "FirstName") FROM stdin;`+"\n"+
+		"1\tJane\n\\.\n"+`COPY public."Order Items" ("Unit.Price") FROM stdin;`+"\n1.50\n\\.\n")
+	defer os.Remove(dumpFile)
+	report, err := MapCoverage(&dbmap, dumpFile)
+	require.Nil(t, err)
+	require.Len(t, report.Tables, 2)
+	for _, table := range report.Tables {
+		if table.Table == "public.UserAccounts" {
+			require.Equal(t, []string{"Id"}, table.UnmappedColumns)
+		} else {
+			require.Equal(t, "public.Order Items", table.Table)
+			require.Empty(t, table.UnmappedColumns)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package gonymizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonSelectorIndexRegex matches the array index or quoted key of a bracket segment in a JSON selector ([0], [*],
+// ['key'], ["key"]).
+var jsonSelectorIndexRegex = regexp.MustCompile(`^\[(\*|[0-9]+|'[^']*'|"[^"]*")\]`)
+
+// jsonSelector is a parsed JSON selector. Every segment is an object key, an array index ([0]), a wildcard (*) that
+// matches any single key or index, or a recursive descent (..) that matches any number of keys and indexes.
+type jsonSelector []string
+
+const (
+	jsonSelectorWildcard  = "*"
+	jsonSelectorRecursive = ".."
+)
+
+// parseJSONSelector parses a JSONPath-like selector such as $.contact.email, addresses[*].street, $..phone, or
+// $['first name']. The leading $ is optional.
+func parseJSONSelector(selector string) (jsonSelector, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(selector), "$")
+	if len(rest) == 0 && len(selector) == 0 {
+		return nil, fmt.Errorf("Invalid JSON selector %q", selector)
+	}
+
+	var segments jsonSelector
+	for len(rest) > 0 {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			segments = append(segments, jsonSelectorRecursive)
+			rest = rest[2:]
+			if len(rest) == 0 {
+				return nil, fmt.Errorf("Invalid JSON selector %q", selector)
+			}
+			if rest[0] == '[' {
+				continue
+			}
+		case rest[0] == '.':
+			rest = rest[1:]
+		case rest[0] == '[':
+			match := jsonSelectorIndexRegex.FindStringSubmatch(rest)
+			if match == nil {
+				return nil, fmt.Errorf("Invalid JSON selector %q", selector)
+			}
+			switch segment := match[1]; {
+			case segment == jsonSelectorWildcard:
+				segments = append(segments, segment)
+			case segment[0] == '\'' || segment[0] == '"':
+				segments = append(segments, segment[1:len(segment)-1])
+			default:
+				segments = append(segments, "["+segment+"]")
+			}
+			rest = rest[len(match[0]):]
+			continue
+		case len(segments) > 0:
+			return nil, fmt.Errorf("Invalid JSON selector %q", selector)
+		}
+
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		if end == 0 {
+			return nil, fmt.Errorf("Invalid JSON selector %q", selector)
+		}
+		segments = append(segments, rest[:end])
+		rest = rest[end:]
+	}
+	return segments, nil
+}
+
+// matches returns true if the selector matches the path (object keys and [n] array indexes) of a JSON value.
+func (selector jsonSelector) matches(path []string) bool {
+	if len(selector) == 0 {
+		return len(path) == 0
+	}
+	switch selector[0] {
+	case jsonSelectorRecursive:
+		for i := 0; i <= len(path); i++ {
+			if selector[1:].matches(path[i:]) {
+				return true
+			}
+		}
+		return false
+	case jsonSelectorWildcard:
+		return len(path) > 0 && selector[1:].matches(path[1:])
+	}
+	return len(path) > 0 && selector[0] == path[0] && selector[1:].matches(path[1:])
+}
+
+// jsonRule is an inner processor definition of the JSONB processor with its parsed selectors.
+type jsonRule struct {
+	procDef   ProcessorDefinition
+	selectors []jsonSelector
+}
+
+// parseJSONRules parses the selectors (Keys) of every inner processor definition.
+func parseJSONRules(procDefs []ProcessorDefinition) ([]jsonRule, error) {
+	rules := make([]jsonRule, 0, len(procDefs))
+	for _, procDef := range procDefs {
+		if len(procDef.Keys) == 0 {
+			return nil, fmt.Errorf("Expected Keys (JSON selectors) for processor %s", procDef.Name)
+		}
+		rule := jsonRule{procDef: procDef}
+		for _, key := range procDef.Keys {
+			selector, err := parseJSONSelector(key)
+			if err != nil {
+				return nil, err
+			}
+			rule.selectors = append(rule.selectors, selector)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// validateJSONB checks the inner processor definitions and selectors of a JSONB processor definition.
+func (procDef ProcessorDefinition) validateJSONB() error {
+	if procDef.Name != "JSONB" {
+		return nil
+	}
+	if len(procDef.Processors) == 0 {
+		return fmt.Errorf("Expected at least one inner processor for processor %s", procDef.Name)
+	}
+	_, err := parseJSONRules(procDef.Processors)
+	return err
+}
+
+// processJSONDocument runs the values of the decoded JSON document matched by the rules through the processors of the
+// rules. A value matched by several rules is run through all of them in order. When a rule matches an object or array
+// every string and number inside it is processed.
+func processJSONDocument(cmap *ColumnMapper, document interface{}, rules []jsonRule) (interface{}, error) {
+	var walk func(value interface{}, path []string, matched []ProcessorDefinition) (interface{}, error)
+	walk = func(value interface{}, path []string, matched []ProcessorDefinition) (interface{}, error) {
+		for _, rule := range rules {
+			for _, selector := range rule.selectors {
+				if selector.matches(path) {
+					matched = append(matched[:len(matched):len(matched)], rule.procDef)
+					break
+				}
+			}
+		}
+
+		var err error
+		switch v := value.(type) {
+		case map[string]interface{}:
+			// Sorted so the random values are drawn in the same order in every run
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if v[key], err = walk(v[key], append(path[:len(path):len(path)], key), matched); err != nil {
+					return nil, err
+				}
+			}
+		case []interface{}:
+			for i, child := range v {
+				index := "[" + strconv.Itoa(i) + "]"
+				if v[i], err = walk(child, append(path[:len(path):len(path)], index), matched); err != nil {
+					return nil, err
+				}
+			}
+		case string:
+			if len(matched) > 0 {
+				inner := *cmap
+				inner.Processors = matched
+				return processValue(&inner, v)
+			}
+		case json.Number:
+			if len(matched) > 0 {
+				inner := *cmap
+				inner.Processors = matched
+				output, err := processValue(&inner, v.String())
+				if err != nil {
+					return nil, err
+				}
+				// Keep numbers as numbers unless the processor returned something else (including numbers JSON does
+				// not allow, such as 0123)
+				if _, err := strconv.ParseFloat(output, 64); err == nil && json.Valid([]byte(output)) {
+					return json.Number(output), nil
+				}
+				return output, nil
+			}
+		}
+		return value, nil
+	}
+	return walk(document, nil, nil)
+}
+
+// escapeCopyValue escapes the characters of a value that have to be backslash escaped in a COPY row (see
+// unescapeCopyValue).
+func escapeCopyValue(value string) string {
+	return strings.NewReplacer(
+		"\\", "\\\\",
+		"\b", "\\b",
+		"\f", "\\f",
+		"\n", "\\n",
+		"\r", "\\r",
+		"\t", "\\t",
+		"\v", "\\v",
+	).Replace(value)
+}
+
+// encodeJSONDocument encodes the JSON document without escaping HTML characters.
+func encodeJSONDocument(document interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(document); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -0,0 +1,90 @@
+package gonymizer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSONSelector(t *testing.T) {
+	var selectors = map[string]jsonSelector{
+		"$":                    nil,
+		"email":                {"email"},
+		"$.contact.email":      {"contact", "email"},
+		"addresses[*].street":  {"addresses", "*", "street"},
+		"$.phones[0]":          {"phones", "[0]"},
+		"$..phone":             {"..", "phone"},
+		"$..[1]":               {"..", "[1]"},
+		"$['first name'].text": {"first name", "text"},
+		`$["a.b"]`:             {"a.b"},
+		"$.*":                  {"*"},
+	}
+	for input, expected := range selectors {
+		selector, err := parseJSONSelector(input)
+		require.Nil(t, err, input)
+		require.Equal(t, expected, selector, input)
+	}
+
+	for _, input := range []string{"", "$.", "$..", "a..", "$.a[x]", "$.a[0]b", "$['a]"} {
+		_, err := parseJSONSelector(input)
+		require.NotNil(t, err, input)
+	}
+
+	selector, _ := parseJSONSelector("$..phone")
+	require.True(t, selector.matches([]string{"phone"}))
+	require.True(t, selector.matches([]string{"contacts", "[3]", "phone"}))
+	require.False(t, selector.matches([]string{"phone", "number"}))
+
+	selector, _ = parseJSONSelector("contacts[*].email")
+	require.True(t, selector.matches([]string{"contacts", "[0]", "email"}))
+	require.False(t, selector.matches([]string{"contacts", "email"}))
+}
+
+func TestProcessorJSONB(t *testing.T) {
+	jsonbTest := ColumnMapper{
+		Processors: []ProcessorDefinition{{Name: "JSONB", Processors: []ProcessorDefinition{
+			{Name: "ScrubString", Keys: []string{"$.email", "$.contacts[*].email"}},
+			{Name: "RandomDigits", Keys: []string{"$..phone"}},
+			{Name: "ScrubString", Keys: []string{"$.address"}},
+		}}},
+	}
+
+	input := `{"email":"jane@example.com","phone":5551234567,"preferences":{"theme":"dark","email":"keep"},` +
+		`"contacts":[{"email":"bob@example.com","phone":"555-1111"}],"address":{"street":"1 Main St","zip":12345},` +
+		`"note":"a\\\\b","active":true}`
+	output, err := ProcessorJSONB(&jsonbTest, input)
+	require.Nil(t, err)
+
+	var document map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(unescapeCopyValue(output)), &document))
+	require.Equal(t, "****************", document["email"])
+	require.Equal(t, map[string]interface{}{"theme": "dark", "email": "keep"}, document["preferences"])
+	require.Equal(t, "a\\b", document["note"])
+	require.Equal(t, true, document["active"])
+	if phone, ok := document["phone"].(string); ok {
+		// RandomDigits picked a leading zero, which JSON numbers can not have
+		require.Regexp(t, `^0\d{9}$`, phone)
+	} else {
+		require.IsType(t, float64(0), document["phone"])
+	}
+
+	contact := document["contacts"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, "***************", contact["email"])
+	require.NotEqual(t, "555-1111", contact["phone"])
+	require.Equal(t, map[string]interface{}{"street": "*********", "zip": "*****"}, document["address"])
+
+	output, err = ProcessorJSONB(&jsonbTest, "")
+	require.Nil(t, err)
+	require.Equal(t, "", output)
+
+	_, err = ProcessorJSONB(&jsonbTest, "{not json")
+	require.NotNil(t, err)
+
+	require.Nil(t, jsonbTest.Processors[0].validateJSONB())
+	require.NotNil(t, ProcessorDefinition{Name: "JSONB"}.validateJSONB())
+	require.NotNil(t, ProcessorDefinition{Name: "JSONB", Processors: []ProcessorDefinition{{Name: "ScrubString"}}}.
+		validateJSONB())
+	require.NotNil(t, ProcessorDefinition{Name: "JSONB", Processors: []ProcessorDefinition{{Name: "ScrubString",
+		Keys: []string{"$.a[x]"}}}}.validateJSONB())
+}
@@ -0,0 +1,331 @@
+package gonymizer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// location is a real place used to create correlated fake location values. PostalCode is a pattern where every # is
+// replaced by a random digit.
+type location struct {
+	City        string
+	State       string
+	StateAbbrev string
+	PostalCode  string
+	Country     string
+	CountryCode string
+	Latitude    float64
+	Longitude   float64
+}
+
+// locations are the places used by the FakeLocation processor.
+var locations = []location{
+	{"New York", "New York", "NY", "100##", "United States", "US", 40.7128, -74.0060},
+	{"Los Angeles", "California", "CA", "900##", "United States", "US", 34.0522, -118.2437},
+	{"Chicago", "Illinois", "IL", "606##", "United States", "US", 41.8781, -87.6298},
+	{"Houston", "Texas", "TX", "770##", "United States", "US", 29.7604, -95.3698},
+	{"Phoenix", "Arizona", "AZ", "850##", "United States", "US", 33.4484, -112.0740},
+	{"Philadelphia", "Pennsylvania", "PA", "191##", "United States", "US", 39.9526, -75.1652},
+	{"San Antonio", "Texas", "TX", "782##", "United States", "US", 29.4241, -98.4936},
+	{"San Diego", "California", "CA", "921##", "United States", "US", 32.7157, -117.1611},
+	{"Dallas", "Texas", "TX", "752##", "United States", "US", 32.7767, -96.7970},
+	{"San Jose", "California", "CA", "951##", "United States", "US", 37.3382, -121.8863},
+	{"Austin", "Texas", "TX", "787##", "United States", "US", 30.2672, -97.7431},
+	{"Jacksonville", "Florida", "FL", "322##", "United States", "US", 30.3322, -81.6557},
+	{"Columbus", "Ohio", "OH", "432##", "United States", "US", 39.9612, -82.9988},
+	{"Charlotte", "North Carolina", "NC", "282##", "United States", "US", 35.2271, -80.8431},
+	{"Indianapolis", "Indiana", "IN", "462##", "United States", "US", 39.7684, -86.1581},
+	{"Seattle", "Washington", "WA", "981##", "United States", "US", 47.6062, -122.3321},
+	{"Denver", "Colorado", "CO", "802##", "United States", "US", 39.7392, -104.9903},
+	{"Boston", "Massachusetts", "MA", "021##", "United States", "US", 42.3601, -71.0589},
+	{"Nashville", "Tennessee", "TN", "372##", "United States", "US", 36.1627, -86.7816},
+	{"Portland", "Oregon", "OR", "972##", "United States", "US", 45.5152, -122.6784},
+	{"Las Vegas", "Nevada", "NV", "891##", "United States", "US", 36.1699, -115.1398},
+	{"Atlanta", "Georgia", "GA", "303##", "United States", "US", 33.7490, -84.3880},
+	{"Miami", "Florida", "FL", "331##", "United States", "US", 25.7617, -80.1918},
+	{"Minneapolis", "Minnesota", "MN", "554##", "United States", "US", 44.9778, -93.2650},
+	{"Toronto", "Ontario", "ON", "M#A #A#", "Canada", "CA", 43.6532, -79.3832},
+	{"Vancouver", "British Columbia", "BC", "V#B #C#", "Canada", "CA", 49.2827, -123.1207},
+	{"London", "Greater London", "", "SW# #AA", "United Kingdom", "GB", 51.5074, -0.1278},
+	{"Manchester", "Greater Manchester", "", "M## #AA", "United Kingdom", "GB", 53.4808, -2.2426},
+	{"Paris", "Île-de-France", "", "750##", "France", "FR", 48.8566, 2.3522},
+	{"Lyon", "Auvergne-Rhône-Alpes", "", "6900#", "France", "FR", 45.7640, 4.8357},
+	{"Berlin", "Berlin", "BE", "10###", "Germany", "DE", 52.5200, 13.4050},
+	{"Munich", "Bavaria", "BY", "80###", "Germany", "DE", 48.1351, 11.5820},
+	{"Madrid", "Community of Madrid", "M", "280##", "Spain", "ES", 40.4168, -3.7038},
+	{"Rome", "Lazio", "RM", "001##", "Italy", "IT", 41.9028, 12.4964},
+	{"Amsterdam", "North Holland", "NH", "10## AB", "Netherlands", "NL", 52.3676, 4.9041},
+	{"Sydney", "New South Wales", "NSW", "20##", "Australia", "AU", -33.8688, 151.2093},
+	{"Melbourne", "Victoria", "VIC", "30##", "Australia", "AU", -37.8136, 144.9631},
+	{"Tokyo", "Tokyo", "", "1##-####", "Japan", "JP", 35.6762, 139.6503},
+	{"Mexico City", "Mexico City", "CDMX", "06###", "Mexico", "MX", 19.4326, -99.1332},
+	{"São Paulo", "São Paulo", "SP", "01###-###", "Brazil", "BR", -23.5505, -46.6333},
+}
+
+// locationFields are the fields of a location record that can be used by the FakeLocation processor.
+var locationFields = map[string]bool{
+	"city":           true,
+	"state":          true,
+	"state_abbrev":   true,
+	"postal_code":    true,
+	"country":        true,
+	"country_code":   true,
+	"latitude":       true,
+	"longitude":      true,
+	"street_address": true,
+}
+
+// fakeLocationRecord returns all location fields of a random location (see locationRecord).
+func fakeLocationRecord() map[string]string {
+	return locationRecord(locations[rng.Intn(len(locations))])
+}
+
+// locationRecord returns all location fields of loc. Postal codes are randomized and coordinates are moved up to ~5km
+// so they stay within the city. The street address is in the format of the country (see fakeCountryStreet).
+func locationRecord(loc location) map[string]string {
+	var postalCode strings.Builder
+	for _, c := range loc.PostalCode {
+		switch c {
+		case '#':
+			postalCode.WriteString(randomNumeric())
+		default:
+			postalCode.WriteRune(c)
+		}
+	}
+
+	return map[string]string{
+		"city":           loc.City,
+		"state":          loc.State,
+		"state_abbrev":   loc.StateAbbrev,
+		"postal_code":    postalCode.String(),
+		"country":        loc.Country,
+		"country_code":   loc.CountryCode,
+		"latitude":       strconv.FormatFloat(loc.Latitude+(rng.Float64()-0.5)/10, 'f', 6, 64),
+		"longitude":      strconv.FormatFloat(loc.Longitude+(rng.Float64()-0.5)/10, 'f', 6, 64),
+		"street_address": fakeCountryStreet(loc.CountryCode),
+	}
+}
+
+// groupValue returns the field of the current row's record for group. The record is created using generate the first
+// time the group is used in the row.
+func groupValue(group, field string, generate func() map[string]string) (string, error) {
+	record, ok := currentRow.Groups[group]
+	if !ok {
+		record = generate()
+		currentRow.Groups[group] = record
+	}
+
+	value, ok := record[field]
+	if !ok {
+		return "", fmt.Errorf("Unknown field %s for group %s", field, group)
+	}
+	return value, nil
+}
+
+// countryCodes maps lower case country names and ISO 3166-1 alpha-2/alpha-3 codes to the alpha-2 code of the countries
+// supported by the FakeCountryAddress processor.
+var countryCodes = map[string]string{
+	"us": "US", "usa": "US", "united states": "US", "united states of america": "US",
+	"ca": "CA", "can": "CA", "canada": "CA",
+	"gb": "GB", "gbr": "GB", "uk": "GB", "united kingdom": "GB", "great britain": "GB", "england": "GB",
+	"fr": "FR", "fra": "FR", "france": "FR",
+	"de": "DE", "deu": "DE", "germany": "DE", "deutschland": "DE",
+	"es": "ES", "esp": "ES", "spain": "ES", "españa": "ES",
+	"it": "IT", "ita": "IT", "italy": "IT", "italia": "IT",
+	"nl": "NL", "nld": "NL", "netherlands": "NL", "the netherlands": "NL", "nederland": "NL",
+	"au": "AU", "aus": "AU", "australia": "AU",
+	"jp": "JP", "jpn": "JP", "japan": "JP",
+	"mx": "MX", "mex": "MX", "mexico": "MX", "méxico": "MX",
+	"br": "BR", "bra": "BR", "brazil": "BR", "brasil": "BR",
+}
+
+// countryPostalCodeRegexes are used to detect the country of an address that does not contain a country name. They
+// are checked in order so the more specific formats come first.
+var countryPostalCodeRegexes = []struct {
+	CountryCode string
+	Regex       *regexp.Regexp
+}{
+	{"CA", regexp.MustCompile(`\b[A-Z][0-9][A-Z] ?[0-9][A-Z][0-9]\b`)},
+	{"GB", regexp.MustCompile(`\b[A-Z]{1,2}[0-9][A-Z0-9]? [0-9][A-Z]{2}\b`)},
+	{"NL", regexp.MustCompile(`\b[0-9]{4} ?[A-Z]{2}\b`)},
+	{"BR", regexp.MustCompile(`\b[0-9]{5}-[0-9]{3}\b`)},
+	{"JP", regexp.MustCompile(`\b[0-9]{3}-[0-9]{4}\b`)},
+	{"US", regexp.MustCompile(`\b[A-Z]{2},? [0-9]{5}(-[0-9]{4})?\b`)},
+}
+
+// countryStreetFormats create the street line of an address in the format of the country from a house number and a
+// street name.
+var countryStreetFormats = map[string]func(number, street string) string{
+	"US": func(number, street string) string { return fmt.Sprintf("%s %s %s", number, street, usStreetSuffix()) },
+	"CA": func(number, street string) string { return fmt.Sprintf("%s %s %s", number, street, usStreetSuffix()) },
+	"GB": func(number, street string) string { return fmt.Sprintf("%s %s Road", number, street) },
+	"FR": func(number, street string) string { return fmt.Sprintf("%s rue %s", number, street) },
+	"DE": func(number, street string) string { return fmt.Sprintf("%sstraße %s", street, number) },
+	"ES": func(number, street string) string { return fmt.Sprintf("Calle %s %s", street, number) },
+	"IT": func(number, street string) string { return fmt.Sprintf("Via %s %s", street, number) },
+	"NL": func(number, street string) string { return fmt.Sprintf("%sstraat %s", street, number) },
+	"AU": func(number, street string) string { return fmt.Sprintf("%s %s St", number, street) },
+	"JP": func(number, street string) string {
+		return fmt.Sprintf("%d-%d-%d %s", rng.Intn(9)+1, rng.Intn(20)+1, rng.Intn(30)+1, street)
+	},
+	"MX": func(number, street string) string { return fmt.Sprintf("Calle %s %s", street, number) },
+	"BR": func(number, street string) string { return fmt.Sprintf("Rua %s, %s", street, number) },
+}
+
+// countryAddressFormats create an address in the format of the country. The arguments are the location (city, state,
+// postal code) and the street line (see countryStreetFormats).
+var countryAddressFormats = map[string]func(loc map[string]string, street string) string{
+	"US": func(loc map[string]string, street string) string {
+		return fmt.Sprintf("%s, %s, %s %s", street, loc["city"], loc["state_abbrev"], loc["postal_code"])
+	},
+	"CA": func(loc map[string]string, street string) string {
+		return fmt.Sprintf("%s, %s, %s %s", street, loc["city"], loc["state_abbrev"], loc["postal_code"])
+	},
+	"GB": func(loc map[string]string, street string) string {
+		return fmt.Sprintf("%s, %s %s", street, loc["city"], loc["postal_code"])
+	},
+	"FR": func(loc map[string]string, street string) string {
+		return fmt.Sprintf("%s, %s %s", street, loc["postal_code"], loc["city"])
+	},
+	"DE": func(loc map[string]string, street string) string {
+		return fmt.Sprintf("%s, %s %s", street, loc["postal_code"], loc["city"])
+	},
+	"ES": func(loc map[string]string, street string) string {
+		return fmt.Sprintf("%s, %s %s", street, loc["postal_code"], loc["city"])
+	},
+	"IT": func(loc map[string]string, street string) string {
+		return fmt.Sprintf("%s, %s %s %s", street, loc["postal_code"], loc["city"], loc["state_abbrev"])
+	},
+	"NL": func(loc map[string]string, street string) string {
+		return fmt.Sprintf("%s, %s %s", street, loc["postal_code"], loc["city"])
+	},
+	"AU": func(loc map[string]string, street string) string {
+		return fmt.Sprintf("%s, %s %s %s", street, loc["city"], loc["state_abbrev"], loc["postal_code"])
+	},
+	"JP": func(loc map[string]string, street string) string {
+		return fmt.Sprintf("%s, %s %s", street, loc["city"], loc["postal_code"])
+	},
+	"MX": func(loc map[string]string, street string) string {
+		return fmt.Sprintf("%s, %s %s, %s", street, loc["postal_code"], loc["city"], loc["state_abbrev"])
+	},
+	"BR": func(loc map[string]string, street string) string {
+		return fmt.Sprintf("%s, %s - %s, %s", street, loc["city"], loc["state_abbrev"], loc["postal_code"])
+	},
+}
+
+// localeLanguages maps the languages of a Locale without a region to the country whose address format is used.
+var localeLanguages = map[string]string{
+	"en": "US", "fr": "FR", "de": "DE", "es": "ES", "it": "IT", "nl": "NL", "ja": "JP", "pt": "BR",
+}
+
+// localeCountry returns the alpha-2 code of the country of a Locale: a language (de), a language and region (de-AT,
+// en_GB), or a country name or code (see countryCodes). The country must be supported by FakeCountryAddress.
+func localeCountry(locale string) (string, error) {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		if code, ok := countryCodes[locale[i+1:]]; ok {
+			return code, nil
+		}
+		return "", fmt.Errorf("Unsupported region in Locale %q", locale)
+	}
+	if code, ok := localeLanguages[locale]; ok {
+		return code, nil
+	}
+	if code, ok := countryCodes[locale]; ok {
+		return code, nil
+	}
+	return "", fmt.Errorf("Unsupported Locale %q", locale)
+}
+
+// localeRecord returns the location record (see countryRecord) of a random location in the country of the Locale of
+// the processor definition. Nil is returned if the definition has no Locale.
+func (procDef ProcessorDefinition) localeRecord() (map[string]string, error) {
+	if len(procDef.Locale) == 0 {
+		return nil, nil
+	}
+	countryCode, err := localeCountry(procDef.Locale)
+	if err != nil {
+		return nil, err
+	}
+	return countryRecord(countryCode), nil
+}
+
+// validateLocale checks that the Locale of a processor definition is supported (see localeCountry).
+func (procDef ProcessorDefinition) validateLocale() error {
+	if len(procDef.Locale) == 0 {
+		return nil
+	}
+	_, err := localeCountry(procDef.Locale)
+	return err
+}
+
+// supportedCountryCodes returns the sorted alpha-2 codes of the countries supported by FakeCountryAddress.
+func supportedCountryCodes() []string {
+	var codes []string
+	for code := range countryAddressFormats {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// usStreetSuffix returns a random abbreviated street suffix.
+func usStreetSuffix() string {
+	suffixes := []string{"St", "Ave", "Rd", "Blvd", "Dr", "Ln", "Ct", "Way", "Pl"}
+	return suffixes[rng.Intn(len(suffixes))]
+}
+
+// detectCountry returns the alpha-2 country code of the address. A country name (or alpha-3 code) at the end of the
+// address is used first, then the format of the postal code. US is returned if the country cannot be detected.
+func detectCountry(address string) string {
+	// Lines may be separated by new lines escaped by the dump file
+	lines := strings.Replace(address, "\\n", "\n", -1)
+	parts := strings.FieldsFunc(lines, func(r rune) bool { return r == ',' || r == '\n' })
+	if len(parts) > 1 {
+		last := strings.ToLower(strings.TrimSpace(parts[len(parts)-1]))
+		// Two letter codes are skipped since they are also used for states (CA, DE, IN, ...)
+		if code, ok := countryCodes[last]; ok && len(last) > 2 {
+			return code
+		}
+	}
+
+	for _, postalCode := range countryPostalCodeRegexes {
+		if postalCode.Regex.MatchString(address) {
+			return postalCode.CountryCode
+		}
+	}
+	return "US"
+}
+
+// fakeCountryAddress returns a fake address in the format of the country (alpha-2 code) using a real city and postal
+// code from that country (see countryRecord). Unsupported countries use the US format.
+func fakeCountryAddress(countryCode string) string {
+	if _, ok := countryAddressFormats[countryCode]; !ok {
+		countryCode = "US"
+	}
+	return countryAddressFormats[countryCode](countryRecord(countryCode), fakeCountryStreet(countryCode))
+}
+
+// fakeCountryStreet returns a fake street line in the format of the supported country (alpha-2 code).
+func fakeCountryStreet(countryCode string) string {
+	return countryStreetFormats[countryCode](randomNumber(rng.Intn(3)+1), fakeAddressWord(countryCode))
+}
+
+// countryRecord returns the location record (see locationRecord) of a random location in the supported country
+// (alpha-2 code). The city is taken from the country directory of the data pack when it is loaded (see LoadDataPack).
+func countryRecord(countryCode string) map[string]string {
+	var candidates []location
+	for _, loc := range locations {
+		if loc.CountryCode == countryCode {
+			candidates = append(candidates, loc)
+		}
+	}
+	record := locationRecord(candidates[rng.Intn(len(candidates))])
+	if cities := dataPack[countryCode+"/"+DataPackCities]; len(cities) > 0 {
+		record["city"] = cities[rng.Intn(len(cities))]
+	}
+	return record
+}
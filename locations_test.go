@@ -0,0 +1,173 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorLocation(t *testing.T) {
+	field := func(name string) *ColumnMapper {
+		return &ColumnMapper{
+			Processors: []ProcessorDefinition{{Name: "FakeLocation", Group: "billing", Field: name}},
+		}
+	}
+
+	currentRow = newRowContext(nil, nil)
+	city, err := ProcessorLocation(field("city"), "Paris")
+	require.Nil(t, err)
+	stateAbbrev, err := ProcessorLocation(field("state_abbrev"), "TX")
+	require.Nil(t, err)
+	country, err := ProcessorLocation(field("country"), "Canada")
+	require.Nil(t, err)
+	postalCode, err := ProcessorLocation(field("postal_code"), "90210")
+	require.Nil(t, err)
+	require.NotContains(t, postalCode, "#")
+
+	found := false
+	for _, loc := range locations {
+		if loc.City == city && loc.StateAbbrev == stateAbbrev && loc.Country == country {
+			found = true
+		}
+	}
+	require.True(t, found, "%s, %s, %s is not a known location", city, stateAbbrev, country)
+
+	// The street address is in the format of the country of the location
+	for _, loc := range locations {
+		if loc.CountryCode == "DE" {
+			require.Regexp(t, `^[A-Za-z]+straße [0-9]+$`, locationRecord(loc)["street_address"])
+		}
+	}
+
+	_, err = ProcessorLocation(field("planet"), "Earth")
+	require.NotNil(t, err)
+}
+
+func TestProcessRowLocationGroups(t *testing.T) {
+	mapper := &DBMapper{
+		DBName: "pii_localtest",
+		ColumnMaps: []ColumnMapper{
+			{
+				TableSchema: "public",
+				TableName:   "addresses",
+				ColumnName:  "city",
+				Processors:  []ProcessorDefinition{{Name: "FakeLocation", Field: "city"}},
+			},
+			{
+				TableSchema: "public",
+				TableName:   "addresses",
+				ColumnName:  "country",
+				Processors:  []ProcessorDefinition{{Name: "FakeLocation", Field: "country"}},
+			},
+		},
+	}
+	state := &LineState{
+		IsRow:       true,
+		SchemaName:  "public",
+		TableName:   "addresses",
+		ColumnNames: []string{"city", "country"},
+	}
+
+	// Every row must get a city and country from the same location
+	for i := 0; i < 50; i++ {
+		_, output, err := processRow(mapper, state, "Paris\tCanada\n")
+		require.Nil(t, err)
+		found := false
+		for _, loc := range locations {
+			if output == loc.City+"\t"+loc.Country+"\n" {
+				found = true
+			}
+		}
+		require.True(t, found, output)
+	}
+}
+
+func TestDetectCountry(t *testing.T) {
+	require.Equal(t, "FR", detectCountry("8 avenue Foch, 75116 Paris, France"))
+	require.Equal(t, "DE", detectCountry("Hauptstraße 5\\n10115 Berlin\\nGermany"))
+	require.Equal(t, "US", detectCountry("123 Main St, Los Angeles, CA 90001"))
+	require.Equal(t, "CA", detectCountry("100 Queen St W, Toronto, ON M5H 2N2"))
+	require.Equal(t, "GB", detectCountry("10 Downing Street, London SW1A 2AA"))
+	require.Equal(t, "NL", detectCountry("Damrak 1, 1012 LG Amsterdam"))
+	require.Equal(t, "JP", detectCountry("1-1 Chiyoda, Tokyo 100-0001"))
+	require.Equal(t, "US", detectCountry("somewhere"))
+}
+
+func TestProcessorCountryAddress(t *testing.T) {
+	cmap := ColumnMapper{
+		Processors: []ProcessorDefinition{{Name: "FakeCountryAddress"}},
+	}
+
+	output, err := ProcessorCountryAddress(&cmap, "8 avenue Foch, 75116 Paris, France")
+	require.Nil(t, err)
+	require.Regexp(t, `^[0-9]+ rue [A-Za-z]+, [0-9]{5} (Paris|Lyon)$`, output)
+
+	// The country column takes precedence over the input
+	cmap.Processors[0].CountryColumn = "country"
+	currentRow = newRowContext([]string{"address", "country"}, []string{"8 avenue Foch, 75116 Paris", "DEU"})
+	output, err = ProcessorCountryAddress(&cmap, "8 avenue Foch, 75116 Paris")
+	require.Nil(t, err)
+	require.Regexp(t, `^[A-Za-z]+straße [0-9]+, [0-9]{5} (Berlin|Munich)$`, output)
+
+	currentRow = newRowContext([]string{"address", "country"}, []string{"123 Main St, Austin, TX 78701", "\\N"})
+	output, err = ProcessorCountryAddress(&cmap, "123 Main St, Austin, TX 78701")
+	require.Nil(t, err)
+	require.Regexp(t, `^[0-9]+ [A-Za-z]+ [A-Za-z]+, [A-Za-z ]+, [A-Z]{2} [0-9]{5}$`, output)
+}
+
+func TestLocaleCountry(t *testing.T) {
+	for locale, expected := range map[string]string{"de": "DE", "de-AT": "", "en_GB": "GB", "pt-BR": "BR",
+		"ja": "JP", "Germany": "DE", "MX": "MX", "xx": ""} {
+		code, err := localeCountry(locale)
+		if len(expected) == 0 {
+			require.NotNil(t, err, locale)
+			continue
+		}
+		require.Nil(t, err, locale)
+		require.Equal(t, expected, code, locale)
+	}
+
+	require.Nil(t, ProcessorDefinition{Name: "FakeCity", Locale: "fr"}.validateLocale())
+	require.NotNil(t, ProcessorDefinition{Name: "FakeCity", Locale: "klingon"}.validateLocale())
+}
+
+func TestProcessorLocale(t *testing.T) {
+	cmap := ColumnMapper{Processors: []ProcessorDefinition{{Name: "FakeStreetAddress", Locale: "de"}}}
+	output, err := ProcessorAddress(&cmap, "123 Main St")
+	require.Nil(t, err)
+	require.Regexp(t, `^[A-Za-z]+straße [0-9]+$`, output)
+
+	cmap.Processors[0].Name = "FakeCity"
+	output, err = ProcessorCity(&cmap, "Austin")
+	require.Nil(t, err)
+	require.Contains(t, []string{"Berlin", "Munich"}, output)
+
+	cmap.Processors[0].Name = "FakeState"
+	output, err = ProcessorState(&cmap, "Texas")
+	require.Nil(t, err)
+	require.Contains(t, []string{"Berlin", "Bavaria"}, output)
+
+	cmap.Processors[0].Name = "FakeZip"
+	output, err = ProcessorZip(&cmap, "78701")
+	require.Nil(t, err)
+	require.Regexp(t, `^[0-9]{5}$`, output)
+
+	cmap.Processors[0].Locale = "en-GB"
+	output, err = ProcessorZip(&cmap, "78701")
+	require.Nil(t, err)
+	require.Regexp(t, `^[A-Z]{1,2}[0-9]{1,2} [0-9][A-Z]{2}$`, output)
+
+	cmap.Processors[0].Locale = "klingon"
+	_, err = ProcessorZip(&cmap, "78701")
+	require.NotNil(t, err)
+}
+
+func TestProcessorCountry(t *testing.T) {
+	output, err := ProcessorCountry(&cMap, "United States")
+	require.Nil(t, err)
+	require.NotEqual(t, "", output)
+
+	output, err = ProcessorCountry(&cMap, "US")
+	require.Nil(t, err)
+	require.Contains(t, supportedCountryCodes(), output)
+}
@@ -0,0 +1,68 @@
+package gonymizer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// lookupTables caches the replacements of every lookup file that has been loaded so each file is only read once.
+var lookupTables = map[string]map[string]string{}
+
+// loadLookupFile will load a CSV file of original and replacement values (one pair per line) and return the
+// replacements keyed by their original value. Lines starting with # are skipped. An original value listed twice with
+// different replacements is an error, so reviewed files stay unambiguous.
+func loadLookupFile(path string) (map[string]string, error) {
+	if table, ok := lookupTables[path]; ok {
+		return table, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Error("Failure to open file: ", err)
+		log.Error("path: ", path)
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+
+	table := map[string]string{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			log.Error("Unable to read lookup file: ", err)
+			log.Error("path: ", path)
+			return nil, err
+		}
+		if replacement, ok := table[record[0]]; ok && replacement != record[1] {
+			return nil, fmt.Errorf("Conflicting replacements %q and %q for %q in lookup file %s", replacement,
+				record[1], record[0], path)
+		}
+		table[record[0]] = record[1]
+	}
+
+	log.Infof("Loaded %d replacements from: %s", len(table), path)
+	lookupTables[path] = table
+	return table, nil
+}
+
+// validateLookupFile loads the LookupFile of a LookupFile processor definition, so a missing or invalid file is found
+// before any data is processed.
+func (procDef ProcessorDefinition) validateLookupFile() error {
+	if procDef.Name != "LookupFile" {
+		return nil
+	}
+	if len(procDef.LookupFile) == 0 {
+		return fmt.Errorf("Expected the path of a CSV file in LookupFile for processor %s", procDef.Name)
+	}
+	_, err := loadLookupFile(procDef.LookupFile)
+	return err
+}
@@ -0,0 +1,76 @@
+package gonymizer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeLookupFile writes the CSV content to a temporary lookup file and returns its path.
+func writeLookupFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "gonymizer-lookup-*.csv")
+	require.Nil(t, err)
+	_, err = f.WriteString(content)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+	return f.Name()
+}
+
+func TestProcessorLookupFile(t *testing.T) {
+	path := writeLookupFile(t, "# account,replacement\nAcme Corp,Reviewed Account 1\n\"Tab\tCo\",\"Reviewed, Inc\"\n")
+	defer os.Remove(path)
+	defer delete(lookupTables, path)
+
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{{Name: "LookupFile", LookupFile: path}}}
+	output, err := ProcessorLookupFile(cmap, "Acme Corp")
+	require.Nil(t, err)
+	require.Equal(t, "Reviewed Account 1", output)
+
+	// Values are unescaped from the COPY format
+	output, err = ProcessorLookupFile(cmap, "Tab\\tCo")
+	require.Nil(t, err)
+	require.Equal(t, "Reviewed, Inc", output)
+
+	// Values that are not in the file require fallback processors
+	_, err = ProcessorLookupFile(cmap, "Globex")
+	require.NotNil(t, err)
+	cmap.Processors[0].Processors = []ProcessorDefinition{{Name: "Null"}}
+	output, err = ProcessorLookupFile(cmap, "Globex")
+	require.Nil(t, err)
+	require.Equal(t, "\\N", output)
+}
+
+func TestLoadLookupFile(t *testing.T) {
+	path := writeLookupFile(t, "a,1\nb,2\na,1\n")
+	defer os.Remove(path)
+	defer delete(lookupTables, path)
+	table, err := loadLookupFile(path)
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"a": "1", "b": "2"}, table)
+
+	conflict := writeLookupFile(t, "a,1\na,2\n")
+	defer os.Remove(conflict)
+	_, err = loadLookupFile(conflict)
+	require.NotNil(t, err)
+
+	columns := writeLookupFile(t, "a,1,x\n")
+	defer os.Remove(columns)
+	_, err = loadLookupFile(columns)
+	require.NotNil(t, err)
+
+	_, err = loadLookupFile(path + ".missing")
+	require.NotNil(t, err)
+}
+
+func TestValidateLookupFile(t *testing.T) {
+	path := writeLookupFile(t, "a,1\n")
+	defer os.Remove(path)
+	defer delete(lookupTables, path)
+
+	require.Nil(t, ProcessorDefinition{Name: "LookupFile", LookupFile: path}.validateLookupFile())
+	require.NotNil(t, ProcessorDefinition{Name: "LookupFile"}.validateLookupFile())
+	require.NotNil(t, ProcessorDefinition{Name: "LookupFile", LookupFile: path + ".missing"}.validateLookupFile())
+	require.Nil(t, ProcessorDefinition{Name: "Identity"}.validateLookupFile())
+}
@@ -0,0 +1,80 @@
+package gonymizer
+
+import (
+	"container/list"
+)
+
+// lruCache is a bounded, least recently used, string to string cache. It is not safe for concurrent use.
+type lruCache struct {
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List // front is the most recently used entry
+
+	// onEvict is called with the least recently used entry when it is evicted, if set.
+	onEvict func(key, value string)
+
+	Hits   int64
+	Misses int64
+}
+
+// lruEntry is a single key/value pair stored in the lruCache order list.
+type lruEntry struct {
+	key   string
+	value string
+}
+
+// newLRUCache returns an empty cache that holds at most capacity entries.
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		items:    map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// get returns the cached value for key and marks it as the most recently used entry.
+func (c *lruCache) get(key string) (string, bool) {
+	element, ok := c.items[key]
+	if !ok {
+		c.Misses++
+		return "", false
+	}
+	c.Hits++
+	c.order.MoveToFront(element)
+	return element.Value.(*lruEntry).value, true
+}
+
+// add stores value under key. If the cache is full the least recently used entry is evicted.
+func (c *lruCache) add(key, value string) {
+	if element, ok := c.items[key]; ok {
+		element.Value.(*lruEntry).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		entry := oldest.Value.(*lruEntry)
+		delete(c.items, entry.key)
+		if c.onEvict != nil {
+			c.onEvict(entry.key, entry.value)
+		}
+	}
+}
+
+// remove removes the entry of key from the cache and returns true if there was one. onEvict is not called.
+func (c *lruCache) remove(key string) bool {
+	element, ok := c.items[key]
+	if ok {
+		c.order.Remove(element)
+		delete(c.items, key)
+	}
+	return ok
+}
+
+// len returns the number of entries in the cache.
+func (c *lruCache) len() int {
+	return c.order.Len()
+}
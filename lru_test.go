@@ -0,0 +1,31 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRUCache(t *testing.T) {
+	cache := newLRUCache(2)
+	cache.add("a", "1")
+	cache.add("b", "2")
+
+	value, ok := cache.get("a")
+	require.True(t, ok)
+	require.Equal(t, "1", value)
+
+	// "b" is now the least recently used entry and is evicted
+	cache.add("c", "3")
+	_, ok = cache.get("b")
+	require.False(t, ok)
+	require.Equal(t, 2, cache.len())
+
+	cache.add("a", "4")
+	value, ok = cache.get("a")
+	require.True(t, ok)
+	require.Equal(t, "4", value)
+
+	require.Equal(t, int64(2), cache.Hits)
+	require.Equal(t, int64(1), cache.Misses)
+}
@@ -21,6 +21,11 @@ const TestLoadFileDb = "gon_loader_test"
 // Input Test Files
 const TestDbFile = "testing/test_db.sql"
 const TestMapFile = "testing/test_map.json"
+const TestIncludeMapFile = "testing/test_map_include.json"
+const TestIncludeCycleMapFile = "testing/test_map_include_cycle.json"
+const TestCampaignFile = "testing/test_campaign.json"
+const TestSuppressionListFile = "testing/test_suppression_list.csv"
+const TestNameBlocklistFile = "testing/test_name_blocklist.txt"
 const TestPreProcessFile = "testing/test_pre_process.sql"
 const TestPostProcessFile = "testing/test_post_process.sql"
 const TestSQLCommandFile = "testing/test_sql_command_file.sql"
@@ -35,6 +40,15 @@ const TestGenerateSchemaFile = "testing/output.TestGenerateSchemaFile.sql"
 const TestMapOutputFile = "testing/output.TestMapperFile.json"
 const TestFileInjectorFile = "testing/output.TestFileInjectorFile.sql"
 const TestProcessDumpfile = "testing/output.TestProcessDumpFile.sql"
+const TestSampleDumpFile = "testing/output.TestSampleDumpFile.sql"
+const TestBatchDumpFile = "testing/output.TestBatchDumpFile.sql"
+const TestShardDumpFile = "testing/output.TestShardDumpFile.sql"
+const TestDistributedDumpFile = "testing/output.TestDistributedDumpFile.sql"
+const TestBenchmarkDumpFile = "testing/output.TestBenchmarkDumpFile.sql"
+const TestPipelineDumpFile = "testing/output.TestPipelineDumpFile.sql"
+const TestRowFilterDumpFile = "testing/output.TestRowFilterDumpFile.sql"
+const TestRedactionDumpFile = "testing/output.TestRedactionDumpFile.sql"
+const TestSeedDumpFile = "testing/output.TestSeedDumpFile.sql"
 
 // Test schemaPrefix
 const TestSchemaPrefix = ""
@@ -122,12 +136,17 @@ func seqUnitTests(t *testing.T) {
 	// Processors.go
 	t.Run("ProcessorFunc", TestProcessorFunc)
 	t.Run("ProcessorAlphaNumericScrambler", TestProcessorAlphaNumericScrambler)
+	t.Run("ProcessorAlphaNumericScramblerPreserveRepeats", TestProcessorAlphaNumericScramblerPreserveRepeats)
+	t.Run("scrambleString", TestScrambleString)
+	t.Run("registerProcessor", TestRegisterProcessor)
 	t.Run("ProcessorAddress", TestProcessorAddress)
 	t.Run("ProcessorCity", TestProcessorCity)
 	t.Run("ProcessorEmailAddress", TestProcessorEmailAddress)
 	t.Run("ProcessorEmptyJson", TestProcessorEmptyJson)
 	t.Run("ProcessorFirstName", TestProcessorFirstName)
+	t.Run("ProcessorFirstNamePreserveCase", TestProcessorFirstNamePreserveCase)
 	t.Run("ProcessorFakeFullName", TestProcessorFullName)
+	t.Run("ProcessorNameConsistency", TestProcessorNameConsistency)
 	t.Run("ProcessorIdentity", TestProcessorIdentity)
 	t.Run("ProcessorIPv4", TestProcessorIPv4)
 	t.Run("ProcessorLastName", TestProcessorLastName)
@@ -137,12 +156,361 @@ func seqUnitTests(t *testing.T) {
 	t.Run("ProcessorUserName", TestProcessorUserName)
 	t.Run("ProcessorZip", TestProcessorZip)
 	t.Run("ProcessorCompanyName", TestProcessorCompanyName)
+	t.Run("ProcessorIndustry", TestProcessorIndustry)
+	t.Run("ProcessorJobTitle", TestProcessorJobTitle)
 	t.Run("ProcessorRandomBoolean", TestProcessorRandomBoolean)
 	t.Run("ProcessorRandomDate", TestProcessorRandomDate)
 	t.Run("ProcessorRandomDigits", TestProcessorRandomDigits)
+	t.Run("ProcessorRandomTimestamp", TestProcessorRandomTimestamp)
 	t.Run("ProcessorRandomUUID", TestProcessorRandomUUID)
 	t.Run("ProcessorScrubString", TestProcessorScrubString)
 	t.Run("randomizeUUID", TestRandomizeUUID)
+	t.Run("ProcessorBase64Payload", TestProcessorBase64Payload)
+	t.Run("matchCase", TestMatchCase)
+	t.Run("ProcessorAddressPreserveFormat", TestProcessorAddressPreserveFormat)
+	t.Run("ordinal", TestOrdinal)
+	t.Run("ProcessorEIN", TestProcessorEIN)
+	t.Run("ProcessorUTR", TestProcessorUTR)
+	t.Run("ProcessorVATNumber", TestProcessorVATNumber)
+	t.Run("luhnCheckDigit", TestLuhnCheckDigit)
+	t.Run("ProcessorMRZ", TestProcessorMRZ)
+	t.Run("ProcessorPassportNumber", TestProcessorPassportNumber)
+	t.Run("mrzCheckDigit", TestMRZCheckDigit)
+	t.Run("ProcessorBankAccountNumber", TestProcessorBankAccountNumber)
+	t.Run("ProcessorRoutingNumber", TestProcessorRoutingNumber)
+	t.Run("abaCheckDigit", TestABACheckDigit)
+	t.Run("ProcessorCryptoAddress", TestProcessorCryptoAddress)
+	t.Run("ProcessorDeviceSerial", TestProcessorDeviceSerial)
+	t.Run("ProcessorIMEI", TestProcessorIMEI)
+	t.Run("ProcessorIMSI", TestProcessorIMSI)
+	t.Run("ProcessorUserAgent", TestProcessorUserAgent)
+	t.Run("ProcessorHostname", TestProcessorHostname)
+	t.Run("ProcessorFilePath", TestProcessorFilePath)
+	t.Run("ProcessorSocialHandle", TestProcessorSocialHandle)
+	t.Run("ProcessorSSN", TestProcessorSSN)
+
+	// api.go
+	t.Run("processorRegistry", TestProcessorRegistry)
+	t.Run("anonymizer", TestAnonymizer)
+	t.Run("consistencyStore", TestConsistencyStore)
+
+	// array.go
+	t.Run("mapPGArray", TestMapPGArray)
+	t.Run("processorArrayWrapper", TestProcessorArrayWrapper)
+
+	// audit.go
+	t.Run("auditDumpFiles", TestAuditDumpFiles)
+	t.Run("unchangedValue", TestUnchangedValue)
+	t.Run("reprocessUnchangedParent", TestReprocessUnchangedParent)
+
+	// blocklist.go
+	t.Run("loadNameBlocklist", TestLoadNameBlocklist)
+	t.Run("notBlockedName", TestNotBlockedName)
+
+	// bucket.go
+	t.Run("processorBucket", TestProcessorBucket)
+	t.Run("validateBucket", TestValidateBucket)
+
+	// campaign.go
+	t.Run("loadCampaign", TestLoadCampaign)
+	t.Run("runCampaign", TestRunCampaign)
+	t.Run("runCampaignTargetsIsolated", TestRunCampaignTargetsIsolated)
+
+	// categorical.go
+	t.Run("processorCategorical", TestProcessorCategorical)
+	t.Run("validateCategorical", TestValidateCategorical)
+
+	// condition.go
+	t.Run("processorConditionMatches", TestProcessorConditionMatches)
+	t.Run("processValueCondition", TestProcessValueCondition)
+	t.Run("validateCondition", TestValidateCondition)
+
+	// connstring.go
+	t.Run("processorConnectionString", TestProcessorConnectionString)
+
+	// coverage.go
+	t.Run("mapCoverage", TestMapCoverage)
+
+	// crypto_address.go
+	t.Run("base58CheckEncode", TestBase58CheckEncode)
+	t.Run("bech32Encode", TestBech32Encode)
+	t.Run("eip55Checksum", TestEIP55Checksum)
+	t.Run("fakeBitcoinAddress", TestFakeBitcoinAddress)
+
+	// currency.go
+	t.Run("processorRandomAmount", TestProcessorRandomAmount)
+	t.Run("currencyDecimals", TestCurrencyDecimals)
+
+	// datapack.go
+	t.Run("loadDataPack", TestLoadDataPack)
+
+	// dashboard.go
+	t.Run("dashboard", TestDashboard)
+	t.Run("dashboardPercent", TestDashboardPercent)
+
+	// dateshift.go
+	t.Run("processorDateShift", TestProcessorDateShift)
+	t.Run("validateDateShift", TestValidateDateShift)
+
+	// diff.go
+	t.Run("diffOutputs", TestDiffOutputs)
+
+	// distributed.go
+	t.Run("coordinator", TestCoordinator)
+	t.Run("coordinatorLease", TestCoordinatorLease)
+	t.Run("consistencyStateMerge", TestConsistencyStateMerge)
+
+	// duration.go
+	t.Run("processorRandomDuration", TestProcessorRandomDuration)
+	t.Run("perturbIntervalTime", TestPerturbIntervalTime)
+
+	// email.go
+	t.Run("processorEmailDomainPreserving", TestProcessorEmailDomainPreserving)
+	t.Run("validateEmailDomains", TestValidateEmailDomains)
+
+	// freetext.go
+	t.Run("processorFreeText", TestProcessorFreeText)
+	t.Run("freeTextNames", TestFreeTextNames)
+	t.Run("freeTextDataPackNames", TestFreeTextDataPackNames)
+
+	// fromregex.go
+	t.Run("fromRegex", TestFromRegex)
+	t.Run("processorFromRegex", TestProcessorFromRegex)
+	t.Run("validatePattern", TestValidatePattern)
+
+	// geo.go
+	t.Run("processorGeoFuzz", TestProcessorGeoFuzz)
+	t.Run("processorGeoSnap", TestProcessorGeoSnap)
+	t.Run("validateGeo", TestValidateGeo)
+
+	// generator.go
+	t.Run("processDumpFileSampleRows", TestProcessDumpFileSampleRows)
+	t.Run("processDumpFileStrict", TestProcessDumpFileStrict)
+	t.Run("processDumpFileBatch", TestProcessDumpFileBatch)
+	t.Run("processValueChain", TestProcessValueChain)
+	t.Run("processBatchChain", TestProcessBatchChain)
+	t.Run("runProcessorCache", TestRunProcessorCache)
+
+	// generated.go
+	t.Run("dropGeneratedColumns", TestDropGeneratedColumns)
+	t.Run("processDumpFileGeneratedColumns", TestProcessDumpFileGeneratedColumns)
+
+	// govid.go
+	t.Run("randomFormat", TestRandomFormat)
+	t.Run("processorDriversLicense", TestProcessorDriversLicense)
+	t.Run("validateFormats", TestValidateFormats)
+
+	// hipaa.go
+	t.Run("processorHIPAAAge", TestProcessorHIPAAAge)
+
+	// hmac.go
+	t.Run("processorHMACScrambler", TestProcessorHMACScrambler)
+	t.Run("validateHMACKey", TestValidateHMACKey)
+	t.Run("processorHashToken", TestProcessorHashToken)
+	t.Run("validateHashToken", TestValidateHashToken)
+
+	// histogram.go
+	t.Run("lengthHistogram", TestLengthHistogram)
+	t.Run("collectLengthHistograms", TestCollectLengthHistograms)
+	t.Run("fitLength", TestFitLength)
+
+	// iban.go
+	t.Run("fakeIBAN", TestFakeIBAN)
+	t.Run("fakeBIC", TestFakeBIC)
+	t.Run("processorIBAN", TestProcessorIBAN)
+
+	// identifier.go
+	t.Run("quoteIdentifier", TestQuoteIdentifier)
+	t.Run("parseCopyLineIdentifiers", TestParseCopyLineIdentifiers)
+	t.Run("columnMapperIdentifiers", TestColumnMapperIdentifiers)
+
+	// jsonb.go
+	t.Run("parseJSONSelector", TestParseJSONSelector)
+	t.Run("processorJSONB", TestProcessorJSONB)
+
+	// locations.go
+	t.Run("processorLocation", TestProcessorLocation)
+	t.Run("processRowLocationGroups", TestProcessRowLocationGroups)
+	t.Run("detectCountry", TestDetectCountry)
+	t.Run("processorCountryAddress", TestProcessorCountryAddress)
+	t.Run("localeCountry", TestLocaleCountry)
+	t.Run("processorLocale", TestProcessorLocale)
+	t.Run("processorCountry", TestProcessorCountry)
+
+	// lookup.go
+	t.Run("processorLookupFile", TestProcessorLookupFile)
+	t.Run("loadLookupFile", TestLoadLookupFile)
+	t.Run("validateLookupFile", TestValidateLookupFile)
+
+	// lru.go
+	t.Run("lruCache", TestLRUCache)
+
+	// mapper.go
+	t.Run("loadConfigSkeletonInclude", TestLoadConfigSkeletonInclude)
+	t.Run("validateReviewed", TestValidateReviewed)
+
+	// mappings.go
+	t.Run("saveMappings", TestSaveMappings)
+	t.Run("processDumpFileMappings", TestProcessDumpFileMappings)
+
+	// mappingstore.go
+	t.Run("mappingStores", TestMappingStores)
+	t.Run("redisStore", TestRedisStore)
+	t.Run("openMappingStore", TestOpenMappingStore)
+	t.Run("mappedValue", TestMappedValue)
+	t.Run("processDumpFileMappingStore", TestProcessDumpFileMappingStore)
+
+	// mappingstore_bounded.go
+	t.Run("boundedMappingStore", TestBoundedMappingStore)
+	t.Run("generateMapped", TestGenerateMapped)
+	t.Run("processDumpFileMaxMappings", TestProcessDumpFileMaxMappings)
+
+	// mask.go
+	t.Run("processorMaskPartial", TestProcessorMaskPartial)
+	t.Run("validateMaskPartial", TestValidateMaskPartial)
+
+	// maxlength.go
+	t.Run("maxLengthOutput", TestMaxLengthOutput)
+	t.Run("truncateMaxLength", TestTruncateMaxLength)
+	t.Run("validateMaxLength", TestValidateMaxLength)
+
+	// mock.go
+	t.Run("exportMockData", TestExportMockData)
+	t.Run("loadMockResources", TestLoadMockResources)
+	t.Run("unescapeCopyValue", TestUnescapeCopyValue)
+
+	// notify.go
+	t.Run("notifyRun", TestNotifyRun)
+	t.Run("parseNotifier", TestParseNotifier)
+
+	// null.go
+	t.Run("processorNull", TestProcessorNull)
+	t.Run("nullBehavior", TestNullBehavior)
+	t.Run("validateNullBehavior", TestValidateNullBehavior)
+
+	// patterns.go
+	t.Run("loadPIIPatterns", TestLoadPIIPatterns)
+	t.Run("matchPIIPatterns", TestMatchPIIPatterns)
+	t.Run("registerPIIPattern", TestRegisterPIIPattern)
+
+	// phone.go
+	t.Run("callingCodeLength", TestCallingCodeLength)
+	t.Run("fakePhoneFormat", TestFakePhoneFormat)
+	t.Run("processorPhoneNumberPreserveFormat", TestProcessorPhoneNumberPreserveFormat)
+
+	// pipeline.go
+	t.Run("readAhead", TestReadAhead)
+	t.Run("writeBehind", TestWriteBehind)
+	t.Run("processDumpFilePipelineDepth", TestProcessDumpFilePipelineDepth)
+
+	// presets.go
+	t.Run("mapPresets", TestMapPresets)
+	t.Run("findMapPreset", TestFindMapPreset)
+	t.Run("loadConfigSkeletonPreset", TestLoadConfigSkeletonPreset)
+
+	// protobuf.go
+	t.Run("processorProtobufPayload", TestProcessorProtobufPayload)
+	t.Run("readProtoVarint", TestReadProtoVarint)
+
+	// regexreplace.go
+	t.Run("processorRegexReplace", TestProcessorRegexReplace)
+	t.Run("validateRegexReplace", TestValidateRegexReplace)
+
+	// redaction.go
+	t.Run("processDumpFileRowRedaction", TestProcessDumpFileRowRedaction)
+	t.Run("rowRedactionRedact", TestRowRedactionRedact)
+	t.Run("validateRowRedactions", TestValidateRowRedactions)
+
+	// reprocess.go
+	t.Run("reprocessDumpFile", TestReprocessDumpFile)
+	t.Run("reprocessMappingsFile", TestReprocessMappingsFile)
+	t.Run("reprocessDatabaseRow", TestReprocessDatabaseRow)
+
+	// response.go
+	t.Run("processorRandomizedResponse", TestProcessorRandomizedResponse)
+	t.Run("validateRandomizedResponse", TestValidateRandomizedResponse)
+
+	// rowfilter.go
+	t.Run("processDumpFileRowFilter", TestProcessDumpFileRowFilter)
+	t.Run("rowFilterKeeps", TestRowFilterKeeps)
+	t.Run("validateRowFilters", TestValidateRowFilters)
+
+	// scanner.go
+	t.Run("dumpScanner", TestDumpScanner)
+	t.Run("passThrough", TestPassThrough)
+
+	// seed.go
+	t.Run("setSeed", TestSetSeed)
+	t.Run("processDumpFileSeed", TestProcessDumpFileSeed)
+	t.Run("setSecureRandom", TestSetSecureRandom)
+	t.Run("processDumpFileSecureRandom", TestProcessDumpFileSecureRandom)
+
+	// sequence.go
+	t.Run("processorSequentialInt", TestProcessorSequentialInt)
+
+	// shard.go
+	t.Run("processDumpFileShards", TestProcessDumpFileShards)
+	t.Run("mergeShardReports", TestMergeShardReports)
+	t.Run("planShards", TestPlanShards)
+
+	// simulate.go
+	t.Run("simulateDumpFile", TestSimulateDumpFile)
+
+	// smoke.go
+	t.Run("loadSmokeQueries", TestLoadSmokeQueries)
+	t.Run("smokeReport", TestSmokeReport)
+	t.Run("parsePGURI", TestParsePGURI)
+
+	// split.go
+	t.Run("splitDumpFileByTable", TestSplitDumpFileByTable)
+	t.Run("splitDumpFileBySize", TestSplitDumpFileBySize)
+	t.Run("parseSize", TestParseSize)
+
+	// stats.go
+	t.Run("compareDumpStatistics", TestCompareDumpStatistics)
+	t.Run("columnStats", TestColumnStats)
+	t.Run("statsValue", TestStatsValue)
+	t.Run("chiSquare", TestChiSquare)
+
+	// subnet.go
+	t.Run("processorSubnetIP", TestProcessorSubnetIP)
+	t.Run("validateSubnets", TestValidateSubnets)
+	t.Run("processorIPAddress", TestProcessorIPAddress)
+
+	// suggest.go
+	t.Run("suggestProcessors", TestSuggestProcessors)
+
+	// suppression.go
+	t.Run("loadSuppressionList", TestLoadSuppressionList)
+	t.Run("notSuppressed", TestNotSuppressed)
+
+	// timeout.go
+	t.Run("processorTimeout", TestProcessorTimeout)
+	t.Run("validateTimeout", TestValidateTimeout)
+
+	// unicode.go
+	t.Run("scrambleUnicode", TestScrambleUnicode)
+	t.Run("processorAlphaNumericScramblerUnicode", TestProcessorAlphaNumericScramblerUnicode)
+	t.Run("validateUnicodeMode", TestValidateUnicodeMode)
+
+	// url.go
+	t.Run("fakeURL", TestFakeURL)
+	t.Run("safeDomain", TestSafeDomain)
+	t.Run("processorURL", TestProcessorURL)
+
+	// useragent.go
+	t.Run("anonymizeUserAgent", TestAnonymizeUserAgent)
+
+	// valueclass.go
+	t.Run("classifyValue", TestClassifyValue)
+	t.Run("processorValueClass", TestProcessorValueClass)
+
+	// vault.go
+	t.Run("vault", TestVault)
+	t.Run("processorTokenize", TestProcessorTokenize)
+
+	// watchdog.go
+	t.Run("watchdog", TestWatchdog)
 
 	// Below are tests that require the test database to be loaded into Postgres for testing functionality. This requires
 	// one to update the map file as well as create fake data in the testing/test_db.sql file when  updating users
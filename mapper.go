@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -20,6 +21,91 @@ type ProcessorDefinition struct {
 	Min      float64
 	Variance float64
 
+	// optional processor specific settings
+	PrefixLength     int      `json:",omitempty"`
+	IPv6PrefixLength int      `json:",omitempty"`
+	PreserveLength   bool     `json:",omitempty"`
+	PreserveCase     bool     `json:",omitempty"`
+	PreserveFormat   bool     `json:",omitempty"`
+	Keys             []string `json:",omitempty"`
+	DescriptorSet    string   `json:",omitempty"`
+	MessageType      string   `json:",omitempty"`
+	Group            string   `json:",omitempty"`
+	Field            string   `json:",omitempty"`
+	CountryColumn    string   `json:",omitempty"`
+	Locale           string   `json:",omitempty"`
+	SubjectColumn    string   `json:",omitempty"`
+	Currency         string   `json:",omitempty"`
+	CurrencyColumn   string   `json:",omitempty"`
+	UTC              bool     `json:",omitempty"`
+	Probability      float64  `json:",omitempty"`
+	Categories       []string `json:",omitempty"`
+
+	// relative weights of the Categories (see Categorical)
+	Weights []float64 `json:",omitempty"`
+
+	// column of the row holding the other coordinate of separate latitude and longitude columns (see GeoFuzz)
+	CoordinateColumn string `json:",omitempty"`
+
+	// format templates of generated identifiers by the value of FormatColumn (e.g. a state code), * for every other
+	// value (see FakeDriversLicense)
+	Formats      map[string]string `json:",omitempty"`
+	FormatColumn string            `json:",omitempty"`
+
+	// salt, number of characters (default 16), and encoding (hex, base32, or base62) of tokens (see HashToken)
+	Salt     string `json:",omitempty"`
+	Length   int    `json:",omitempty"`
+	Encoding string `json:",omitempty"`
+
+	// number of trailing characters kept (with PrefixLength leading characters) and the character replacing the
+	// others (see MaskPartial)
+	SuffixLength  int    `json:",omitempty"`
+	MaskCharacter string `json:",omitempty"`
+
+	// path of a CSV file of original and replacement values (see LookupFile)
+	LookupFile string `json:",omitempty"`
+
+	// keep the repeated characters of scrambled values and replace their non-ASCII letters and digits with those of
+	// the same script (script, default), with ASCII (ascii), or not at all (keep) (see AlphaNumericScrambler)
+	PreserveRepeats bool   `json:",omitempty"`
+	UnicodeMode     string `json:",omitempty"`
+
+	// keep the country calling code of phone numbers (see FakePhoneNumber)
+	PreserveCountryCode bool `json:",omitempty"`
+
+	// bucket width (starting at Min) or ascending lower bounds of the buckets, numbers or dates (see Bucket)
+	Width      float64  `json:",omitempty"`
+	Boundaries []string `json:",omitempty"`
+
+	// regular expression and replacement template with $1 and {{Processor $1}} references (see RegexReplace), or the
+	// regular expression of generated values (see FromRegex)
+	Regex       string `json:",omitempty"`
+	Replacement string `json:",omitempty"`
+
+	// source CIDR to documentation range CIDR (see FakeSubnetIP)
+	Subnets map[string]string `json:",omitempty"`
+
+	// domains (and their subdomains) whose URLs are kept (see FakeURL)
+	SafeDomains []string `json:",omitempty"`
+
+	// source e-mail domain to target domain, * for every other domain (see EmailDomainPreserving)
+	Domains map[string]string `json:",omitempty"`
+
+	// only run the processor for the rows matching the condition on another column
+	When *ProcessorCondition `json:",omitempty"`
+
+	// optional inner processors for wrapper processors (such as Base64Payload)
+	Processors []ProcessorDefinition `json:",omitempty"`
+
+	// reuse the output of earlier calls with the same input (see ProcessOptions.CacheSize)
+	Cache bool `json:",omitempty"`
+
+	// sample output lengths from the value lengths of the column in the dump file (see sampledLength)
+	LengthHistogram bool `json:",omitempty"`
+
+	// maximum time (e.g. "2s") a single value may take before the run is stopped (see callProcessor)
+	Timeout string `json:",omitempty"`
+
 	Comment string
 }
 
@@ -37,42 +123,72 @@ type ColumnMapper struct {
 
 	IsNullable bool
 
+	// GENERATED ALWAYS AS (...) STORED columns are computed by PostgreSQL and left out of the processed dump file
+	IsGenerated bool `json:",omitempty"`
+
+	// maximum number of characters of a varchar(n) or char(n) column, longer processor output is regenerated or
+	// truncated to fit (see maxLengthOutput)
+	MaxLength int `json:",omitempty"`
+
+	// regular expression the values of the column must match, e.g. of a CHECK constraint (see FromRegex)
+	Pattern string `json:",omitempty"`
+
+	// what happens to NULL values: preserve (default), nullify, or process (see NullBehaviorPreserve)
+	NullBehavior string `json:",omitempty"`
+
+	// privacy review sign-off (see DBMapper.ValidateReviewed)
+	ReviewedBy    string `json:",omitempty"`
+	ReviewedAt    string `json:",omitempty"`
+	Justification string `json:",omitempty"`
+
 	Processors []ProcessorDefinition
 }
 
+// processorDefinition returns the ProcessorDefinition for the named processor (as named in the ProcessorCatalog) from
+// the column's list of processors. If the processor is not listed an empty definition is returned so the processor can
+// fall back to its default behavior.
+func (cmap *ColumnMapper) processorDefinition(name string) ProcessorDefinition {
+	for _, procDef := range cmap.Processors {
+		if procDef.Name == name {
+			return procDef
+		}
+	}
+	return ProcessorDefinition{}
+}
+
 // DBMapper is the main structure for the map file JSON object and is used to map all database columns that will be
 // anonymized.
 type DBMapper struct {
 	DBName       string
 	SchemaPrefix string
 	Seed         int64
+	Include      []string `json:",omitempty"`
 	ColumnMaps   []ColumnMapper
+
+	// RowFilters select the rows of tables that are written to the processed dump file (see RowFilter)
+	RowFilters []RowFilter `json:",omitempty"`
+
+	// RowRedactions redact whole rows of tables (see RowRedaction)
+	RowRedactions []RowRedaction `json:",omitempty"`
 }
 
 // ColumnMapper returns the address of the ColumnMapper object if it matches the given parameters otherwise it returns
 // nil. Special cases exist for sharded schemas using the schema-prefix. See documentation for details.
 func (dbMap DBMapper) ColumnMapper(schemaName, tableName, columnName string) *ColumnMapper {
 
-	// Some names may contain quotes if the name is a reserved word. For example tableName public.order would be a
-	// conflict with ORDER BY so PSQL will add quotes to the name. I.E. public."order". Remove the quotes so we can match
-	// whatever is in the map file.
-	if strings.Contains(schemaName, "\"") {
-		schemaName = strings.Replace(schemaName, "\"", "", -1)
-	}
-	if strings.Contains(tableName, "\"") {
-		tableName = strings.Replace(tableName, "\"", "", -1)
-	}
-	if strings.Contains(columnName, "\"") {
-		columnName = strings.Replace(columnName, "\"", "", -1)
-	}
+	// Some names may contain quotes if the name is a reserved word, mixed-case, or contains spaces or dots. For example
+	// tableName public.order would be a conflict with ORDER BY so PSQL will add quotes to the name. I.E. public."order".
+	// Unquote the names (in the dump file and the map file) so they match.
+	schemaName = unquoteIdentifier(schemaName)
+	tableName = unquoteIdentifier(tableName)
+	columnName = unquoteIdentifier(columnName)
 	for _, cmap := range dbMap.ColumnMaps {
-		//log.Infoln("dbMap.SchemaPrefix-> ", dbMap.SchemaPrefix)
-		//log.Infoln("schemaName-> ", schemaName)
+		mapTable, mapColumn := unquoteIdentifier(cmap.TableName), unquoteIdentifier(cmap.ColumnName)
 
-		if len(dbMap.SchemaPrefix) > 0 && strings.HasPrefix(schemaName, dbMap.SchemaPrefix) && cmap.TableName == tableName &&
-			cmap.ColumnName == columnName {
+		if len(dbMap.SchemaPrefix) > 0 && strings.HasPrefix(schemaName, dbMap.SchemaPrefix) && mapTable == tableName &&
+			mapColumn == columnName {
 			return &cmap
-		} else if cmap.TableSchema == schemaName && cmap.TableName == tableName && cmap.ColumnName == columnName {
+		} else if unquoteIdentifier(cmap.TableSchema) == schemaName && mapTable == tableName && mapColumn == columnName {
 			return &cmap
 		}
 	}
@@ -84,6 +200,131 @@ func (dbMap *DBMapper) Validate() error {
 	if len(dbMap.DBName) == 0 {
 		return errors.New("Expected non-empty DBName")
 	}
+	for _, cmap := range dbMap.ColumnMaps {
+		if err := cmap.validateNullBehavior(); err != nil {
+			return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+		}
+		if err := cmap.validateMaxLength(); err != nil {
+			return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+		}
+		if err := cmap.validatePattern(); err != nil {
+			return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+		}
+		for _, procDef := range cmap.Processors {
+			if err := procDef.validateName(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateTimeout(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateValueClasses(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateSubnets(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateRandomizedResponse(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateJSONB(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateArrayWrapper(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateDateShift(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateEmailDomains(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateIPPrefixLengths(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateBucket(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateRegexReplace(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateLocale(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateGeo(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateFormats(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateHashToken(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateMaskPartial(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateCategorical(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateLookupFile(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateCondition(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+			if err := procDef.validateUnicodeMode(); err != nil {
+				return fmt.Errorf("%s.%s.%s: %s", cmap.TableSchema, cmap.TableName, cmap.ColumnName, err)
+			}
+		}
+	}
+	for i := range dbMap.RowFilters {
+		if err := dbMap.RowFilters[i].validate(); err != nil {
+			return err
+		}
+	}
+	for i := range dbMap.RowRedactions {
+		if err := dbMap.RowRedactions[i].validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateName checks that the processor, and every inner processor, is registered in the ProcessorCatalog (see
+// RegisterProcessor).
+func (procDef ProcessorDefinition) validateName() error {
+	if _, ok := ProcessorCatalog[procDef.Name]; !ok {
+		return fmt.Errorf("Unknown processor %q. Expected one of %s", procDef.Name,
+			strings.Join(RegisteredProcessors(), ", "))
+	}
+	for _, inner := range procDef.Processors {
+		if err := inner.validateName(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateReviewed is used to verify that every column in the map has been signed off by a privacy review. A column is
+// reviewed when ReviewedBy and ReviewedAt are set. Columns that are left as-is using the Identity processor must also
+// contain a Justification.
+func (dbMap *DBMapper) ValidateReviewed() error {
+	var unreviewed []string
+
+	for _, cmap := range dbMap.ColumnMaps {
+		column := fmt.Sprintf("%s.%s.%s", cmap.TableSchema, cmap.TableName, cmap.ColumnName)
+		if len(cmap.ReviewedBy) == 0 || len(cmap.ReviewedAt) == 0 {
+			unreviewed = append(unreviewed, column)
+		} else if len(cmap.Justification) == 0 && len(cmap.processorDefinition("Identity").Name) > 0 {
+			unreviewed = append(unreviewed, column+" (Identity requires a Justification)")
+		}
+	}
+
+	if len(unreviewed) > 0 {
+		for _, column := range unreviewed {
+			log.Error("Unreviewed column: ", column)
+		}
+		return fmt.Errorf("Expected all columns to be reviewed, found %d unreviewed columns", len(unreviewed))
+	}
 	return nil
 }
 
@@ -146,8 +387,40 @@ func WriteConfigSkeleton(dbmap *DBMapper, filepath string) error {
 }
 
 // LoadConfigSkeleton will load the column-map into memory for use in dumping, processing, and loading of SQL files.
+// Any map files listed in Include are loaded and merged into the column-map. See mergeMap for details.
 func LoadConfigSkeleton(givenPathToFile string) (*DBMapper, error) {
-	pathToFile := givenPathToFile
+	dbmap, err := readMapFile(givenPathToFile, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	err = dbmap.Validate()
+	if err != nil {
+		log.Error(err)
+		log.Error("dbmap: ", dbmap)
+		return nil, err
+	}
+
+	return dbmap, nil
+}
+
+// readMapFile will decode the map file found at givenPathToFile and merge in every map file (or map preset, see
+// MapPresetPrefix) listed in its Include field. Relative include paths are relative to the directory of the including
+// map file. When a column is defined in
+// more than one included map the first include wins. The seen map contains the map files currently being loaded and is
+// used to detect include cycles.
+func readMapFile(givenPathToFile string, seen map[string]bool) (*DBMapper, error) {
+	pathToFile, err := filepath.Abs(givenPathToFile)
+	if err != nil {
+		log.Error(err)
+		log.Error("givenPathToFile: ", givenPathToFile)
+		return nil, err
+	}
+	if seen[pathToFile] {
+		return nil, fmt.Errorf("Map file %s includes itself", givenPathToFile)
+	}
+	seen[pathToFile] = true
+	defer delete(seen, pathToFile)
 
 	f, err := os.Open(pathToFile)
 	if err != nil {
@@ -170,16 +443,89 @@ func LoadConfigSkeleton(givenPathToFile string) (*DBMapper, error) {
 		return nil, err
 	}
 
-	err = dbmap.Validate()
-	if err != nil {
-		log.Error(err)
-		log.Error("dbmap: ", dbmap)
-		return nil, err
+	for _, include := range dbmap.Include {
+		if strings.HasPrefix(include, MapPresetPrefix) {
+			preset, err := findMapPreset(include)
+			if err != nil {
+				log.Error(err)
+				log.Error("givenPathToFile: ", givenPathToFile)
+				return nil, err
+			}
+			log.Debugf("Including map preset: %s@%d", preset.Name, preset.Version)
+			dbmap.mergeMap(preset.presetMap())
+			continue
+		}
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(pathToFile), include)
+		}
+		log.Debug("Including map file: ", include)
+		base, err := readMapFile(include, seen)
+		if err != nil {
+			log.Error("Unable to include map file: ", include)
+			log.Error("givenPathToFile: ", givenPathToFile)
+			return nil, err
+		}
+		dbmap.mergeMap(base)
 	}
 
 	return dbmap, nil
 }
 
+// mergeMap will merge the included base map into dbMap. Columns defined in dbMap override the base map columns with
+// the same schema, table, and column name, row filters and redactions those with the same schema and table name.
+// DBName, SchemaPrefix, and Seed are only taken from the base map when they are not set in dbMap.
+func (dbMap *DBMapper) mergeMap(base *DBMapper) {
+	if len(dbMap.DBName) == 0 {
+		dbMap.DBName = base.DBName
+	}
+	if len(dbMap.SchemaPrefix) == 0 {
+		dbMap.SchemaPrefix = base.SchemaPrefix
+	}
+	if dbMap.Seed == 0 {
+		dbMap.Seed = base.Seed
+	}
+
+	for _, cmap := range base.ColumnMaps {
+		overridden := false
+		for _, override := range dbMap.ColumnMaps {
+			if override.TableSchema == cmap.TableSchema && override.TableName == cmap.TableName &&
+				override.ColumnName == cmap.ColumnName {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			dbMap.ColumnMaps = append(dbMap.ColumnMaps, cmap)
+		}
+	}
+
+	// Row filters and redactions are merged the same way, by schema and table name
+	for _, filter := range base.RowFilters {
+		overridden := false
+		for _, override := range dbMap.RowFilters {
+			if override.TableSchema == filter.TableSchema && override.TableName == filter.TableName {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			dbMap.RowFilters = append(dbMap.RowFilters, filter)
+		}
+	}
+	for _, redaction := range base.RowRedactions {
+		overridden := false
+		for _, override := range dbMap.RowRedactions {
+			if override.TableSchema == redaction.TableSchema && override.TableName == redaction.TableName {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			dbMap.RowRedactions = append(dbMap.RowRedactions, redaction)
+		}
+	}
+}
+
 // findColumn searches the in-memory loaded column map using the specified parameters.
 func findColumn(columns []ColumnMapper, columnName, tableName, schemaPrefix, schema,
 	dataType string) (col ColumnMapper) {
@@ -201,8 +547,8 @@ func findColumn(columns []ColumnMapper, columnName, tableName, schemaPrefix, sch
 }
 
 // addColumn creates a ColumnMapper structure based on the input parameters.
-func addColumn(columnName, tableName, schema, dataType string, ordinalPosition int,
-	isNullable bool) ColumnMapper {
+func addColumn(columnName, tableName, schema, dataType string, ordinalPosition, maxLength int,
+	isNullable, isGenerated bool) ColumnMapper {
 	col := ColumnMapper{}
 
 	col.Processors = []ProcessorDefinition{
@@ -214,7 +560,9 @@ func addColumn(columnName, tableName, schema, dataType string, ordinalPosition i
 	col.ColumnName = columnName
 	col.DataType = dataType
 	col.OrdinalPosition = ordinalPosition
+	col.MaxLength = maxLength
 	col.IsNullable = isNullable
+	col.IsGenerated = isGenerated
 	col.TableSchema = schema
 
 	return col
@@ -286,7 +634,9 @@ func mapColumns(db *sql.DB, columns []ColumnMapper, schemaPrefix, schema string,
 			columnName      string
 			dataType        string
 			ordinalPosition int
+			maxLength       int
 			isNullable      bool
+			isGenerated     bool
 			exclude         bool
 			col             ColumnMapper
 		)
@@ -300,7 +650,9 @@ func mapColumns(db *sql.DB, columns []ColumnMapper, schemaPrefix, schema string,
 				&columnName,
 				&dataType,
 				&ordinalPosition,
+				&maxLength,
 				&isNullable,
+				&isGenerated,
 			)
 
 			// If we are working on a schema prefix, make sure to use the schema prefix + * as a name, otherwise empty
@@ -327,9 +679,21 @@ func mapColumns(db *sql.DB, columns []ColumnMapper, schemaPrefix, schema string,
 			// add to the column map
 			col = findColumn(columns, columnName, tableName, schemaPrefix, schema, dataType)
 			if col.TableSchema == "" && col.ColumnName == "" {
-				col = addColumn(columnName, tableName, schema, dataType, ordinalPosition, isNullable)
+				col = addColumn(columnName, tableName, schema, dataType, ordinalPosition, maxLength, isNullable,
+					isGenerated)
 				// Continuously append into the column map (old and new together)
 				columns = append(columns, col)
+			} else if isGenerated || maxLength > 0 {
+				// Mark generated columns and set the length limit of existing map files as well
+				for i := range columns {
+					if columns[i].TableSchema == col.TableSchema && columns[i].TableName == col.TableName &&
+						columns[i].ColumnName == col.ColumnName {
+						columns[i].IsGenerated = columns[i].IsGenerated || isGenerated
+						if columns[i].MaxLength == 0 {
+							columns[i].MaxLength = maxLength
+						}
+					}
+				}
 			}
 		}
 
@@ -37,3 +37,55 @@ func TestLoadConfigSkeleton(t *testing.T) {
 	_, err = LoadConfigSkeleton("/dev/null")
 	require.NotNil(t, err)
 }
+
+func TestLoadConfigSkeletonInclude(t *testing.T) {
+	dbmap, err := LoadConfigSkeleton(TestIncludeMapFile)
+	require.Nil(t, err)
+	require.Equal(t, "pii_include_test", dbmap.DBName)
+	require.Equal(t, int64(1542749714), dbmap.Seed)
+	require.Len(t, dbmap.ColumnMaps, 2)
+
+	// Columns in the including map override the base map
+	cmap := dbmap.ColumnMapper("public", "purchasers", "first_name")
+	require.NotNil(t, cmap)
+	require.Equal(t, "Identity", cmap.Processors[0].Name)
+
+	cmap = dbmap.ColumnMapper("public", "purchasers", "email")
+	require.NotNil(t, cmap)
+	require.Equal(t, "FakeEmailAddress", cmap.Processors[0].Name)
+
+	_, err = LoadConfigSkeleton(TestIncludeCycleMapFile)
+	require.NotNil(t, err)
+}
+
+func TestValidateReviewed(t *testing.T) {
+	dbmap, err := LoadConfigSkeleton(TestMapFile)
+	require.Nil(t, err)
+	require.NotNil(t, dbmap.ValidateReviewed())
+
+	dbmap = &DBMapper{
+		DBName: "pii_localtest",
+		ColumnMaps: []ColumnMapper{
+			{
+				TableSchema: "public",
+				TableName:   "purchasers",
+				ColumnName:  "email",
+				ReviewedBy:  "privacy@example.com",
+				ReviewedAt:  "2019-06-01",
+				Processors:  []ProcessorDefinition{{Name: "FakeEmailAddress"}},
+			},
+			{
+				TableSchema: "public",
+				TableName:   "purchasers",
+				ColumnName:  "id",
+				ReviewedBy:  "privacy@example.com",
+				ReviewedAt:  "2019-06-01",
+				Processors:  []ProcessorDefinition{{Name: "Identity"}},
+			},
+		},
+	}
+	require.NotNil(t, dbmap.ValidateReviewed())
+
+	dbmap.ColumnMaps[1].Justification = "Sequential ids do not identify a purchaser"
+	require.Nil(t, dbmap.ValidateReviewed())
+}
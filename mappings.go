@@ -0,0 +1,92 @@
+package gonymizer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MappingsKeyEnv is the environment variable the CLI reads the key (64 hex characters) of the mappings file from (see
+// ParseVaultKey).
+const MappingsKeyEnv = "GONYMIZER_MAPPINGS_KEY"
+
+// mappingsMagic is written at the start of every mappings file and authenticated with its content.
+const mappingsMagic = "GONYMIZER-MAPPINGS-1\n"
+
+// SaveMappings will save the consistent mappings (AlphaNumericMap and UUIDMap) to path, encrypted with AES-256-GCM
+// using the 256-bit key. Loading the file before processing a later dump (see LoadMappings) maps the values seen before
+// the same way, so consistency is kept across dumps processed on different days.
+func SaveMappings(path string, key []byte) error {
+	aead, err := mappingsCipher(key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(currentConsistencyState())
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+
+	output := append([]byte(mappingsMagic), nonce...)
+	output = aead.Seal(output, nonce, data, []byte(mappingsMagic))
+	tmpFile := path + ".tmp"
+	if err = os.Remove(tmpFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err = ioutil.WriteFile(tmpFile, output, 0600); err != nil {
+		log.Error("Unable to write mappings: ", err)
+		return err
+	}
+	return os.Rename(tmpFile, path)
+}
+
+// LoadMappings will add the consistent mappings saved by SaveMappings to AlphaNumericMap and UUIDMap. The key must be
+// the key the file was saved with.
+func LoadMappings(path string, key []byte) error {
+	aead, err := mappingsCipher(key)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if len(data) < len(mappingsMagic)+aead.NonceSize() || string(data[:len(mappingsMagic)]) != mappingsMagic {
+		return fmt.Errorf("%s is not a mappings file", path)
+	}
+	nonce := data[len(mappingsMagic) : len(mappingsMagic)+aead.NonceSize()]
+	data, err = aead.Open(nil, nonce, data[len(mappingsMagic)+aead.NonceSize():], []byte(mappingsMagic))
+	if err != nil {
+		return fmt.Errorf("Wrong key or modified mappings file %s", path)
+	}
+
+	var state ConsistencyState
+	if err = json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("Unable to parse mappings %s: %s", path, err)
+	}
+	loadConsistencyState(state)
+	return nil
+}
+
+// mappingsCipher returns the AES-256-GCM cipher of the mappings file key.
+func mappingsCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("Expected a mappings key of 256 bits")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,85 @@
+package gonymizer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveMappings(t *testing.T) {
+	alphaNumericMap, uuidMap := AlphaNumericMap, UUIDMap
+	defer func() { AlphaNumericMap, UUIDMap = alphaNumericMap, uuidMap }()
+
+	dir, err := ioutil.TempDir("", "gonymizer-mappings")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mappings.bin")
+	key := bytes.Repeat([]byte{7}, 32)
+
+	Consistency().Reset()
+	AlphaNumericMap["public.users.id"] = map[string]string{"jane.doe": "xkqp.zrw"}
+	input, output := uuid.New(), uuid.New()
+	UUIDMap[input] = output
+
+	// A temporary file left behind readable by everyone is not reused
+	require.Nil(t, ioutil.WriteFile(path+".tmp", nil, 0644))
+	require.Nil(t, SaveMappings(path, key))
+
+	// The file is encrypted
+	data, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	require.False(t, bytes.Contains(data, []byte("jane.doe")))
+	info, err := os.Stat(path)
+	require.Nil(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	Consistency().Reset()
+	require.Nil(t, LoadMappings(path, key))
+	require.Equal(t, "xkqp.zrw", AlphaNumericMap["public.users.id"]["jane.doe"])
+	require.Equal(t, output, UUIDMap[input])
+
+	require.NotNil(t, LoadMappings(path, bytes.Repeat([]byte{8}, 32)))
+	require.NotNil(t, LoadMappings(path, []byte("short")))
+	require.NotNil(t, SaveMappings(path, []byte("short")))
+	require.True(t, os.IsNotExist(LoadMappings(filepath.Join(dir, "missing.bin"), key)))
+
+	data[len(data)-1] ^= 1
+	require.Nil(t, ioutil.WriteFile(path, data, 0600))
+	require.NotNil(t, LoadMappings(path, key))
+	require.Nil(t, ioutil.WriteFile(path, []byte("{}"), 0600))
+	require.NotNil(t, LoadMappings(path, key))
+}
+
+func TestProcessDumpFileMappings(t *testing.T) {
+	alphaNumericMap, uuidMap := AlphaNumericMap, UUIDMap
+	defer func() { AlphaNumericMap, UUIDMap = alphaNumericMap, uuidMap }()
+
+	dir, err := ioutil.TempDir("", "gonymizer-mappings")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	opts := ProcessOptions{MappingsFile: filepath.Join(dir, "mappings.bin"), MappingsKey: bytes.Repeat([]byte{7}, 32)}
+
+	columnMap, err := LoadConfigSkeleton(TestMapFile)
+	require.Nil(t, err)
+
+	// A run with another seed (on another day) maps the values seen before the same way
+	Consistency().Reset()
+	columnMap.Seed = 1
+	require.Nil(t, ProcessDumpFileWithOptions(columnMap, TestDbFile, TestSeedDumpFile, "", "", false, opts))
+	require.NotEmpty(t, UUIDMap)
+	first := map[uuid.UUID]uuid.UUID{}
+	for input, output := range UUIDMap {
+		first[input] = output
+	}
+
+	Consistency().Reset()
+	columnMap.Seed = 2
+	require.Nil(t, ProcessDumpFileWithOptions(columnMap, TestDbFile, TestSeedDumpFile, "", "", false, opts))
+	require.Equal(t, first, UUIDMap)
+	require.Nil(t, os.Remove(TestSeedDumpFile))
+}
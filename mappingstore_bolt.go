@@ -0,0 +1,113 @@
+package gonymizer
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltStore is a MappingStore keeping the mappings in a BoltDB file, a bucket per namespace. Writes are not synced to
+// disk until the store is closed, so a run that does not finish leaves a file that may miss its last mappings.
+type boltStore struct {
+	db *bolt.DB
+}
+
+// openBoltStore opens (or creates) the BoltDB file at path.
+func openBoltStore(path string) (MappingStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 10 * time.Second, NoSync: true})
+	if err != nil {
+		return nil, err
+	}
+	return &boltStore{db: db}, nil
+}
+
+// Get returns the value mapped to key in the namespace, and false if key is not mapped.
+func (store *boltStore) Get(namespace, key string) (string, bool, error) {
+	var (
+		value string
+		ok    bool
+	)
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(namespace))
+		if bucket == nil {
+			return nil
+		}
+		if data := bucket.Get([]byte(key)); data != nil {
+			value, ok = string(data), true
+		}
+		return nil
+	})
+	return value, ok, err
+}
+
+// Add maps key to value in the namespace unless key is mapped already, and returns the mapped value.
+func (store *boltStore) Add(namespace, key, value string) (string, error) {
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(namespace))
+		if err != nil {
+			return err
+		}
+		if data := bucket.Get([]byte(key)); data != nil {
+			value = string(data)
+			return nil
+		}
+		return bucket.Put([]byte(key), []byte(value))
+	})
+	return value, err
+}
+
+// Set maps key to value in the namespace, replacing the value mapped before.
+func (store *boltStore) Set(namespace, key, value string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(namespace))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), []byte(value))
+	})
+}
+
+// Delete removes the mapping of key in the namespace, if any.
+func (store *boltStore) Delete(namespace, key string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket([]byte(namespace)); bucket != nil {
+			return bucket.Delete([]byte(key))
+		}
+		return nil
+	})
+}
+
+// Len returns the number of keys mapped in the namespace.
+func (store *boltStore) Len(namespace string) (int, error) {
+	count := 0
+	err := store.db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket([]byte(namespace)); bucket != nil {
+			count = bucket.Stats().KeyN
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Next returns the next number of the sequence of the namespace's bucket.
+func (store *boltStore) Next(namespace string) (int64, error) {
+	var next uint64
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(namespace))
+		if err != nil {
+			return err
+		}
+		next, err = bucket.NextSequence()
+		return err
+	})
+	return int64(next), err
+}
+
+// Close syncs the mappings to disk and closes the BoltDB file.
+func (store *boltStore) Close() error {
+	if err := store.db.Sync(); err != nil {
+		store.db.Close()
+		return err
+	}
+	return store.db.Close()
+}
@@ -0,0 +1,197 @@
+package gonymizer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/icrowley/fake"
+)
+
+// boundedStore is an in-memory MappingStore holding at most capacity mappings. When it is full the least recently
+// used mapping is evicted. Values are generated with the random number generators seeded from the HMAC of the
+// namespace and key with the secret of the store (see generateMapped), so an evicted mapping is generated again with
+// the same value. The mappings of SequentialInt columns cannot be generated again and are never evicted.
+type boundedStore struct {
+	cache     *lruCache
+	counts    map[string]int
+	sequences memoryMappings
+	secret    []byte
+}
+
+// mappedSeed is the seed of the value generated by generateMapped, mappedDepth the number of values being generated
+// (a URL token generated for a URL of a consistent column).
+var (
+	mappedSeed  int64
+	mappedDepth int
+)
+
+// memoryMappings are mappings by namespace and key that are kept in memory without a bound.
+type memoryMappings map[string]map[string]string
+
+// NewBoundedMappingStore returns an in-memory MappingStore holding at most capacity mappings, for dumps with more
+// distinct values than fit in memory. Values of evicted mappings are generated again from the secret, which should be
+// the HMAC key of the run (see ProcessOptions.HMACKey) to keep them consistent across runs. Without a secret one is
+// drawn from the random number generator of the processors, so a seeded run (see SetSeed) is still reproducible.
+func NewBoundedMappingStore(capacity int, secret []byte) MappingStore {
+	if len(secret) == 0 {
+		secret = make([]byte, sha256.Size)
+		readRandom(secret)
+	}
+	store := &boundedStore{
+		cache:     newLRUCache(capacity),
+		counts:    map[string]int{},
+		sequences: memoryMappings{},
+		secret:    secret,
+	}
+	store.cache.onEvict = func(key, value string) {
+		store.counts[key[:strings.IndexByte(key, 0)]]--
+	}
+	return store
+}
+
+// Get returns the value mapped to key in the namespace, and false if key is not mapped (or has been evicted).
+func (store *boundedStore) Get(namespace, key string) (string, bool, error) {
+	if strings.HasPrefix(namespace, sequenceMapKey+":") {
+		value, ok := store.sequences[namespace][key]
+		return value, ok, nil
+	}
+	value, ok := store.cache.get(namespace + "\x00" + key)
+	return value, ok, nil
+}
+
+// Add maps key to value in the namespace unless key is mapped already, and returns the mapped value.
+func (store *boundedStore) Add(namespace, key, value string) (string, error) {
+	if mapped, ok, _ := store.Get(namespace, key); ok {
+		return mapped, nil
+	}
+	return value, store.Set(namespace, key, value)
+}
+
+// Set maps key to value in the namespace, replacing the value mapped before.
+func (store *boundedStore) Set(namespace, key, value string) error {
+	if strings.HasPrefix(namespace, sequenceMapKey+":") {
+		if store.sequences[namespace] == nil {
+			store.sequences[namespace] = map[string]string{}
+		}
+		store.sequences[namespace][key] = value
+		return nil
+	}
+	if _, ok := store.cache.items[namespace+"\x00"+key]; !ok {
+		store.counts[namespace]++
+	}
+	store.cache.add(namespace+"\x00"+key, value)
+	return nil
+}
+
+// Delete removes the mapping of key in the namespace, if any.
+func (store *boundedStore) Delete(namespace, key string) error {
+	if strings.HasPrefix(namespace, sequenceMapKey+":") {
+		delete(store.sequences[namespace], key)
+		return nil
+	}
+	if store.cache.remove(namespace + "\x00" + key) {
+		store.counts[namespace]--
+	}
+	return nil
+}
+
+// Len returns the number of keys mapped in the namespace that have not been evicted.
+func (store *boundedStore) Len(namespace string) (int, error) {
+	if strings.HasPrefix(namespace, sequenceMapKey+":") {
+		return len(store.sequences[namespace]), nil
+	}
+	return store.counts[namespace], nil
+}
+
+// Next returns one more than the number of keys mapped in the namespace that have not been evicted. Keys of
+// SequentialInt columns are never evicted, so their numbers are unique.
+func (store *boundedStore) Next(namespace string) (int64, error) {
+	count, err := store.Len(namespace)
+	return int64(count) + 1, err
+}
+
+// Close does nothing, the mappings are kept in memory.
+func (store *boundedStore) Close() error {
+	return nil
+}
+
+// seed returns the seed of the random number generators for the mapping of key in the namespace: the HMAC of the
+// namespace and key with the secret of the store.
+func (store *boundedStore) seed(namespace, key string) int64 {
+	mac := hmac.New(sha256.New, store.secret)
+	mac.Write([]byte(namespace + "\x00" + key))
+	return int64(binary.BigEndian.Uint64(mac.Sum(nil)))
+}
+
+// generateMapped runs generate to create the value mapped to key in the namespace. When the mapping store is a
+// bounded store the random number generators of the processors (including that of the fake data package) are seeded
+// for the key while generate runs (see boundedStore.seed), so the same key always gets the same value.
+func generateMapped(namespace, key string, generate func() string) string {
+	store, ok := mappingStore.(*boundedStore)
+	if !ok {
+		return generate()
+	}
+
+	seed := store.seed(namespace, key)
+	rngSource.mutex.Lock()
+	saved := rngSource.src
+	rngSource.src = rand.NewSource(seed)
+	rngSource.mutex.Unlock()
+	fake.Seed(seed)
+	parentSeed := mappedSeed
+	mappedSeed = seed
+	mappedDepth++
+	defer func() {
+		rngSource.mutex.Lock()
+		rngSource.src = saved
+		rngSource.mutex.Unlock()
+		mappedDepth--
+		mappedSeed = parentSeed
+		reseedMapped(namespace, key)
+	}()
+	return generate()
+}
+
+// reseedMapped seeds the fake data package after the mapping of key in the namespace has been generated or found. The
+// math/rand generator of the fake data package cannot be restored, so while another value is being generated it is
+// seeded from that value and the key, whether the mapping was generated or found in the store. The value being
+// generated then does not depend on the mappings evicted before.
+func reseedMapped(namespace, key string) {
+	store, ok := mappingStore.(*boundedStore)
+	if !ok {
+		return
+	}
+	if mappedDepth == 0 {
+		fake.Seed(rng.Int63())
+		return
+	}
+	fake.Seed(mappedSeed ^ store.seed(namespace, key))
+}
+
+// newMappedUUID returns a new random (version 4) UUID for the mapping of key in the namespace (see generateMapped).
+func newMappedUUID(namespace, key string) (uuid.UUID, error) {
+	if _, ok := mappingStore.(*boundedStore); !ok {
+		return uuid.NewRandom()
+	}
+
+	var output uuid.UUID
+	generateMapped(namespace, key, func() string {
+		readRandom(output[:])
+		return ""
+	})
+	output[6] = (output[6] & 0x0f) | 0x40 // Version 4
+	output[8] = (output[8] & 0x3f) | 0x80 // Variant is 10
+	return output, nil
+}
+
+// readRandom fills data (a multiple of 8 bytes long) with random bytes of rng. Unlike rng.Read it does not keep the
+// bytes left over for the next call, which would make the bytes depend on the calls before.
+func readRandom(data []byte) {
+	for i := 0; i+8 <= len(data); i += 8 {
+		binary.BigEndian.PutUint64(data[i:], rng.Uint64())
+	}
+}
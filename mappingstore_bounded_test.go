@@ -0,0 +1,122 @@
+package gonymizer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/icrowley/fake"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoundedMappingStore(t *testing.T) {
+	store := NewBoundedMappingStore(2, []byte("secret"))
+	for _, key := range []string{"jane", "john", "mary"} {
+		value, err := store.Add("public.users.id", key, key+"!")
+		require.Nil(t, err)
+		require.Equal(t, key+"!", value)
+	}
+
+	// The least recently used mapping is evicted
+	_, ok, err := store.Get("public.users.id", "jane")
+	require.Nil(t, err)
+	require.False(t, ok)
+	value, ok, err := store.Get("public.users.id", "mary")
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, "mary!", value)
+	count, err := store.Len("public.users.id")
+	require.Nil(t, err)
+	require.Equal(t, 2, count)
+
+	require.Nil(t, store.Set("public.users.id", "mary", "zrwm"))
+	value, _, _ = store.Get("public.users.id", "mary")
+	require.Equal(t, "zrwm", value)
+	count, _ = store.Len("public.users.id")
+	require.Equal(t, 2, count)
+
+	// SequentialInt mappings are never evicted
+	for _, key := range []string{"1", "2", "3"} {
+		_, err = store.Add(sequenceMapKey+":public.users.id", key, key)
+		require.Nil(t, err)
+	}
+	count, _ = store.Len(sequenceMapKey + ":public.users.id")
+	require.Equal(t, 3, count)
+	count, _ = store.Len("public.users.id")
+	require.Equal(t, 2, count)
+	require.Nil(t, store.Close())
+}
+
+func TestGenerateMapped(t *testing.T) {
+	SetSeed(1)
+	SetMappingStore(NewBoundedMappingStore(2, []byte("secret")))
+	defer SetMappingStore(nil)
+
+	outer := func() string {
+		return mappedValue("public.users.id", "jane", func() string {
+			return scrambleString("jane") + mappedValue("url", "users", func() string {
+				return scrambleString("users")
+			}) + fake.FirstName()
+		})
+	}
+	first := outer()
+	user := mappedValue("url", "users", nil)
+
+	// jane is evicted while users is kept, so jane is generated again finding users in the store
+	_, ok, _ := mappingStore.Get("url", "users")
+	require.True(t, ok)
+	mappedValue("url", "other", func() string { return "other" })
+	_, ok, _ = mappingStore.Get("public.users.id", "jane")
+	require.False(t, ok)
+	require.Equal(t, first, outer())
+
+	// Both are evicted and generated again
+	mappedValue("url", "one", func() string { return "one" })
+	mappedValue("url", "two", func() string { return "two" })
+	require.Equal(t, first, outer())
+	require.Equal(t, user, mappedValue("url", "users", nil))
+	require.Equal(t, 0, mappedDepth)
+
+	// Evicted UUIDs are generated again as well
+	input := "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	output, err := ProcessorRandomUUID(nil, input)
+	require.Nil(t, err)
+	mappedValue("url", "three", func() string { return "three" })
+	mappedValue("url", "four", func() string { return "four" })
+	again, err := ProcessorRandomUUID(nil, input)
+	require.Nil(t, err)
+	require.Equal(t, output, again)
+	require.NotEqual(t, input, output)
+
+	// Another secret maps the values differently
+	SetMappingStore(NewBoundedMappingStore(2, []byte("other secret")))
+	require.NotEqual(t, first, outer())
+}
+
+func TestProcessDumpFileMaxMappings(t *testing.T) {
+	alphaNumericMap, uuidMap := AlphaNumericMap, UUIDMap
+	defer func() { AlphaNumericMap, UUIDMap = alphaNumericMap, uuidMap }()
+
+	columnMap, err := LoadConfigSkeleton(TestMapFile)
+	require.Nil(t, err)
+	columnMap.Seed = 1
+
+	// A seeded run keeping a single mapping in memory is still reproducible
+	process := func() []byte {
+		Consistency().Reset()
+		opts := ProcessOptions{MaxMappings: 1}
+		require.Nil(t, ProcessDumpFileWithOptions(columnMap, TestDbFile, TestSeedDumpFile, "", "", false, opts))
+		output, err := ioutil.ReadFile(TestSeedDumpFile)
+		require.Nil(t, err)
+		return output
+	}
+	first := process()
+	require.Empty(t, UUIDMap)
+	require.Equal(t, memoryStore{}, mappingStore)
+	require.True(t, bytes.Equal(first, process()))
+	require.Nil(t, os.Remove(TestSeedDumpFile))
+
+	opts := ProcessOptions{MaxMappings: 1, MappingsFile: "mappings.bin"}
+	require.NotNil(t, ProcessDumpFileWithOptions(columnMap, TestDbFile, TestSeedDumpFile, "", "", false, opts))
+}
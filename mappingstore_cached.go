@@ -0,0 +1,86 @@
+package gonymizer
+
+import (
+	"sync"
+)
+
+// cachedMappings is the number of mappings a cachedStore keeps in memory.
+const cachedMappings = 100000
+
+// cachedStore is a MappingStore caching the most recently used mappings of a store kept on a server, so every value
+// seen before is mapped without a round trip. The value added first to a key is never replaced by Add, so the cached
+// mappings only go stale when another worker sharing the store calls Set for the same key.
+type cachedStore struct {
+	mutex sync.Mutex
+	store MappingStore
+	cache *lruCache
+}
+
+// newCachedStore returns a MappingStore caching up to capacity mappings of store.
+func newCachedStore(store MappingStore, capacity int) MappingStore {
+	return &cachedStore{store: store, cache: newLRUCache(capacity)}
+}
+
+// Get returns the value mapped to key in the namespace, and false if key is not mapped.
+func (store *cachedStore) Get(namespace, key string) (string, bool, error) {
+	store.mutex.Lock()
+	value, ok := store.cache.get(namespace + "\x00" + key)
+	store.mutex.Unlock()
+	if ok {
+		return value, true, nil
+	}
+
+	value, ok, err := store.store.Get(namespace, key)
+	if ok {
+		store.cached(namespace, key, value)
+	}
+	return value, ok, err
+}
+
+// Add maps key to value in the namespace unless key is mapped already, and returns the mapped value.
+func (store *cachedStore) Add(namespace, key, value string) (string, error) {
+	mapped, err := store.store.Add(namespace, key, value)
+	if err == nil {
+		store.cached(namespace, key, mapped)
+	}
+	return mapped, err
+}
+
+// Set maps key to value in the namespace, replacing the value mapped before.
+func (store *cachedStore) Set(namespace, key, value string) error {
+	err := store.store.Set(namespace, key, value)
+	if err == nil {
+		store.cached(namespace, key, value)
+	}
+	return err
+}
+
+// Delete removes the mapping of key in the namespace, if any.
+func (store *cachedStore) Delete(namespace, key string) error {
+	store.mutex.Lock()
+	store.cache.remove(namespace + "\x00" + key)
+	store.mutex.Unlock()
+	return store.store.Delete(namespace, key)
+}
+
+// Len returns the number of keys mapped in the namespace.
+func (store *cachedStore) Len(namespace string) (int, error) {
+	return store.store.Len(namespace)
+}
+
+// Next returns the next number of the counter of the namespace.
+func (store *cachedStore) Next(namespace string) (int64, error) {
+	return store.store.Next(namespace)
+}
+
+// Close closes the cached store.
+func (store *cachedStore) Close() error {
+	return store.store.Close()
+}
+
+// cached adds the mapping to the cache.
+func (store *cachedStore) cached(namespace, key, value string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.cache.add(namespace+"\x00"+key, value)
+}
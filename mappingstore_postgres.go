@@ -0,0 +1,105 @@
+package gonymizer
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// postgresMappingsTable is the table the Postgres mapping store keeps the mappings in, postgresCountersTable the table
+// with the counter of every namespace (see MappingStore.Next).
+const (
+	postgresMappingsTable = "gonymizer_mappings"
+	postgresCountersTable = "gonymizer_counters"
+)
+
+// postgresStore is a MappingStore keeping the mappings in a table of a PostgreSQL database (see
+// postgresMappingsTable).
+type postgresStore struct {
+	db *sql.DB
+}
+
+// openPostgresStore connects to the PostgreSQL database of the URL and creates the mappings and counters tables if
+// they do not exist.
+func openPostgresStore(url string) (MappingStore, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+	for _, query := range []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (namespace text NOT NULL, key text NOT NULL, value text NOT NULL,
+			PRIMARY KEY (namespace, key))`, postgresMappingsTable),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (namespace text PRIMARY KEY, value bigint NOT NULL)",
+			postgresCountersTable),
+	} {
+		if _, err = db.Exec(query); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &postgresStore{db: db}, nil
+}
+
+// Get returns the value mapped to key in the namespace, and false if key is not mapped.
+func (store *postgresStore) Get(namespace, key string) (string, bool, error) {
+	var value string
+	query := fmt.Sprintf("SELECT value FROM %s WHERE namespace = $1 AND key = $2", postgresMappingsTable)
+	err := store.db.QueryRow(query, namespace, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return value, err == nil, err
+}
+
+// Add maps key to value in the namespace unless key is mapped already, and returns the mapped value. The value is
+// added and the mapped value returned by a single statement. Only when another worker adds key at the same time is the
+// mapped value read with a second one.
+func (store *postgresStore) Add(namespace, key, value string) (string, error) {
+	var mapped string
+	query := fmt.Sprintf(`WITH added AS (INSERT INTO %[1]s (namespace, key, value) VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING RETURNING value)
+		SELECT value FROM added UNION ALL SELECT value FROM %[1]s WHERE namespace = $1 AND key = $2`,
+		postgresMappingsTable)
+	err := store.db.QueryRow(query, namespace, key, value).Scan(&mapped)
+	if err == sql.ErrNoRows {
+		mapped, _, err = store.Get(namespace, key)
+	}
+	return mapped, err
+}
+
+// Set maps key to value in the namespace, replacing the value mapped before.
+func (store *postgresStore) Set(namespace, key, value string) error {
+	query := fmt.Sprintf(`INSERT INTO %s (namespace, key, value) VALUES ($1, $2, $3)
+		ON CONFLICT (namespace, key) DO UPDATE SET value = EXCLUDED.value`, postgresMappingsTable)
+	_, err := store.db.Exec(query, namespace, key, value)
+	return err
+}
+
+// Delete removes the mapping of key in the namespace, if any.
+func (store *postgresStore) Delete(namespace, key string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE namespace = $1 AND key = $2", postgresMappingsTable)
+	_, err := store.db.Exec(query, namespace, key)
+	return err
+}
+
+// Len returns the number of keys mapped in the namespace.
+func (store *postgresStore) Len(namespace string) (int, error) {
+	var count int
+	query := fmt.Sprintf("SELECT count(*) FROM %s WHERE namespace = $1", postgresMappingsTable)
+	err := store.db.QueryRow(query, namespace).Scan(&count)
+	return count, err
+}
+
+// Next increments the counter of the namespace in the counters table and returns it. The row of the counter is
+// locked by the update, so every worker gets a number of its own.
+func (store *postgresStore) Next(namespace string) (int64, error) {
+	var next int64
+	query := fmt.Sprintf(`INSERT INTO %[1]s (namespace, value) VALUES ($1, 1)
+		ON CONFLICT (namespace) DO UPDATE SET value = %[1]s.value + 1 RETURNING value`, postgresCountersTable)
+	err := store.db.QueryRow(query, namespace).Scan(&next)
+	return next, err
+}
+
+// Close closes the connections to the database.
+func (store *postgresStore) Close() error {
+	return store.db.Close()
+}
@@ -0,0 +1,207 @@
+package gonymizer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisKeyPrefix is added to the namespace to get the key of the Redis hash holding its mappings. redisCountersKey is
+// the key of the hash with the counter of every namespace (see MappingStore.Next).
+const (
+	redisKeyPrefix   = "gonymizer:"
+	redisCountersKey = "gonymizer-counters"
+)
+
+// redisTimeout is the time connecting to the Redis server, or sending a command and reading its reply, may take.
+const redisTimeout = 10 * time.Second
+
+// redisAddScript adds the value unless the key is mapped already and returns the mapped value, in a single round trip.
+const redisAddScript = `if redis.call('HSETNX', KEYS[1], ARGV[1], ARGV[2]) == 1 then return ARGV[2] end
+return redis.call('HGET', KEYS[1], ARGV[1])`
+
+// redisStore is a MappingStore keeping the mappings in hashes of a Redis server (see redisKeyPrefix). It speaks the
+// few commands it needs of the Redis protocol (RESP) over a single connection. The connection is closed after an I/O
+// error (a reply may be left unread), so every later command fails with that error.
+type redisStore struct {
+	mutex  sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	err    error
+}
+
+// openRedisStore connects to the Redis server of the URL: redis://[:password@]host[:port][/database].
+func openRedisStore(uri string) (MappingStore, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	if len(u.Port()) == 0 {
+		host = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	conn, err := net.DialTimeout("tcp", host, redisTimeout)
+	if err != nil {
+		return nil, err
+	}
+	store := &redisStore{conn: conn, reader: bufio.NewReader(conn)}
+
+	if u.User != nil {
+		password, ok := u.User.Password()
+		if !ok {
+			password = u.User.Username()
+		}
+		if _, err = store.do("AUTH", password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if database := strings.Trim(u.Path, "/"); len(database) > 0 {
+		if _, err = store.do("SELECT", database); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// Get returns the value mapped to key in the namespace, and false if key is not mapped.
+func (store *redisStore) Get(namespace, key string) (string, bool, error) {
+	reply, err := store.do("HGET", redisKeyPrefix+namespace, key)
+	if err != nil || reply == nil {
+		return "", false, err
+	}
+	return *reply, true, nil
+}
+
+// Add maps key to value in the namespace unless key is mapped already, and returns the mapped value (see
+// redisAddScript).
+func (store *redisStore) Add(namespace, key, value string) (string, error) {
+	reply, err := store.do("EVAL", redisAddScript, "1", redisKeyPrefix+namespace, key, value)
+	if err != nil || reply == nil {
+		return "", err
+	}
+	return *reply, nil
+}
+
+// Set maps key to value in the namespace, replacing the value mapped before.
+func (store *redisStore) Set(namespace, key, value string) error {
+	_, err := store.do("HSET", redisKeyPrefix+namespace, key, value)
+	return err
+}
+
+// Delete removes the mapping of key in the namespace, if any.
+func (store *redisStore) Delete(namespace, key string) error {
+	_, err := store.do("HDEL", redisKeyPrefix+namespace, key)
+	return err
+}
+
+// Len returns the number of keys mapped in the namespace.
+func (store *redisStore) Len(namespace string) (int, error) {
+	reply, err := store.do("HLEN", redisKeyPrefix+namespace)
+	if err != nil || reply == nil {
+		return 0, err
+	}
+	return strconv.Atoi(*reply)
+}
+
+// Next increments the counter of the namespace and returns it.
+func (store *redisStore) Next(namespace string) (int64, error) {
+	reply, err := store.do("HINCRBY", redisCountersKey, namespace, "1")
+	if err != nil || reply == nil {
+		return 0, err
+	}
+	return strconv.ParseInt(*reply, 10, 64)
+}
+
+// Close closes the connection to the Redis server.
+func (store *redisStore) Close() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.err != nil {
+		return nil
+	}
+	store.err = errors.New("Redis connection is closed")
+	return store.conn.Close()
+}
+
+// do sends the command to the Redis server and returns its reply: nil for a nil reply, the text of a status, integer,
+// or bulk string reply otherwise. Error replies are returned as errors. The connection is closed when the command
+// cannot be sent or its reply read within redisTimeout.
+func (store *redisStore) do(args ...string) (*string, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.err != nil {
+		return nil, store.err
+	}
+
+	reply, err := store.roundTrip(args)
+	if _, ok := err.(redisError); err != nil && !ok {
+		store.conn.Close()
+		store.err = fmt.Errorf("Redis connection closed after an error: %s", err)
+	}
+	return reply, err
+}
+
+// redisError is an error reply of the Redis server. The connection can still be used after one.
+type redisError string
+
+// Error returns the error message.
+func (e redisError) Error() string {
+	return "Redis: " + string(e)
+}
+
+// roundTrip sends the command to the Redis server and reads its reply (see do).
+func (store *redisStore) roundTrip(args []string) (*string, error) {
+	if err := store.conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(store.conn, b.String()); err != nil {
+		return nil, err
+	}
+
+	line, err := store.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) == 0 {
+		return nil, errors.New("Empty reply from the Redis server")
+	}
+
+	reply := line[1:]
+	switch line[0] {
+	case '+', ':':
+		return &reply, nil
+	case '-':
+		return nil, redisError(reply)
+	case '$':
+		length, err := strconv.Atoi(reply)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid bulk string length from the Redis server: %q", reply)
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		data := make([]byte, length+2)
+		if _, err = io.ReadFull(store.reader, data); err != nil {
+			return nil, err
+		}
+		value := string(data[:length])
+		return &value, nil
+	}
+	return nil, fmt.Errorf("Unexpected reply from the Redis server: %q", line)
+}
@@ -0,0 +1,338 @@
+package gonymizer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappingStores(t *testing.T) {
+	alphaNumericMap, uuidMap := AlphaNumericMap, UUIDMap
+	defer func() { AlphaNumericMap, UUIDMap = alphaNumericMap, uuidMap }()
+	Consistency().Reset()
+
+	dir, err := ioutil.TempDir("", "gonymizer-mappingstore")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	bolt, err := OpenMappingStore("bolt://" + filepath.Join(dir, "mappings.db"))
+	require.Nil(t, err)
+
+	server := newFakeRedisServer(t, "secret")
+	defer server.Close()
+	redis, err := OpenMappingStore("redis://:secret@" + server.Addr().String() + "/2")
+	require.Nil(t, err)
+	_, err = OpenMappingStore("redis://:wrong@" + server.Addr().String())
+	require.NotNil(t, err)
+
+	input, output := uuid.New().String(), uuid.New().String()
+	for name, store := range map[string]MappingStore{"memory": memoryStore{}, "bolt": bolt, "redis": redis} {
+		value, ok, err := store.Get("public.users.id", "jane")
+		require.Nil(t, err, name)
+		require.False(t, ok, name)
+		require.Equal(t, "", value, name)
+
+		// The value added first wins
+		value, err = store.Add("public.users.id", "jane", "xkqp")
+		require.Nil(t, err, name)
+		require.Equal(t, "xkqp", value, name)
+		value, err = store.Add("public.users.id", "jane", "zrwm")
+		require.Nil(t, err, name)
+		require.Equal(t, "xkqp", value, name)
+		value, ok, err = store.Get("public.users.id", "jane")
+		require.Nil(t, err, name)
+		require.True(t, ok, name)
+		require.Equal(t, "xkqp", value, name)
+
+		require.Nil(t, store.Set("public.users.id", "jane", "zrwm"), name)
+		value, _, err = store.Get("public.users.id", "jane")
+		require.Nil(t, err, name)
+		require.Equal(t, "zrwm", value, name)
+
+		value, err = store.Add(uuidMapKey, input, output)
+		require.Nil(t, err, name)
+		require.Equal(t, output, value, name)
+
+		count, err := store.Len("public.users.id")
+		require.Nil(t, err, name)
+		require.Equal(t, 1, count, name)
+		count, err = store.Len("public.orders.id")
+		require.Nil(t, err, name)
+		require.Equal(t, 0, count, name)
+
+		// Every key added gets the next number of the counter
+		for i := int64(1); i <= 3; i++ {
+			next, err := store.Next("sequence:public.orders.id")
+			require.Nil(t, err, name)
+			require.Equal(t, i, next, name)
+			_, err = store.Add("sequence:public.orders.id", strconv.FormatInt(i*7, 10), strconv.FormatInt(next, 10))
+			require.Nil(t, err, name)
+		}
+
+		require.Nil(t, store.Delete("sequence:public.orders.id", "7"), name)
+		_, ok, err = store.Get("sequence:public.orders.id", "7")
+		require.Nil(t, err, name)
+		require.False(t, ok, name)
+		require.Nil(t, store.Delete("public.payments.id", "7"), name)
+		require.Nil(t, store.Close(), name)
+	}
+
+	// The in-memory store keeps the mappings in the global maps
+	require.Equal(t, "zrwm", AlphaNumericMap["public.users.id"]["jane"])
+	require.Equal(t, output, UUIDMap[uuid.MustParse(input)].String())
+
+	// The BoltDB file keeps the mappings after it is closed
+	bolt, err = OpenMappingStore("bolt://" + filepath.Join(dir, "mappings.db"))
+	require.Nil(t, err)
+	value, ok, err := bolt.Get("public.users.id", "jane")
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, "zrwm", value)
+	require.Nil(t, bolt.Close())
+}
+
+func TestRedisStore(t *testing.T) {
+	server := newFakeRedisServer(t, "")
+	defer server.Close()
+	store, err := OpenMappingStore("redis://" + server.Addr().String())
+	require.Nil(t, err)
+	defer store.Close()
+
+	// Mappings read or added before are cached
+	_, err = store.Add("url", "users", "xkqp")
+	require.Nil(t, err)
+	commands := atomic.LoadInt64(&server.commands)
+	for i := 0; i < 3; i++ {
+		value, ok, err := store.Get("url", "users")
+		require.Nil(t, err)
+		require.True(t, ok)
+		require.Equal(t, "xkqp", value)
+	}
+	require.Equal(t, commands, atomic.LoadInt64(&server.commands))
+
+	// An error reply keeps the connection, an I/O error closes it
+	redis := store.(*cachedStore).store.(*redisStore)
+	_, err = redis.do("PING")
+	require.NotNil(t, err)
+	_, _, err = store.Get("url", "orders")
+	require.Nil(t, err)
+	_, err = redis.do("QUIT")
+	require.NotNil(t, err)
+	_, _, err = store.Get("url", "orders")
+	require.NotNil(t, err)
+}
+
+func TestOpenMappingStore(t *testing.T) {
+	store, err := OpenMappingStore("")
+	require.Nil(t, err)
+	require.Equal(t, memoryStore{}, store)
+	store, err = OpenMappingStore("memory")
+	require.Nil(t, err)
+	require.Equal(t, memoryStore{}, store)
+
+	_, err = OpenMappingStore("mongodb://localhost")
+	require.NotNil(t, err)
+	_, err = OpenMappingStore("bolt://" + filepath.Join(os.TempDir(), "missing-gonymizer-dir", "mappings.db"))
+	require.NotNil(t, err)
+}
+
+func TestMappedValue(t *testing.T) {
+	alphaNumericMap, uuidMap := AlphaNumericMap, UUIDMap
+	defer func() { AlphaNumericMap, UUIDMap = alphaNumericMap, uuidMap }()
+	Consistency().Reset()
+
+	require.Equal(t, "xkqp", mappedValue("url", "jane", func() string { return "xkqp" }))
+	require.Equal(t, "xkqp", mappedValue("url", "jane", func() string { return "zrwm" }))
+	require.Nil(t, mappingStoreError())
+
+	// Errors of the store are kept until mappingStoreError is called
+	require.Equal(t, "zrwm", mappedValue(uuidMapKey, "not a uuid", func() string { return "zrwm" }))
+	require.NotNil(t, mappingStoreError())
+	require.Nil(t, mappingStoreError())
+
+	SetMappingStore(memoryStore{})
+	defer SetMappingStore(nil)
+	cmap := ColumnMapper{Processors: []ProcessorDefinition{{Name: "RandomUUID"}}}
+	_, err := ProcessorRandomUUID(&cmap, "not a uuid")
+	require.NotNil(t, err)
+}
+
+func TestProcessDumpFileMappingStore(t *testing.T) {
+	alphaNumericMap, uuidMap := AlphaNumericMap, UUIDMap
+	defer func() { AlphaNumericMap, UUIDMap = alphaNumericMap, uuidMap }()
+
+	dir, err := ioutil.TempDir("", "gonymizer-mappingstore")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	columnMap, err := LoadConfigSkeleton(TestMapFile)
+	require.Nil(t, err)
+
+	process := func(seed int64) int {
+		store, err := OpenMappingStore("bolt://" + filepath.Join(dir, "mappings.db"))
+		require.Nil(t, err)
+		defer store.Close()
+
+		Consistency().Reset()
+		columnMap.Seed = seed
+		opts := ProcessOptions{MappingStore: store}
+		require.Nil(t, ProcessDumpFileWithOptions(columnMap, TestDbFile, TestSeedDumpFile, "", "", false, opts))
+		count, err := store.Len(uuidMapKey)
+		require.Nil(t, err)
+		return count
+	}
+
+	// The mappings are kept in the store instead of the in-memory maps, so a later run maps the same UUIDs without
+	// adding any
+	count := process(1)
+	require.NotZero(t, count)
+	require.Empty(t, UUIDMap)
+	require.Equal(t, memoryStore{}, mappingStore)
+	require.Equal(t, count, process(2))
+	require.Nil(t, os.Remove(TestSeedDumpFile))
+
+	// A mappings file requires the in-memory store
+	opts := ProcessOptions{MappingStore: memoryStore{}, MappingsFile: filepath.Join(dir, "mappings.bin"),
+		MappingsKey: bytes.Repeat([]byte{7}, 32)}
+	require.Nil(t, ProcessDumpFileWithOptions(columnMap, TestDbFile, TestSeedDumpFile, "", "", false, opts))
+	require.Nil(t, os.Remove(TestSeedDumpFile))
+	store, err := OpenMappingStore("bolt://" + filepath.Join(dir, "mappings.db"))
+	require.Nil(t, err)
+	defer store.Close()
+	opts.MappingStore = store
+	require.NotNil(t, ProcessDumpFileWithOptions(columnMap, TestDbFile, TestSeedDumpFile, "", "", false, opts))
+}
+
+// fakeRedisServer answers the commands of the Redis store from hashes kept in memory.
+type fakeRedisServer struct {
+	net.Listener
+	password string
+	hashes   map[string]map[string]string
+	commands int64
+}
+
+// newFakeRedisServer starts a fake Redis server on a random local port requiring the password.
+func newFakeRedisServer(t *testing.T, password string) *fakeRedisServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	server := &fakeRedisServer{Listener: listener, password: password, hashes: map[string]map[string]string{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+// serve answers the commands of a single connection. The hashes are only used by one connection at a time.
+func (server *fakeRedisServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readFakeRedisCommand(reader)
+		if err != nil {
+			return
+		}
+		atomic.AddInt64(&server.commands, 1)
+
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			reply = "+OK\r\n"
+			if args[1] != server.password {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case "SELECT":
+			reply = "+OK\r\n"
+		case "HGET":
+			reply = "$-1\r\n"
+			if value, ok := server.hashes[args[1]][args[2]]; ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+			}
+		case "HSET", "HSETNX":
+			if server.hashes[args[1]] == nil {
+				server.hashes[args[1]] = map[string]string{}
+			}
+			_, ok := server.hashes[args[1]][args[2]]
+			reply = ":0\r\n"
+			if !ok || args[0] == "HSET" {
+				server.hashes[args[1]][args[2]] = args[3]
+			}
+			if !ok {
+				reply = ":1\r\n"
+			}
+		case "EVAL":
+			// The script of redisStore.Add
+			if server.hashes[args[3]] == nil {
+				server.hashes[args[3]] = map[string]string{}
+			}
+			if _, ok := server.hashes[args[3]][args[4]]; !ok {
+				server.hashes[args[3]][args[4]] = args[5]
+			}
+			value := server.hashes[args[3]][args[4]]
+			reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+		case "HINCRBY":
+			if server.hashes[args[1]] == nil {
+				server.hashes[args[1]] = map[string]string{}
+			}
+			value, _ := strconv.Atoi(server.hashes[args[1]][args[2]])
+			increment, _ := strconv.Atoi(args[3])
+			server.hashes[args[1]][args[2]] = strconv.Itoa(value + increment)
+			reply = fmt.Sprintf(":%d\r\n", value+increment)
+		case "HDEL":
+			delete(server.hashes[args[1]], args[2])
+			reply = ":1\r\n"
+		case "HLEN":
+			reply = fmt.Sprintf(":%d\r\n", len(server.hashes[args[1]]))
+		case "QUIT":
+			return
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		if _, err = io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// readFakeRedisCommand reads a command (an array of bulk strings) sent by the Redis store.
+func readFakeRedisCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, length+2)
+		if _, err = io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:length])
+	}
+	return args, nil
+}
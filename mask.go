@@ -0,0 +1,68 @@
+package gonymizer
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultMaskCharacter is the character replacing masked characters when MaskCharacter is not set.
+const defaultMaskCharacter = "*"
+
+// maskPartial replaces the characters of the input with the MaskCharacter (default defaultMaskCharacter) except for the
+// first PrefixLength and the last SuffixLength characters. With PreserveFormat set only letters and digits are masked
+// and counted, so separators are kept. Inputs that do not have more characters than are kept are masked completely,
+// so short values are not leaked. The input is unescaped from (and escaped back to) the COPY format, so an escape
+// sequence is a single character.
+//
+// Example:
+// "****-****-1234" = maskPartial(ProcessorDefinition{SuffixLength: 4, PreserveFormat: true}, "4111-1111-1234")
+func maskPartial(procDef ProcessorDefinition, input string) string {
+	mask, _ := utf8.DecodeRuneInString(procDef.MaskCharacter)
+	if len(procDef.MaskCharacter) == 0 {
+		mask, _ = utf8.DecodeRuneInString(defaultMaskCharacter)
+	}
+	masked := func(r rune) bool {
+		return !procDef.PreserveFormat || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	text := unescapeCopyValue(input)
+	count := 0
+	for _, r := range text {
+		if masked(r) {
+			count++
+		}
+	}
+	keepEnds := count > procDef.PrefixLength+procDef.SuffixLength
+
+	output := make([]rune, 0, len(text))
+	position := 0
+	for _, r := range text {
+		if !masked(r) {
+			output = append(output, r)
+			continue
+		}
+		if keepEnds && (position < procDef.PrefixLength || position >= count-procDef.SuffixLength) {
+			output = append(output, r)
+		} else {
+			output = append(output, mask)
+		}
+		position++
+	}
+	return escapeCopyValue(string(output))
+}
+
+// validateMaskPartial checks the lengths and the MaskCharacter of a MaskPartial processor definition.
+func (procDef ProcessorDefinition) validateMaskPartial() error {
+	if procDef.Name != "MaskPartial" {
+		return nil
+	}
+	if procDef.PrefixLength < 0 || procDef.SuffixLength < 0 {
+		return fmt.Errorf("Expected a PrefixLength and SuffixLength of at least 0 for processor %s", procDef.Name)
+	}
+	if len(procDef.MaskCharacter) > 0 && utf8.RuneCountInString(procDef.MaskCharacter) != 1 {
+		return fmt.Errorf("Expected a single MaskCharacter for processor %s, got %q", procDef.Name,
+			procDef.MaskCharacter)
+	}
+	return nil
+}
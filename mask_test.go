@@ -0,0 +1,46 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorMaskPartial(t *testing.T) {
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{{Name: "MaskPartial", SuffixLength: 4}}}
+	output, err := ProcessorMaskPartial(cmap, "4111111111111234")
+	require.Nil(t, err)
+	require.Equal(t, "************1234", output)
+
+	// Separators are masked unless PreserveFormat is set
+	output, err = ProcessorMaskPartial(cmap, "4111-1111-1111-1234")
+	require.Nil(t, err)
+	require.Equal(t, "***************1234", output)
+	cmap.Processors[0].PreserveFormat = true
+	output, err = ProcessorMaskPartial(cmap, "4111-1111-1111-1234")
+	require.Nil(t, err)
+	require.Equal(t, "****-****-****-1234", output)
+
+	cmap.Processors[0] = ProcessorDefinition{Name: "MaskPartial", PrefixLength: 1, SuffixLength: 1, MaskCharacter: "•"}
+	output, err = ProcessorMaskPartial(cmap, "Jörg")
+	require.Nil(t, err)
+	require.Equal(t, "J••g", output)
+
+	// Values that are too short are masked completely
+	output, err = ProcessorMaskPartial(cmap, "Al")
+	require.Nil(t, err)
+	require.Equal(t, "••", output)
+
+	// Escape sequences are a single character
+	cmap.Processors[0].MaskCharacter = ""
+	output, err = ProcessorMaskPartial(cmap, "a\\tb\\\\c")
+	require.Nil(t, err)
+	require.Equal(t, "a***c", output)
+}
+
+func TestValidateMaskPartial(t *testing.T) {
+	require.Nil(t, ProcessorDefinition{Name: "MaskPartial", SuffixLength: 4, MaskCharacter: "#"}.validateMaskPartial())
+	require.NotNil(t, ProcessorDefinition{Name: "MaskPartial", SuffixLength: -1}.validateMaskPartial())
+	require.NotNil(t, ProcessorDefinition{Name: "MaskPartial", MaskCharacter: "**"}.validateMaskPartial())
+	require.Nil(t, ProcessorDefinition{Name: "Identity", MaskCharacter: "**"}.validateMaskPartial())
+}
@@ -0,0 +1,57 @@
+package gonymizer
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// maxLengthOutput returns an output of the processor that fits the MaxLength of the column. When the output is longer
+// the processor is run again (up to maxLengthAttempts times) for an output that fits, otherwise the shortest output is
+// truncated (see truncateMaxLength). The output is returned unchanged when the column has no MaxLength.
+func maxLengthOutput(cmap *ColumnMapper, procDef ProcessorDefinition, pfunc ProcessorFunc, input,
+	output string) (string, error) {
+	if cmap.MaxLength <= 0 || copyValueLength(output) <= cmap.MaxLength {
+		return output, nil
+	}
+
+	for attempt := 0; attempt < maxLengthAttempts; attempt++ {
+		next, err := runCachedProcessor(cmap, procDef, pfunc, input)
+		if err != nil {
+			return "", err
+		}
+		if next == output {
+			// The processor is deterministic for this input
+			break
+		}
+		if copyValueLength(next) < copyValueLength(output) {
+			output = next
+		}
+		if copyValueLength(output) <= cmap.MaxLength {
+			return output, nil
+		}
+	}
+	return truncateMaxLength(cmap, output), nil
+}
+
+// truncateMaxLength truncates the COPY escaped value to the MaxLength of the column. NULL values and columns without a
+// MaxLength are returned as they are.
+func truncateMaxLength(cmap *ColumnMapper, value string) string {
+	if cmap.MaxLength <= 0 || value == "\\N" || copyValueLength(value) <= cmap.MaxLength {
+		return value
+	}
+	return escapeCopyValue(string([]rune(unescapeCopyValue(value))[:cmap.MaxLength]))
+}
+
+// copyValueLength returns the number of characters of the COPY escaped value as PostgreSQL counts them for
+// varchar(n) columns.
+func copyValueLength(value string) int {
+	return utf8.RuneCountInString(unescapeCopyValue(value))
+}
+
+// validateMaxLength checks that the MaxLength of the column is not negative.
+func (cmap ColumnMapper) validateMaxLength() error {
+	if cmap.MaxLength < 0 {
+		return fmt.Errorf("Expected a MaxLength >= 0, got %d", cmap.MaxLength)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package gonymizer
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxLengthOutput(t *testing.T) {
+	cmap := ColumnMapper{MaxLength: 5}
+	procDef := ProcessorDefinition{Name: "Lengths"}
+
+	// The processor is run again until the output fits
+	outputs := []string{"1234567", "123456", "1234"}
+	calls := 0
+	pfunc := func(cmap *ColumnMapper, input string) (string, error) {
+		calls++
+		return outputs[calls%len(outputs)], nil
+	}
+	output, err := maxLengthOutput(&cmap, procDef, pfunc, "input", outputs[0])
+	require.Nil(t, err)
+	require.Equal(t, "1234", output)
+	require.Equal(t, 2, calls)
+
+	// The output of deterministic processors is truncated
+	output, err = runProcessor(&cmap, procDef, func(cmap *ColumnMapper, input string) (string, error) {
+		return "123 Long Street Name", nil
+	}, "input")
+	require.Nil(t, err)
+	require.Equal(t, "123 L", output)
+
+	// Columns without a MaxLength are left as they are
+	output, err = runProcessor(&ColumnMapper{}, procDef, func(cmap *ColumnMapper, input string) (string, error) {
+		return "123 Long Street Name", nil
+	}, "input")
+	require.Nil(t, err)
+	require.Equal(t, "123 Long Street Name", output)
+
+	// Fake addresses fit a varchar(20) column
+	cmap = ColumnMapper{MaxLength: 20, Processors: []ProcessorDefinition{{Name: "FakeStreetAddress"}}}
+	for i := 0; i < 100; i++ {
+		output, err = processValue(&cmap, "1600 Pennsylvania Avenue NW")
+		require.Nil(t, err)
+		require.True(t, utf8.RuneCountInString(output) <= 20, output)
+	}
+}
+
+func TestTruncateMaxLength(t *testing.T) {
+	cmap := ColumnMapper{MaxLength: 4}
+	require.Equal(t, "José", truncateMaxLength(&cmap, "José Smith"))
+	require.Equal(t, "a\\tb\\\\", truncateMaxLength(&cmap, "a\\tb\\\\c"))
+	require.Equal(t, "\\N", truncateMaxLength(&cmap, "\\N"))
+	require.Equal(t, "abc", truncateMaxLength(&cmap, "abc"))
+	require.Equal(t, "abcdef", truncateMaxLength(&ColumnMapper{}, "abcdef"))
+}
+
+func TestValidateMaxLength(t *testing.T) {
+	require.Nil(t, ColumnMapper{}.validateMaxLength())
+	require.Nil(t, ColumnMapper{MaxLength: 50}.validateMaxLength())
+	require.NotNil(t, ColumnMapper{MaxLength: -1}.validateMaxLength())
+}
@@ -0,0 +1,183 @@
+package gonymizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// mockNumberRegex matches values that are written to mock data as JSON numbers. Numbers with leading zeros (zip codes,
+// account numbers) are kept as strings.
+var mockNumberRegex = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?$`)
+
+// MockResource maps the rows of a table to the shape of an API resource. Fields maps every field of the resource to a
+// column of the table. Nested objects are created for fields containing dots (e.g. "contact.email"). When Fields is
+// empty every column is exported under its own name. Limit is the maximum number of rows exported (0 for every row).
+type MockResource struct {
+	Table  string
+	Fields map[string]string `json:",omitempty"`
+	Limit  int               `json:",omitempty"`
+}
+
+// LoadMockResources will load a JSON object of resource names to MockResources from path.
+func LoadMockResources(path string) (map[string]MockResource, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	var resources map[string]MockResource
+	if err = json.Unmarshal(data, &resources); err != nil {
+		log.Error(err)
+		log.Error("path: ", path)
+		return nil, err
+	}
+	for name, resource := range resources {
+		if len(resource.Table) == 0 {
+			return nil, fmt.Errorf("Resource %s of %s has no Table", name, path)
+		}
+	}
+	return resources, nil
+}
+
+// ExportMockData reads the rows of an anonymized dump file and returns them as JSON fixtures: a list of objects for
+// every resource. When resources is nil every table is exported under its name (schema.table) with at most limit rows
+// (0 for every row). NULL is exported as null, booleans (t/f) as true/false, and numbers as JSON numbers. The result
+// can be written with WriteMockData and used directly by API mocks (e.g. as a json-server db.json).
+func ExportMockData(dumpFile string, resources map[string]MockResource, limit int) (map[string][]interface{}, error) {
+	rows, err := openDumpRows(dumpFile)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.close()
+
+	data := map[string][]interface{}{}
+	for name := range resources {
+		data[name] = []interface{}{}
+	}
+	for {
+		values, err := rows.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		table := rows.state.SchemaName + "." + rows.state.TableName
+
+		if resources == nil {
+			if limit > 0 && len(data[table]) >= limit {
+				continue
+			}
+			object, err := mockObject(nil, rows.state.ColumnNames, values)
+			if err != nil {
+				return nil, err
+			}
+			data[table] = append(data[table], object)
+			continue
+		}
+
+		for name, resource := range resources {
+			if resource.Table != table || (resource.Limit > 0 && len(data[name]) >= resource.Limit) {
+				continue
+			}
+			object, err := mockObject(resource.Fields, rows.state.ColumnNames, values)
+			if err != nil {
+				return nil, fmt.Errorf("Resource %s: %s", name, err)
+			}
+			data[name] = append(data[name], object)
+		}
+	}
+	return data, nil
+}
+
+// WriteMockData will save the mock data to filepath as JSON.
+func WriteMockData(data map[string][]interface{}, filepath string) error {
+	return writeJSONFile(filepath, data)
+}
+
+// mockObject returns the object of a single row. Fields maps the fields of the object to columns, when it is empty
+// every column is used.
+func mockObject(fields map[string]string, columns, values []string) (map[string]interface{}, error) {
+	object := map[string]interface{}{}
+	if len(fields) == 0 {
+		for i, column := range columns {
+			if i < len(values) {
+				object[column] = mockValue(values[i])
+			}
+		}
+		return object, nil
+	}
+
+	for field, column := range fields {
+		i := indexOf(columns, column)
+		if i < 0 || i >= len(values) {
+			return nil, fmt.Errorf("Column %s of field %s not found", column, field)
+		}
+
+		// Walk (and create) the nested objects of the field
+		parent := object
+		path := strings.Split(field, ".")
+		for _, name := range path[:len(path)-1] {
+			child, ok := parent[name].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				parent[name] = child
+			}
+			parent = child
+		}
+		parent[path[len(path)-1]] = mockValue(values[i])
+	}
+	return object, nil
+}
+
+// mockValue converts a COPY value to a JSON value.
+func mockValue(value string) interface{} {
+	switch {
+	case value == "\\N":
+		return nil
+	case value == "t":
+		return true
+	case value == "f":
+		return false
+	case mockNumberRegex.MatchString(value):
+		return json.Number(value)
+	}
+	return unescapeCopyValue(value)
+}
+
+// unescapeCopyValue replaces the backslash escapes of a COPY value (\t, \n, \\, ...) with their characters.
+func unescapeCopyValue(value string) string {
+	if !strings.Contains(value, "\\") {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package gonymizer
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportMockData(t *testing.T) {
+	dumpFile := writeTestAuditFile(t, "COPY public.users (id, name, email, zip, active, note) FROM stdin;\n"+
+		"1\tJane\tjane@example.com\t02134\tt\tline\\none\n"+
+		"2\tJohn\t\\N\t94107\tf\t\\N\n\\.\n"+
+		"COPY public.orders (id, total) FROM stdin;\n1\t10.50\n\\.\n")
+	defer os.Remove(dumpFile)
+
+	data, err := ExportMockData(dumpFile, nil, 1)
+	require.Nil(t, err)
+	output, err := json.Marshal(data)
+	require.Nil(t, err)
+	require.JSONEq(t, `{
+		"public.users": [{"id": 1, "name": "Jane", "email": "jane@example.com", "zip": "02134", "active": true,
+			"note": "line\none"}],
+		"public.orders": [{"id": 1, "total": 10.50}]
+	}`, string(output))
+
+	resources := map[string]MockResource{
+		"users": {Table: "public.users", Fields: map[string]string{"id": "id", "fullName": "name",
+			"contact.email": "email", "contact.zip": "zip"}},
+		"payments": {Table: "public.payments"},
+	}
+	data, err = ExportMockData(dumpFile, resources, 0)
+	require.Nil(t, err)
+	output, err = json.Marshal(data)
+	require.Nil(t, err)
+	require.JSONEq(t, `{
+		"users": [
+			{"id": 1, "fullName": "Jane", "contact": {"email": "jane@example.com", "zip": "02134"}},
+			{"id": 2, "fullName": "John", "contact": {"email": null, "zip": 94107}}
+		],
+		"payments": []
+	}`, string(output))
+
+	resources["users"].Fields["missing"] = "missing"
+	_, err = ExportMockData(dumpFile, resources, 0)
+	require.NotNil(t, err)
+}
+
+func TestLoadMockResources(t *testing.T) {
+	path := writeTestAuditFile(t, `{"users": {"Table": "public.users", "Fields": {"id": "id"}, "Limit": 10}}`)
+	defer os.Remove(path)
+	resources, err := LoadMockResources(path)
+	require.Nil(t, err)
+	require.Equal(t, MockResource{Table: "public.users", Fields: map[string]string{"id": "id"}, Limit: 10},
+		resources["users"])
+
+	invalid := writeTestAuditFile(t, `{"users": {"Fields": {"id": "id"}}}`)
+	defer os.Remove(invalid)
+	_, err = LoadMockResources(invalid)
+	require.NotNil(t, err)
+}
+
+func TestUnescapeCopyValue(t *testing.T) {
+	require.Equal(t, "plain", unescapeCopyValue("plain"))
+	require.Equal(t, "a\tb\nc\\d", unescapeCopyValue(`a\tb\nc\\d`))
+	require.Equal(t, "trailing\\", unescapeCopyValue(`trailing\`))
+}
@@ -0,0 +1,210 @@
+package gonymizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+	log "github.com/sirupsen/logrus"
+)
+
+// notifyTimeout is the time a notification may take before it is abandoned.
+const notifyTimeout = 30 * time.Second
+
+// Run statuses of a RunSummary.
+const (
+	RunSucceeded = "success"
+	RunFailed    = "failure"
+)
+
+// Error categories of a failed run (see RunSummary).
+const (
+	RunErrorProcessor = "processor error"
+	RunErrorTimeout   = "processor timeout"
+	RunErrorOther     = "error"
+)
+
+// RunSummary describes a finished gonymizer run. It is sent as JSON to every notification target. The error of a
+// failed run may contain values of the dump file, so only its category (see RunErrorProcessor) and the table, column,
+// and line of a processor error are sent.
+type RunSummary struct {
+	Command  string
+	Status   string
+	Error    string `json:",omitempty"`
+	Table    string `json:",omitempty"`
+	Column   string `json:",omitempty"`
+	Line     int64  `json:",omitempty"`
+	Host     string
+	Version  string
+	Started  time.Time
+	Finished time.Time
+	Duration string
+	Details  map[string]string `json:",omitempty"`
+}
+
+// NewRunSummary returns the summary of a run of command that started at started and finished now. The run failed when
+// err is not nil, and is summarized by its category (see RunSummary).
+func NewRunSummary(command string, started time.Time, err error, details map[string]string) *RunSummary {
+	host, _ := os.Hostname()
+	summary := &RunSummary{
+		Command:  command,
+		Status:   RunSucceeded,
+		Host:     host,
+		Version:  Version(),
+		Started:  started,
+		Finished: time.Now(),
+		Details:  details,
+	}
+	summary.Duration = summary.Finished.Sub(started).Round(time.Second).String()
+	if err != nil {
+		summary.Status = RunFailed
+		summary.Error = RunErrorOther
+		var processorErr *ProcessorError
+		if errors.As(err, &processorErr) {
+			summary.Error = RunErrorProcessor
+			summary.Table, summary.Column, summary.Line = processorErr.Table, processorErr.Column, processorErr.Line
+		}
+		var timeoutErr *ProcessorTimeoutError
+		if errors.As(err, &timeoutErr) {
+			summary.Error = RunErrorTimeout
+		}
+	}
+	return summary
+}
+
+// Notifier sends a RunSummary to a notification target.
+type Notifier interface {
+	Notify(summary *RunSummary) error
+}
+
+// WebhookNotifier POSTs the RunSummary as JSON to URL.
+type WebhookNotifier struct {
+	URL string
+}
+
+// Notify sends the summary to the webhook.
+func (n WebhookNotifier) Notify(summary *RunSummary) error {
+	return postJSON(n.URL, summary)
+}
+
+// SlackNotifier posts a message with the RunSummary to a Slack incoming webhook.
+type SlackNotifier struct {
+	WebhookURL string
+}
+
+// Notify sends the summary to Slack.
+func (n SlackNotifier) Notify(summary *RunSummary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	icon := ":white_check_mark:"
+	if summary.Status == RunFailed {
+		icon = ":x:"
+	}
+	text := fmt.Sprintf("%s gonymizer %s %s on %s after %s\n```%s```", icon, summary.Command, summary.Status,
+		summary.Host, summary.Duration, data)
+	return postJSON(n.WebhookURL, map[string]string{"text": text})
+}
+
+// SNSNotifier publishes the RunSummary as JSON to the SNS topic TopicARN.
+type SNSNotifier struct {
+	TopicARN string
+}
+
+// Notify publishes the summary to the SNS topic.
+func (n SNSNotifier) Notify(summary *RunSummary) error {
+	// arn:aws:sns:<region>:<account>:<topic>
+	parts := strings.Split(n.TopicARN, ":")
+	if len(parts) != 6 {
+		return fmt.Errorf("Invalid SNS topic ARN: %s", n.TopicARN)
+	}
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(parts[3])})
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	_, err = sns.New(sess).Publish(&sns.PublishInput{
+		TopicArn: aws.String(n.TopicARN),
+		Subject:  aws.String(fmt.Sprintf("gonymizer %s %s", summary.Command, summary.Status)),
+		Message:  aws.String(string(data)),
+	})
+	return err
+}
+
+// ParseNotifier returns the Notifier for a notification target: an SNS topic ARN (arn:aws:sns:...), a Slack incoming
+// webhook (https://hooks.slack.com/...), or any other http(s) URL, which receives the summary as JSON.
+func ParseNotifier(target string) (Notifier, error) {
+	switch {
+	case strings.HasPrefix(target, "arn:aws:sns:"):
+		return SNSNotifier{TopicARN: target}, nil
+	case strings.HasPrefix(target, "https://hooks.slack.com/"):
+		return SlackNotifier{WebhookURL: target}, nil
+	case strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://"):
+		return WebhookNotifier{URL: target}, nil
+	}
+	return nil, fmt.Errorf("Unknown notification target: %s", target)
+}
+
+// NotifyRun sends the summary to every target. A failing target does not stop the other targets from being notified;
+// the first error is returned.
+func NotifyRun(targets []string, summary *RunSummary) error {
+	var firstErr error
+	for _, target := range targets {
+		notifier, err := ParseNotifier(target)
+		if err == nil {
+			err = notifier.Notify(summary)
+		}
+		if err != nil {
+			log.Errorf("Unable to notify %s: %s", redactURL(target), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// postJSON POSTs v as JSON to target and expects a 2xx response.
+func postJSON(target string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: notifyTimeout}
+	resp, err := client.Post(target, "application/json", bytes.NewReader(data))
+	if urlErr, ok := err.(*url.Error); ok {
+		// Leave the URL (and its secret) out of the error
+		return urlErr.Err
+	} else if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Unexpected response status: %s", resp.Status)
+	}
+	return nil
+}
+
+// redactURL removes the path and query of a URL, which contain the secret of webhooks, for logging.
+func redactURL(target string) string {
+	if i := strings.Index(target, "://"); i >= 0 {
+		if j := strings.Index(target[i+3:], "/"); j >= 0 {
+			return target[:i+3+j] + "/..."
+		}
+	}
+	return target
+}
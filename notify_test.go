@@ -0,0 +1,85 @@
+package gonymizer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyRun(t *testing.T) {
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	summary := NewRunSummary("process", time.Now().Add(-time.Minute), errors.New("disk full"),
+		map[string]string{"DumpFile": "dump.sql"})
+	require.Equal(t, RunFailed, summary.Status)
+	require.Equal(t, RunErrorOther, summary.Error)
+	require.Equal(t, "1m0s", summary.Duration)
+
+	// The error of a processor may contain a value of the dump file, only where it failed is sent
+	err := fmt.Errorf("Processing failed: %w", &ProcessorError{Table: "public.users", Column: "email", Line: 42,
+		Err: errors.New("Invalid e-mail address: jane@example.com")})
+	failed := NewRunSummary("process", time.Now(), err, nil)
+	require.Equal(t, RunErrorProcessor, failed.Error)
+	require.Equal(t, "public.users", failed.Table)
+	require.Equal(t, "email", failed.Column)
+	require.Equal(t, int64(42), failed.Line)
+	data, _ := json.Marshal(failed)
+	require.NotContains(t, string(data), "jane@example.com")
+	timedOut := NewRunSummary("process", time.Now(), &ProcessorError{Err: &ProcessorTimeoutError{Processor: "Exec"}},
+		nil)
+	require.Equal(t, RunErrorTimeout, timedOut.Error)
+
+	require.Nil(t, NotifyRun([]string{server.URL + "/hook"}, summary))
+	require.Len(t, bodies, 1)
+	var received RunSummary
+	require.Nil(t, json.Unmarshal(bodies[0], &received))
+	require.Equal(t, "process", received.Command)
+	require.Equal(t, "dump.sql", received.Details["DumpFile"])
+
+	// Every target is notified even when one fails
+	err = NotifyRun([]string{server.URL + "/fail", "ftp://example.com", server.URL + "/hook"}, summary)
+	require.NotNil(t, err)
+	require.Len(t, bodies, 3)
+
+	// Slack
+	require.Nil(t, SlackNotifier{WebhookURL: server.URL + "/slack"}.Notify(NewRunSummary("process", time.Now(), nil,
+		nil)))
+	var message map[string]string
+	require.Nil(t, json.Unmarshal(bodies[3], &message))
+	require.Contains(t, message["text"], "gonymizer process success")
+}
+
+func TestParseNotifier(t *testing.T) {
+	notifier, err := ParseNotifier("arn:aws:sns:us-east-1:123456789012:gonymizer")
+	require.Nil(t, err)
+	require.IsType(t, SNSNotifier{}, notifier)
+
+	notifier, err = ParseNotifier("https://hooks.slack.com/services/T000/B000/XXXX")
+	require.Nil(t, err)
+	require.IsType(t, SlackNotifier{}, notifier)
+
+	notifier, err = ParseNotifier("https://example.com/hooks/gonymizer")
+	require.Nil(t, err)
+	require.IsType(t, WebhookNotifier{}, notifier)
+
+	_, err = ParseNotifier("mailto:team@example.com")
+	require.NotNil(t, err)
+
+	require.NotNil(t, SNSNotifier{TopicARN: "arn:aws:sns:gonymizer"}.Notify(&RunSummary{}))
+	require.Equal(t, "https://hooks.slack.com/...", redactURL("https://hooks.slack.com/services/T000/B000/XXXX"))
+}
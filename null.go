@@ -0,0 +1,49 @@
+package gonymizer
+
+import "fmt"
+
+// NullBehavior values of a ColumnMapper decide what happens to NULL (\N) values of the column.
+const (
+	// NullBehaviorPreserve keeps NULL values as they are, only values that are not NULL are processed. This is the
+	// default.
+	NullBehaviorPreserve = "preserve"
+
+	// NullBehaviorNullify keeps NULL values and also writes NULL when the processors return an empty string, e.g. for
+	// empty values or processors that blank out a value.
+	NullBehaviorNullify = "nullify"
+
+	// NullBehaviorProcess runs the processors for NULL values too, with an empty string as input, e.g. to fill a column
+	// that must not be NULL in the processed dump file with fake values.
+	NullBehaviorProcess = "process"
+)
+
+// processorInput returns the input of the processors of the column for the value, and false if the value is NULL and
+// is written as it is.
+func (cmap *ColumnMapper) processorInput(value string) (string, bool) {
+	if value != "\\N" {
+		return value, true
+	}
+	if cmap.NullBehavior == NullBehaviorProcess {
+		return "", true
+	}
+	return value, false
+}
+
+// nullOutput returns the output of the processors of the column, or NULL if it is empty and the column nullifies
+// empty values.
+func (cmap *ColumnMapper) nullOutput(output string) string {
+	if len(output) == 0 && cmap.NullBehavior == NullBehaviorNullify {
+		return "\\N"
+	}
+	return output
+}
+
+// validateNullBehavior checks that the NullBehavior of the column is known.
+func (cmap ColumnMapper) validateNullBehavior() error {
+	switch cmap.NullBehavior {
+	case "", NullBehaviorPreserve, NullBehaviorNullify, NullBehaviorProcess:
+		return nil
+	}
+	return fmt.Errorf("Unknown NullBehavior %q. Expected one of %s, %s, %s", cmap.NullBehavior, NullBehaviorPreserve,
+		NullBehaviorNullify, NullBehaviorProcess)
+}
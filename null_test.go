@@ -0,0 +1,66 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorNull(t *testing.T) {
+	output, err := ProcessorNull(&cMap, "jane.doe@example.com")
+	require.Nil(t, err)
+	require.Equal(t, "\\N", output)
+}
+
+func TestNullBehavior(t *testing.T) {
+	column := func(name, nullBehavior, processor string) ColumnMapper {
+		return ColumnMapper{
+			TableSchema:  "public",
+			TableName:    "users",
+			ColumnName:   name,
+			NullBehavior: nullBehavior,
+			Processors:   []ProcessorDefinition{{Name: processor}},
+		}
+	}
+	mapper := &DBMapper{
+		ColumnMaps: []ColumnMapper{
+			column("preserved", "", "FakeFirstName"),
+			column("nullified", NullBehaviorNullify, "ScrubString"),
+			column("processed", NullBehaviorProcess, "FakeFirstName"),
+			column("nulled", "", "Null"),
+		},
+	}
+	state := &LineState{
+		IsRow:       true,
+		SchemaName:  "public",
+		TableName:   "users",
+		ColumnNames: []string{"preserved", "nullified", "processed", "nulled"},
+	}
+
+	_, output, err := processRow(mapper, state, "\\N\t\t\\N\tsecret\n")
+	require.Nil(t, err)
+	require.Regexp(t, `^\\N\t\\N\t[A-Z][a-z]+\t\\N\n$`, output)
+
+	_, output, err = processRow(mapper, state, "Jane\tpassword\tJohn\tsecret\n")
+	require.Nil(t, err)
+	require.Regexp(t, `^[A-Z][a-z]+\t\*{8}\t[A-Z][a-z]+\t\\N\n$`, output)
+
+	// The same behavior applies to single values
+	anonymizer := &Anonymizer{mapper: mapper}
+	output, err = anonymizer.Anonymize("public", "users", "processed", "\\N")
+	require.Nil(t, err)
+	require.NotEqual(t, "\\N", output)
+	output, err = anonymizer.Anonymize("public", "users", "nullified", "")
+	require.Nil(t, err)
+	require.Equal(t, "\\N", output)
+	output, err = anonymizer.Anonymize("public", "users", "preserved", "\\N")
+	require.Nil(t, err)
+	require.Equal(t, "\\N", output)
+}
+
+func TestValidateNullBehavior(t *testing.T) {
+	for _, nullBehavior := range []string{"", NullBehaviorPreserve, NullBehaviorNullify, NullBehaviorProcess} {
+		require.Nil(t, ColumnMapper{NullBehavior: nullBehavior}.validateNullBehavior())
+	}
+	require.NotNil(t, ColumnMapper{NullBehavior: "fake"}.validateNullBehavior())
+}
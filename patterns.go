@@ -0,0 +1,198 @@
+package gonymizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PIIPattern is a named regular expression matching a kind of personal data, such as e-mail addresses or card numbers.
+// The whole value must match Regex. Validator optionally names a check that values matching Regex must also pass to
+// reduce false positives (see piiValidators).
+type PIIPattern struct {
+	Name      string
+	Regex     string
+	Validator string `json:",omitempty"`
+
+	regex *regexp.Regexp
+}
+
+// piiValidators are the checks a PIIPattern may use as Validator.
+var piiValidators = map[string]func(string) bool{
+	"ein_us": func(value string) bool { return containsString(einPrefixes, value[:2]) },
+	"iban":   validIBAN,
+	"ip":     func(value string) bool { return net.ParseIP(value) != nil },
+	"luhn":   validLuhn,
+	"ssn_us": validSSN,
+}
+
+// piiPatterns contains the built-in patterns and the patterns registered with RegisterPIIPattern, in the order they
+// are matched. It is the pattern source of PII detection (MapCoverage), the ValueClass processor, and the audit of
+// processed dump files (AuditDumpFiles).
+var piiPatterns []*PIIPattern
+
+// builtinPIIPatterns is the pattern library loaded at startup.
+var builtinPIIPatterns = []PIIPattern{
+	{Name: "email", Regex: `[^@\s]+@[^@\s]+\.[A-Za-z]{2,}`},
+	{Name: "card_pan", Regex: `\d{4}([ -]?\d{4}){2}[ -]?\d{1,7}`, Validator: "luhn"},
+	{Name: "iban", Regex: `[A-Z]{2}\d{2}( ?[A-Z0-9]){11,30}`, Validator: "iban"},
+	{Name: "ssn_us", Regex: `\d{3}-\d{2}-\d{4}`, Validator: "ssn_us"},
+	{Name: "ein_us", Regex: `\d{2}-\d{7}`, Validator: "ein_us"},
+	{Name: "nino_uk", Regex: `[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]`},
+	{Name: "phone_us", Regex: `(\+?1[ .-]?)?(\([2-9]\d{2}\)|[2-9]\d{2})[ .-]?[2-9]\d{2}[ .-]?\d{4}`},
+	{Name: "phone_uk", Regex: `(\+44 ?|0)(\(0\))?\d{2,4}( ?\d{3,4}){2}`},
+	{Name: "phone_e164", Regex: `\+[1-9]\d{7,14}`},
+	{Name: "ipv4", Regex: `\d{1,3}(\.\d{1,3}){3}`, Validator: "ip"},
+	{Name: "ipv6", Regex: `[0-9A-Fa-f:]*:[0-9A-Fa-f:]*:[0-9A-Fa-f:.]*`, Validator: "ip"},
+}
+
+func init() {
+	for _, pattern := range builtinPIIPatterns {
+		if err := RegisterPIIPattern(pattern); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterPIIPattern adds a pattern to the PII pattern library. A pattern with the same name as an existing pattern
+// replaces it, so built-in patterns can be tightened or loosened.
+func RegisterPIIPattern(pattern PIIPattern) error {
+	if len(pattern.Name) == 0 || len(pattern.Regex) == 0 {
+		return fmt.Errorf("Expected a Name and Regex for PII pattern %q", pattern.Name)
+	}
+	if len(pattern.Validator) > 0 && piiValidators[pattern.Validator] == nil {
+		return fmt.Errorf("Unknown validator %q for PII pattern %q", pattern.Validator, pattern.Name)
+	}
+
+	var err error
+	if pattern.regex, err = regexp.Compile(`^(?:` + pattern.Regex + `)$`); err != nil {
+		return fmt.Errorf("Invalid Regex for PII pattern %q: %s", pattern.Name, err)
+	}
+
+	for i, existing := range piiPatterns {
+		if existing.Name == pattern.Name {
+			piiPatterns[i] = &pattern
+			return nil
+		}
+	}
+	piiPatterns = append(piiPatterns, &pattern)
+	return nil
+}
+
+// LoadPIIPatterns will register the patterns of the JSON file found at path (a list of PIIPattern) in the PII pattern
+// library.
+func LoadPIIPatterns(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Error("Failure to open file: ", err)
+		log.Error("path: ", path)
+		return err
+	}
+
+	var patterns []PIIPattern
+	if err = json.Unmarshal(data, &patterns); err != nil {
+		log.Error("Unable to parse PII patterns: ", err)
+		return err
+	}
+	for _, pattern := range patterns {
+		if err = RegisterPIIPattern(pattern); err != nil {
+			log.Error(err)
+			return err
+		}
+	}
+
+	log.Infof("Loaded %d PII pattern(s) from: %s", len(patterns), path)
+	return nil
+}
+
+// MatchPIIPatterns returns the names of the PII patterns that match the value, in library order.
+func MatchPIIPatterns(value string) []string {
+	var names []string
+	for _, pattern := range piiPatterns {
+		if pattern.matches(value) {
+			names = append(names, pattern.Name)
+		}
+	}
+	return names
+}
+
+// matchesPIIPattern returns true if the PII pattern named name exists and matches the value.
+func matchesPIIPattern(name, value string) bool {
+	pattern := findPIIPattern(name)
+	return pattern != nil && pattern.matches(value)
+}
+
+// findPIIPattern returns the PII pattern named name or nil if there is none.
+func findPIIPattern(name string) *PIIPattern {
+	for _, pattern := range piiPatterns {
+		if pattern.Name == name {
+			return pattern
+		}
+	}
+	return nil
+}
+
+// piiPatternNames returns the sorted names of the PII patterns.
+func piiPatternNames() []string {
+	names := make([]string, len(piiPatterns))
+	for i, pattern := range piiPatterns {
+		names[i] = pattern.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// matches returns true if the whole (trimmed) value matches the pattern and passes its validator.
+func (pattern *PIIPattern) matches(value string) bool {
+	value = strings.TrimSpace(value)
+	if !pattern.regex.MatchString(value) {
+		return false
+	}
+	return len(pattern.Validator) == 0 || piiValidators[pattern.Validator](value)
+}
+
+// validLuhn returns true if the digits of the value end with a valid Luhn check digit.
+func validLuhn(value string) bool {
+	digits := digitsOnly(value)
+	if len(digits) < 2 {
+		return false
+	}
+	return luhnCheckDigit(digits[:len(digits)-1]) == int(digits[len(digits)-1]-'0')
+}
+
+// validSSN returns true if the value is a Social Security Number the SSA could have issued: a valid area number and
+// a group number and serial number other than all zeros.
+func validSSN(value string) bool {
+	digits := digitsOnly(value)
+	return len(digits) == 9 && validSSNArea(digits[:3]) && digits[3:5] != "00" && digits[5:] != "0000"
+}
+
+// validIBAN returns true if the value (with or without spaces) has a valid IBAN (ISO 13616) mod 97 checksum.
+func validIBAN(value string) bool {
+	iban := strings.ToUpper(strings.Replace(value, " ", "", -1))
+	if len(iban) < 15 {
+		return false
+	}
+
+	// Move the country code and check digits to the end and replace letters by numbers (A = 10, ..., Z = 35)
+	var numeric strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			numeric.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			fmt.Fprintf(&numeric, "%d", r-'A'+10)
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
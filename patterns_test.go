@@ -0,0 +1,67 @@
+package gonymizer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchPIIPatterns(t *testing.T) {
+	for value, expected := range map[string][]string{
+		"jane@example.com":            {"email"},
+		"4111 1111 1111 1111":         {"card_pan"},
+		"4111111111111112":            nil,
+		"GB82 WEST 1234 5698 7654 32": {"iban"},
+		"GB82 WEST 1234 5698 7654 33": nil,
+		"123-45-6789":                 {"ssn_us"},
+		"666-45-6789":                 nil,
+		"12-3456789":                  {"ein_us"},
+		"07-3456789":                  nil,
+		"AB 12 34 56 C":               {"nino_uk"},
+		"(555) 867-5309":              {"phone_us"},
+		"020 7946 0958":               {"phone_uk"},
+		"+4915112345678":              {"phone_e164"},
+		"192.168.1.10":                {"ipv4"},
+		"999.168.1.10":                nil,
+		"2001:db8::1":                 {"ipv6"},
+		"hello world":                 nil,
+		"12345":                       nil,
+	} {
+		require.Equal(t, expected, MatchPIIPatterns(value), value)
+	}
+}
+
+func TestRegisterPIIPattern(t *testing.T) {
+	defer func(patterns []*PIIPattern) { piiPatterns = patterns }(append([]*PIIPattern(nil), piiPatterns...))
+
+	require.Nil(t, RegisterPIIPattern(PIIPattern{Name: "employee_id", Regex: `EMP-\d{6}`}))
+	require.Equal(t, []string{"employee_id"}, MatchPIIPatterns("EMP-123456"))
+	require.True(t, matchesPIIPattern("employee_id", " EMP-123456 "))
+	require.False(t, matchesPIIPattern("employee_id", "EMP-123456-1"))
+
+	// Replace a built-in pattern
+	count := len(piiPatterns)
+	require.Nil(t, RegisterPIIPattern(PIIPattern{Name: "email", Regex: `[^@\s]+@example\.com`}))
+	require.Len(t, piiPatterns, count)
+	require.Nil(t, MatchPIIPatterns("jane@example.org"))
+
+	require.NotNil(t, RegisterPIIPattern(PIIPattern{Name: "broken", Regex: `(`}))
+	require.NotNil(t, RegisterPIIPattern(PIIPattern{Name: "card", Regex: `\d+`, Validator: "crc"}))
+	require.NotNil(t, RegisterPIIPattern(PIIPattern{Regex: `\d+`}))
+}
+
+func TestLoadPIIPatterns(t *testing.T) {
+	defer func(patterns []*PIIPattern) { piiPatterns = patterns }(append([]*PIIPattern(nil), piiPatterns...))
+
+	path := writeTestAuditFile(t, `[{"Name": "member_card", "Regex": "M\\d{16}", "Validator": "luhn"}]`)
+	defer os.Remove(path)
+	require.Nil(t, LoadPIIPatterns(path))
+	require.Equal(t, []string{"member_card"}, MatchPIIPatterns("M4111111111111111"))
+	require.Nil(t, MatchPIIPatterns("M4111111111111112"))
+
+	invalid := writeTestAuditFile(t, `[{"Name": "member_card"}]`)
+	defer os.Remove(invalid)
+	require.NotNil(t, LoadPIIPatterns(invalid))
+	require.NotNil(t, LoadPIIPatterns("/does/not/exist.json"))
+}
@@ -0,0 +1,89 @@
+package gonymizer
+
+import (
+	"regexp"
+	"strings"
+)
+
+// phoneExtensionRegex matches the extension at the end of a phone number (x123, ext. 123, #123).
+var phoneExtensionRegex = regexp.MustCompile(`(?i)(x|ext\.?|extension|#)\s*\d+\s*$`)
+
+// twoDigitCallingCodes are the two digit ITU-T E.164 country calling codes. Calling codes starting with 1 (NANP) or 7
+// have one digit and all others three, so the length of a calling code is known from its first digits.
+var twoDigitCallingCodes = map[string]bool{
+	"20": true, "27": true, "30": true, "31": true, "32": true, "33": true, "34": true, "36": true, "39": true,
+	"40": true, "41": true, "43": true, "44": true, "45": true, "46": true, "47": true, "48": true, "49": true,
+	"51": true, "52": true, "53": true, "54": true, "55": true, "56": true, "57": true, "58": true, "60": true,
+	"61": true, "62": true, "63": true, "64": true, "65": true, "66": true, "81": true, "82": true, "84": true,
+	"86": true, "90": true, "91": true, "92": true, "93": true, "94": true, "95": true, "98": true,
+}
+
+// callingCodeLength returns the length of the country calling code at the start of the digits of an international
+// phone number.
+func callingCodeLength(digits string) int {
+	length := 3
+	switch {
+	case len(digits) == 0:
+		return 0
+	case digits[0] == '1' || digits[0] == '7':
+		length = 1
+	case len(digits) >= 2 && twoDigitCallingCodes[digits[:2]]:
+		length = 2
+	}
+	if length > len(digits) {
+		return len(digits)
+	}
+	return length
+}
+
+// fakePhoneFormat returns a phone number with the format of the input: the digits are replaced with random digits and
+// the punctuation, spaces, leading +, and extension markers are kept. The country calling code of numbers starting with
+// + (or the 1 of 11 digit NANP numbers) is kept when preserveCountryCode is set, and the trunk prefix 0 of national
+// numbers is always kept. Area codes and exchanges of NANP numbers (10 digit national numbers) start with 2-9 so the
+// number stays valid.
+//
+// Example:
+// "+1 (724) 309-5821 x31" = fakePhoneFormat("+1 (555) 123-4567 x12", true)
+func fakePhoneFormat(input string, preserveCountryCode bool) string {
+	number := input
+	if loc := phoneExtensionRegex.FindStringIndex(input); loc != nil {
+		number = input[:loc[0]]
+	}
+	digits := digitsOnly(number)
+	international := strings.HasPrefix(strings.TrimSpace(input), "+")
+
+	codeLength := 0
+	switch {
+	case international:
+		codeLength = callingCodeLength(digits)
+	case len(digits) == 11 && digits[0] == '1':
+		codeLength, preserveCountryCode = 1, true
+	}
+	nanp := len(digits)-codeLength == 10 && (codeLength == 0 || digits[:codeLength] == "1")
+
+	output := []byte(input)
+	position := 0
+	for i := 0; i < len(output); i++ {
+		if output[i] < '0' || output[i] > '9' {
+			continue
+		}
+		k, national := position, position-codeLength
+		position++
+		switch {
+		case k >= len(digits):
+			// Extension
+			output[i] = numericSet[rng.Intn(numericSetLen)]
+		case k < codeLength && preserveCountryCode:
+			// Country calling code
+		case k < codeLength && k == 0:
+			output[i] = numericSet[1+rng.Intn(numericSetLen-1)]
+		case !international && k == 0 && output[i] == '0':
+			// Trunk prefix
+		case nanp && (national == 0 || national == 3):
+			output[i] = numericSet[2+rng.Intn(numericSetLen-2)]
+		default:
+			output[i] = numericSet[rng.Intn(numericSetLen)]
+		}
+	}
+	return string(output)
+}
@@ -0,0 +1,55 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallingCodeLength(t *testing.T) {
+	require.Equal(t, 1, callingCodeLength("15551234567"))
+	require.Equal(t, 1, callingCodeLength("74951234567"))
+	require.Equal(t, 2, callingCodeLength("442079460958"))
+	require.Equal(t, 2, callingCodeLength("4930123456"))
+	require.Equal(t, 3, callingCodeLength("353123456789"))
+	require.Equal(t, 0, callingCodeLength(""))
+}
+
+func TestFakePhoneFormat(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		output := fakePhoneFormat("+1 (555) 123-4567 x12", true)
+		require.Regexp(t, `^\+1 \([2-9]\d{2}\) [2-9]\d{2}-\d{4} x\d{2}$`, output)
+
+		output = fakePhoneFormat("(555) 123-4567", false)
+		require.Regexp(t, `^\([2-9]\d{2}\) [2-9]\d{2}-\d{4}$`, output)
+
+		output = fakePhoneFormat("1-800-555-1212", false)
+		require.Regexp(t, `^1-[2-9]\d{2}-[2-9]\d{2}-\d{4}$`, output)
+
+		output = fakePhoneFormat("+44 20 7946 0958 ext. 7", true)
+		require.Regexp(t, `^\+44 \d{2} \d{4} \d{4} ext\. \d$`, output)
+
+		output = fakePhoneFormat("+44 20 7946 0958", false)
+		require.Regexp(t, `^\+[1-9]\d \d{2} \d{4} \d{4}$`, output)
+
+		output = fakePhoneFormat("020 7946 0958", false)
+		require.Regexp(t, `^0\d{2} \d{4} \d{4}$`, output)
+	}
+}
+
+func TestProcessorPhoneNumberPreserveFormat(t *testing.T) {
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{{Name: "FakePhoneNumber", PreserveFormat: true}}}
+	output, err := ProcessorPhoneNumber(cmap, "+49 (0)30 1234-5678")
+	require.Nil(t, err)
+	require.Regexp(t, `^\+[1-9]\d \(\d\)\d{2} \d{4}-\d{4}$`, output)
+
+	cmap.Processors[0] = ProcessorDefinition{Name: "FakePhoneNumber", PreserveCountryCode: true}
+	output, err = ProcessorPhoneNumber(cmap, "+49 (0)30 1234-5678")
+	require.Nil(t, err)
+	require.Regexp(t, `^\+49 \(\d\)\d{2} \d{4}-\d{4}$`, output)
+
+	// Empty values are faked
+	output, err = ProcessorPhoneNumber(cmap, "")
+	require.Nil(t, err)
+	require.NotEqual(t, "", output)
+}
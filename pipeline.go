@@ -0,0 +1,186 @@
+package gonymizer
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// defaultPipelineDepth is the number of buffers read ahead of and written behind the processing of a dump file when
+// ProcessOptions.PipelineDepth is not set.
+const defaultPipelineDepth = 4
+
+// pipelineChunk is a buffer passed between the I/O goroutines and the processing goroutine.
+type pipelineChunk struct {
+	data []byte
+	err  error
+}
+
+// readAhead is an io.Reader that reads the underlying reader in its own goroutine up to depth buffers of dumpBufferSize
+// ahead of the caller, so slow reads (network file systems, decompression) overlap with processing. When depth buffers
+// are waiting to be consumed the reading goroutine blocks until the caller catches up.
+type readAhead struct {
+	chunks  chan pipelineChunk
+	free    chan []byte
+	done    chan struct{}
+	once    sync.Once
+	current pipelineChunk
+	offset  int
+	waited  time.Duration
+}
+
+// newReadAhead starts reading r and returns the reader for the buffered data. Close must be called to stop reading.
+func newReadAhead(r io.Reader, depth int) *readAhead {
+	ra := &readAhead{
+		chunks: make(chan pipelineChunk, depth),
+		free:   make(chan []byte, depth+1),
+		done:   make(chan struct{}),
+	}
+	for i := 0; i < depth+1; i++ {
+		ra.free <- make([]byte, dumpBufferSize)
+	}
+	go ra.run(r)
+	return ra
+}
+
+// run reads r into the free buffers until it returns an error (including io.EOF) or the reader is closed.
+func (ra *readAhead) run(r io.Reader) {
+	defer close(ra.chunks)
+	for {
+		var buf []byte
+		select {
+		case buf = <-ra.free:
+		case <-ra.done:
+			return
+		}
+
+		n, err := io.ReadFull(r, buf)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		if n > 0 {
+			select {
+			case ra.chunks <- pipelineChunk{data: buf[:n]}:
+			case <-ra.done:
+				return
+			}
+		}
+		if err != nil {
+			select {
+			case ra.chunks <- pipelineChunk{err: err}:
+			case <-ra.done:
+			}
+			return
+		}
+	}
+}
+
+// Read copies the data read ahead into p, waiting for the reading goroutine if no data is buffered.
+func (ra *readAhead) Read(p []byte) (int, error) {
+	for ra.offset == len(ra.current.data) {
+		if ra.current.err != nil {
+			return 0, ra.current.err
+		}
+		if ra.current.data != nil {
+			ra.free <- ra.current.data[:cap(ra.current.data)]
+		}
+
+		started := time.Now()
+		chunk, ok := <-ra.chunks
+		ra.waited += time.Since(started)
+		if !ok {
+			chunk.err = io.ErrClosedPipe
+		}
+		ra.current, ra.offset = chunk, 0
+	}
+
+	n := copy(p, ra.current.data[ra.offset:])
+	ra.offset += n
+	return n, nil
+}
+
+// Close stops the reading goroutine. It does not close the underlying reader.
+func (ra *readAhead) Close() error {
+	ra.once.Do(func() { close(ra.done) })
+	return nil
+}
+
+// writeBehind is an io.Writer that writes to the underlying writer in its own goroutine, up to depth buffers behind the
+// caller, so slow writes (compression, uploads, slow disks) overlap with processing. When depth buffers are waiting to
+// be written the caller blocks until the writing goroutine catches up. A write error is returned by the next call to
+// Write or Close.
+type writeBehind struct {
+	chunks chan []byte
+	free   chan []byte
+	done   chan struct{}
+	once   sync.Once
+	mu     sync.Mutex
+	err    error
+	waited time.Duration
+}
+
+// newWriteBehind starts writing to w and returns the writer buffering the data. Close must be called to write the
+// buffered data and stop writing.
+func newWriteBehind(w io.Writer, depth int) *writeBehind {
+	wb := &writeBehind{
+		chunks: make(chan []byte, depth),
+		free:   make(chan []byte, depth+1),
+		done:   make(chan struct{}),
+	}
+	for i := 0; i < depth+1; i++ {
+		wb.free <- make([]byte, 0, dumpBufferSize)
+	}
+	go wb.run(w)
+	return wb
+}
+
+// run writes the buffers sent to the writer until it is closed. After an error the remaining buffers are discarded.
+func (wb *writeBehind) run(w io.Writer) {
+	defer close(wb.done)
+	for buf := range wb.chunks {
+		if wb.error() == nil {
+			if _, err := w.Write(buf); err != nil {
+				wb.mu.Lock()
+				wb.err = err
+				wb.mu.Unlock()
+			}
+		}
+		if cap(buf) == dumpBufferSize {
+			wb.free <- buf[:0]
+		}
+	}
+}
+
+// error returns the first error returned by the underlying writer.
+func (wb *writeBehind) error() error {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	return wb.err
+}
+
+// Write copies p to a free buffer and queues it to be written, waiting for the writing goroutine if depth buffers are
+// queued already.
+func (wb *writeBehind) Write(p []byte) (int, error) {
+	if err := wb.error(); err != nil {
+		return 0, err
+	}
+
+	started := time.Now()
+	buf := <-wb.free
+	wb.waited += time.Since(started)
+	if len(p) > cap(buf) {
+		// Lines longer than the buffer get their own buffer that is not reused
+		wb.free <- buf
+		buf = make([]byte, 0, len(p))
+	}
+	wb.chunks <- append(buf, p...)
+	return len(p), nil
+}
+
+// Close waits until the queued buffers are written and returns the first write error. It does not close the
+// underlying writer.
+func (wb *writeBehind) Close() error {
+	wb.once.Do(func() { close(wb.chunks) })
+	<-wb.done
+	return wb.error()
+}
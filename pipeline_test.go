@@ -0,0 +1,137 @@
+package gonymizer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// slowWriter is a writer taking delay for every KiB written, like a compressing or uploading writer.
+type slowWriter struct {
+	bytes.Buffer
+	delay time.Duration
+	err   error
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay * time.Duration(len(p)) / 1024)
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestReadAhead(t *testing.T) {
+	input := strings.Repeat("0123456789abcdef\n", 3*dumpBufferSize/17)
+	ra := newReadAhead(strings.NewReader(input), 2)
+	defer ra.Close()
+	output, err := ioutil.ReadAll(ra)
+	require.Nil(t, err)
+	require.Equal(t, input, string(output))
+
+	// Closing before everything is read stops the reading goroutine
+	ra = newReadAhead(strings.NewReader(input), 1)
+	buf := make([]byte, 10)
+	_, err = ra.Read(buf)
+	require.Nil(t, err)
+	require.Equal(t, input[:10], string(buf))
+	require.Nil(t, ra.Close())
+}
+
+func TestWriteBehind(t *testing.T) {
+	sink := &slowWriter{delay: time.Microsecond}
+	wb := newWriteBehind(sink, 2)
+	var expected bytes.Buffer
+	for i := 0; i < 20; i++ {
+		line := strings.Repeat(string(rune('a'+i)), i*1000) + "\n"
+		expected.WriteString(line)
+		n, err := wb.Write([]byte(line))
+		require.Nil(t, err)
+		require.Equal(t, len(line), n)
+	}
+
+	// Longer than a buffer
+	long := strings.Repeat("x", dumpBufferSize+1)
+	expected.WriteString(long)
+	_, err := wb.Write([]byte(long))
+	require.Nil(t, err)
+	require.Nil(t, wb.Close())
+	require.Nil(t, wb.Close())
+	require.Equal(t, expected.String(), sink.String())
+
+	// Write errors are returned by a later Write or Close
+	failed := errors.New("disk full")
+	wb = newWriteBehind(&slowWriter{err: failed}, 1)
+	_, err = wb.Write([]byte("a\n"))
+	require.Nil(t, err)
+	for i := 0; i < 10 && err == nil; i++ {
+		time.Sleep(time.Millisecond)
+		_, err = wb.Write([]byte("b\n"))
+	}
+	require.Equal(t, failed, err)
+	require.Equal(t, failed, wb.Close())
+}
+
+func TestProcessDumpFilePipelineDepth(t *testing.T) {
+	columnMap, err := LoadConfigSkeleton(TestMapFile)
+	require.Nil(t, err)
+
+	var outputs []string
+	for _, depth := range []int{-1, 1, 0} {
+		require.Nil(t, ProcessDumpFileWithOptions(columnMap, TestDbFile, TestPipelineDumpFile, "", "", false,
+			ProcessOptions{PipelineDepth: depth}))
+		output, err := ioutil.ReadFile(TestPipelineDumpFile)
+		require.Nil(t, err)
+		outputs = append(outputs, string(output))
+	}
+	require.Equal(t, outputs[0], outputs[1])
+	require.Equal(t, outputs[0], outputs[2])
+}
+
+// BenchmarkPipeline measures the throughput of CPU bound processing (scrambling every line) of a dump file written to
+// a slow writer, reading and writing in the processing goroutine (direct) and with read-ahead and write-behind buffers.
+func BenchmarkPipeline(b *testing.B) {
+	data := benchmarkDump(b, 1000)
+	for name, depth := range map[string]int{"direct": -1, "depth-1": 1, "default": defaultPipelineDepth} {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				var (
+					reader io.Reader = bytes.NewReader(data)
+					writer io.Writer = &slowWriter{delay: 5 * time.Microsecond}
+					ra     *readAhead
+					wb     *writeBehind
+				)
+				if depth > 0 {
+					ra, wb = newReadAhead(reader, depth), newWriteBehind(writer, depth)
+					reader, writer = ra, wb
+				}
+
+				scanner := newDumpScanner(reader)
+				out := make([]byte, 0, dumpBufferSize)
+				for {
+					line, err := scanner.next()
+					out = append(out, scrambleString(string(line))...)
+					if len(out) >= dumpBufferSize/16 || err != nil {
+						_, werr := writer.Write(out)
+						require.Nil(b, werr)
+						out = out[:0]
+					}
+					if err != nil {
+						break
+					}
+				}
+				if depth > 0 {
+					require.Nil(b, ra.Close())
+					require.Nil(b, wb.Close())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package gonymizer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MapPresetPrefix marks an Include of a map file that names a built-in map preset instead of a file, e.g.
+// "preset:django-auth@1". Without a version the latest version of the preset is included.
+const MapPresetPrefix = "preset:"
+
+// MapPreset is a versioned set of column maps for the tables of a widely used application or export format in the
+// public schema. A published version never changes, so map files including a pinned version (django-auth@1) always
+// anonymize the same way. Changes are published as a new version.
+type MapPreset struct {
+	Name        string
+	Version     int
+	Description string
+	ColumnMaps  []ColumnMapper
+}
+
+// presetColumns returns the column maps of the table from pairs of column and processor names. E-mail addresses
+// (EmailDomainPreserving) keep a consistent local part but are all moved to example.com.
+func presetColumns(table string, columns ...string) []ColumnMapper {
+	var cmaps []ColumnMapper
+	for i := 0; i+1 < len(columns); i += 2 {
+		procDef := ProcessorDefinition{Name: columns[i+1]}
+		if procDef.Name == "EmailDomainPreserving" {
+			procDef.Domains = map[string]string{emailDomainWildcard: "example.com"}
+		}
+		cmaps = append(cmaps, ColumnMapper{
+			TableSchema: "public",
+			TableName:   table,
+			ColumnName:  columns[i],
+			Processors:  []ProcessorDefinition{procDef},
+		})
+	}
+	return cmaps
+}
+
+// shopifyAddressColumns returns the column maps of the address columns of a Shopify order export with the prefix
+// (billing_ or shipping_).
+func shopifyAddressColumns(prefix string) []ColumnMapper {
+	return presetColumns("orders",
+		prefix+"name", "FakeFullName",
+		prefix+"street", "FakeStreetAddress",
+		prefix+"address1", "FakeStreetAddress",
+		prefix+"address2", "AlphaNumericScrambler",
+		prefix+"company", "FakeCompanyName",
+		prefix+"city", "FakeCity",
+		prefix+"zip", "FakeZip",
+		prefix+"province", "FakeStateAbbrev",
+		prefix+"phone", "FakePhoneNumber",
+	)
+}
+
+// mapPresets are the built-in map presets. Add new versions of a preset instead of changing a published one.
+var mapPresets = []MapPreset{
+	{
+		Name:        "django-auth",
+		Version:     1,
+		Description: "Django users (auth_user), sessions, and the admin log",
+		ColumnMaps: concatColumnMaps(
+			presetColumns("auth_user",
+				"password", "ScrubString",
+				"username", "AlphaNumericScrambler",
+				"first_name", "FakeFirstName",
+				"last_name", "FakeLastName",
+				"email", "EmailDomainPreserving",
+			),
+			presetColumns("django_session", "session_data", "ScrubString"),
+			presetColumns("django_admin_log", "object_repr", "ScrubString"),
+		),
+	},
+	{
+		Name:        "keycloak",
+		Version:     1,
+		Description: "Keycloak users, credentials, federated identities, and events",
+		ColumnMaps: concatColumnMaps(
+			presetColumns("user_entity",
+				"email", "EmailDomainPreserving",
+				"email_constraint", "EmailDomainPreserving",
+				"first_name", "FakeFirstName",
+				"last_name", "FakeLastName",
+				"username", "AlphaNumericScrambler",
+			),
+			presetColumns("credential", "secret_data", "ScrubString"),
+			presetColumns("user_attribute", "value", "AlphaNumericScrambler"),
+			presetColumns("fed_identity",
+				"user_name", "AlphaNumericScrambler",
+				"token", "ScrubString",
+			),
+			presetColumns("event_entity",
+				"ip_address", "FakeIPAddress",
+				"details_json", "EmptyJson",
+			),
+			presetColumns("admin_event_entity",
+				"ip_address", "FakeIPAddress",
+				"representation", "EmptyJson",
+			),
+		),
+	},
+	{
+		Name:        "rails-devise",
+		Version:     1,
+		Description: "Ruby on Rails users table with the Devise authentication columns",
+		ColumnMaps: presetColumns("users",
+			"email", "EmailDomainPreserving",
+			"unconfirmed_email", "EmailDomainPreserving",
+			"encrypted_password", "ScrubString",
+			"reset_password_token", "AlphaNumericScrambler",
+			"confirmation_token", "AlphaNumericScrambler",
+			"unlock_token", "AlphaNumericScrambler",
+			"current_sign_in_ip", "FakeIPAddress",
+			"last_sign_in_ip", "FakeIPAddress",
+		),
+	},
+	{
+		Name:        "shopify-customers",
+		Version:     1,
+		Description: "Shopify customer export (customers.csv) loaded into a customers table",
+		ColumnMaps: presetColumns("customers",
+			"first_name", "FakeFirstName",
+			"last_name", "FakeLastName",
+			"email", "EmailDomainPreserving",
+			"company", "FakeCompanyName",
+			"address1", "FakeStreetAddress",
+			"address2", "AlphaNumericScrambler",
+			"city", "FakeCity",
+			"province", "FakeState",
+			"province_code", "FakeStateAbbrev",
+			"zip", "FakeZip",
+			"phone", "FakePhoneNumber",
+			"note", "ScrubString",
+		),
+	},
+	{
+		Name:        "shopify-orders",
+		Version:     1,
+		Description: "Shopify order export (orders.csv) loaded into an orders table",
+		ColumnMaps: concatColumnMaps(
+			presetColumns("orders",
+				"email", "EmailDomainPreserving",
+				"phone", "FakePhoneNumber",
+				"notes", "ScrubString",
+			),
+			shopifyAddressColumns("billing_"),
+			shopifyAddressColumns("shipping_"),
+		),
+	},
+}
+
+// concatColumnMaps returns the column maps of every list in order.
+func concatColumnMaps(lists ...[]ColumnMapper) []ColumnMapper {
+	var cmaps []ColumnMapper
+	for _, list := range lists {
+		cmaps = append(cmaps, list...)
+	}
+	return cmaps
+}
+
+// MapPresets returns every version of the built-in map presets sorted by name and version.
+func MapPresets() []MapPreset {
+	presets := append([]MapPreset(nil), mapPresets...)
+	sort.Slice(presets, func(i, j int) bool {
+		if presets[i].Name != presets[j].Name {
+			return presets[i].Name < presets[j].Name
+		}
+		return presets[i].Version < presets[j].Version
+	})
+	return presets
+}
+
+// findMapPreset returns the map preset named by the include (preset:name or preset:name@version). Without a version the
+// latest version is returned.
+func findMapPreset(include string) (*MapPreset, error) {
+	name := strings.TrimPrefix(include, MapPresetPrefix)
+	version := 0
+	if at := strings.LastIndexByte(name, '@'); at >= 0 {
+		var err error
+		if version, err = strconv.Atoi(name[at+1:]); err != nil || version < 1 {
+			return nil, fmt.Errorf("Invalid version of map preset %q", include)
+		}
+		name = name[:at]
+	}
+
+	var found *MapPreset
+	for i, preset := range mapPresets {
+		if preset.Name == name && (preset.Version == version || (version == 0 &&
+			(found == nil || preset.Version > found.Version))) {
+			found = &mapPresets[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("Unknown map preset %q", include)
+	}
+	if version == 0 {
+		log.Warnf("Including the latest version of map preset %s (%d), pin it with %s%s@%d", name, found.Version,
+			MapPresetPrefix, name, found.Version)
+	}
+	return found, nil
+}
+
+// presetMap returns the map preset as a map to include, with the preset and its version in the Comment of every column.
+func (preset *MapPreset) presetMap() *DBMapper {
+	dbmap := &DBMapper{ColumnMaps: make([]ColumnMapper, len(preset.ColumnMaps))}
+	for i, cmap := range preset.ColumnMaps {
+		cmap.Comment = fmt.Sprintf("Map preset %s@%d", preset.Name, preset.Version)
+		dbmap.ColumnMaps[i] = cmap
+	}
+	return dbmap
+}
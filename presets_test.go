@@ -0,0 +1,81 @@
+package gonymizer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapPresets(t *testing.T) {
+	presets := MapPresets()
+	require.NotEmpty(t, presets)
+	seen := map[string]bool{}
+	for _, preset := range presets {
+		key := fmt.Sprintf("%s@%d", preset.Name, preset.Version)
+		require.False(t, seen[key], key)
+		seen[key] = true
+
+		dbmap := preset.presetMap()
+		dbmap.DBName = "preset"
+		require.Nil(t, dbmap.Validate(), key)
+	}
+	for _, name := range []string{"django-auth", "keycloak", "rails-devise", "shopify-customers", "shopify-orders"} {
+		require.True(t, seen[name+"@1"], name)
+	}
+}
+
+func TestFindMapPreset(t *testing.T) {
+	defer func() { mapPresets = mapPresets[:len(mapPresets)-1] }()
+	mapPresets = append(mapPresets, MapPreset{Name: "django-auth", Version: 2})
+
+	preset, err := findMapPreset("preset:django-auth@1")
+	require.Nil(t, err)
+	require.Equal(t, 1, preset.Version)
+	require.NotEmpty(t, preset.ColumnMaps)
+
+	preset, err = findMapPreset("preset:django-auth")
+	require.Nil(t, err)
+	require.Equal(t, 2, preset.Version)
+
+	for _, include := range []string{"preset:django-auth@3", "preset:django-auth@x", "preset:wordpress"} {
+		_, err = findMapPreset(include)
+		require.NotNil(t, err, include)
+	}
+}
+
+func TestLoadConfigSkeletonPreset(t *testing.T) {
+	defer delete(AlphaNumericMap, emailMapKey)
+	f, err := ioutil.TempFile("", "gonymizer-preset-*.json")
+	require.Nil(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{
+		"DBName": "app",
+		"Seed": 1,
+		"Include": ["preset:django-auth@1"],
+		"ColumnMaps": [
+			{"TableSchema": "public", "TableName": "auth_user", "ColumnName": "username",
+			 "Processors": [{"Name": "Identity"}]}
+		]
+	}`)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	dbmap, err := LoadConfigSkeleton(f.Name())
+	require.Nil(t, err)
+
+	// Columns of the map file override the preset
+	cmap := dbmap.ColumnMapper("public", "auth_user", "username")
+	require.NotNil(t, cmap)
+	require.Equal(t, "Identity", cmap.Processors[0].Name)
+
+	cmap = dbmap.ColumnMapper("public", "auth_user", "email")
+	require.NotNil(t, cmap)
+	require.Equal(t, "EmailDomainPreserving", cmap.Processors[0].Name)
+	require.Equal(t, "Map preset django-auth@1", cmap.Comment)
+	output, err := ProcessorEmailDomainPreserving(cmap, "jane@customer.com")
+	require.Nil(t, err)
+	require.Regexp(t, `^[a-z]{4}@example\.com$`, output)
+}
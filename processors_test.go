@@ -1,7 +1,11 @@
 package gonymizer
 
 import (
+	"encoding/base64"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -60,6 +64,28 @@ func TestProcessorAlphaNumericScrambler(t *testing.T) {
 	require.Equal(t, outputA, outputC)
 }
 
+func TestProcessorAlphaNumericScramblerPreserveRepeats(t *testing.T) {
+	cmap := ColumnMapper{Processors: []ProcessorDefinition{{Name: "AlphaNumericScrambler", PreserveRepeats: true}}}
+
+	for i := 0; i < 100; i++ {
+		output, err := ProcessorAlphaNumericScrambler(&cmap, "AA-123-AA")
+		require.Nil(t, err)
+		require.Regexp(t, "^[A-Z]{2}-[0-9]{3}-[A-Z]{2}$", output)
+		require.Equal(t, strings.Repeat(output[:1], 4), output[:2]+output[7:])
+		require.NotEqual(t, output[3], output[4])
+		require.NotEqual(t, output[4], output[5])
+	}
+
+	// Different characters stay different even when every character of a set is used
+	output := scrambleRepeats("abcdefghijklmnopqrstuvwxyz0123456789", "")
+	seen := map[rune]bool{}
+	for _, c := range output {
+		require.False(t, seen[c])
+		seen[c] = true
+	}
+	require.Regexp(t, "^[a-z]{26}[0-9]{10}$", output)
+}
+
 func TestProcessorAddress(t *testing.T) {
 	output, err := ProcessorAddress(&cMap, "1234 Testing Lane")
 	require.Nil(t, err)
@@ -100,6 +126,20 @@ func TestProcessorFirstName(t *testing.T) {
 	require.NotEqual(t, output, "")
 }
 
+func TestProcessorFirstNamePreserveCase(t *testing.T) {
+	cmap := ColumnMapper{
+		Processors: []ProcessorDefinition{{Name: "FakeFirstName", PreserveCase: true}},
+	}
+
+	output, err := ProcessorFirstName(&cmap, "JANE")
+	require.Nil(t, err)
+	require.Equal(t, strings.ToUpper(output), output)
+
+	output, err = ProcessorFirstName(&cmap, "jane")
+	require.Nil(t, err)
+	require.Equal(t, strings.ToLower(output), output)
+}
+
 func TestProcessorFullName(t *testing.T) {
 	output, err := ProcessorFullName(&cMap, "Morty & Rick")
 	require.Nil(t, err)
@@ -110,6 +150,37 @@ func TestProcessorFullName(t *testing.T) {
 	require.NotEqual(t, output, "")
 }
 
+func TestProcessorNameConsistency(t *testing.T) {
+	users := ColumnMapper{
+		TableName:    "users",
+		ParentSchema: "public",
+		ParentTable:  "users",
+		ParentColumn: "full_name",
+		Processors:   []ProcessorDefinition{{Name: "FakeFullName", PreserveCase: true}},
+	}
+	orders := users
+	orders.TableName = "orders"
+
+	output, err := ProcessorFullName(&users, "John Smith")
+	require.Nil(t, err)
+	same, err := ProcessorFullName(&orders, "John Smith")
+	require.Nil(t, err)
+	require.Equal(t, output, same)
+
+	upper, err := ProcessorFullName(&orders, " JOHN SMITH")
+	require.Nil(t, err)
+	require.Equal(t, strings.ToUpper(output), upper)
+
+	first := ColumnMapper{ParentSchema: "public", ParentTable: "users", ParentColumn: "first_name"}
+	output, err = ProcessorFirstName(&first, "John")
+	require.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		same, err = ProcessorFirstName(&first, "John")
+		require.Nil(t, err)
+		require.Equal(t, output, same)
+	}
+}
+
 func TestProcessorIdentity(t *testing.T) {
 	output, err := ProcessorIdentity(&cMap, "Hi Rick!")
 	require.Nil(t, err)
@@ -204,6 +275,20 @@ func TestProcessorCompanyName(t *testing.T) {
 	require.NotEqual(t, output, "")
 }
 
+func TestProcessorIndustry(t *testing.T) {
+	output, err := ProcessorIndustry(&cMap, "Interdimensional Travel")
+	require.Nil(t, err)
+	require.NotEqual(t, output, "Interdimensional Travel")
+	require.NotEqual(t, output, "")
+}
+
+func TestProcessorJobTitle(t *testing.T) {
+	output, err := ProcessorJobTitle(&cMap, "Mad Scientist")
+	require.Nil(t, err)
+	require.NotEqual(t, output, "Mad Scientist")
+	require.NotEqual(t, output, "")
+}
+
 func TestProcessorRandomBoolean(t *testing.T) {
 	output, err := ProcessorRandomBoolean(&cMap, "FALSE")
 	require.Nil(t, err)
@@ -229,6 +314,62 @@ func TestProcessorRandomDate(t *testing.T) {
 		require.NotNil(t, err)
 		require.NotEqual(t, output, nil)
 	}
+
+	// Timestamps keep their time of day and offset
+	for input, pattern := range map[string]string{
+		"2018-08-28 13:45:00":              `^2018-\d{2}-\d{2} 13:45:00$`,
+		"2018-08-28 13:45:00+02":           `^2018-\d{2}-\d{2} 13:45:00\+02$`,
+		"2018-08-28 13:45:00.123456-03:30": `^2018-\d{2}-\d{2} 13:45:00\.123456-03:30$`,
+		"2018-08-28T13:45Z":                `^2018-\d{2}-\d{2}T13:45Z$`,
+	} {
+		output, err = ProcessorRandomDate(&cMap, input)
+		require.Nil(t, err)
+		require.Regexp(t, pattern, output)
+	}
+
+	// Normalized to UTC
+	utc := ColumnMapper{Processors: []ProcessorDefinition{{Name: "RandomDate", UTC: true}}}
+	for input, pattern := range map[string]string{
+		"2018-08-28 13:45:00+02":           `^2018-\d{2}-\d{2} 11:45:00\+00$`,
+		"2018-08-28 13:45:00.120000-03:30": `^2018-\d{2}-\d{2} 17:15:00\.120000\+00$`,
+		"2018-12-31 23:30:00-01":           `^2019-\d{2}-\d{2} 00:30:00\+00$`,
+		"2018-08-28 13:45:00":              `^2018-\d{2}-\d{2} 13:45:00$`,
+	} {
+		output, err = ProcessorRandomDate(&utc, input)
+		require.Nil(t, err)
+		require.Regexp(t, pattern, output)
+	}
+}
+
+func TestProcessorRandomTimestamp(t *testing.T) {
+	output, err := ProcessorRandomTimestamp(&cMap, "I AM THE FAIL BOAT!")
+	require.NotNil(t, err)
+
+	// The time of day is randomized with the same precision, the offset is kept
+	for input, pattern := range map[string]string{
+		"1970-01-01":                       `^1970-\d{2}-\d{2}$`,
+		"2018-08-28 13:45:00":              `^2018-\d{2}-\d{2} \d{2}:\d{2}:\d{2}$`,
+		"2018-08-28 13:45:00+02":           `^2018-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\+02$`,
+		"2018-08-28 13:45:00.123456-03:30": `^2018-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{6}-03:30$`,
+		"2018-08-28T13:45Z":                `^2018-\d{2}-\d{2}T\d{2}:\d{2}Z$`,
+	} {
+		output, err = ProcessorRandomTimestamp(&cMap, input)
+		require.Nil(t, err)
+		require.Regexp(t, pattern, output)
+	}
+
+	times := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		output, err = ProcessorRandomTimestamp(&cMap, "2018-08-28 13:45:00.123")
+		require.Nil(t, err)
+		times[output[11:]] = true
+	}
+	require.True(t, len(times) > 1)
+
+	utc := ColumnMapper{Processors: []ProcessorDefinition{{Name: "RandomTimestamp", UTC: true}}}
+	output, err = ProcessorRandomTimestamp(&utc, "2018-12-31 23:30:00-01")
+	require.Nil(t, err)
+	require.Regexp(t, `^2019-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\+00$`, output)
 }
 
 func TestProcessorRandomDigits(t *testing.T) {
@@ -297,3 +438,465 @@ func TestRandomizeUUID(t *testing.T) {
 	require.NotNil(t, err)
 	require.Equal(t, output, "")
 }
+
+func TestProcessorEIN(t *testing.T) {
+	re := regexp.MustCompile(`^[0-9]{2}-[0-9]{7}$`)
+	output, err := ProcessorEIN(&cMap, "12-3456789")
+	require.Nil(t, err)
+	require.True(t, re.MatchString(output))
+	require.Contains(t, einPrefixes, output[:2])
+
+	output, err = ProcessorEIN(&cMap, "123456789")
+	require.Nil(t, err)
+	require.Regexp(t, `^[0-9]{9}$`, output)
+}
+
+func TestProcessorUTR(t *testing.T) {
+	output, err := ProcessorUTR(&cMap, "1234567890")
+	require.Nil(t, err)
+	require.Len(t, output, 10)
+	require.Equal(t, string(output[0]), strconv.Itoa(utrCheckDigit(output[1:])))
+}
+
+func TestProcessorVATNumber(t *testing.T) {
+	var formats = map[string]string{
+		"ATU13585627":    `^ATU[0-9]{8}$`,
+		"BE0428759497":   `^BE[01][0-9]{9}$`,
+		"DE136695976":    `^DE[1-9][0-9]{8}$`,
+		"FR40303265045":  `^FR[0-9]{11}$`,
+		"IT00743110157":  `^IT[0-9]{11}$`,
+		"NL004495445B01": `^NL[0-9]{9}B01$`,
+		"SE556703748501": `^SE[0-9]{12}$`,
+	}
+	for input, format := range formats {
+		output, err := ProcessorVATNumber(&cMap, input)
+		require.Nil(t, err)
+		require.Regexp(t, format, output)
+	}
+
+	// Italian VAT numbers end with a Luhn check digit
+	output, err := ProcessorVATNumber(&cMap, "IT00743110157")
+	require.Nil(t, err)
+	require.Equal(t, string(output[12]), strconv.Itoa(luhnCheckDigit(output[2:12])))
+
+	output, err = ProcessorVATNumber(&cMap, "")
+	require.Nil(t, err)
+	require.Contains(t, vatGenerators, output[:2])
+}
+
+func TestLuhnCheckDigit(t *testing.T) {
+	require.Equal(t, 3, luhnCheckDigit("7992739871"))
+	require.Equal(t, 6, luhnCheckDigit("453201511283036"))
+}
+
+func TestProcessorMRZ(t *testing.T) {
+	const line1 = "P<UTOERIKSSON<<ANNA<MARIA<<<<<<<<<<<<<<<<<<<"
+	const line2 = "L898902C36UTO7408122F1204159ZE184226B<<<<<10"
+
+	for _, sep := range []string{"", "\\n", "\n"} {
+		output, err := ProcessorMRZ(&cMap, line1+sep+line2)
+		require.Nil(t, err)
+		require.Len(t, output, len(line1)+len(sep)+len(line2))
+		require.NotEqual(t, line1+sep+line2, output)
+
+		outLine1 := output[:mrzLineLength]
+		outLine2 := output[mrzLineLength+len(sep):]
+		require.Equal(t, sep, output[mrzLineLength:mrzLineLength+len(sep)])
+		require.Equal(t, "P<UTO", outLine1[:5])
+		require.Equal(t, line2[10:13], outLine2[10:13])
+		require.Equal(t, line2[20:27], outLine2[20:27])
+
+		// Verify check digits for passport number, birth date, expiry, and composite
+		require.Equal(t, strconv.Itoa(mrzCheckDigit(outLine2[0:9])), outLine2[9:10])
+		require.Equal(t, strconv.Itoa(mrzCheckDigit(outLine2[13:19])), outLine2[19:20])
+		require.Equal(t, strconv.Itoa(mrzCheckDigit(outLine2[21:27])), outLine2[27:28])
+		require.Equal(t, strconv.Itoa(mrzCheckDigit(outLine2[0:10]+outLine2[13:20]+outLine2[21:43])), outLine2[43:])
+	}
+
+	_, err := ProcessorMRZ(&cMap, "I AM NOT AN MRZ")
+	require.NotNil(t, err)
+}
+
+func TestProcessorPassportNumber(t *testing.T) {
+	output, err := ProcessorPassportNumber(&cMap, "L898902C3")
+	require.Nil(t, err)
+	require.Regexp(t, `^[A-Z][0-9]{6}[A-Z][0-9]$`, output)
+
+	output, err = ProcessorPassportNumber(&cMap, "")
+	require.Nil(t, err)
+	require.Regexp(t, `^[A-Z][0-9]{8}$`, output)
+}
+
+func TestMRZCheckDigit(t *testing.T) {
+	// Values taken from the ICAO 9303 specimen passport
+	require.Equal(t, 6, mrzCheckDigit("L898902C3"))
+	require.Equal(t, 2, mrzCheckDigit("740812"))
+	require.Equal(t, 9, mrzCheckDigit("120415"))
+}
+
+func TestProcessorBankAccountNumber(t *testing.T) {
+	var accountTest ColumnMapper
+
+	output, err := ProcessorBankAccountNumber(&accountTest, "000123456789")
+	require.Nil(t, err)
+	require.Regexp(t, `^[0-9]{10,12}$`, output)
+
+	accountTest.Processors = []ProcessorDefinition{
+		{
+			Name:           "FakeBankAccountNumber",
+			PrefixLength:   4,
+			PreserveLength: true,
+		},
+	}
+	output, err = ProcessorBankAccountNumber(&accountTest, "4455-66778899")
+	require.Nil(t, err)
+	require.Regexp(t, `^4455-[0-9]{8}$`, output)
+
+	accountTest.ParentSchema = "test_schema"
+	accountTest.ParentTable = "test_table"
+	accountTest.ParentColumn = "account_number"
+	outputA, err := ProcessorBankAccountNumber(&accountTest, "4455-66778899")
+	require.Nil(t, err)
+	outputB, err := ProcessorBankAccountNumber(&accountTest, "4455-66778899")
+	require.Nil(t, err)
+	require.Equal(t, outputA, outputB)
+}
+
+func TestProcessorRoutingNumber(t *testing.T) {
+	output, err := ProcessorRoutingNumber(&cMap, "011000015")
+	require.Nil(t, err)
+	require.Regexp(t, `^[0-9]{9}$`, output)
+	require.Contains(t, abaPrefixes, output[:2])
+	require.Equal(t, strconv.Itoa(abaCheckDigit(output[:8])), output[8:])
+}
+
+func TestABACheckDigit(t *testing.T) {
+	require.Equal(t, 5, abaCheckDigit("01100001"))
+	require.Equal(t, 8, abaCheckDigit("12100035"))
+}
+
+func TestProcessorCryptoAddress(t *testing.T) {
+	var cryptoTest ColumnMapper
+
+	output, err := ProcessorCryptoAddress(&cryptoTest, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+	require.Nil(t, err)
+	require.Regexp(t, `^0x[0-9a-fA-F]{40}$`, output)
+	require.Equal(t, output[2:], eip55Checksum(strings.ToLower(output[2:])))
+
+	output, err = ProcessorCryptoAddress(&cryptoTest, "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2")
+	require.Nil(t, err)
+	require.Regexp(t, `^1[1-9A-HJ-NP-Za-km-z]{25,33}$`, output)
+
+	cryptoTest.ParentSchema = "test_schema"
+	cryptoTest.ParentTable = "test_table"
+	cryptoTest.ParentColumn = "wallet"
+	outputA, err := ProcessorCryptoAddress(&cryptoTest, "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2")
+	require.Nil(t, err)
+	outputB, err := ProcessorCryptoAddress(&cryptoTest, "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2")
+	require.Nil(t, err)
+	require.Equal(t, outputA, outputB)
+}
+
+func TestProcessorDeviceSerial(t *testing.T) {
+	var serialTest ColumnMapper
+
+	output, err := ProcessorDeviceSerial(&serialTest, "FCX1234-AB99")
+	require.Nil(t, err)
+	require.Regexp(t, `^FCX[0-9]{4}-[A-Z]{2}[0-9]{2}$`, output)
+
+	serialTest.Processors = []ProcessorDefinition{{Name: "FakeDeviceSerial", PrefixLength: 5}}
+	output, err = ProcessorDeviceSerial(&serialTest, "FCX1234-AB99")
+	require.Nil(t, err)
+	require.Regexp(t, `^FCX12[0-9]{2}-[A-Z]{2}[0-9]{2}$`, output)
+
+	output, err = ProcessorDeviceSerial(&serialTest, "")
+	require.Nil(t, err)
+	require.Equal(t, "", output)
+}
+
+func TestProcessorIMEI(t *testing.T) {
+	var imeiTest ColumnMapper
+
+	output, err := ProcessorIMEI(&imeiTest, "490154203237518")
+	require.Nil(t, err)
+	require.Regexp(t, `^[0-9]{15}$`, output)
+	require.Equal(t, strconv.Itoa(luhnCheckDigit(output[:14])), output[14:])
+
+	imeiTest.Processors = []ProcessorDefinition{{Name: "FakeIMEI", PrefixLength: 8}}
+	output, err = ProcessorIMEI(&imeiTest, "49-015420-323751-8")
+	require.Nil(t, err)
+	require.Equal(t, "49015420", output[:8])
+	require.Equal(t, strconv.Itoa(luhnCheckDigit(output[:14])), output[14:])
+}
+
+func TestProcessorIMSI(t *testing.T) {
+	output, err := ProcessorIMSI(&cMap, "310260123456789")
+	require.Nil(t, err)
+	require.Regexp(t, `^310260[0-9]{9}$`, output)
+
+	output, err = ProcessorIMSI(&cMap, "")
+	require.Nil(t, err)
+	require.Regexp(t, `^[0-9]{15}$`, output)
+}
+
+func TestProcessorUserAgent(t *testing.T) {
+	input := "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0 MyToolbar/1.2.3-user4471"
+	output, err := ProcessorUserAgent(&cMap, input)
+	require.Nil(t, err)
+	require.Equal(t, "Mozilla/5.0 (X11; Linux x86_64; rv:115.0) Gecko/20100101 Firefox/115.0", output)
+}
+
+func TestProcessorHostname(t *testing.T) {
+	outputA, err := ProcessorHostname(&cMap, "web1.prod.example.com")
+	require.Nil(t, err)
+	outputB, err := ProcessorHostname(&cMap, "DB01.prod.example.com")
+	require.Nil(t, err)
+
+	labelsA := strings.Split(outputA, ".")
+	labelsB := strings.Split(outputB, ".")
+	require.Len(t, labelsA, 4)
+	require.Len(t, labelsB, 4)
+	require.NotEqual(t, "web1", labelsA[0])
+	require.Regexp(t, `^[a-z]{3}[0-9]$`, labelsA[0])
+	require.Equal(t, labelsA[1:], labelsB[1:])
+	require.Equal(t, "com", labelsA[3])
+
+	output, err := ProcessorHostname(&cMap, "mail.example.co.uk.")
+	require.Nil(t, err)
+	require.Regexp(t, `^[a-z]{4}\.[a-z]{7}\.co\.uk\.$`, output)
+
+	output, err = ProcessorHostname(&cMap, "localhost")
+	require.Nil(t, err)
+	require.Regexp(t, `^[a-z]{9}$`, output)
+}
+
+func TestProcessorFilePath(t *testing.T) {
+	outputA, err := ProcessorFilePath(&cMap, "/home/jsmith/projects/Jane_Smith.pdf")
+	require.Nil(t, err)
+	require.Regexp(t, `^/home/[a-z]{6}/projects/[A-Z][a-z]{3}_[A-Z][a-z]{4}\.pdf$`, outputA)
+	require.NotContains(t, outputA, "jsmith")
+
+	outputB, err := ProcessorFilePath(&cMap, "/home/jsmith/.bashrc")
+	require.Nil(t, err)
+	require.Equal(t, strings.Split(outputA, "/")[2], strings.Split(outputB, "/")[2])
+
+	output, err := ProcessorFilePath(&cMap, `C:\\Users\\JaneD\\Documents\\patient_1234567\\scan.tar.gz`)
+	require.Nil(t, err)
+	require.Regexp(t, `^C:\\\\Users\\\\[A-Z][a-z]{3}[A-Z]\\\\Documents\\\\[a-z]{7}_[0-9]{7}\\\\[a-z]{4}\.tar\.gz$`,
+		output)
+
+	output, err = ProcessorFilePath(&cMap, "")
+	require.Nil(t, err)
+	require.Equal(t, "", output)
+}
+
+func TestProcessorSocialHandle(t *testing.T) {
+	handle, err := ProcessorSocialHandle(&cMap, "@JSmith")
+	require.Nil(t, err)
+	require.Regexp(t, `^@[a-z0-9_]+$`, handle)
+
+	output, err := ProcessorSocialHandle(&cMap, "jsmith")
+	require.Nil(t, err)
+	require.Equal(t, handle[1:], output)
+
+	output, err = ProcessorSocialHandle(&cMap, "https://twitter.com/jsmith/status/12345?s=20")
+	require.Nil(t, err)
+	require.Equal(t, "https://twitter.com/"+handle[1:], output)
+
+	output, err = ProcessorSocialHandle(&cMap, "https://www.linkedin.com/in/jsmith/")
+	require.Nil(t, err)
+	require.Equal(t, "https://www.linkedin.com/in/"+handle[1:], output)
+
+	output, err = ProcessorSocialHandle(&cMap, "https://www.tiktok.com/@jsmith")
+	require.Nil(t, err)
+	require.Equal(t, "https://www.tiktok.com/"+handle, output)
+
+	output, err = ProcessorSocialHandle(&cMap, "twitter.com/jsmith")
+	require.Nil(t, err)
+	require.Equal(t, "twitter.com/"+handle[1:], output)
+
+	output, err = ProcessorSocialHandle(&cMap, "www.instagram.com/JSmith/?hl=en")
+	require.Nil(t, err)
+	require.Equal(t, "www.instagram.com/"+handle[1:], output)
+
+	output, err = ProcessorSocialHandle(&cMap, "")
+	require.Nil(t, err)
+	require.Equal(t, "", output)
+}
+
+func TestProcessorSSN(t *testing.T) {
+	var ssnTest ColumnMapper
+
+	for i := 0; i < 1000; i++ {
+		output, err := ProcessorSSN(&ssnTest, "123-45-6789")
+		require.Nil(t, err)
+		require.Regexp(t, `^[0-8][0-9]{2}-[0-9]{2}-[0-9]{4}$`, output)
+		require.True(t, validSSNArea(output[:3]))
+		require.NotEqual(t, "00", output[4:6])
+		require.NotEqual(t, "0000", output[7:])
+	}
+
+	output, err := ProcessorSSN(&ssnTest, "123456789")
+	require.Nil(t, err)
+	require.Regexp(t, `^[0-9]{9}$`, output)
+
+	ssnTest.Processors = []ProcessorDefinition{{Name: "FakeSSN", PrefixLength: 3}}
+	output, err = ProcessorSSN(&ssnTest, "123-45-6789")
+	require.Nil(t, err)
+	require.Regexp(t, `^123-[0-9]{2}-[0-9]{4}$`, output)
+
+	ssnTest.Processors = []ProcessorDefinition{{Name: "FakeSSN", PrefixLength: 5}}
+	output, err = ProcessorSSN(&ssnTest, "123456789")
+	require.Nil(t, err)
+	require.Regexp(t, `^12345[0-9]{4}$`, output)
+
+	// Invalid area numbers are never kept
+	output, err = ProcessorSSN(&ssnTest, "666-45-6789")
+	require.Nil(t, err)
+	require.NotEqual(t, "666", output[:3])
+}
+
+func TestProcessorBase64Payload(t *testing.T) {
+	cmap := ColumnMapper{
+		Processors: []ProcessorDefinition{
+			{
+				Name:       "Base64Payload",
+				Processors: []ProcessorDefinition{{Name: "ScrubString"}},
+			},
+		},
+	}
+
+	// Plain text payload
+	output, err := ProcessorBase64Payload(&cmap, base64.StdEncoding.EncodeToString([]byte("Jane Doe")))
+	require.Nil(t, err)
+	payload, err := base64.StdEncoding.DecodeString(output)
+	require.Nil(t, err)
+	require.Equal(t, "********", string(payload))
+
+	// JSON payload keeps the document structure and only processes the listed keys
+	cmap.Processors[0].Keys = []string{"email"}
+	input := `{"contacts":[{"email":"jane@example.com"}],"id":12345678901234567890,"name":"Jane Doe"}`
+	output, err = ProcessorBase64Payload(&cmap, base64.URLEncoding.EncodeToString([]byte(input)))
+	require.Nil(t, err)
+	payload, err = base64.URLEncoding.DecodeString(output)
+	require.Nil(t, err)
+	require.Equal(t, `{"contacts":[{"email":"****************"}],"id":12345678901234567890,"name":"Jane Doe"}`,
+		string(payload))
+
+	output, err = ProcessorBase64Payload(&cmap, "")
+	require.Nil(t, err)
+	require.Equal(t, "", output)
+
+	_, err = ProcessorBase64Payload(&cmap, "not base64!")
+	require.NotNil(t, err)
+}
+
+func TestMatchCase(t *testing.T) {
+	require.Equal(t, "SMITH", matchCase("JONES", "Smith"))
+	require.Equal(t, "MARY SMITH", matchCase("JANE O'DOE", "Mary Smith"))
+	require.Equal(t, "smith", matchCase("jones", "Smith"))
+	require.Equal(t, "Mary Smith", matchCase("Jane Doe", "mary smith"))
+	require.Equal(t, "Mary O'Smith", matchCase("Jane", "MARY O'SMITH"))
+	require.Equal(t, "Mary Smith", matchCase("McDonald", "Mary Smith"))
+	require.Equal(t, "Mary Smith", matchCase("12345", "Mary Smith"))
+}
+
+func TestProcessorAddressPreserveFormat(t *testing.T) {
+	cmap := ColumnMapper{
+		Processors: []ProcessorDefinition{{Name: "FakeStreetAddress", PreserveFormat: true}},
+	}
+
+	input := "123 N. Main St. Apt 4C\\nAnytown, CA 90210"
+	output, err := ProcessorAddress(&cmap, input)
+	require.Nil(t, err)
+	require.NotEqual(t, input, output)
+	require.Regexp(t, `^[1-9][0-9]{2} N\. [A-Z][a-z]+ St\. Apt [0-9][A-Z]\\n[A-Z][a-z]+, CA [1-9][0-9]{4}$`, output)
+
+	output, err = ProcessorAddress(&cmap, "1600 PENNSYLVANIA AVENUE NW, SUITE 100")
+	require.Nil(t, err)
+	require.Regexp(t, `^[1-9][0-9]{3} [A-Z]+ AVENUE NW, SUITE [1-9][0-9]{2}$`, output)
+
+	output, err = ProcessorAddress(&cmap, "22nd st")
+	require.Nil(t, err)
+	require.Regexp(t, `^[1-9][0-9](st|nd|rd|th) st$`, output)
+}
+
+func TestOrdinal(t *testing.T) {
+	require.Equal(t, "1st", ordinal("1"))
+	require.Equal(t, "22nd", ordinal("22"))
+	require.Equal(t, "103rd", ordinal("103"))
+	require.Equal(t, "11th", ordinal("11"))
+	require.Equal(t, "112th", ordinal("112"))
+	require.Equal(t, "5th", ordinal("5"))
+}
+
+func TestScrambleString(t *testing.T) {
+	input := "Jane Doe, 4111-1111-1111-1111 <jane@example.com>"
+	output := scrambleString(input)
+	require.Equal(t, len(input), len(output))
+	for i := 0; i < len(input); i++ {
+		require.Equal(t, scrambleClasses[input[i]], scrambleClasses[output[i]], "%q != %q", input[i], output[i])
+		if scrambleClasses[input[i]] == 0 {
+			require.Equal(t, input[i], output[i])
+		}
+	}
+
+	// Every digit shows up
+	seen := map[byte]bool{}
+	for _, c := range []byte(scrambleString(strings.Repeat("0", 1000))) {
+		seen[c] = true
+	}
+	require.Len(t, seen, 10)
+}
+
+func TestRegisterProcessor(t *testing.T) {
+	reverse := func(cmap *ColumnMapper, input string) (string, error) { return "esrever", nil }
+	defer delete(ProcessorCatalog, "TestReverse")
+
+	require.Nil(t, RegisterProcessor("TestReverse", reverse))
+	require.NotNil(t, RegisterProcessor("TestReverse", reverse))
+	require.NotNil(t, RegisterProcessor("FakeEmailAddress", reverse))
+	require.NotNil(t, RegisterProcessor("", reverse))
+	require.NotNil(t, RegisterProcessor("TestNil", nil))
+
+	names := RegisteredProcessors()
+	require.Contains(t, names, "TestReverse")
+	require.Contains(t, names, "FakeEmailAddress")
+	require.True(t, sort.StringsAreSorted(names))
+
+	// Map files can use the processor once it is registered
+	dbmap := &DBMapper{
+		DBName: "pii_localtest",
+		ColumnMaps: []ColumnMapper{{
+			TableSchema: "public",
+			TableName:   "users",
+			ColumnName:  "name",
+			Processors:  []ProcessorDefinition{{Name: "TestReverse"}},
+		}},
+	}
+	require.Nil(t, dbmap.Validate())
+	delete(ProcessorCatalog, "TestReverse")
+	require.NotNil(t, dbmap.Validate())
+
+	// Inner processors are checked as well
+	dbmap.ColumnMaps[0].Processors = []ProcessorDefinition{{Name: "ValueClass", Processors: []ProcessorDefinition{
+		{Name: "FakeEmailAddres", Keys: []string{"email"}},
+	}}}
+	require.NotNil(t, dbmap.Validate())
+}
+
+func BenchmarkScrambleString(b *testing.B) {
+	inputs := map[string]string{
+		"digits": strings.Repeat("4111111111111111", 64),
+		"mixed":  strings.Repeat("Jane Doe <jane.doe42@example.com>, ", 32),
+	}
+	for name, input := range inputs {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				scrambleString(input)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package gonymizer
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Protocol buffer wire types. See: https://developers.google.com/protocol-buffers/docs/encoding
+const (
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+	protoWireFixed32 = 5
+)
+
+// Field types (FieldDescriptorProto.Type) from google/protobuf/descriptor.proto that can be anonymized.
+const (
+	protoTypeString  = 9
+	protoTypeMessage = 11
+	protoTypeBytes   = 12
+)
+
+// errProtoTruncated is returned when a protobuf message ends in the middle of a field.
+var errProtoTruncated = errors.New("Truncated protobuf message")
+
+// protoDescriptorSets caches the message descriptors of every descriptor set file that has been loaded so the file is
+// only read once per run.
+var protoDescriptorSets = map[string]map[string]*protoMessageDescriptor{}
+
+// protoField is a single field read from an encoded protobuf message. Raw contains the complete encoded field (tag and
+// value) so fields that are not modified can be written back untouched. Value contains the payload of length-delimited
+// fields and Varint contains the value of varint fields.
+type protoField struct {
+	Number   int
+	WireType int
+	Raw      []byte
+	Value    []byte
+	Varint   uint64
+}
+
+// protoFieldDescriptor is the part of a FieldDescriptorProto needed to walk an encoded message.
+type protoFieldDescriptor struct {
+	Name     string
+	Number   int
+	Type     int
+	TypeName string
+}
+
+// protoMessageDescriptor is the part of a DescriptorProto needed to walk an encoded message. Fields are keyed by field
+// number.
+type protoMessageDescriptor struct {
+	Name   string
+	Fields map[int]protoFieldDescriptor
+}
+
+// loadProtoDescriptorSet will load a FileDescriptorSet (as created by `protoc --descriptor_set_out`) and return every
+// message descriptor in the set keyed by its fully qualified name (package.Message.Nested).
+func loadProtoDescriptorSet(path string) (map[string]*protoMessageDescriptor, error) {
+	if registry, ok := protoDescriptorSets[path]; ok {
+		return registry, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := parseProtoMessage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := map[string]*protoMessageDescriptor{}
+	for _, file := range files {
+		// FileDescriptorSet.file = 1
+		if file.Number != 1 || file.WireType != protoWireBytes {
+			continue
+		}
+		fileFields, err := parseProtoMessage(file.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		// FileDescriptorProto.package = 2, FileDescriptorProto.message_type = 4
+		var pkg string
+		for _, field := range fileFields {
+			if field.Number == 2 && field.WireType == protoWireBytes {
+				pkg = string(field.Value)
+			}
+		}
+		for _, field := range fileFields {
+			if field.Number == 4 && field.WireType == protoWireBytes {
+				if err = parseProtoDescriptor(field.Value, pkg, registry); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	protoDescriptorSets[path] = registry
+	return registry, nil
+}
+
+// parseProtoDescriptor will parse an encoded DescriptorProto (and its nested types) into registry. Scope is the package
+// or parent message name of the message.
+func parseProtoDescriptor(data []byte, scope string, registry map[string]*protoMessageDescriptor) error {
+	fields, err := parseProtoMessage(data)
+	if err != nil {
+		return err
+	}
+
+	msg := &protoMessageDescriptor{Fields: map[int]protoFieldDescriptor{}}
+	var nestedTypes [][]byte
+
+	// DescriptorProto.name = 1, DescriptorProto.field = 2, DescriptorProto.nested_type = 3
+	for _, field := range fields {
+		if field.WireType != protoWireBytes {
+			continue
+		}
+		switch field.Number {
+		case 1:
+			msg.Name = string(field.Value)
+		case 2:
+			fd, err := parseProtoFieldDescriptor(field.Value)
+			if err != nil {
+				return err
+			}
+			msg.Fields[fd.Number] = fd
+		case 3:
+			nestedTypes = append(nestedTypes, field.Value)
+		}
+	}
+
+	if len(scope) > 0 {
+		msg.Name = scope + "." + msg.Name
+	}
+	registry[msg.Name] = msg
+
+	for _, nested := range nestedTypes {
+		if err = parseProtoDescriptor(nested, msg.Name, registry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseProtoFieldDescriptor will parse an encoded FieldDescriptorProto.
+func parseProtoFieldDescriptor(data []byte) (protoFieldDescriptor, error) {
+	var fd protoFieldDescriptor
+
+	fields, err := parseProtoMessage(data)
+	if err != nil {
+		return fd, err
+	}
+
+	// FieldDescriptorProto.name = 1, .number = 3, .type = 5, .type_name = 6
+	for _, field := range fields {
+		switch field.Number {
+		case 1:
+			fd.Name = string(field.Value)
+		case 3:
+			fd.Number = int(field.Varint)
+		case 5:
+			fd.Type = int(field.Varint)
+		case 6:
+			fd.TypeName = strings.TrimPrefix(string(field.Value), ".")
+		}
+	}
+	return fd, nil
+}
+
+// anonymizeProtoMessage will run the string and bytes fields of the encoded message found in paths through their
+// processors and return the re-encoded message. Paths are dotted field names (contact.email) relative to the top level
+// message and prefix is the path of the current message. Fields that are not listed (including unknown fields) are
+// copied as-is.
+func anonymizeProtoMessage(cmap *ColumnMapper, data []byte, msg *protoMessageDescriptor,
+	registry map[string]*protoMessageDescriptor, prefix string, paths map[string][]ProcessorDefinition) ([]byte, error) {
+
+	fields, err := parseProtoMessage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]byte, 0, len(data))
+	for _, field := range fields {
+		fd, ok := msg.Fields[field.Number]
+		if !ok || field.WireType != protoWireBytes {
+			output = append(output, field.Raw...)
+			continue
+		}
+
+		path := prefix + fd.Name
+		switch {
+		case fd.Type == protoTypeMessage && hasProtoPathPrefix(paths, path+"."):
+			nested, ok := registry[fd.TypeName]
+			if !ok {
+				return nil, fmt.Errorf("Unknown protobuf message type: %s", fd.TypeName)
+			}
+			value, err := anonymizeProtoMessage(cmap, field.Value, nested, registry, path+".", paths)
+			if err != nil {
+				return nil, err
+			}
+			output = appendProtoBytes(output, field.Number, value)
+		case (fd.Type == protoTypeString || fd.Type == protoTypeBytes) && len(paths[path]) > 0:
+			inner := *cmap
+			inner.Processors = paths[path]
+			value, err := processValue(&inner, string(field.Value))
+			if err != nil {
+				return nil, err
+			}
+			output = appendProtoBytes(output, field.Number, []byte(value))
+		default:
+			output = append(output, field.Raw...)
+		}
+	}
+	return output, nil
+}
+
+// hasProtoPathPrefix returns true if any of the paths start with prefix.
+func hasProtoPathPrefix(paths map[string][]ProcessorDefinition, prefix string) bool {
+	for path := range paths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseProtoMessage will split an encoded protobuf message into its fields. Groups (deprecated) are not supported.
+func parseProtoMessage(data []byte) ([]protoField, error) {
+	var fields []protoField
+
+	for len(data) > 0 {
+		tag, size, err := readProtoVarint(data)
+		if err != nil {
+			return nil, err
+		}
+
+		field := protoField{Number: int(tag >> 3), WireType: int(tag & 7)}
+		switch field.WireType {
+		case protoWireVarint:
+			value, n, err := readProtoVarint(data[size:])
+			if err != nil {
+				return nil, err
+			}
+			field.Varint = value
+			size += n
+		case protoWireFixed64:
+			size += 8
+		case protoWireBytes:
+			length, n, err := readProtoVarint(data[size:])
+			if err != nil {
+				return nil, err
+			}
+			size += n
+			if length > uint64(len(data)-size) {
+				return nil, errProtoTruncated
+			}
+			field.Value = data[size : size+int(length)]
+			size += int(length)
+		case protoWireFixed32:
+			size += 4
+		default:
+			return nil, fmt.Errorf("Unsupported protobuf wire type: %d", field.WireType)
+		}
+
+		if size > len(data) {
+			return nil, errProtoTruncated
+		}
+		field.Raw = data[:size]
+		fields = append(fields, field)
+		data = data[size:]
+	}
+	return fields, nil
+}
+
+// readProtoVarint will read a base 128 varint from the start of data and return the value and the number of bytes read.
+func readProtoVarint(data []byte) (uint64, int, error) {
+	var value uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		value |= uint64(data[i]&0x7f) << (7 * uint(i))
+		if data[i] < 0x80 {
+			return value, i + 1, nil
+		}
+	}
+	return 0, 0, errProtoTruncated
+}
+
+// appendProtoVarint will append value to buf as a base 128 varint.
+func appendProtoVarint(buf []byte, value uint64) []byte {
+	for value >= 0x80 {
+		buf = append(buf, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(buf, byte(value))
+}
+
+// appendProtoBytes will append a length-delimited field to buf.
+func appendProtoBytes(buf []byte, number int, value []byte) []byte {
+	buf = appendProtoVarint(buf, uint64(number)<<3|protoWireBytes)
+	buf = appendProtoVarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+// decodeBinaryValue will decode a binary column value found in a dump file. PostgreSQL bytea values (\\x0a1b...) are
+// hex decoded, anything else is base64 decoded. The returned function encodes a value back to the same format.
+func decodeBinaryValue(input string) ([]byte, func([]byte) string, error) {
+	for _, prefix := range []string{"\\\\x", "\\x"} {
+		if strings.HasPrefix(input, prefix) {
+			data, err := hex.DecodeString(input[len(prefix):])
+			if err != nil {
+				return nil, nil, err
+			}
+			return data, func(b []byte) string { return prefix + hex.EncodeToString(b) }, nil
+		}
+	}
+
+	for _, encoding := range base64Encodings {
+		if data, err := encoding.DecodeString(input); err == nil {
+			return data, encoding.EncodeToString, nil
+		}
+	}
+	return nil, nil, errors.New("Expected a hex bytea or base64 encoded value")
+}
@@ -0,0 +1,132 @@
+package gonymizer
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// appendProtoVarintField appends a varint field to buf.
+func appendProtoVarintField(buf []byte, number int, value uint64) []byte {
+	buf = appendProtoVarint(buf, uint64(number)<<3|protoWireVarint)
+	return appendProtoVarint(buf, value)
+}
+
+// testProtoField returns an encoded FieldDescriptorProto.
+func testProtoField(name string, number, fieldType int, typeName string) []byte {
+	var field []byte
+	field = appendProtoBytes(field, 1, []byte(name))
+	field = appendProtoVarintField(field, 3, uint64(number))
+	field = appendProtoVarintField(field, 5, uint64(fieldType))
+	if len(typeName) > 0 {
+		field = appendProtoBytes(field, 6, []byte(typeName))
+	}
+	return field
+}
+
+// writeTestDescriptorSet writes a descriptor set for the following proto file and returns the path to the file:
+//
+//	package acme;
+//	message User {
+//	  message Contact { string email = 1; string phone = 2; }
+//	  string name = 1; Contact contact = 2; int64 id = 3; repeated string tags = 4;
+//	}
+func writeTestDescriptorSet(t *testing.T) string {
+	var contact []byte
+	contact = appendProtoBytes(contact, 1, []byte("Contact"))
+	contact = appendProtoBytes(contact, 2, testProtoField("email", 1, protoTypeString, ""))
+	contact = appendProtoBytes(contact, 2, testProtoField("phone", 2, protoTypeString, ""))
+
+	var user []byte
+	user = appendProtoBytes(user, 1, []byte("User"))
+	user = appendProtoBytes(user, 2, testProtoField("name", 1, protoTypeString, ""))
+	user = appendProtoBytes(user, 2, testProtoField("contact", 2, protoTypeMessage, ".acme.User.Contact"))
+	user = appendProtoBytes(user, 2, testProtoField("id", 3, 3, ""))
+	user = appendProtoBytes(user, 2, testProtoField("tags", 4, protoTypeString, ""))
+	user = appendProtoBytes(user, 3, contact)
+
+	var file []byte
+	file = appendProtoBytes(file, 1, []byte("user.proto"))
+	file = appendProtoBytes(file, 2, []byte("acme"))
+	file = appendProtoBytes(file, 4, user)
+
+	f, err := ioutil.TempFile("", "gonymizer-*.pb")
+	require.Nil(t, err)
+	_, err = f.Write(appendProtoBytes(nil, 1, file))
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+	return f.Name()
+}
+
+func TestProcessorProtobufPayload(t *testing.T) {
+	descriptorSet := writeTestDescriptorSet(t)
+	defer os.Remove(descriptorSet)
+
+	var contact []byte
+	contact = appendProtoBytes(contact, 1, []byte("jane@example.com"))
+	contact = appendProtoBytes(contact, 2, []byte("555-0100"))
+
+	var user []byte
+	user = appendProtoBytes(user, 1, []byte("Jane Doe"))
+	user = appendProtoBytes(user, 2, contact)
+	user = appendProtoVarintField(user, 3, 42)
+	user = appendProtoBytes(user, 4, []byte("vip"))
+	user = appendProtoBytes(user, 15, []byte("unknown field"))
+
+	cmap := ColumnMapper{
+		Processors: []ProcessorDefinition{
+			{
+				Name:          "ProtobufPayload",
+				DescriptorSet: descriptorSet,
+				MessageType:   "acme.User",
+				Processors:    []ProcessorDefinition{{Name: "ScrubString", Keys: []string{"name", "contact.email"}}},
+			},
+		},
+	}
+
+	var expectedContact []byte
+	expectedContact = appendProtoBytes(expectedContact, 1, []byte("****************"))
+	expectedContact = appendProtoBytes(expectedContact, 2, []byte("555-0100"))
+
+	var expected []byte
+	expected = appendProtoBytes(expected, 1, []byte("********"))
+	expected = appendProtoBytes(expected, 2, expectedContact)
+	expected = appendProtoVarintField(expected, 3, 42)
+	expected = appendProtoBytes(expected, 4, []byte("vip"))
+	expected = appendProtoBytes(expected, 15, []byte("unknown field"))
+
+	// PostgreSQL bytea
+	output, err := ProcessorProtobufPayload(&cmap, "\\\\x"+hex.EncodeToString(user))
+	require.Nil(t, err)
+	require.Equal(t, "\\\\x"+hex.EncodeToString(expected), output)
+
+	// Base64
+	output, err = ProcessorProtobufPayload(&cmap, base64.StdEncoding.EncodeToString(user))
+	require.Nil(t, err)
+	require.Equal(t, base64.StdEncoding.EncodeToString(expected), output)
+
+	// Truncated message
+	_, err = ProcessorProtobufPayload(&cmap, "\\\\x"+hex.EncodeToString(user[:len(user)-2]))
+	require.NotNil(t, err)
+
+	cmap.Processors[0].MessageType = "acme.Missing"
+	_, err = ProcessorProtobufPayload(&cmap, "\\\\x"+hex.EncodeToString(user))
+	require.NotNil(t, err)
+}
+
+func TestReadProtoVarint(t *testing.T) {
+	for _, value := range []uint64{0, 1, 127, 128, 300, 1 << 35, 1<<64 - 1} {
+		buf := appendProtoVarint(nil, value)
+		decoded, n, err := readProtoVarint(buf)
+		require.Nil(t, err)
+		require.Equal(t, value, decoded)
+		require.Equal(t, len(buf), n)
+	}
+
+	_, _, err := readProtoVarint([]byte{0x80, 0x80})
+	require.NotNil(t, err)
+}
@@ -0,0 +1,92 @@
+package gonymizer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Actions of a RowRedaction.
+const (
+	// RedactDrop removes the matching rows from the processed dump file
+	RedactDrop = "drop"
+
+	// RedactNull replaces the values of the matching rows with NULL
+	RedactNull = "null"
+
+	// RedactScrub replaces the values of the matching rows with asterisks (see ScrubString)
+	RedactScrub = "scrub"
+)
+
+// RowRedaction redacts whole rows of a table, e.g. all rows of employees, instead of configuring every column. The
+// columns listed in Keep (such as the primary key) are processed as usual, every other value of a matching row is
+// replaced according to the Action and never reaches the processors.
+//
+// Example map file definition:
+// {"TableSchema": "public", "TableName": "users", "Where": [{"Column": "is_employee", "In": ["t"]}],
+// "Action": "scrub", "Keep": ["id", "is_employee"]}
+type RowRedaction struct {
+	TableSchema string
+	TableName   string
+
+	// Where selects the rows matching all of the conditions (see ProcessorCondition), every row if it is empty
+	Where []ProcessorCondition `json:",omitempty"`
+
+	// Action is drop, null, or scrub (default). Scrubbed columns that are not text in the map file (see
+	// ColumnMapper.DataType) are replaced with NULL, since asterisks are not valid values of other types.
+	Action string `json:",omitempty"`
+
+	// Keep lists the columns of matching rows that are kept (and processed as usual)
+	Keep []string `json:",omitempty"`
+}
+
+// rowRedaction returns the row redaction of the table or nil if the table has none.
+func (dbMap DBMapper) rowRedaction(schemaName, tableName string) *RowRedaction {
+	for i, redaction := range dbMap.RowRedactions {
+		if dbMap.matchesTable(redaction.TableSchema, redaction.TableName, schemaName, tableName) {
+			return &dbMap.RowRedactions[i]
+		}
+	}
+	return nil
+}
+
+// matches returns true if the row is redacted.
+func (redaction *RowRedaction) matches(row *rowContext) (bool, error) {
+	for i := range redaction.Where {
+		matches, err := redaction.Where[i].matches(row)
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// redact returns the redacted value of the column of a matching row. False is returned for the columns that are kept.
+func (redaction *RowRedaction) redact(columnName string, cmap *ColumnMapper, value string) (string, bool) {
+	if containsString(redaction.Keep, columnName) {
+		return value, false
+	}
+	if value == "\\N" || redaction.Action == RedactNull ||
+		(cmap != nil && len(cmap.DataType) > 0 && !isCharacterType(cmap.DataType)) {
+		return "\\N", true
+	}
+	return scrubString(unescapeCopyValue(value)), true
+}
+
+// validate checks the table, conditions, and action of the row redaction.
+func (redaction *RowRedaction) validate() error {
+	if len(redaction.TableName) == 0 {
+		return errors.New("Expected the TableName of the row redaction")
+	}
+	for i := range redaction.Where {
+		if err := redaction.Where[i].validate(); err != nil {
+			return fmt.Errorf("Where condition of the row redaction of %s.%s: %s", redaction.TableSchema,
+				redaction.TableName, err)
+		}
+	}
+	switch redaction.Action {
+	case "", RedactDrop, RedactNull, RedactScrub:
+		return nil
+	}
+	return fmt.Errorf("Expected Action drop, null, or scrub for the row redaction of %s.%s, got %q",
+		redaction.TableSchema, redaction.TableName, redaction.Action)
+}
@@ -0,0 +1,107 @@
+package gonymizer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessDumpFileRowRedaction(t *testing.T) {
+	calls := 0
+	BatchProcessorCatalog["TestUpperCase"] = BatchProcessorFunc(func(cells []Cell) ([]string, error) {
+		calls += len(cells)
+		outputs := make([]string, len(cells))
+		for i, cell := range cells {
+			outputs[i] = strings.ToUpper(cell.Value)
+		}
+		return outputs, nil
+	})
+	defer delete(BatchProcessorCatalog, "TestUpperCase")
+
+	columnMap, err := LoadConfigSkeleton(TestMapFile)
+	require.Nil(t, err)
+	for i, cmap := range columnMap.ColumnMaps {
+		if cmap.TableName == "purchasers" && cmap.ColumnName == "first_name" {
+			columnMap.ColumnMaps[i].Processors = []ProcessorDefinition{{Name: "TestUpperCase"}}
+		}
+	}
+	columnMap.RowRedactions = []RowRedaction{
+		{
+			TableSchema: "public",
+			TableName:   "purchasers",
+			Where:       []ProcessorCondition{{Column: "last_name", In: []string{"Smith", "Dent"}}},
+			Keep:        []string{"id"},
+		},
+		{TableSchema: "public", TableName: "distributors", Action: RedactNull, Keep: []string{"id"},
+			Where: []ProcessorCondition{{Column: "id", In: []string{"2"}}}},
+		{TableSchema: "public", TableName: "authors", Action: RedactDrop},
+	}
+	for i := range columnMap.RowRedactions {
+		require.Nil(t, columnMap.RowRedactions[i].validate())
+	}
+	require.Nil(t, ProcessDumpFileWithOptions(
+		columnMap,
+		TestDbFile,
+		TestRedactionDumpFile,
+		"",
+		"",
+		true,
+		ProcessOptions{}))
+
+	rows := map[string][]string{}
+	require.Nil(t, forEachDumpRow(TestRedactionDumpFile, func(state *LineState, values []string) error {
+		rows[state.TableName] = append(rows[state.TableName], strings.Join(values, "|"))
+		return nil
+	}))
+	require.Equal(t, []string{
+		"1|******|*****|******************",
+		"2|JANET|Borders|borders@example.com",
+		"3|*********|****|*****************",
+		"4|COLUMBO|Gallardo|columbo@example.com",
+	}, rows["purchasers"])
+	// Redacted values never reach the processors
+	require.Equal(t, 2, calls)
+
+	require.Equal(t, "2|\\N|\\N|\\N|\\N", rows["distributors"][1])
+	require.NotContains(t, rows, "authors")
+	require.Nil(t, os.Remove(TestRedactionDumpFile))
+}
+
+func TestRowRedactionRedact(t *testing.T) {
+	redaction := &RowRedaction{TableName: "users", Keep: []string{"id"}}
+	text := &ColumnMapper{DataType: "text"}
+	date := &ColumnMapper{DataType: "date"}
+
+	output, redacted := redaction.redact("id", nil, "42")
+	require.False(t, redacted)
+	require.Equal(t, "42", output)
+
+	output, _ = redaction.redact("name", text, "Jörg\\tX")
+	require.Equal(t, "******", output)
+	output, _ = redaction.redact("name", nil, "Jane")
+	require.Equal(t, "****", output)
+	output, _ = redaction.redact("born", date, "1980-01-01")
+	require.Equal(t, "\\N", output)
+	output, _ = redaction.redact("name", text, "\\N")
+	require.Equal(t, "\\N", output)
+
+	redaction.Action = RedactNull
+	output, _ = redaction.redact("name", text, "Jane")
+	require.Equal(t, "\\N", output)
+}
+
+func TestValidateRowRedactions(t *testing.T) {
+	dbMap := &DBMapper{DBName: "test", RowRedactions: []RowRedaction{{TableSchema: "public", TableName: "users",
+		Where: []ProcessorCondition{{Column: "is_employee", In: []string{"t"}}}, Action: RedactScrub}}}
+	require.Nil(t, dbMap.Validate())
+
+	dbMap.RowRedactions[0].Action = "delete"
+	require.NotNil(t, dbMap.Validate())
+	dbMap.RowRedactions[0].Action = ""
+	dbMap.RowRedactions[0].Where[0].In = nil
+	require.NotNil(t, dbMap.Validate())
+	dbMap.RowRedactions[0] = RowRedaction{TableSchema: "public"}
+	require.NotNil(t, dbMap.Validate())
+}
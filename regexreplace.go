@@ -0,0 +1,89 @@
+package gonymizer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// regexReplaceCallRegex matches the processor calls in the Replacement of a RegexReplace processor definition:
+// {{FakeFirstName}} processes the whole match, {{FakeFirstName $1}} or {{FakeFirstName ${name}}} a capture group.
+var regexReplaceCallRegex = regexp.MustCompile(`\{\{\s*(\w+)(?:\s+(\$\d+|\$\{\w+\}))?\s*\}\}`)
+
+// regexReplaceRegexes caches the compiled Regex of every RegexReplace processor definition.
+var regexReplaceRegexes = map[string]*regexp.Regexp{}
+
+// compileRegexReplace returns the compiled regular expression, compiling it the first time it is used.
+func compileRegexReplace(expr string) (*regexp.Regexp, error) {
+	if re, ok := regexReplaceRegexes[expr]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid Regex %q: %s", expr, err)
+	}
+	regexReplaceRegexes[expr] = re
+	return re, nil
+}
+
+// regexReplace replaces every match of the Regex of the processor definition in the input with its Replacement.
+// Capture group references ($1, ${name}) are expanded as in regexp.Regexp.Expand and every processor call ({{Name}} or
+// {{Name $1}}) is replaced with the output of the processor for the match or capture group.
+func regexReplace(cmap *ColumnMapper, procDef ProcessorDefinition, input string) (string, error) {
+	re, err := compileRegexReplace(procDef.Regex)
+	if err != nil {
+		return "", err
+	}
+	template := procDef.Replacement
+	calls := regexReplaceCallRegex.FindAllStringSubmatchIndex(template, -1)
+
+	var b strings.Builder
+	last := 0
+	for _, match := range re.FindAllStringSubmatchIndex(input, -1) {
+		b.WriteString(input[last:match[0]])
+		last = match[1]
+
+		position := 0
+		for _, call := range calls {
+			b.Write(re.ExpandString(nil, template[position:call[0]], input, match))
+			position = call[1]
+
+			name, argument := template[call[2]:call[3]], "$0"
+			if call[4] >= 0 {
+				argument = template[call[4]:call[5]]
+			}
+			pfunc := ProcessorCatalog[name]
+			if pfunc == nil {
+				return "", fmt.Errorf("Unknown processor %q in Replacement", name)
+			}
+			output, err := pfunc(cmap, string(re.ExpandString(nil, argument, input, match)))
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(output)
+		}
+		b.Write(re.ExpandString(nil, template[position:], input, match))
+	}
+	b.WriteString(input[last:])
+	return b.String(), nil
+}
+
+// validateRegexReplace checks that a RegexReplace processor definition has a valid Regex and that the processors
+// called in its Replacement exist.
+func (procDef ProcessorDefinition) validateRegexReplace() error {
+	if procDef.Name != "RegexReplace" {
+		return nil
+	}
+	if len(procDef.Regex) == 0 {
+		return fmt.Errorf("Expected a Regex for processor %s", procDef.Name)
+	}
+	if _, err := compileRegexReplace(procDef.Regex); err != nil {
+		return err
+	}
+	for _, call := range regexReplaceCallRegex.FindAllStringSubmatch(procDef.Replacement, -1) {
+		if _, ok := ProcessorCatalog[call[1]]; !ok || call[1] == procDef.Name {
+			return fmt.Errorf("Unknown processor %q in the Replacement of processor %s", call[1], procDef.Name)
+		}
+	}
+	return nil
+}
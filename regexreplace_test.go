@@ -0,0 +1,51 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorRegexReplace(t *testing.T) {
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{{
+		Name:        "RegexReplace",
+		Regex:       `Contact (?P<name>[A-Z][a-z]+) at (\d{3}-\d{4})`,
+		Replacement: "Contact {{FakeFirstName ${name}}} at {{AlphaNumericScrambler $2}}",
+	}}}
+	output, err := ProcessorRegexReplace(cmap, "Note: Contact John at 555-1234. Contact Mary at 555-9876 later.")
+	require.Nil(t, err)
+	require.Regexp(t, `^Note: Contact [A-Z][a-z]+ at \d{3}-\d{4}\. Contact [A-Z][a-z]+ at \d{3}-\d{4} later\.$`, output)
+	require.NotContains(t, output, "555-1234")
+
+	// Capture groups without processor calls and calls for the whole match
+	cmap.Processors[0].Regex = `(\w+)@(\w+)\.com`
+	cmap.Processors[0].Replacement = "$1 at $2"
+	output, err = ProcessorRegexReplace(cmap, "mail jane@example.com")
+	require.Nil(t, err)
+	require.Equal(t, "mail jane at example", output)
+
+	cmap.Processors[0].Replacement = "<{{ScrubString}}>"
+	output, err = ProcessorRegexReplace(cmap, "mail jane@example.com now")
+	require.Nil(t, err)
+	require.Equal(t, "mail <****************> now", output)
+
+	// Values without a match are kept
+	output, err = ProcessorRegexReplace(cmap, "no address")
+	require.Nil(t, err)
+	require.Equal(t, "no address", output)
+}
+
+func TestValidateRegexReplace(t *testing.T) {
+	require.Nil(t, ProcessorDefinition{Name: "RegexReplace", Regex: `\d+`, Replacement: "{{RandomDigits}}"}.
+		validateRegexReplace())
+	require.Nil(t, ProcessorDefinition{Name: "Identity"}.validateRegexReplace())
+
+	for _, procDef := range []ProcessorDefinition{
+		{Name: "RegexReplace"},
+		{Name: "RegexReplace", Regex: `(\d+`},
+		{Name: "RegexReplace", Regex: `\d+`, Replacement: "{{FakeUnicorn}}"},
+		{Name: "RegexReplace", Regex: `\d+`, Replacement: "{{RegexReplace $0}}"},
+	} {
+		require.NotNil(t, procDef.validateRegexReplace(), procDef.Regex+procDef.Replacement)
+	}
+}
@@ -0,0 +1,419 @@
+package gonymizer
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ReprocessOptions selects the columns that ReprocessDumpFile and ReprocessDatabase process again.
+type ReprocessOptions struct {
+	// Tables (schema.table or table) and Columns (column, table.column, or schema.table.column) that are processed
+	// again. Only columns in the map file are processed. When both are set a column must match both.
+	Tables  []string
+	Columns []string
+
+	// SourceFile is the original (PII) dump file the processed dump file was created from. When set, the selected
+	// columns are processed from their original values so consistent mappings (see MappingsFile) give the same output
+	// as the rest of the processed dump file. Otherwise the values found in the processed dump file are processed again.
+	SourceFile string
+
+	// MappingsFile is the path to the encrypted consistent mappings saved by the run that created the processed dump
+	// file (see ProcessOptions.MappingsFile). It is loaded before reprocessing (if it exists) and saved again afterwards
+	// with any new mappings using MappingsKey (256 bits).
+	MappingsFile string
+	MappingsKey  []byte
+}
+
+// ReprocessDumpFile will copy the processed dump file to dst and run only the selected columns (see ReprocessOptions)
+// through the processors of the map file again. This avoids a full run when a single map entry was corrected. Rows of
+// the source dump file are matched to the processed dump file by their position in the table and columns by their name,
+// so the processed dump file may be sampled or hold generated columns but must not be a shard split by row ranges (see
+// ShardPlan). Tables with a row filter or a dropping row redaction cannot be reprocessed from the source dump file, as
+// their rows no longer line up with it. To update a database in place use ReprocessDatabase.
+func ReprocessDumpFile(mapper *DBMapper, processedFile, dst string, opts ReprocessOptions) error {
+	selected, err := startReprocessing(mapper, opts)
+	if err != nil {
+		return err
+	}
+
+	srcFile, err := os.Open(processedFile)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	defer srcFile.Close()
+	scanner := newDumpScanner(srcFile)
+
+	var source *dumpRows
+	if len(opts.SourceFile) > 0 {
+		if source, err = openDumpRows(opts.SourceFile); err != nil {
+			log.Error(err)
+			return err
+		}
+		defer source.close()
+	}
+
+	dstFile, err := os.Create(dst)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	defer dstFile.Close()
+	dstWriter := bufio.NewWriterSize(dstFile, dumpBufferSize)
+
+	state := new(LineState)
+	for eof := false; !eof; {
+		line, err := scanner.next()
+		if err == io.EOF {
+			eof = true
+		} else if err != nil {
+			return err
+		}
+		state.LineNum++
+
+		inputLine := string(line)
+		trimmedInput := strings.TrimLeftFunc(inputLine, unicode.IsSpace)
+		switch {
+		case !state.IsRow && strings.HasPrefix(trimmedInput, StateChangeTokenBeginCopy):
+			state.parseCopyLine(inputLine)
+			state.mapColumns(selected)
+		case state.IsRow && strings.HasPrefix(trimmedInput, StateChangeTokenEndCopy):
+			state.Clear()
+		case state.IsRow && len(trimmedInput) > 0:
+			state.RowNumber++
+			if state.Mapped {
+				if inputLine, err = reprocessRow(selected, state, inputLine, source); err != nil {
+					log.Errorf("Unable to reprocess line %d of %s: %s", state.LineNum, processedFile, err)
+					return err
+				}
+			}
+		}
+
+		if _, err = dstWriter.WriteString(inputLine); err != nil {
+			return err
+		}
+	}
+	if err = dstWriter.Flush(); err != nil {
+		return err
+	}
+	return finishReprocessing(opts)
+}
+
+// ReprocessDatabase runs only the selected columns (see ReprocessOptions) of the tables in the database of conf through
+// the processors of the map file again and updates the rows in place, one transaction per table. The values found in
+// the database are processed, as the database does not keep the order of the rows of a source dump file: SourceFile is
+// not supported. Tables with a row filter or a dropping row redaction are refused, and generated columns are left to
+// the database.
+func ReprocessDatabase(conf PGConfig, mapper *DBMapper, opts ReprocessOptions) error {
+	if len(opts.SourceFile) > 0 {
+		return errors.New("Expected a processed dump file to reprocess from a source dump file")
+	}
+	selected, err := startReprocessing(mapper, opts)
+	if err != nil {
+		return err
+	}
+
+	db, err := OpenDB(conf)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var tables [][2]string
+	for _, cmap := range selected.ColumnMaps {
+		if table := [2]string{cmap.TableSchema, cmap.TableName}; !containsTable(tables, table) {
+			tables = append(tables, table)
+		}
+	}
+	for _, table := range tables {
+		rows, err := reprocessTable(db, selected, table[0], table[1])
+		if err != nil {
+			log.Errorf("Unable to reprocess %s.%s: %s", table[0], table[1], err)
+			return err
+		}
+		log.Infof("Reprocessed %d rows of %s.%s", rows, table[0], table[1])
+	}
+	return finishReprocessing(opts)
+}
+
+// startReprocessing returns the column maps of mapper selected by opts and loads the consistent mappings of opts.
+func startReprocessing(mapper *DBMapper, opts ReprocessOptions) (*DBMapper, error) {
+	if err := checkTimedOutCalls(); err != nil {
+		return nil, err
+	}
+	selected, err := selectColumns(mapper, opts.Tables, opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.MappingsFile) > 0 {
+		if err = LoadMappings(opts.MappingsFile, opts.MappingsKey); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if mapper.Seed != 0 {
+		SetSeed(mapper.Seed)
+	}
+	return selected, nil
+}
+
+// finishReprocessing saves the consistent mappings of opts with any new mappings.
+func finishReprocessing(opts ReprocessOptions) error {
+	if len(opts.MappingsFile) > 0 {
+		log.Info("Saving mappings to: ", opts.MappingsFile)
+		return SaveMappings(opts.MappingsFile, opts.MappingsKey)
+	}
+	return nil
+}
+
+// containsTable returns true if the schema and table name pair is in the list.
+func containsTable(tables [][2]string, table [2]string) bool {
+	for _, entry := range tables {
+		if entry == table {
+			return true
+		}
+	}
+	return false
+}
+
+// reprocessTable updates the selected columns of every row of the table in the database. Returns the number of rows.
+func reprocessTable(db *sql.DB, selected *DBMapper, schemaName, tableName string) (int64, error) {
+	if selected.removesRows(schemaName, tableName) {
+		return 0, fmt.Errorf("Rows of %s.%s are removed by a row filter or redaction, reprocess the dump file instead",
+			schemaName, tableName)
+	}
+
+	columnNames, err := tableColumns(db, schemaName, tableName)
+	if err != nil {
+		return 0, err
+	}
+	state := &LineState{IsRow: true, SchemaName: schemaName, TableName: tableName, ColumnNames: columnNames}
+	state.mapColumns(selected)
+	updated := updatedColumns(state)
+	if len(updated) == 0 {
+		return 0, fmt.Errorf("None of the selected columns were found in %s.%s", schemaName, tableName)
+	}
+
+	table := quoteIdentifier(schemaName) + "." + quoteIdentifier(tableName)
+	columns := make([]string, len(columnNames))
+	for i, columnName := range columnNames {
+		columns[i] = quoteIdentifier(columnName) + "::text"
+	}
+	assignments := make([]string, len(updated))
+	for i, column := range updated {
+		assignments[i] = fmt.Sprintf("%s = $%d", quoteIdentifier(columnNames[column]), i+1)
+	}
+
+	// The rows are read outside of the transaction so they keep the ctid they were read with
+	rows, err := db.Query(fmt.Sprintf("SELECT ctid::text, %s FROM %s", strings.Join(columns, ", "), table))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	update, err := tx.Prepare(fmt.Sprintf("UPDATE %s SET %s WHERE ctid = $%d::tid", table,
+		strings.Join(assignments, ", "), len(updated)+1))
+	if err != nil {
+		return 0, err
+	}
+	defer update.Close()
+
+	var ctid string
+	values := make([]sql.NullString, len(columnNames))
+	dest := []interface{}{&ctid}
+	for i := range values {
+		dest = append(dest, &values[i])
+	}
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return state.RowNumber, err
+		}
+		state.RowNumber++
+		args, err := reprocessDatabaseRow(selected, state, values, updated)
+		if err != nil {
+			return state.RowNumber, err
+		}
+		if _, err = update.Exec(append(args, ctid)...); err != nil {
+			return state.RowNumber, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return state.RowNumber, err
+	}
+	return state.RowNumber, tx.Commit()
+}
+
+// tableColumns returns the names of the columns of the table in the database in their order.
+func tableColumns(db *sql.DB, schemaName, tableName string) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = $1
+			AND table_name = $2
+		ORDER BY ordinal_position;`, schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columnNames []string
+	for rows.Next() {
+		var columnName string
+		if err = rows.Scan(&columnName); err != nil {
+			return nil, err
+		}
+		columnNames = append(columnNames, columnName)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(columnNames) == 0 {
+		return nil, fmt.Errorf("Table %s.%s was not found in the database", schemaName, tableName)
+	}
+	return columnNames, nil
+}
+
+// updatedColumns returns the indexes of the mapped columns of state that can be updated: any but generated columns.
+func updatedColumns(state *LineState) []int {
+	var updated []int
+	for i, cmap := range state.ColumnMaps {
+		if cmap != nil && !cmap.IsGenerated {
+			updated = append(updated, i)
+		}
+	}
+	return updated
+}
+
+// reprocessDatabaseRow processes the values of a row read from the database like a COPY row (see processRow) and
+// returns the new values of the updated columns. NULL values are returned as nil.
+func reprocessDatabaseRow(selected *DBMapper, state *LineState, values []sql.NullString,
+	updated []int) ([]interface{}, error) {
+
+	rowVals := make([]string, len(values))
+	for i, value := range values {
+		rowVals[i] = "\\N"
+		if value.Valid {
+			rowVals[i] = escapeCopyValue(value.String)
+		}
+	}
+	_, outputLine, err := processRow(selected, state, strings.Join(rowVals, "\t"))
+	if err != nil {
+		return nil, err
+	}
+
+	outputVals := strings.Split(outputLine, "\t")
+	args := make([]interface{}, len(updated))
+	for i, column := range updated {
+		if outputVals[column] != "\\N" {
+			args[i] = unescapeCopyValue(outputVals[column])
+		}
+	}
+	return args, nil
+}
+
+// reprocessRow processes the selected columns of a row of the processed dump file again. When source is set the
+// values are read from the same row of the source dump file instead, taking each column from the source column of the
+// same name.
+func reprocessRow(selected *DBMapper, state *LineState, inputLine string, source *dumpRows) (string, error) {
+	if source == nil {
+		_, outputLine, err := processRow(selected, state, inputLine)
+		return outputLine, err
+	}
+	if selected.removesRows(state.SchemaName, state.TableName) {
+		return "", fmt.Errorf("Rows of %s.%s are removed by a row filter or redaction and cannot be matched to the "+
+			"source dump file", state.SchemaName, state.TableName)
+	}
+
+	var sourceValues []string
+	for sourceValues == nil || source.state.SchemaName != state.SchemaName ||
+		source.state.TableName != state.TableName || source.state.RowNumber != state.RowNumber {
+		var err error
+		if sourceValues, err = source.next(); err == io.EOF {
+			return "", fmt.Errorf("Row %d of %s.%s was not found in the source dump file", state.RowNumber,
+				state.SchemaName, state.TableName)
+		} else if err != nil {
+			return "", err
+		}
+	}
+
+	// Order the source values like the columns of the processed dump file, which leaves out generated columns (see
+	// dropGeneratedColumns). Columns that are not reprocessed keep their processed value.
+	ordered := make([]string, len(state.ColumnNames))
+	for i, columnName := range state.ColumnNames {
+		value, ok := source.value(sourceValues, columnName)
+		if !ok && state.ColumnMaps[i] != nil {
+			return "", fmt.Errorf("Column %s of %s.%s was not found in the source dump file", columnName,
+				state.SchemaName, state.TableName)
+		}
+		ordered[i] = value
+	}
+
+	_, sourceLine, err := processRow(selected, state, strings.Join(ordered, "\t")+"\n")
+	if err != nil {
+		return "", err
+	}
+	values := strings.Split(inputLine, "\t")
+	sourceOutput := strings.Split(sourceLine, "\t")
+	if len(values) != len(sourceOutput) {
+		return "", fmt.Errorf("Expected %d columns in %s.%s, found %d", len(sourceOutput), state.SchemaName,
+			state.TableName, len(values))
+	}
+	for i, cmap := range state.ColumnMaps {
+		if cmap != nil {
+			values[i] = sourceOutput[i]
+		}
+	}
+	if strings.HasSuffix(inputLine, "\n") && !strings.HasSuffix(values[len(values)-1], "\n") {
+		values[len(values)-1] += "\n"
+	}
+	return strings.Join(values, "\t"), nil
+}
+
+// selectColumns returns a copy of mapper with only the column maps that match tables and columns.
+func selectColumns(mapper *DBMapper, tables, columns []string) (*DBMapper, error) {
+	if len(tables) == 0 && len(columns) == 0 {
+		return nil, errors.New("Expected at least one table or column to reprocess")
+	}
+
+	selected := *mapper
+	selected.ColumnMaps = nil
+	for _, cmap := range mapper.ColumnMaps {
+		table := cmap.TableSchema + "." + cmap.TableName
+		if len(tables) > 0 && !nameMatches(tables, table, cmap.TableName) {
+			continue
+		}
+		if len(columns) > 0 && !nameMatches(columns, table+"."+cmap.ColumnName, cmap.TableName+"."+cmap.ColumnName,
+			cmap.ColumnName) {
+			continue
+		}
+		selected.ColumnMaps = append(selected.ColumnMaps, cmap)
+	}
+	if len(selected.ColumnMaps) == 0 {
+		return nil, fmt.Errorf("No columns in the map file match tables %v and columns %v", tables, columns)
+	}
+	return &selected, nil
+}
+
+// nameMatches returns true if one of the names is in the list.
+func nameMatches(list []string, names ...string) bool {
+	for _, entry := range list {
+		for _, name := range names {
+			if entry == name {
+				return true
+			}
+		}
+	}
+	return false
+}
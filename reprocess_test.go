@@ -0,0 +1,164 @@
+package gonymizer
+
+import (
+	"bytes"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReprocessDumpFile(t *testing.T) {
+	ProcessorCatalog["TestUpper"] = func(cmap *ColumnMapper, input string) (string, error) {
+		return strings.ToUpper(input), nil
+	}
+	defer delete(ProcessorCatalog, "TestUpper")
+
+	mapper := &DBMapper{
+		DBName: "pii_localtest",
+		Seed:   1,
+		ColumnMaps: []ColumnMapper{
+			{TableSchema: "public", TableName: "users", ColumnName: "name",
+				Processors: []ProcessorDefinition{{Name: "TestUpper"}}},
+			{TableSchema: "public", TableName: "users", ColumnName: "email",
+				Processors: []ProcessorDefinition{{Name: "TestUpper"}}},
+		},
+	}
+
+	source := writeTestAuditFile(t, "COPY public.users (id, name, email) FROM stdin;\n"+
+		"1\tjane\tjane@example.com\n2\tjohn\t\\N\n\\.\n")
+	defer os.Remove(source)
+	processed := writeTestAuditFile(t, "SET x = 1;\nCOPY public.users (id, name, email) FROM stdin;\n"+
+		"1\tmary\tjane@example.com\n2\tpaul\t\\N\n\\.\n")
+	defer os.Remove(processed)
+	dst := processed + ".out"
+	defer os.Remove(dst)
+
+	// Reprocess the values of the processed dump file
+	require.Nil(t, ReprocessDumpFile(mapper, processed, dst, ReprocessOptions{Columns: []string{"users.email"}}))
+	output, err := ioutil.ReadFile(dst)
+	require.Nil(t, err)
+	require.Equal(t, "SET x = 1;\nCOPY public.users (id, name, email) FROM stdin;\n"+
+		"1\tmary\tJANE@EXAMPLE.COM\n2\tpaul\t\\N\n\\.\n", string(output))
+
+	// Reprocess the original values of the source dump file
+	require.Nil(t, ReprocessDumpFile(mapper, processed, dst, ReprocessOptions{
+		Tables:     []string{"public.users"},
+		Columns:    []string{"name"},
+		SourceFile: source,
+	}))
+	output, err = ioutil.ReadFile(dst)
+	require.Nil(t, err)
+	require.Equal(t, "SET x = 1;\nCOPY public.users (id, name, email) FROM stdin;\n"+
+		"1\tJANE\tjane@example.com\n2\tJOHN\t\\N\n\\.\n", string(output))
+
+	// Columns are matched by name when generated columns were left out of the processed dump file
+	generated := writeTestAuditFile(t, "COPY public.users (id, full_name, email, name) FROM stdin;\n"+
+		"1\tJane Doe\tjane@example.com\tjane\n2\tJohn Doe\t\\N\tjohn\n\\.\n")
+	defer os.Remove(generated)
+	require.Nil(t, ReprocessDumpFile(mapper, processed, dst, ReprocessOptions{
+		Columns:    []string{"name"},
+		SourceFile: generated,
+	}))
+	output, err = ioutil.ReadFile(dst)
+	require.Nil(t, err)
+	require.Equal(t, "SET x = 1;\nCOPY public.users (id, name, email) FROM stdin;\n"+
+		"1\tJANE\tjane@example.com\n2\tJOHN\t\\N\n\\.\n", string(output))
+
+	// Rows removed by a row filter cannot be matched to the source dump file
+	filtered := *mapper
+	filtered.RowFilters = []RowFilter{{TableSchema: "public", TableName: "users", SampleRate: 0.5}}
+	require.NotNil(t, ReprocessDumpFile(&filtered, processed, dst, ReprocessOptions{
+		Columns:    []string{"name"},
+		SourceFile: source,
+	}))
+
+	require.NotNil(t, ReprocessDumpFile(mapper, processed, dst, ReprocessOptions{}))
+	require.NotNil(t, ReprocessDumpFile(mapper, processed, dst, ReprocessOptions{Columns: []string{"id"}}))
+}
+
+func TestReprocessMappingsFile(t *testing.T) {
+	alphaNumericMap, uuidMap := AlphaNumericMap, UUIDMap
+	defer func() { AlphaNumericMap, UUIDMap = alphaNumericMap, uuidMap }()
+
+	mapper := &DBMapper{
+		DBName: "pii_localtest",
+		Seed:   1,
+		ColumnMaps: []ColumnMapper{
+			{TableSchema: "public", TableName: "users", ColumnName: "code", ParentSchema: "public", ParentTable: "users",
+				ParentColumn: "code", Processors: []ProcessorDefinition{{Name: "AlphaNumericScrambler"}}},
+		},
+	}
+	processed := writeTestAuditFile(t, "COPY public.users (id, code) FROM stdin;\n1\tabc\n\\.\n")
+	defer os.Remove(processed)
+	dst := processed + ".out"
+	defer os.Remove(dst)
+	mappings := processed + ".mappings"
+	defer os.Remove(mappings)
+	key := bytes.Repeat([]byte{7}, 32)
+
+	// The mappings are loaded before and saved encrypted after reprocessing
+	Consistency().Reset()
+	AlphaNumericMap["public.users.code"] = map[string]string{"abc": "xyz"}
+	require.Nil(t, SaveMappings(mappings, key))
+	Consistency().Reset()
+	opts := ReprocessOptions{Columns: []string{"code"}, MappingsFile: mappings, MappingsKey: key}
+	require.Nil(t, ReprocessDumpFile(mapper, processed, dst, opts))
+	output, err := ioutil.ReadFile(dst)
+	require.Nil(t, err)
+	require.Equal(t, "COPY public.users (id, code) FROM stdin;\n1\txyz\n\\.\n", string(output))
+
+	Consistency().Reset()
+	require.Nil(t, LoadMappings(mappings, key))
+	require.Equal(t, "xyz", AlphaNumericMap["public.users.code"]["abc"])
+	opts.MappingsKey = bytes.Repeat([]byte{8}, 32)
+	require.NotNil(t, ReprocessDumpFile(mapper, processed, dst, opts))
+}
+
+func TestReprocessDatabaseRow(t *testing.T) {
+	ProcessorCatalog["TestUpper"] = func(cmap *ColumnMapper, input string) (string, error) {
+		return strings.ToUpper(input), nil
+	}
+	defer delete(ProcessorCatalog, "TestUpper")
+
+	mapper := &DBMapper{
+		ColumnMaps: []ColumnMapper{
+			{TableSchema: "public", TableName: "users", ColumnName: "name",
+				Processors: []ProcessorDefinition{{Name: "TestUpper"}}},
+			{TableSchema: "public", TableName: "users", ColumnName: "email",
+				Processors: []ProcessorDefinition{{Name: "TestUpper"}}},
+			{TableSchema: "public", TableName: "users", ColumnName: "full_name", IsGenerated: true},
+		},
+	}
+	state := &LineState{IsRow: true, SchemaName: "public", TableName: "users",
+		ColumnNames: []string{"id", "name", "email", "full_name"}}
+	state.mapColumns(mapper)
+	updated := updatedColumns(state)
+	require.Equal(t, []int{1, 2}, updated)
+
+	// Values are escaped like COPY values and NULL stays NULL
+	args, err := reprocessDatabaseRow(mapper, state, []sql.NullString{
+		{String: "1", Valid: true},
+		{String: "jane\\doe", Valid: true},
+		{},
+		{String: "Jane Doe", Valid: true},
+	}, updated)
+	require.Nil(t, err)
+	require.Equal(t, []interface{}{"JANE\\DOE", nil}, args)
+
+	args, err = reprocessDatabaseRow(mapper, state, []sql.NullString{
+		{String: "2", Valid: true},
+		{String: "\\N", Valid: true},
+		{String: "john@example.com", Valid: true},
+		{},
+	}, updated)
+	require.Nil(t, err)
+	require.Equal(t, []interface{}{"\\N", "JOHN@EXAMPLE.COM"}, args)
+
+	// The database keeps no row order to match a source dump file to
+	require.NotNil(t, ReprocessDatabase(PGConfig{}, mapper, ReprocessOptions{Columns: []string{"name"},
+		SourceFile: "dump.sql"}))
+}
@@ -0,0 +1,84 @@
+package gonymizer
+
+import (
+	"fmt"
+)
+
+// defaultFlipProbability is the probability of a value being flipped by the RandomizedResponse processor when the
+// processor definition does not set a Probability.
+const defaultFlipProbability = 0.25
+
+// booleanOpposites maps each spelling of a boolean to the same spelling of its opposite.
+var booleanOpposites = map[string]string{
+	"t": "f", "f": "t", "T": "F", "F": "T",
+	"true": "false", "false": "true", "TRUE": "FALSE", "FALSE": "TRUE", "True": "False", "False": "True",
+	"y": "n", "n": "y", "Y": "N", "N": "Y",
+	"yes": "no", "no": "yes", "YES": "NO", "NO": "YES", "Yes": "No", "No": "Yes",
+	"1": "0", "0": "1",
+}
+
+// randomizedResponse flips a boolean, or replaces a category with a different category of categories, with the given
+// probability. Each row keeps plausible deniability while the prevalence p of a value in the whole column can still be
+// estimated from its observed prevalence q. For booleans p = (q - probability) / (1 - 2 * probability) and for k
+// categories p = (q - probability / (k - 1)) / (1 - probability * k / (k - 1)).
+func randomizedResponse(input string, probability float64, categories []string) (string, error) {
+	if len(categories) == 0 {
+		opposite, ok := booleanOpposites[input]
+		if !ok {
+			return "", fmt.Errorf("Expected a boolean, got %q", input)
+		}
+		if rng.Float64() < probability {
+			return opposite, nil
+		}
+		return input, nil
+	}
+
+	index := -1
+	for i, category := range categories {
+		if category == input {
+			index = i
+		}
+	}
+	if index < 0 {
+		return "", fmt.Errorf("Expected one of the Categories, got %q", input)
+	}
+	if rng.Float64() < probability {
+		// Pick one of the other categories
+		other := rng.Intn(len(categories) - 1)
+		if other >= index {
+			other++
+		}
+		return categories[other], nil
+	}
+	return input, nil
+}
+
+// validateRandomizedResponse checks that the Probability of a RandomizedResponse processor definition still allows the
+// prevalence of a value to be estimated (see randomizedResponse) and that the Categories are unique.
+func (procDef ProcessorDefinition) validateRandomizedResponse() error {
+	if procDef.Name != "RandomizedResponse" {
+		return nil
+	}
+
+	if len(procDef.Categories) == 1 {
+		return fmt.Errorf("Expected at least two Categories for processor %s", procDef.Name)
+	}
+
+	limit := 0.5
+	if len(procDef.Categories) > 0 {
+		limit = 1 - 1/float64(len(procDef.Categories))
+	}
+	if procDef.Probability < 0 || procDef.Probability >= limit {
+		return fmt.Errorf("Expected a Probability between 0 and %g for processor %s, got %g", limit, procDef.Name,
+			procDef.Probability)
+	}
+
+	seen := make(map[string]bool)
+	for _, category := range procDef.Categories {
+		if seen[category] {
+			return fmt.Errorf("Duplicate category %q for processor %s", category, procDef.Name)
+		}
+		seen[category] = true
+	}
+	return nil
+}
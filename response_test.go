@@ -0,0 +1,66 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorRandomizedResponse(t *testing.T) {
+	cmap := &ColumnMapper{
+		Processors: []ProcessorDefinition{{Name: "RandomizedResponse", Probability: 0.2}},
+	}
+
+	flipped := 0
+	for i := 0; i < 1000; i++ {
+		output, err := ProcessorRandomizedResponse(cmap, "t")
+		require.Nil(t, err)
+		require.Contains(t, []string{"t", "f"}, output)
+		if output == "f" {
+			flipped++
+		}
+	}
+	require.InDelta(t, 200, flipped, 75)
+
+	output, err := ProcessorRandomizedResponse(cmap, "Yes")
+	require.Nil(t, err)
+	require.Contains(t, []string{"Yes", "No"}, output)
+
+	_, err = ProcessorRandomizedResponse(cmap, "maybe")
+	require.NotNil(t, err)
+
+	// Categories
+	cmap.Processors[0].Categories = []string{"A", "B", "C"}
+	cmap.Processors[0].Probability = 0.5
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		output, err = ProcessorRandomizedResponse(cmap, "B")
+		require.Nil(t, err)
+		counts[output]++
+	}
+	require.Len(t, counts, 3)
+	require.InDelta(t, 500, counts["B"], 100)
+
+	_, err = ProcessorRandomizedResponse(cmap, "D")
+	require.NotNil(t, err)
+}
+
+func TestValidateRandomizedResponse(t *testing.T) {
+	procDef := ProcessorDefinition{Name: "RandomizedResponse"}
+	require.Nil(t, procDef.validateRandomizedResponse())
+
+	procDef.Probability = 0.5
+	require.NotNil(t, procDef.validateRandomizedResponse())
+
+	procDef.Categories = []string{"A", "B", "C"}
+	require.Nil(t, procDef.validateRandomizedResponse())
+
+	procDef.Categories = []string{"A", "B", "A"}
+	require.NotNil(t, procDef.validateRandomizedResponse())
+
+	procDef.Categories = []string{"A"}
+	procDef.Probability = 0
+	require.NotNil(t, procDef.validateRandomizedResponse())
+
+	require.Nil(t, ProcessorDefinition{Name: "Identity", Probability: 2}.validateRandomizedResponse())
+}
@@ -0,0 +1,88 @@
+package gonymizer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RowFilter selects the rows of a table that are written to the processed dump file, so smaller staging databases can
+// be created while processing. Rows that are not selected are dropped before any processors are run. Dropping rows
+// does not keep referential integrity: rows of other tables may still reference the dropped rows.
+//
+// Example map file definition:
+// {"TableSchema": "public", "TableName": "events", "Where": [{"Column": "type", "NotIn": ["debug"]}],
+// "SampleRate": 0.1}
+type RowFilter struct {
+	TableSchema string
+	TableName   string
+
+	// Where keeps only the rows matching all of the conditions (like a SQL WHERE clause of ANDed predicates)
+	Where []ProcessorCondition `json:",omitempty"`
+
+	// SampleRate keeps a random fraction (between 0 and 1) of the rows matching Where. A value of 0 keeps them all.
+	SampleRate float64 `json:",omitempty"`
+}
+
+// rowFilter returns the row filter of the table or nil if the table has none.
+func (dbMap DBMapper) rowFilter(schemaName, tableName string) *RowFilter {
+	for i, filter := range dbMap.RowFilters {
+		if dbMap.matchesTable(filter.TableSchema, filter.TableName, schemaName, tableName) {
+			return &dbMap.RowFilters[i]
+		}
+	}
+	return nil
+}
+
+// removesRows returns true if a row filter or a dropping row redaction of the map file removes rows of the table. The
+// rows of such a table in the processed dump file cannot be matched to the source dump file by their position.
+func (dbMap DBMapper) removesRows(schemaName, tableName string) bool {
+	if dbMap.rowFilter(schemaName, tableName) != nil {
+		return true
+	}
+	redaction := dbMap.rowRedaction(schemaName, tableName)
+	return redaction != nil && redaction.Action == RedactDrop
+}
+
+// matchesTable returns true if the schema and table of the map file (of a row filter or redaction) match the schema
+// and table of the dump file. Like ColumnMapper, any schema starting with the SchemaPrefix matches.
+func (dbMap DBMapper) matchesTable(mapSchema, mapTable, schemaName, tableName string) bool {
+	if unquoteIdentifier(mapTable) != unquoteIdentifier(tableName) {
+		return false
+	}
+	schemaName = unquoteIdentifier(schemaName)
+	return unquoteIdentifier(mapSchema) == schemaName ||
+		(len(dbMap.SchemaPrefix) > 0 && strings.HasPrefix(schemaName, dbMap.SchemaPrefix))
+}
+
+// keeps returns true if the row with the values of the columns is kept.
+func (filter *RowFilter) keeps(columnNames, values []string) (bool, error) {
+	if len(filter.Where) > 0 {
+		row := newRowContext(columnNames, values)
+		for i := range filter.Where {
+			matches, err := filter.Where[i].matches(row)
+			if err != nil || !matches {
+				return false, err
+			}
+		}
+	}
+	return filter.SampleRate == 0 || rng.Float64() < filter.SampleRate, nil
+}
+
+// validate checks the table, conditions, and sample rate of the row filter.
+func (filter *RowFilter) validate() error {
+	if len(filter.TableName) == 0 {
+		return errors.New("Expected the TableName of the row filter")
+	}
+	for i := range filter.Where {
+		if err := filter.Where[i].validate(); err != nil {
+			return fmt.Errorf("Where condition of the row filter of %s.%s: %s", filter.TableSchema, filter.TableName,
+				err)
+		}
+	}
+	if filter.SampleRate < 0 || filter.SampleRate > 1 {
+		return fmt.Errorf("Expected a SampleRate between 0 and 1 for the row filter of %s.%s, got %g",
+			filter.TableSchema, filter.TableName, filter.SampleRate)
+	}
+	return nil
+}
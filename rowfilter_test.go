@@ -0,0 +1,81 @@
+package gonymizer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessDumpFileRowFilter(t *testing.T) {
+	columnMap, err := LoadConfigSkeleton(TestMapFile)
+	require.Nil(t, err)
+	columnMap.RowFilters = []RowFilter{
+		{
+			TableSchema: "public",
+			TableName:   "purchasers",
+			Where: []ProcessorCondition{
+				{Column: "last_name", NotIn: []string{"Borders"}},
+				{Column: "email", Regex: "@example\\.com$"},
+			},
+		},
+		{TableSchema: "public", TableName: "books", SampleRate: 1},
+		{TableSchema: "public", TableName: "authors", Where: []ProcessorCondition{{Column: "id", In: []string{"0"}}}},
+	}
+	require.Nil(t, columnMap.Validate())
+	require.Nil(t, ProcessDumpFileWithOptions(
+		columnMap,
+		TestDbFile,
+		TestRowFilterDumpFile,
+		"",
+		"",
+		true,
+		ProcessOptions{SampleRows: 2}))
+
+	rowCounts := map[string]int{}
+	var purchasers []string
+	require.Nil(t, forEachDumpRow(TestRowFilterDumpFile, func(state *LineState, values []string) error {
+		rowCounts[state.TableName]++
+		if state.TableName == "purchasers" {
+			purchasers = append(purchasers, values[0])
+		}
+		return nil
+	}))
+	// Filtered rows do not count towards SampleRows
+	require.Equal(t, []string{"1", "3"}, purchasers)
+	require.Equal(t, map[string]int{"books": 2, "distributors": 2, "purchasers": 2}, rowCounts)
+	require.Nil(t, os.Remove(TestRowFilterDumpFile))
+}
+
+func TestRowFilterKeeps(t *testing.T) {
+	filter := &RowFilter{TableName: "events", Where: []ProcessorCondition{{Column: "type", NotIn: []string{"debug"}}}}
+	keep, err := filter.keeps([]string{"id", "type"}, []string{"1", "debug\n"})
+	require.Nil(t, err)
+	require.False(t, keep)
+	keep, err = filter.keeps([]string{"id", "type"}, []string{"1", "click\n"})
+	require.Nil(t, err)
+	require.True(t, keep)
+
+	filter = &RowFilter{TableName: "events", SampleRate: 0.1}
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		if keep, _ := filter.keeps(nil, nil); keep {
+			kept++
+		}
+	}
+	require.InDelta(t, 1000, kept, 200)
+}
+
+func TestValidateRowFilters(t *testing.T) {
+	dbMap := &DBMapper{DBName: "test", RowFilters: []RowFilter{{TableSchema: "public", TableName: "events",
+		Where: []ProcessorCondition{{Column: "type", In: []string{"click"}}}, SampleRate: 0.5}}}
+	require.Nil(t, dbMap.Validate())
+
+	dbMap.RowFilters[0].SampleRate = 1.5
+	require.NotNil(t, dbMap.Validate())
+	dbMap.RowFilters[0].SampleRate = 0
+	dbMap.RowFilters[0].Where[0].Column = ""
+	require.NotNil(t, dbMap.Validate())
+	dbMap.RowFilters[0] = RowFilter{TableSchema: "public"}
+	require.NotNil(t, dbMap.Validate())
+}
@@ -0,0 +1,61 @@
+package gonymizer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode"
+)
+
+// dumpBufferSize is the size of the read and write buffers used when processing a dump file.
+const dumpBufferSize = 1 << 20
+
+// dumpScanner reads the lines of a dump file without allocating a new string for every line. Lines are returned as
+// slices of the reader's buffer (or of a reused buffer for lines longer than dumpBufferSize) and are only valid until
+// the next call to next.
+type dumpScanner struct {
+	reader *bufio.Reader
+	line   []byte
+}
+
+// newDumpScanner returns a scanner reading from r.
+func newDumpScanner(r io.Reader) *dumpScanner {
+	return &dumpScanner{reader: bufio.NewReaderSize(r, dumpBufferSize)}
+}
+
+// next returns the next line including its trailing newline. Like bufio.Reader.ReadString the last line of the file is
+// returned together with io.EOF.
+func (s *dumpScanner) next() ([]byte, error) {
+	line, err := s.reader.ReadSlice('\n')
+	if err != bufio.ErrBufferFull {
+		return line, err
+	}
+
+	// The line does not fit in the reader's buffer
+	s.line = append(s.line[:0], line...)
+	for err == bufio.ErrBufferFull {
+		line, err = s.reader.ReadSlice('\n')
+		s.line = append(s.line, line...)
+	}
+	return s.line, err
+}
+
+// passThrough returns true if the line is written to the processed dump file as-is, in which case it never has to be
+// converted to a string. This is the case for the schema and for the rows of tables without any mapped (or generated)
+// columns.
+func passThrough(state *LineState, opts ProcessOptions, line []byte) bool {
+	trimmed := bytes.TrimLeftFunc(line, unicode.IsSpace)
+	if !state.IsRow {
+		return opts.writesSchema() && !bytes.HasPrefix(trimmed, []byte(StateChangeTokenBeginCopy))
+	}
+	if state.Mapped || state.Skipped || state.Batched || len(state.DroppedColumns) > 0 || opts.SampleRows > 0 ||
+		state.RowFilter != nil || state.RowRedaction != nil ||
+		bytes.HasPrefix(trimmed, []byte(StateChangeTokenEndCopy)) || opts.ShardPlan.split(state.CopyCount) {
+		return false
+	}
+	if len(trimmed) > 0 {
+		state.RowNumber++
+		state.RowCount++
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package gonymizer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpScanner(t *testing.T) {
+	long := strings.Repeat("x", dumpBufferSize+10)
+	input := "first\n" + long + "\n\nlast"
+	scanner := newDumpScanner(strings.NewReader(input))
+
+	var lines []string
+	for {
+		line, err := scanner.next()
+		lines = append(lines, string(line))
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+	}
+	require.Equal(t, []string{"first\n", long + "\n", "\n", "last"}, lines)
+}
+
+func TestPassThrough(t *testing.T) {
+	state := new(LineState)
+	require.True(t, passThrough(state, ProcessOptions{}, []byte("CREATE TABLE public.books (\n")))
+	require.False(t, passThrough(state, ProcessOptions{}, []byte("COPY public.books (id) FROM stdin;\n")))
+	require.False(t, passThrough(state, ProcessOptions{ShardCount: 2, ShardIndex: 1}, []byte("SET x = 1;\n")))
+
+	state.IsRow = true
+	require.True(t, passThrough(state, ProcessOptions{}, []byte("1\tabc\n")))
+	require.Equal(t, int64(1), state.RowCount)
+	require.False(t, passThrough(state, ProcessOptions{}, []byte("\\.\n")))
+	require.False(t, passThrough(state, ProcessOptions{SampleRows: 10}, []byte("1\tabc\n")))
+
+	state.Mapped = true
+	require.False(t, passThrough(state, ProcessOptions{}, []byte("1\tabc\n")))
+}
+
+// benchmarkDump returns the test dump file repeated n times.
+func benchmarkDump(b *testing.B, n int) []byte {
+	data, err := ioutil.ReadFile(TestDbFile)
+	require.Nil(b, err)
+	return bytes.Repeat(data, n)
+}
+
+func BenchmarkDumpScanner(b *testing.B) {
+	data := benchmarkDump(b, 1000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		scanner := newDumpScanner(bytes.NewReader(data))
+		for {
+			if _, err := scanner.next(); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkReadString(b *testing.B) {
+	data := benchmarkDump(b, 1000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		reader := bufio.NewReader(bytes.NewReader(data))
+		for {
+			if _, err := reader.ReadString('\n'); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkProcessDumpFile(b *testing.B) {
+	columnMap, err := LoadConfigSkeleton(TestMapFile)
+	require.Nil(b, err)
+
+	src, err := ioutil.TempFile("", "gonymizer-bench-*.sql")
+	require.Nil(b, err)
+	defer os.Remove(src.Name())
+	_, err = src.Write(benchmarkDump(b, 100))
+	require.Nil(b, err)
+	require.Nil(b, src.Close())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		require.Nil(b, ProcessDumpFile(columnMap, src.Name(), TestBenchmarkDumpFile, "", "", false))
+	}
+	b.StopTimer()
+	require.Nil(b, os.Remove(TestBenchmarkDumpFile))
+}
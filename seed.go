@@ -0,0 +1,119 @@
+package gonymizer
+
+import (
+	"bufio"
+	crand "crypto/rand"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/icrowley/fake"
+)
+
+var (
+	// rngSource is the source of rng: a math/rand source seeded by SetSeed, or crypto/rand (see SetSecureRandom).
+	rngSource = &lockedSource{src: rand.NewSource(time.Now().UnixNano())}
+
+	// rng is the random number generator of all processors. Seeding it with SetSeed makes processing runs
+	// reproducible.
+	rng = rand.New(rngSource)
+)
+
+// lockedSource is a rand.Source that is safe for concurrent use, as processors may run in a goroutine of their own
+// (see Timeout).
+type lockedSource struct {
+	mutex sync.Mutex
+	src   rand.Source
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer as an int64.
+func (s *lockedSource) Int63() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.src.Int63()
+}
+
+// Seed uses the provided seed value to initialize the source to a deterministic state.
+func (s *lockedSource) Seed(seed int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.src.Seed(seed)
+}
+
+// secure returns true if the source reads from crypto/rand.
+func (s *lockedSource) secure() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.src.(*cryptoSource)
+	return ok
+}
+
+// cryptoSource is a rand.Source reading from crypto/rand. Seed has no effect. It is not safe for concurrent use.
+type cryptoSource struct {
+	reader *bufio.Reader
+}
+
+// newCryptoSource returns a cryptoSource buffering the random bytes of crypto/rand.
+func newCryptoSource() *cryptoSource {
+	return &cryptoSource{reader: bufio.NewReaderSize(crand.Reader, 4096)}
+}
+
+// Int63 returns a non-negative random 63-bit integer read from crypto/rand. It panics if crypto/rand fails, as the
+// rand.Source interface has no way to return the error.
+func (s *cryptoSource) Int63() int64 {
+	var b [8]byte
+	if _, err := io.ReadFull(s.reader, b[:]); err != nil {
+		panic(err)
+	}
+	return int64(binary.BigEndian.Uint64(b[:]) &^ (1 << 63))
+}
+
+// Seed does nothing, crypto/rand cannot be seeded.
+func (s *cryptoSource) Seed(seed int64) {}
+
+// SetSeed seeds the random number generators of the processors, including those of the fake data and UUID packages.
+// Processing the same dump file with the same map file and seed then writes a byte-identical processed dump file, as
+// long as no processor times out (see Timeout) and the consistent mappings (see LoadMappings) are the same.
+// SetSeed has no effect while the secure random number generator is used (see SetSecureRandom).
+func SetSeed(seed int64) {
+	if rngSource.secure() {
+		return
+	}
+	rng.Seed(seed)
+	fake.Seed(seed)
+	uuid.SetRand(rng)
+}
+
+// reseedSecureFake seeds the math/rand generator of the fake data package from crypto/rand while the secure random
+// number generator is used (see SetSecureRandom). It is called before every processor runs, so the state of the
+// generator cannot be recovered from the fake values of the rows before.
+func reseedSecureFake() {
+	if rngSource.secure() {
+		fake.Seed(rng.Int63())
+	}
+}
+
+// SetSecureRandom switches the random number generator of the processors to crypto/rand when enabled, for data where
+// the predictability of math/rand is not acceptable. Processing runs are then no longer reproducible. The fake data
+// package has a math/rand generator of its own, which is seeded from crypto/rand for every value (see
+// reseedSecureFake). Disabling it switches back to a math/rand generator seeded with the current time (see SetSeed).
+func SetSecureRandom(enabled bool) {
+	if enabled == rngSource.secure() {
+		return
+	}
+
+	rngSource.mutex.Lock()
+	defer rngSource.mutex.Unlock()
+	if !enabled {
+		rngSource.src = rand.NewSource(time.Now().UnixNano())
+		uuid.SetRand(nil)
+		return
+	}
+	source := newCryptoSource()
+	rngSource.src = source
+	fake.Seed(source.Int63())
+	uuid.SetRand(nil)
+}
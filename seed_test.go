@@ -0,0 +1,100 @@
+package gonymizer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/icrowley/fake"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetSeed(t *testing.T) {
+	alphaNumericMap, uuidMap := AlphaNumericMap, UUIDMap
+	defer func() { AlphaNumericMap, UUIDMap = alphaNumericMap, uuidMap }()
+
+	values := func(seed int64) []string {
+		Consistency().Reset()
+		SetSeed(seed)
+		cmap := ColumnMapper{}
+		firstName, err := ProcessorFirstName(&cmap, "Jane")
+		require.Nil(t, err)
+		id, err := ProcessorRandomUUID(&cmap, "9f7d3f5e-43f6-4c1b-9d7a-6c4b1f2e8a90")
+		require.Nil(t, err)
+		code, err := fromRegex(`^[A-Z]{2}[0-9]{6}$`)
+		require.Nil(t, err)
+		return []string{scrambleString("Jane Doe 4111"), firstName, id, code, fakeIBAN("DE89370400440532013000", 0)}
+	}
+	require.Equal(t, values(42), values(42))
+	require.NotEqual(t, values(42), values(43))
+}
+
+func TestProcessDumpFileSeed(t *testing.T) {
+	alphaNumericMap, uuidMap := AlphaNumericMap, UUIDMap
+	defer func() { AlphaNumericMap, UUIDMap = alphaNumericMap, uuidMap }()
+
+	columnMap, err := LoadConfigSkeleton(TestMapFile)
+	require.Nil(t, err)
+	columnMap.Seed = 42
+
+	process := func() []byte {
+		Consistency().Reset()
+		require.Nil(t, ProcessDumpFile(columnMap, TestDbFile, TestSeedDumpFile, "", "", false))
+		output, err := ioutil.ReadFile(TestSeedDumpFile)
+		require.Nil(t, err)
+		return output
+	}
+	first := process()
+	require.Equal(t, first, process())
+	require.Nil(t, os.Remove(TestSeedDumpFile))
+}
+
+func TestSetSecureRandom(t *testing.T) {
+	defer SetSecureRandom(false)
+
+	SetSecureRandom(true)
+	require.True(t, rngSource.secure())
+
+	// Seeding has no effect on crypto/rand
+	SetSeed(42)
+	first := scrambleString("Jane Doe 4111-1111-1111-1111")
+	SetSeed(42)
+	require.NotEqual(t, first, scrambleString("Jane Doe 4111-1111-1111-1111"))
+	for i := 0; i < 1000; i++ {
+		require.True(t, rng.Int63() >= 0)
+		n := rng.Intn(10)
+		require.True(t, n >= 0 && n < 10)
+	}
+
+	// The fake data package is seeded from crypto/rand before every value
+	fake.Seed(42)
+	first = fake.CharactersN(20)
+	fake.Seed(42)
+	reseedSecureFake()
+	require.NotEqual(t, first, fake.CharactersN(20))
+
+	SetSecureRandom(false)
+	require.False(t, rngSource.secure())
+	SetSeed(42)
+	first = scrambleString("Jane Doe 4111-1111-1111-1111")
+	SetSeed(42)
+	require.Equal(t, first, scrambleString("Jane Doe 4111-1111-1111-1111"))
+}
+
+func TestProcessDumpFileSecureRandom(t *testing.T) {
+	defer SetSecureRandom(false)
+
+	columnMap, err := LoadConfigSkeleton(TestMapFile)
+	require.Nil(t, err)
+	columnMap.Seed = 0
+	require.Nil(t, ProcessDumpFileWithOptions(columnMap, TestDbFile, TestSeedDumpFile, "", "", false,
+		ProcessOptions{SecureRandom: true}))
+	require.True(t, rngSource.secure())
+	require.Nil(t, os.Remove(TestSeedDumpFile))
+
+	// The next run switches back to the seeded generator
+	columnMap.Seed = 42
+	require.Nil(t, ProcessDumpFile(columnMap, TestDbFile, TestSeedDumpFile, "", "", false))
+	require.False(t, rngSource.secure())
+	require.Nil(t, os.Remove(TestSeedDumpFile))
+}
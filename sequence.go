@@ -0,0 +1,41 @@
+package gonymizer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sequenceMapKey is the key in the AlphaNumericMap used to store the new value of every ID of the SequentialInt
+// processor. The parent (or the column itself) is added to keep the sequences of different columns apart.
+const sequenceMapKey = "sequence"
+
+// sequentialInt returns the new value of the integer ID: the next value of a counter starting at start, in the order
+// the IDs are first seen. The same ID always gets the same value, so IDs stay unique, and IDs that appear in ascending
+// order (as the rows of a table dumped in primary key order) keep their order. The counter is that of the mapping store
+// (see MappingStore.Next), so workers sharing a store never give two IDs the same value. The counter and mapping are
+// shared by every column with the same parent, and a column without a parent shares them with the columns that name it
+// as their parent.
+func sequentialInt(cmap *ColumnMapper, input string, start int64) (string, error) {
+	number, err := strconv.ParseInt(strings.TrimSpace(input), 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("Expected an integer, got %q", input)
+	}
+	// Formatting the number again makes 007 and 7 the same ID
+	id := strconv.FormatInt(number, 10)
+
+	key := fmt.Sprintf("%s:%s.%s.%s", sequenceMapKey, cmap.TableSchema, cmap.TableName, cmap.ColumnName)
+	if cmap.ParentSchema != "" && cmap.ParentTable != "" && cmap.ParentColumn != "" {
+		key = fmt.Sprintf("%s:%s.%s.%s", sequenceMapKey, cmap.ParentSchema, cmap.ParentTable, cmap.ParentColumn)
+	}
+	output, ok, err := mappingStore.Get(key, id)
+	if err != nil || ok {
+		return output, err
+	}
+
+	next, err := mappingStore.Next(key)
+	if err != nil {
+		return "", err
+	}
+	return addMapping(key, id, strconv.FormatInt(start+next-1, 10))
+}
@@ -0,0 +1,45 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorSequentialInt(t *testing.T) {
+	users := &ColumnMapper{TableSchema: "public", TableName: "users", ColumnName: "id",
+		Processors: []ProcessorDefinition{{Name: "SequentialInt", Min: 1000}}}
+	orders := &ColumnMapper{TableSchema: "public", TableName: "orders", ColumnName: "user_id",
+		ParentSchema: "public", ParentTable: "users", ParentColumn: "id",
+		Processors: []ProcessorDefinition{{Name: "SequentialInt", Min: 1000}}}
+	defer delete(AlphaNumericMap, sequenceMapKey+":public.users.id")
+
+	var outputs []string
+	for _, id := range []string{"17", "42", "0099"} {
+		output, err := ProcessorSequentialInt(users, id)
+		require.Nil(t, err)
+		outputs = append(outputs, output)
+	}
+	require.Equal(t, []string{"1000", "1001", "1002"}, outputs)
+
+	// Columns with the IDs as parent get the same values
+	output, err := ProcessorSequentialInt(orders, "42")
+	require.Nil(t, err)
+	require.Equal(t, "1001", output)
+	output, err = ProcessorSequentialInt(orders, "99")
+	require.Nil(t, err)
+	require.Equal(t, "1002", output)
+	output, err = ProcessorSequentialInt(orders, "7")
+	require.Nil(t, err)
+	require.Equal(t, "1003", output)
+
+	// Other columns have their own sequence
+	other := &ColumnMapper{TableSchema: "public", TableName: "items", ColumnName: "id"}
+	defer delete(AlphaNumericMap, sequenceMapKey+":public.items.id")
+	output, err = ProcessorSequentialInt(other, "42")
+	require.Nil(t, err)
+	require.Equal(t, "1", output)
+
+	_, err = ProcessorSequentialInt(other, "4a")
+	require.NotNil(t, err)
+}
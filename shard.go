@@ -0,0 +1,341 @@
+package gonymizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// shardPollInterval is how often the leader checks the coordination directory for the reports of the other shards.
+var shardPollInterval = time.Second
+
+// ShardReport is the report written by a single shard to the coordination directory once its part of the dump file has
+// been processed. Tables contains the number of rows written for each schema.table.
+type ShardReport struct {
+	Shard         int
+	ShardCount    int
+	ProcessedFile string
+	Tables        map[string]int64
+	Rows          int64
+	Started       time.Time
+	Finished      time.Time
+}
+
+// MergedShardReport is the final report of a sharded run and is written by the leader once every shard has finished.
+type MergedShardReport struct {
+	ShardCount int
+	Shards     []ShardReport
+	Tables     map[string]int64
+	Rows       int64
+}
+
+// NewShardReport will create the report for the shard by counting the rows in its processed dump file.
+func NewShardReport(processedFile string, opts ProcessOptions, started time.Time) (*ShardReport, error) {
+	report := &ShardReport{
+		Shard:         opts.ShardIndex,
+		ShardCount:    opts.ShardCount,
+		ProcessedFile: processedFile,
+		Tables:        map[string]int64{},
+		Started:       started,
+		Finished:      time.Now(),
+	}
+	err := forEachDumpRow(processedFile, func(state *LineState, values []string) error {
+		report.Tables[fmt.Sprintf("%s.%s", state.SchemaName, state.TableName)]++
+		report.Rows++
+		return nil
+	})
+	return report, err
+}
+
+// WriteShardReport will save the shard's report to the coordination directory dir. The coordination key identifies the
+// run (e.g. the name of the Kubernetes Job) so several runs may share the same directory.
+func WriteShardReport(dir, key string, report *ShardReport) error {
+	return writeJSONFile(shardFile(dir, key, strconv.Itoa(report.Shard)), report)
+}
+
+// ElectLeader will try to become the leader of the run identified by key. The first shard to create the leader file in
+// the coordination directory wins. The leader is responsible for merging the reports of all shards.
+func ElectLeader(dir, key string, shard int) (bool, error) {
+	f, err := os.OpenFile(shardFile(dir, key, "leader"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return false, nil
+	} else if err != nil {
+		log.Error(err)
+		log.Error("dir: ", dir)
+		return false, err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(strconv.Itoa(shard))
+	return err == nil, err
+}
+
+// MergeShardReports waits up to timeout for all shardCount shards of the run identified by key to write their reports
+// to the coordination directory and merges them. The merged report is also written to the coordination directory.
+func MergeShardReports(dir, key string, shardCount int, timeout time.Duration) (*MergedShardReport, error) {
+	deadline := time.Now().Add(timeout)
+	var reports []ShardReport
+
+	for shard := 0; shard < shardCount; shard++ {
+		path := shardFile(dir, key, strconv.Itoa(shard))
+		for {
+			data, err := ioutil.ReadFile(path)
+			if err == nil {
+				var report ShardReport
+				if err = json.Unmarshal(data, &report); err != nil {
+					log.Error(err)
+					log.Error("path: ", path)
+					return nil, err
+				}
+				reports = append(reports, report)
+				break
+			} else if !os.IsNotExist(err) {
+				return nil, err
+			}
+
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("Timed out waiting for the report of shard %d: %s", shard, path)
+			}
+			time.Sleep(shardPollInterval)
+		}
+	}
+
+	merged := mergeShardReports(shardCount, reports)
+	return merged, writeJSONFile(shardFile(dir, key, "report"), merged)
+}
+
+// mergeShardReports adds up the row counts of the shard reports.
+func mergeShardReports(shardCount int, reports []ShardReport) *MergedShardReport {
+	merged := &MergedShardReport{ShardCount: shardCount, Shards: reports, Tables: map[string]int64{}}
+	for _, report := range reports {
+		for table, rows := range report.Tables {
+			merged.Tables[table] += rows
+		}
+		merged.Rows += report.Rows
+	}
+	sort.Slice(merged.Shards, func(i, j int) bool { return merged.Shards[i].Shard < merged.Shards[j].Shard })
+	return merged
+}
+
+// WriteMergedShardReport will save the merged report to filepath as JSON.
+func WriteMergedShardReport(report *MergedShardReport, filepath string) error {
+	return writeJSONFile(filepath, report)
+}
+
+// shardFile returns the path of a file in the coordination directory for the run identified by key.
+func shardFile(dir, key, name string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s.%s.json", key, name))
+}
+
+// writeJSONFile will write v to filepath as JSON. The file is written to a temporary file first and then renamed so
+// readers never see a partial file. Reports and states may contain values of the dump file, so the file is only
+// readable by its owner from the moment it is created.
+func writeJSONFile(filepath string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	// A temporary file left behind by an earlier run keeps its permissions when it is written again
+	tmpFile := filepath + ".tmp"
+	if err = os.Remove(tmpFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err = ioutil.WriteFile(tmpFile, data, 0600); err != nil {
+		log.Error("Failure to write file: ", err)
+		log.Error("filepath: ", tmpFile)
+		return err
+	}
+	return os.Rename(tmpFile, filepath)
+}
+
+// createPrivateFile will create (or truncate) the file at filepath readable only by its owner, like the files written
+// by writeJSONFile.
+func createPrivateFile(filepath string) (*os.File, error) {
+	f, err := os.OpenFile(filepath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	// An existing file keeps its permissions when it is truncated
+	if err = f.Chmod(0600); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// ShardPlan assigns the work of a sharded run to the shards by size. Every COPY block of the dump file is a unit of
+// work. Blocks larger than an even share of the dump file are split into row ranges so one huge table does not keep a
+// single shard busy long after the others are done. Units are assigned largest first to the least loaded shard.
+type ShardPlan struct {
+	ShardCount int
+	Units      []ShardUnit
+
+	byCopy map[int64][]ShardUnit
+}
+
+// ShardUnit is a range of rows of a COPY block assigned to a shard. Copy is the position of the COPY block in the dump
+// file and FirstRow and LastRow (inclusive) are row numbers in the block. Both start at 1.
+type ShardUnit struct {
+	Table    string
+	Copy     int64
+	FirstRow int64
+	LastRow  int64
+	Bytes    int64
+	Shard    int
+}
+
+// PlanShards reads the dump file and measures the size of every COPY block to create the ShardPlan for shardCount
+// shards.
+func PlanShards(dumpFile string, shardCount int) (*ShardPlan, error) {
+	if shardCount < 1 {
+		return nil, fmt.Errorf("Expected at least one shard, got %d", shardCount)
+	}
+
+	f, err := os.Open(dumpFile)
+	if err != nil {
+		log.Error(err)
+		log.Error("dumpFile: ", dumpFile)
+		return nil, err
+	}
+	defer f.Close()
+
+	// Measure the number of bytes and rows of every COPY block
+	var (
+		blocks []ShardUnit
+		total  int64
+	)
+	state := new(LineState)
+	scanner := newDumpScanner(f)
+	for {
+		line, err := scanner.next()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		trimmed := bytes.TrimLeftFunc(line, unicode.IsSpace)
+		switch {
+		case !state.IsRow && bytes.HasPrefix(trimmed, []byte(StateChangeTokenBeginCopy)):
+			state.parseCopyLine(string(line))
+			blocks = append(blocks, ShardUnit{
+				Table:    fmt.Sprintf("%s.%s", state.SchemaName, state.TableName),
+				Copy:     int64(len(blocks) + 1),
+				FirstRow: 1,
+			})
+		case state.IsRow && bytes.HasPrefix(trimmed, []byte(StateChangeTokenEndCopy)):
+			state.Clear()
+		case state.IsRow && len(trimmed) > 0:
+			blocks[len(blocks)-1].LastRow++
+			blocks[len(blocks)-1].Bytes += int64(len(line))
+			total += int64(len(line))
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return newShardPlan(blocks, total, shardCount), nil
+}
+
+// newShardPlan splits the COPY blocks into units no larger than an even share of the total size and assigns them to
+// the shards.
+func newShardPlan(blocks []ShardUnit, total int64, shardCount int) *ShardPlan {
+	plan := &ShardPlan{ShardCount: shardCount}
+	share := total / int64(shardCount)
+
+	for _, block := range blocks {
+		parts := int64(1)
+		if share > 0 && block.Bytes > share {
+			parts = (block.Bytes + share - 1) / share
+		}
+		if parts > int64(shardCount) {
+			parts = int64(shardCount)
+		}
+		if parts > block.LastRow {
+			parts = block.LastRow
+		}
+		if parts <= 1 {
+			plan.Units = append(plan.Units, block)
+			continue
+		}
+
+		// Split the rows evenly, the last part gets the remainder
+		rows := block.LastRow / parts
+		for part := int64(0); part < parts; part++ {
+			unit := block
+			unit.FirstRow = part*rows + 1
+			if part < parts-1 {
+				unit.LastRow = (part + 1) * rows
+			}
+			unit.Bytes = block.Bytes * (unit.LastRow - unit.FirstRow + 1) / block.LastRow
+			plan.Units = append(plan.Units, unit)
+		}
+	}
+
+	// Largest unit first to the least loaded shard
+	order := make([]int, len(plan.Units))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return plan.Units[order[i]].Bytes > plan.Units[order[j]].Bytes })
+
+	loads := make([]int64, shardCount)
+	for _, i := range order {
+		shard := 0
+		for s := range loads {
+			if loads[s] < loads[shard] {
+				shard = s
+			}
+		}
+		plan.Units[i].Shard = shard
+		loads[shard] += plan.Units[i].Bytes
+	}
+	return plan
+}
+
+// owns returns true if the row of the COPY block is assigned to the shard. A rowNumber of 0 returns true if any of the
+// rows of the COPY block are assigned to the shard. COPY blocks that are not in the plan belong to shard 0 and rows
+// past the end of the last unit belong to the shard of the last unit.
+func (plan *ShardPlan) owns(shard int, copyCount, rowNumber int64) bool {
+	units := plan.units(copyCount)
+	if len(units) == 0 {
+		return shard == 0
+	}
+	for _, unit := range units {
+		if rowNumber == 0 {
+			if unit.Shard == shard {
+				return true
+			}
+		} else if rowNumber >= unit.FirstRow && rowNumber <= unit.LastRow {
+			return unit.Shard == shard
+		}
+	}
+	return rowNumber > 0 && units[len(units)-1].Shard == shard
+}
+
+// split returns true if the rows of the COPY block are split across more than one shard.
+func (plan *ShardPlan) split(copyCount int64) bool {
+	return plan != nil && len(plan.units(copyCount)) > 1
+}
+
+// units returns the units of the COPY block.
+func (plan *ShardPlan) units(copyCount int64) []ShardUnit {
+	if plan.byCopy == nil {
+		plan.byCopy = map[int64][]ShardUnit{}
+		for _, unit := range plan.Units {
+			plan.byCopy[unit.Copy] = append(plan.byCopy[unit.Copy], unit)
+		}
+	}
+	return plan.byCopy[copyCount]
+}
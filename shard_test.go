@@ -0,0 +1,153 @@
+package gonymizer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessDumpFileShards(t *testing.T) {
+	columnMap, err := LoadConfigSkeleton(TestMapFile)
+	require.Nil(t, err)
+
+	tables := map[string]int64{}
+	for shard := 0; shard < 2; shard++ {
+		opts := ProcessOptions{ShardCount: 2, ShardIndex: shard}
+		require.Nil(t, ProcessDumpFileWithOptions(columnMap, TestDbFile, TestShardDumpFile, "", "", true, opts))
+
+		report, err := NewShardReport(TestShardDumpFile, opts, time.Now())
+		require.Nil(t, err)
+		require.NotEmpty(t, report.Tables)
+		for table, rows := range report.Tables {
+			require.Zero(t, tables[table], "%s was processed by more than one shard", table)
+			tables[table] = rows
+		}
+
+		// Only the first shard writes the schema
+		output, err := ioutil.ReadFile(TestShardDumpFile)
+		require.Nil(t, err)
+		if shard == 0 {
+			require.Contains(t, string(output), "CREATE OR REPLACE FUNCTION create_distributor")
+		} else {
+			require.NotContains(t, string(output), "CREATE")
+		}
+	}
+	require.Len(t, tables, 4)
+	require.Nil(t, os.Remove(TestShardDumpFile))
+
+	err = ProcessDumpFileWithOptions(columnMap, TestDbFile, TestShardDumpFile, "", "", true,
+		ProcessOptions{ShardCount: 2, ShardIndex: 2})
+	require.NotNil(t, err)
+
+	// Columns mapped from a parent column require a shared mapping store
+	parentMap := *columnMap
+	parentMap.ColumnMaps = append([]ColumnMapper(nil), columnMap.ColumnMaps...)
+	cmap := &parentMap.ColumnMaps[0]
+	cmap.ParentSchema, cmap.ParentTable, cmap.ParentColumn = cmap.TableSchema, cmap.TableName, cmap.ColumnName
+	opts := ProcessOptions{ShardCount: 2, ShardIndex: 1}
+	require.NotNil(t, ProcessDumpFileWithOptions(&parentMap, TestDbFile, TestShardDumpFile, "", "", true, opts))
+
+	server := newFakeRedisServer(t, "")
+	defer server.Close()
+	opts.MappingStore, err = OpenMappingStore("redis://" + server.Addr().String())
+	require.Nil(t, err)
+	defer opts.MappingStore.Close()
+	require.Nil(t, ProcessDumpFileWithOptions(&parentMap, TestDbFile, TestShardDumpFile, "", "", true, opts))
+	require.Nil(t, os.Remove(TestShardDumpFile))
+}
+
+func TestMergeShardReports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gonymizer-shards")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	leader, err := ElectLeader(dir, "job", 1)
+	require.Nil(t, err)
+	require.True(t, leader)
+	leader, err = ElectLeader(dir, "job", 0)
+	require.Nil(t, err)
+	require.False(t, leader)
+
+	shardPollInterval = time.Millisecond
+	defer func() { shardPollInterval = time.Second }()
+
+	require.Nil(t, WriteShardReport(dir, "job", &ShardReport{Shard: 1, ShardCount: 2,
+		Tables: map[string]int64{"public.books": 3}, Rows: 3}))
+	_, err = MergeShardReports(dir, "job", 2, 10*time.Millisecond)
+	require.NotNil(t, err)
+
+	require.Nil(t, WriteShardReport(dir, "job", &ShardReport{Shard: 0, ShardCount: 2,
+		Tables: map[string]int64{"public.authors": 2, "public.books": 1}, Rows: 3}))
+	merged, err := MergeShardReports(dir, "job", 2, time.Second)
+	require.Nil(t, err)
+	require.Equal(t, int64(6), merged.Rows)
+	require.Equal(t, map[string]int64{"public.authors": 2, "public.books": 4}, merged.Tables)
+	require.Equal(t, 0, merged.Shards[0].Shard)
+	require.FileExists(t, shardFile(dir, "job", "report"))
+}
+
+// writeShardTestDump writes a dump file with two small tables and one large table and returns its path.
+func writeShardTestDump(t *testing.T) string {
+	var dump strings.Builder
+	dump.WriteString("CREATE TABLE public.users (id integer);\n")
+	for _, table := range []string{"users", "events", "accounts"} {
+		rows := 5
+		if table == "events" {
+			rows = 300
+		}
+		fmt.Fprintf(&dump, "COPY public.%s (id, value) FROM stdin;\n", table)
+		for i := 1; i <= rows; i++ {
+			fmt.Fprintf(&dump, "%d\t%s-%d\n", i, table, i)
+		}
+		dump.WriteString("\\.\n\n")
+	}
+
+	f, err := ioutil.TempFile("", "gonymizer-*.sql")
+	require.Nil(t, err)
+	_, err = f.WriteString(dump.String())
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+	return f.Name()
+}
+
+func TestPlanShards(t *testing.T) {
+	dumpFile := writeShardTestDump(t)
+	defer os.Remove(dumpFile)
+
+	plan, err := PlanShards(dumpFile, 3)
+	require.Nil(t, err)
+
+	var events []ShardUnit
+	for _, unit := range plan.Units {
+		if unit.Table == "public.events" {
+			events = append(events, unit)
+		}
+	}
+	require.Len(t, events, 3)
+	require.Equal(t, int64(1), events[0].FirstRow)
+	require.Equal(t, int64(300), events[2].LastRow)
+
+	// Every row is written by exactly one shard
+	rows := map[string]int{}
+	for shard := 0; shard < 3; shard++ {
+		opts := ProcessOptions{ShardCount: 3, ShardIndex: shard, ShardPlan: plan}
+		require.Nil(t, ProcessDumpFileWithOptions(&DBMapper{Seed: 1}, dumpFile, TestShardDumpFile, "", "", false, opts))
+		require.Nil(t, forEachDumpRow(TestShardDumpFile, func(state *LineState, values []string) error {
+			rows[values[1]]++
+			return nil
+		}))
+	}
+	require.Len(t, rows, 310)
+	for value, count := range rows {
+		require.Equal(t, 1, count, value)
+	}
+	require.Nil(t, os.Remove(TestShardDumpFile))
+
+	_, err = PlanShards(dumpFile, 0)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,112 @@
+package gonymizer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// maxSimulationErrors is the number of distinct error messages kept for every column in a SimulationReport.
+const maxSimulationErrors = 5
+
+// SimulationColumn contains the outcome of running the values of a column through its processors. ErrorLines and
+// UnchangedLines contain the first line numbers (in the dump file) of the values that failed or were left unchanged.
+type SimulationColumn struct {
+	Column         string
+	Values         int64
+	Errors         int64
+	Unchanged      int64
+	ErrorMessages  []string `json:",omitempty"`
+	ErrorLines     []int64  `json:",omitempty"`
+	UnchangedLines []int64  `json:",omitempty"`
+}
+
+// SimulationReport is the result of replaying a map file against a dump file. Only columns with errors or unchanged
+// values are listed.
+type SimulationReport struct {
+	Rows      int64
+	Values    int64
+	Errors    int64
+	Unchanged int64
+	Columns   []SimulationColumn
+}
+
+// SimulateDumpFile runs every value of the mapped columns of the dump file (e.g. an archived older dump) through the
+// processors of the map file without writing a processed dump file, and reports the values that fail to process or
+// are left unchanged (see unchangedValue). This validates map changes against realistic data. When sampleRows is
+// greater than 0 only the first sampleRows rows of every table are processed.
+func SimulateDumpFile(mapper *DBMapper, dumpFile string, sampleRows int64) (*SimulationReport, error) {
+	rows, err := openDumpRows(dumpFile)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.close()
+	if mapper.Seed != 0 {
+		SetSeed(mapper.Seed)
+	}
+
+	columns := map[string]*SimulationColumn{}
+	report := new(SimulationReport)
+	for {
+		values, err := rows.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		state := rows.state
+		if sampleRows > 0 && state.RowNumber > sampleRows {
+			continue
+		}
+		report.Rows++
+
+		row := newRowContext(state.ColumnNames, values)
+		for i, columnName := range state.ColumnNames {
+			cmap := mapper.ColumnMapper(state.SchemaName, state.TableName, columnName)
+			if cmap == nil || len(cmap.Processors) == 0 || i >= len(values) || values[i] == "\\N" {
+				continue
+			}
+
+			key := fmt.Sprintf("%s.%s.%s", state.SchemaName, state.TableName, columnName)
+			column, ok := columns[key]
+			if !ok {
+				column = &SimulationColumn{Column: key}
+				columns[key] = column
+			}
+			column.Values++
+			report.Values++
+
+			outputs, err := processBatch(cmap, []Cell{{Column: cmap, Value: values[i], row: row}})
+			if err != nil {
+				column.Errors++
+				report.Errors++
+				if len(column.ErrorLines) < maxAuditLines {
+					column.ErrorLines = append(column.ErrorLines, state.LineNum)
+				}
+				if len(column.ErrorMessages) < maxSimulationErrors && !containsString(column.ErrorMessages,
+					err.Error()) {
+					column.ErrorMessages = append(column.ErrorMessages, err.Error())
+				}
+			} else if unchangedValue(cmap, values[i], outputs[0]) {
+				column.Unchanged++
+				report.Unchanged++
+				if len(column.UnchangedLines) < maxAuditLines {
+					column.UnchangedLines = append(column.UnchangedLines, state.LineNum)
+				}
+			}
+		}
+	}
+
+	for _, column := range columns {
+		if column.Errors > 0 || column.Unchanged > 0 {
+			report.Columns = append(report.Columns, *column)
+		}
+	}
+	sort.Slice(report.Columns, func(i, j int) bool { return report.Columns[i].Column < report.Columns[j].Column })
+	return report, nil
+}
+
+// WriteSimulationReport will save the simulation report to filepath as JSON.
+func WriteSimulationReport(report *SimulationReport, filepath string) error {
+	return writeJSONFile(filepath, report)
+}
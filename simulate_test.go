@@ -0,0 +1,64 @@
+package gonymizer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulateDumpFile(t *testing.T) {
+	mapper := &DBMapper{
+		DBName: "pii_localtest",
+		Seed:   42,
+		ColumnMaps: []ColumnMapper{
+			{
+				TableSchema: "public",
+				TableName:   "users",
+				ColumnName:  "uuid",
+				Processors:  []ProcessorDefinition{{Name: "RandomUUID"}},
+			},
+			{
+				TableSchema: "public",
+				TableName:   "users",
+				ColumnName:  "password",
+				Processors:  []ProcessorDefinition{{Name: "ScrubString"}},
+			},
+			{
+				TableSchema: "public",
+				TableName:   "users",
+				ColumnName:  "country",
+				Processors:  []ProcessorDefinition{{Name: "Identity"}},
+			},
+		},
+	}
+
+	dumpFile := writeTestAuditFile(t, "COPY public.users (id, uuid, password, country) FROM stdin;\n"+
+		"1\t5d9b4a3e-27a4-4b5d-9c8e-0a1b2c3d4e5f\tsecret\tUS\n"+
+		"2\tnot-a-uuid\t******\tCanada\n"+
+		"3\t\\N\t\\N\tPeru\n\\.\n")
+	defer os.Remove(dumpFile)
+
+	report, err := SimulateDumpFile(mapper, dumpFile, 0)
+	require.Nil(t, err)
+	require.Equal(t, int64(3), report.Rows)
+	require.Equal(t, int64(1), report.Errors)
+	require.Equal(t, int64(1), report.Unchanged)
+	require.Len(t, report.Columns, 2)
+
+	require.Equal(t, "public.users.password", report.Columns[0].Column)
+	require.Equal(t, []int64{3}, report.Columns[0].UnchangedLines)
+	require.Equal(t, "public.users.uuid", report.Columns[1].Column)
+	require.Equal(t, int64(2), report.Columns[1].Values)
+	require.Equal(t, []int64{3}, report.Columns[1].ErrorLines)
+	require.Len(t, report.Columns[1].ErrorMessages, 1)
+
+	// Sampling
+	report, err = SimulateDumpFile(mapper, dumpFile, 1)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), report.Rows)
+	require.Empty(t, report.Columns)
+
+	_, err = SimulateDumpFile(mapper, "missing.sql", 0)
+	require.NotNil(t, err)
+}
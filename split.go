@@ -0,0 +1,281 @@
+package gonymizer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SplitManifestFile is the name of the manifest written to the split directory (see SplitDumpFile).
+const SplitManifestFile = "manifest.json"
+
+// splitFileNameRegex matches the characters that are replaced in table names used as file names.
+var splitFileNameRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// SplitOptions configures how SplitDumpFile splits a processed dump file.
+type SplitOptions struct {
+	// Dir is the directory the parts and the manifest are written to. It is created if it does not exist.
+	Dir string
+
+	// ByTable writes the schema, every COPY block, and everything after the COPY blocks (indexes, constraints,
+	// sequence values) to their own files. Otherwise the dump file is cut into parts of at most MaxSize bytes.
+	ByTable bool
+	MaxSize int64
+}
+
+// SplitFile is a part of a split dump file. Parts must be loaded in the order they are listed in the manifest.
+type SplitFile struct {
+	Name   string
+	Size   int64
+	SHA256 string
+	Tables []string `json:",omitempty"`
+	Rows   int64
+}
+
+// SplitManifest is the index of a split dump file.
+type SplitManifest struct {
+	Source  string
+	Mode    string
+	Created time.Time
+	Files   []SplitFile
+}
+
+// splitWriter writes a part of a split dump file while counting its size and computing its checksum.
+type splitWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+	hash   hash.Hash
+	part   SplitFile
+}
+
+// newSplitWriter creates the part named name in dir and writes the session settings of the dump file to it.
+func newSplitWriter(dir, name string, header []byte) (*splitWriter, error) {
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		log.Error("Unable to create part of split dump file: ", err)
+		return nil, err
+	}
+	w := &splitWriter{file: file, hash: sha256.New(), part: SplitFile{Name: name}}
+	w.writer = bufio.NewWriterSize(io.MultiWriter(file, w.hash), dumpBufferSize)
+	if _, err = w.write(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// write appends the line to the part.
+func (w *splitWriter) write(line []byte) (int, error) {
+	n, err := w.writer.Write(line)
+	w.part.Size += int64(n)
+	return n, err
+}
+
+// addTable records that the part contains rows of the table.
+func (w *splitWriter) addTable(table string) {
+	if !containsString(w.part.Tables, table) {
+		w.part.Tables = append(w.part.Tables, table)
+	}
+}
+
+// close flushes and closes the part and returns its manifest entry.
+func (w *splitWriter) close() (SplitFile, error) {
+	if err := w.writer.Flush(); err != nil {
+		w.file.Close()
+		return w.part, err
+	}
+	w.part.SHA256 = hex.EncodeToString(w.hash.Sum(nil))
+	return w.part, w.file.Close()
+}
+
+// isSessionSetting returns true if the line of the schema changes a setting of the psql session (such as SET
+// session_replication_role). Every part of a split dump file starts with these lines so parts can be loaded in separate
+// sessions.
+func isSessionSetting(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("SET ")) || bytes.HasPrefix(line, []byte("SELECT pg_catalog.set_config("))
+}
+
+// SplitDumpFile splits the processed dump file into per-table files or size-capped parts (see SplitOptions) so they can
+// be uploaded in parallel and restored selectively. The parts and a manifest (SplitManifestFile) listing every part in
+// load order with its size, SHA-256 checksum, tables, and number of rows are written to opts.Dir.
+func SplitDumpFile(dumpFile string, opts SplitOptions) (*SplitManifest, error) {
+	if !opts.ByTable && opts.MaxSize <= 0 {
+		return nil, errors.New("Expected ByTable or a MaxSize greater than 0")
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		log.Error("Unable to create split directory: ", err)
+		return nil, err
+	}
+
+	srcFile, err := os.Open(dumpFile)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	defer srcFile.Close()
+
+	manifest := &SplitManifest{Source: filepath.Base(dumpFile), Mode: "size", Created: time.Now().UTC()}
+	if opts.ByTable {
+		manifest.Mode = "table"
+	}
+
+	var (
+		scanner  = newDumpScanner(srcFile)
+		state    = new(LineState)
+		header   []byte
+		copyLine []byte
+		inData   bool
+		current  *splitWriter
+	)
+	defer func() {
+		if current != nil {
+			current.file.Close()
+		}
+	}()
+
+	// next closes the current part and starts the next one. Per-table files are named after what they contain.
+	next := func(contents string) error {
+		if current != nil {
+			part, err := current.close()
+			current = nil
+			if err != nil {
+				return err
+			}
+			manifest.Files = append(manifest.Files, part)
+		}
+		name := fmt.Sprintf("part-%04d.sql", len(manifest.Files)+1)
+		if opts.ByTable {
+			name = fmt.Sprintf("%03d-%s.sql", len(manifest.Files), splitFileNameRegex.ReplaceAllString(contents, "_"))
+		}
+
+		var err error
+		if len(manifest.Files) == 0 {
+			// The first part contains the session settings already
+			current, err = newSplitWriter(opts.Dir, name, nil)
+		} else {
+			current, err = newSplitWriter(opts.Dir, name, header)
+		}
+		return err
+	}
+	if err = next("schema"); err != nil {
+		return nil, err
+	}
+
+	for {
+		line, err := scanner.next()
+		if err != nil && err != io.EOF {
+			log.Error(err)
+			return nil, err
+		}
+		trimmed := bytes.TrimLeftFunc(line, unicode.IsSpace)
+
+		switch {
+		case bytes.HasPrefix(trimmed, []byte(StateChangeTokenBeginCopy)):
+			state.parseCopyLine(string(line))
+			table := state.SchemaName + "." + state.TableName
+			copyLine = append(copyLine[:0], line...)
+			inData = true
+			if opts.ByTable {
+				if err := next(table); err != nil {
+					return nil, err
+				}
+			} else if current.part.Size > int64(len(header)) && current.part.Size+int64(len(line)) > opts.MaxSize {
+				if err := next(""); err != nil {
+					return nil, err
+				}
+			}
+			current.addTable(table)
+		case state.IsRow && bytes.HasPrefix(trimmed, []byte(StateChangeTokenEndCopy)):
+			state.Clear()
+		case state.IsRow && len(trimmed) > 0:
+			// Leave room to end the COPY block in this part
+			if !opts.ByTable && current.part.Size+int64(len(line)+len(StateChangeTokenEndCopy)+1) > opts.MaxSize &&
+				current.part.Size > int64(len(header)+len(copyLine)) {
+				if _, err := current.write([]byte(StateChangeTokenEndCopy + "\n")); err != nil {
+					return nil, err
+				}
+				if err := next(""); err != nil {
+					return nil, err
+				}
+				if _, err := current.write(copyLine); err != nil {
+					return nil, err
+				}
+				current.addTable(state.SchemaName + "." + state.TableName)
+			}
+			current.part.Rows++
+		case !state.IsRow && len(trimmed) > 0:
+			if !inData && isSessionSetting(trimmed) {
+				header = append(header, line...)
+			}
+			if opts.ByTable && inData && len(current.part.Tables) > 0 && !bytes.HasPrefix(trimmed, []byte("--")) {
+				if err := next("post-data"); err != nil {
+					return nil, err
+				}
+			} else if !opts.ByTable && current.part.Size > int64(len(header)) &&
+				current.part.Size+int64(len(line)) > opts.MaxSize {
+				if err := next(""); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if _, err := current.write(line); err != nil {
+			return nil, err
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	part, err := current.close()
+	current = nil
+	if err != nil {
+		return nil, err
+	}
+	manifest.Files = append(manifest.Files, part)
+
+	log.Infof("Split %s into %d file(s) in %s", dumpFile, len(manifest.Files), opts.Dir)
+	return manifest, writeJSONFile(filepath.Join(opts.Dir, SplitManifestFile), manifest)
+}
+
+// ParseSize parses a size such as 5GB, 500MB, 64KiB, or 1024 (bytes). Units are powers of 1024.
+func ParseSize(size string) (int64, error) {
+	var units = []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"TIB", 1 << 40}, {"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	}
+
+	number := strings.ToUpper(strings.TrimSpace(size))
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(number, unit.suffix) {
+			number = strings.TrimSpace(strings.TrimSuffix(number, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	var value float64
+	if _, err := fmt.Sscanf(number, "%g", &value); err != nil || value <= 0 ||
+		strings.TrimRight(number, "0123456789.") != "" {
+		return 0, fmt.Errorf("Invalid size %q", size)
+	}
+	return int64(value * float64(multiplier)), nil
+}
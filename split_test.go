@@ -0,0 +1,119 @@
+package gonymizer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const splitTestDump = "SET statement_timeout = 0;\n" +
+	"SELECT pg_catalog.set_config('search_path', '', false);\n" +
+	"CREATE TABLE public.users (id integer, name text);\n" +
+	"CREATE TABLE public.\"Order Items\" (id integer);\n\n" +
+	"-- Data for Name: users\n" +
+	"COPY public.users (id, name) FROM stdin;\n1\tAnn\n2\tBob\n3\tCid\n\\.\n\n" +
+	"-- Data for Name: Order Items\n" +
+	"COPY public.\"Order Items\" (id) FROM stdin;\n1\n\\.\n\n" +
+	"ALTER TABLE ONLY public.users ADD CONSTRAINT users_pkey PRIMARY KEY (id);\n"
+
+func readSplitFile(t *testing.T, dir, name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, name))
+	require.Nil(t, err)
+	return string(data)
+}
+
+func TestSplitDumpFileByTable(t *testing.T) {
+	dumpFile := writeTestAuditFile(t, splitTestDump)
+	defer os.Remove(dumpFile)
+	dir, err := ioutil.TempDir("", "gonymizer-split-")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	manifest, err := SplitDumpFile(dumpFile, SplitOptions{Dir: dir, ByTable: true})
+	require.Nil(t, err)
+	require.Equal(t, "table", manifest.Mode)
+	require.Len(t, manifest.Files, 4)
+
+	require.Equal(t, "000-schema.sql", manifest.Files[0].Name)
+	require.Equal(t, "001-public.users.sql", manifest.Files[1].Name)
+	require.Equal(t, []string{"public.users"}, manifest.Files[1].Tables)
+	require.Equal(t, int64(3), manifest.Files[1].Rows)
+	require.Equal(t, "002-public.Order_Items.sql", manifest.Files[2].Name)
+	require.Equal(t, int64(1), manifest.Files[2].Rows)
+	require.Equal(t, "003-post-data.sql", manifest.Files[3].Name)
+
+	users := readSplitFile(t, dir, manifest.Files[1].Name)
+	require.True(t, strings.HasPrefix(users, "SET statement_timeout = 0;\n"+
+		"SELECT pg_catalog.set_config('search_path', '', false);\nCOPY public.users (id, name) FROM stdin;\n"))
+	require.Contains(t, readSplitFile(t, dir, manifest.Files[3].Name), "ADD CONSTRAINT users_pkey")
+	require.Equal(t, int64(len(users)), manifest.Files[1].Size)
+	require.Len(t, manifest.Files[1].SHA256, 64)
+
+	// Concatenating the parts (without the repeated session settings) gives the dump file back
+	var joined string
+	for i, file := range manifest.Files {
+		contents := readSplitFile(t, dir, file.Name)
+		if i > 0 {
+			contents = contents[len("SET statement_timeout = 0;\nSELECT pg_catalog.set_config('search_path', '', false);\n"):]
+		}
+		joined += contents
+	}
+	require.Equal(t, splitTestDump, joined)
+
+	var saved SplitManifest
+	require.Nil(t, json.Unmarshal([]byte(readSplitFile(t, dir, SplitManifestFile)), &saved))
+	require.Len(t, saved.Files, 4)
+}
+
+func TestSplitDumpFileBySize(t *testing.T) {
+	dumpFile := writeTestAuditFile(t, splitTestDump)
+	defer os.Remove(dumpFile)
+	dir, err := ioutil.TempDir("", "gonymizer-split-")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = SplitDumpFile(dumpFile, SplitOptions{Dir: dir})
+	require.NotNil(t, err)
+
+	manifest, err := SplitDumpFile(dumpFile, SplitOptions{Dir: dir, MaxSize: 200})
+	require.Nil(t, err)
+	require.Equal(t, "size", manifest.Mode)
+	require.True(t, len(manifest.Files) > 1)
+
+	var rows int64
+	for _, file := range manifest.Files {
+		rows += file.Rows
+		contents := readSplitFile(t, dir, file.Name)
+		require.Equal(t, strings.Count(contents, "COPY "), strings.Count(contents, "\\.\n"), file.Name)
+		if file.Rows > 0 {
+			require.True(t, file.Size <= 200, file.Name)
+		}
+	}
+	require.Equal(t, int64(4), rows)
+}
+
+func TestParseSize(t *testing.T) {
+	var sizes = map[string]int64{
+		"1024":  1024,
+		"5GB":   5 << 30,
+		"500mb": 500 << 20,
+		"64KiB": 64 << 10,
+		"1.5G":  3 << 29,
+		"10 MB": 10 << 20,
+	}
+	for input, expected := range sizes {
+		size, err := ParseSize(input)
+		require.Nil(t, err, input)
+		require.Equal(t, expected, size, input)
+	}
+
+	for _, input := range []string{"", "GB", "-1GB", "5XB", "1e3"} {
+		_, err := ParseSize(input)
+		require.NotNil(t, err, input)
+	}
+}
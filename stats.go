@@ -0,0 +1,237 @@
+package gonymizer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// statsHistogramBins is the number of equal width bins used when comparing the distribution of a column.
+const statsHistogramBins = 10
+
+// statsDateLayouts are the date/time layouts (as written by pg_dump) that are recognized when collecting statistics.
+var statsDateLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// ColumnStats contains summary statistics for the numeric or date values of a single column. Dates are measured in
+// seconds since the Unix epoch.
+type ColumnStats struct {
+	Count    int64
+	Nulls    int64
+	Min      float64
+	Max      float64
+	Mean     float64
+	StdDev   float64
+	NullRate float64
+
+	m2 float64 // running sum of squares of differences from the mean (Welford's algorithm)
+}
+
+// ColumnStatsReport compares the statistics of a column in the source dump file to the same column in the processed
+// dump file. ChiSquare is the chi-square statistic of the processed histogram against the source histogram (using the
+// source min/max to create the bins). A ChiSquare of 0 means the distributions are identical.
+type ColumnStatsReport struct {
+	Column    string
+	IsDate    bool
+	Source    ColumnStats
+	Processed ColumnStats
+	ChiSquare float64
+}
+
+// StatsReport is the statistical similarity report of all numeric and date columns that are anonymized by the map.
+type StatsReport struct {
+	Columns []ColumnStatsReport
+}
+
+// add adds a value to the running statistics.
+func (cs *ColumnStats) add(value float64) {
+	cs.Count++
+	if cs.Count == 1 || value < cs.Min {
+		cs.Min = value
+	}
+	if cs.Count == 1 || value > cs.Max {
+		cs.Max = value
+	}
+	delta := value - cs.Mean
+	cs.Mean += delta / float64(cs.Count)
+	cs.m2 += delta * (value - cs.Mean)
+}
+
+// finish calculates the final standard deviation and null rate once all values have been added.
+func (cs *ColumnStats) finish() {
+	if cs.Count > 1 {
+		cs.StdDev = math.Sqrt(cs.m2 / float64(cs.Count-1))
+	}
+	if total := cs.Count + cs.Nulls; total > 0 {
+		cs.NullRate = float64(cs.Nulls) / float64(total)
+	}
+}
+
+// CompareDumpStatistics reads the source (PII) dump file and the processed dump file and compares the distribution of
+// every numeric and date column that is anonymized by the map. This is used to quantify how much the anonymization
+// distorted the data.
+func CompareDumpStatistics(mapper *DBMapper, src, dst string) (*StatsReport, error) {
+	// First pass: collect min/max/mean/stddev so we know the histogram bounds of the source
+	sourceStats, dates, err := collectDumpStatistics(mapper, src)
+	if err != nil {
+		return nil, err
+	}
+	processedStats, _, err := collectDumpStatistics(mapper, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	// Second pass: build the histograms for both files using the bins from the source
+	sourceHistograms, err := collectDumpHistograms(mapper, src, sourceStats)
+	if err != nil {
+		return nil, err
+	}
+	processedHistograms, err := collectDumpHistograms(mapper, dst, sourceStats)
+	if err != nil {
+		return nil, err
+	}
+
+	report := new(StatsReport)
+	for column, source := range sourceStats {
+		if source.Count == 0 {
+			continue
+		}
+		processed, ok := processedStats[column]
+		if !ok {
+			processed = new(ColumnStats)
+		}
+		report.Columns = append(report.Columns, ColumnStatsReport{
+			Column:    column,
+			IsDate:    dates[column],
+			Source:    *source,
+			Processed: *processed,
+			ChiSquare: chiSquare(sourceHistograms[column], processedHistograms[column]),
+		})
+	}
+	sort.Slice(report.Columns, func(i, j int) bool { return report.Columns[i].Column < report.Columns[j].Column })
+	return report, nil
+}
+
+// WriteStatsReport will save the statistical similarity report to filepath as JSON.
+func WriteStatsReport(report *StatsReport, filepath string) error {
+	return writeJSONFile(filepath, report)
+}
+
+// collectDumpStatistics collects summary statistics for every mapped column in the dump file. The statistics are keyed
+// by schema.table.column. The second map returned marks the columns which contain dates.
+func collectDumpStatistics(mapper *DBMapper, path string) (map[string]*ColumnStats, map[string]bool, error) {
+	stats := map[string]*ColumnStats{}
+	dates := map[string]bool{}
+
+	err := forEachDumpRow(path, func(state *LineState, values []string) error {
+		for i, columnName := range state.ColumnNames {
+			if mapper.ColumnMapper(state.SchemaName, state.TableName, columnName) == nil {
+				continue
+			}
+			key := fmt.Sprintf("%s.%s.%s", state.SchemaName, state.TableName, columnName)
+
+			cs, found := stats[key]
+			if !found {
+				cs = new(ColumnStats)
+				stats[key] = cs
+			}
+			if values[i] == "\\N" {
+				cs.Nulls++
+			} else if value, isDate, ok := statsValue(values[i]); ok {
+				cs.add(value)
+				dates[key] = dates[key] || isDate
+			}
+		}
+		return nil
+	})
+
+	for _, cs := range stats {
+		cs.finish()
+	}
+	return stats, dates, err
+}
+
+// collectDumpHistograms counts the values of every column found in bounds into equal width histograms using the
+// column's min/max from bounds. Values outside of the bounds are counted in the first or last bin.
+func collectDumpHistograms(mapper *DBMapper, path string, bounds map[string]*ColumnStats) (map[string][]int64, error) {
+	histograms := map[string][]int64{}
+
+	err := forEachDumpRow(path, func(state *LineState, values []string) error {
+		for i, columnName := range state.ColumnNames {
+			key := fmt.Sprintf("%s.%s.%s", state.SchemaName, state.TableName, columnName)
+			cs, found := bounds[key]
+			if !found {
+				continue
+			}
+			value, _, ok := statsValue(values[i])
+			if !ok {
+				continue
+			}
+
+			if histograms[key] == nil {
+				histograms[key] = make([]int64, statsHistogramBins)
+			}
+			bin := 0
+			if width := (cs.Max - cs.Min) / statsHistogramBins; width > 0 {
+				bin = int((value - cs.Min) / width)
+			}
+			if bin < 0 {
+				bin = 0
+			} else if bin >= statsHistogramBins {
+				bin = statsHistogramBins - 1
+			}
+			histograms[key][bin]++
+		}
+		return nil
+	})
+	return histograms, err
+}
+
+// statsValue parses a dump file value as a number or date. Dates are returned as seconds since the Unix epoch.
+func statsValue(value string) (float64, bool, bool) {
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f, false, true
+	}
+	for _, layout := range statsDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return float64(t.Unix()), true, true
+		}
+	}
+	return 0, false, false
+}
+
+// chiSquare calculates the chi-square statistic of the observed histogram against the expected histogram. The expected
+// counts are scaled to the total of the observed histogram. Bins with no expected values are skipped.
+func chiSquare(expected, observed []int64) float64 {
+	var expectedTotal, observedTotal int64
+	for i := range expected {
+		expectedTotal += expected[i]
+	}
+	for i := range observed {
+		observedTotal += observed[i]
+	}
+	if expectedTotal == 0 || observedTotal == 0 {
+		return 0
+	}
+
+	var chi float64
+	scale := float64(observedTotal) / float64(expectedTotal)
+	for i := range expected {
+		if expected[i] == 0 {
+			continue
+		}
+		e := float64(expected[i]) * scale
+		var o float64
+		if i < len(observed) {
+			o = float64(observed[i])
+		}
+		chi += (o - e) * (o - e) / e
+	}
+	return chi
+}
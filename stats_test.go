@@ -0,0 +1,74 @@
+package gonymizer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const TestStatsReportFile = "testing/output.TestStatsReport.json"
+
+func TestCompareDumpStatistics(t *testing.T) {
+	columnMap, err := LoadConfigSkeleton(TestMapFile)
+	require.Nil(t, err)
+
+	// Comparing a dump file against itself should produce identical statistics
+	report, err := CompareDumpStatistics(columnMap, TestDbFile, TestDbFile)
+	require.Nil(t, err)
+	require.NotEmpty(t, report.Columns)
+	for _, column := range report.Columns {
+		require.Equal(t, column.Source, column.Processed, column.Column)
+		require.Equal(t, 0.0, column.ChiSquare, column.Column)
+	}
+
+	require.Nil(t, WriteStatsReport(report, TestStatsReportFile))
+	info, err := os.Stat(TestStatsReportFile)
+	require.Nil(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	require.Nil(t, os.Remove(TestStatsReportFile))
+
+	_, err = CompareDumpStatistics(columnMap, "testing/does_not_exist.sql", TestDbFile)
+	require.NotNil(t, err)
+}
+
+func TestColumnStats(t *testing.T) {
+	cs := new(ColumnStats)
+	for _, v := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		cs.add(v)
+	}
+	cs.Nulls = 2
+	cs.finish()
+
+	require.Equal(t, int64(8), cs.Count)
+	require.Equal(t, 2.0, cs.Min)
+	require.Equal(t, 9.0, cs.Max)
+	require.Equal(t, 5.0, cs.Mean)
+	require.InDelta(t, 2.138, cs.StdDev, 0.001)
+	require.Equal(t, 0.2, cs.NullRate)
+}
+
+func TestStatsValue(t *testing.T) {
+	value, isDate, ok := statsValue("42.5")
+	require.True(t, ok)
+	require.False(t, isDate)
+	require.Equal(t, 42.5, value)
+
+	value, isDate, ok = statsValue("1970-01-02")
+	require.True(t, ok)
+	require.True(t, isDate)
+	require.Equal(t, 86400.0, value)
+
+	_, isDate, ok = statsValue("2019-06-01 12:00:00.123456-07")
+	require.True(t, ok)
+	require.True(t, isDate)
+
+	_, _, ok = statsValue("not a number")
+	require.False(t, ok)
+}
+
+func TestChiSquare(t *testing.T) {
+	require.Equal(t, 0.0, chiSquare([]int64{1, 2, 3}, []int64{2, 4, 6}))
+	require.Equal(t, 0.0, chiSquare([]int64{0, 0}, []int64{1, 1}))
+	require.Equal(t, 2.0, chiSquare([]int64{1, 1}, []int64{2, 0}))
+}
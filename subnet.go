@@ -0,0 +1,262 @@
+package gonymizer
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// subnetMapKey is the key in the AlphaNumericMap used to store the consistent mapping of source subnets to
+// documentation subnets.
+const subnetMapKey = "subnet"
+
+// subnetIPv4MapKey is the key in the AlphaNumericMap used to store the documentation /24 of every source /24, in the
+// order they are first seen. It is a sequence namespace (see sequenceMapKey) so the mappings are never evicted from a
+// bounded mapping store.
+const subnetIPv4MapKey = sequenceMapKey + ":" + subnetMapKey
+
+// Default sizes of the source subnets that are mapped as a whole when an address is not in one of the Subnets of the
+// FakeSubnetIP processor definition.
+const (
+	defaultIPv4SubnetBits = 24
+	defaultIPv6SubnetBits = 64
+)
+
+// documentationSubnets are the address ranges reserved for documentation (RFC 5737 and RFC 3849). They are never
+// routed on the internet.
+var documentationSubnets = []*net.IPNet{
+	mustParseCIDR("192.0.2.0/24"),
+	mustParseCIDR("198.51.100.0/24"),
+	mustParseCIDR("203.0.113.0/24"),
+	mustParseCIDR("2001:db8::/32"),
+}
+
+// mustParseCIDR parses a CIDR and panics if it is invalid.
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return subnet
+}
+
+// fakeSubnetIP maps an IPv4 or IPv6 address (optionally with a /prefix as stored in inet and cidr columns) into a
+// documentation range. Addresses in one of the source CIDRs of subnets are mapped into its target CIDR. Other addresses
+// have their /24 (IPv4) or /64 (IPv6) subnet consistently mapped to a documentation subnet. In both cases the host part
+// of the address is kept so the network topology is preserved. A /prefix shorter than the prefix of the target is set
+// to the prefix of the target, as a network larger than the target cannot be written into it.
+func fakeSubnetIP(subnets map[string]string, input string) (string, error) {
+	address, suffix := input, ""
+	if slash := strings.Index(input, "/"); slash >= 0 {
+		address, suffix = input[:slash], input[slash:]
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return "", fmt.Errorf("Unable to parse IP address: %s", input)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	source, target, err := findSubnetMapping(subnets, ip)
+	if err != nil {
+		return "", err
+	}
+	if source == nil {
+		bits := defaultIPv6SubnetBits
+		if len(ip) == net.IPv4len {
+			bits = defaultIPv4SubnetBits
+		}
+		source = &net.IPNet{IP: ip.Mask(net.CIDRMask(bits, len(ip)*8)), Mask: net.CIDRMask(bits, len(ip)*8)}
+		if target, err = defaultSubnetTarget(source); err != nil {
+			return "", err
+		}
+	}
+
+	output := make(net.IP, len(target.IP))
+	for i := range output {
+		output[i] = target.IP[i] | (ip[i] &^ source.Mask[i] &^ target.Mask[i])
+	}
+	if len(suffix) > 0 {
+		var prefix int
+		if _, err = fmt.Sscanf(suffix, "/%d", &prefix); err != nil {
+			return "", fmt.Errorf("Unable to parse IP address: %s", input)
+		}
+		if targetBits, _ := target.Mask.Size(); prefix < targetBits {
+			suffix = fmt.Sprintf("/%d", targetBits)
+		}
+	}
+	return output.String() + suffix, nil
+}
+
+// findSubnetMapping returns the most specific source CIDR of subnets that contains ip and its target CIDR. Nil is
+// returned when no source CIDR contains ip.
+func findSubnetMapping(subnets map[string]string, ip net.IP) (source, target *net.IPNet, err error) {
+	bestBits := -1
+	for sourceCIDR, targetCIDR := range subnets {
+		_, s, err := net.ParseCIDR(sourceCIDR)
+		if err != nil {
+			return nil, nil, err
+		}
+		if bits, _ := s.Mask.Size(); len(s.IP) == len(ip) && s.Contains(ip) && bits > bestBits {
+			if _, target, err = net.ParseCIDR(targetCIDR); err != nil {
+				return nil, nil, err
+			}
+			source, bestBits = s, bits
+		}
+	}
+	return source, target, nil
+}
+
+// defaultSubnetTarget returns the documentation subnet consistently mapped to source. IPv4 subnets are mapped to the
+// three /24 documentation ranges in the order they are first seen, and an error is returned for a fourth source /24
+// since two source subnets sharing a range would merge their hosts. IPv6 subnets are mapped to a random /64 in
+// 2001:db8::/32.
+func defaultSubnetTarget(source *net.IPNet) (*net.IPNet, error) {
+	if len(source.IP) == net.IPv4len {
+		output, ok, err := mappingStore.Get(subnetIPv4MapKey, source.String())
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return mustParseCIDR(output), nil
+		}
+		next, err := mappingStore.Next(subnetIPv4MapKey)
+		if err != nil {
+			return nil, err
+		}
+		if next > 3 {
+			return nil, fmt.Errorf("Unable to map more than three IPv4 /24 subnets to the documentation ranges, list "+
+				"the source subnet of %s in Subnets", source)
+		}
+		output, err = mappingStore.Add(subnetIPv4MapKey, source.String(), documentationSubnets[next-1].String())
+		if err != nil {
+			return nil, err
+		}
+		return mustParseCIDR(output), nil
+	}
+
+	output := mappedValue(subnetMapKey, source.String(), func() string {
+		ip := make(net.IP, net.IPv6len)
+		copy(ip, documentationSubnets[3].IP)
+		rng.Read(ip[4:8])
+		return fmt.Sprintf("%s/%d", ip, defaultIPv6SubnetBits)
+	})
+	return mustParseCIDR(output), nil
+}
+
+// randomizeIPHost keeps the first bits of the IPv4 or IPv6 address (optionally with a /prefix as stored in inet and
+// cidr columns) and replaces the remaining host bits with random bits. When the input is a network address (all bits
+// after its /prefix are zero, as in cidr columns) only the bits between bits and the /prefix are replaced so the output
+// is a valid network address as well. Host parts that are all zeros or all ones (IPv4 network and broadcast
+// addresses) are avoided when possible.
+func randomizeIPHost(input string, ipv4Bits, ipv6Bits int) (string, error) {
+	address, suffix := input, ""
+	if slash := strings.Index(input, "/"); slash >= 0 {
+		address, suffix = input[:slash], input[slash:]
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return "", fmt.Errorf("Unable to parse IP address: %s", input)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	size := len(ip) * 8
+	bits := ipv6Bits
+	if len(ip) == net.IPv4len {
+		bits = ipv4Bits
+	}
+	if bits < 0 || bits > size {
+		return "", fmt.Errorf("Invalid prefix length %d for IP address: %s", bits, input)
+	}
+
+	// host is the mask of the bits that are replaced
+	host := make(net.IP, len(ip))
+	keep := net.CIDRMask(bits, size)
+	for i := range host {
+		host[i] = ^keep[i]
+	}
+	if len(suffix) > 0 {
+		var networkBits int
+		if _, err := fmt.Sscanf(suffix, "/%d", &networkBits); err != nil || networkBits < 0 || networkBits > size {
+			return "", fmt.Errorf("Unable to parse IP address: %s", input)
+		}
+		network := net.CIDRMask(networkBits, size)
+		if ip.Mask(network).Equal(ip) {
+			for i := range host {
+				host[i] &= network[i]
+			}
+		}
+	}
+
+	output := make(net.IP, len(ip))
+	random := make([]byte, len(ip))
+	for attempt := 0; attempt < 10; attempt++ {
+		rng.Read(random)
+		allZeros, allOnes := true, true
+		for i := range output {
+			output[i] = ip[i]&^host[i] | random[i]&host[i]
+			allZeros = allZeros && output[i]&host[i] == 0
+			allOnes = allOnes && output[i]&host[i] == host[i]
+		}
+		if len(suffix) > 0 || len(ip) != net.IPv4len || (!allZeros && !allOnes) {
+			break
+		}
+	}
+	return output.String() + suffix, nil
+}
+
+// validateIPPrefixLengths checks the prefix lengths of a FakeIPAddress processor definition.
+func (procDef ProcessorDefinition) validateIPPrefixLengths() error {
+	if procDef.Name != "FakeIPAddress" {
+		return nil
+	}
+	if procDef.PrefixLength < 0 || procDef.PrefixLength > 32 {
+		return fmt.Errorf("Expected PrefixLength between 0 and 32 for processor %s, got %d", procDef.Name,
+			procDef.PrefixLength)
+	}
+	if procDef.IPv6PrefixLength < 0 || procDef.IPv6PrefixLength > 128 {
+		return fmt.Errorf("Expected IPv6PrefixLength between 0 and 128 for processor %s, got %d", procDef.Name,
+			procDef.IPv6PrefixLength)
+	}
+	return nil
+}
+
+// validateSubnets checks that the Subnets of a FakeSubnetIP processor definition are valid CIDRs of the same address
+// family and that every target is inside a documentation range. A target smaller than its source would map different
+// hosts of the source to the same address.
+func (procDef ProcessorDefinition) validateSubnets() error {
+	for sourceCIDR, targetCIDR := range procDef.Subnets {
+		_, source, err := net.ParseCIDR(sourceCIDR)
+		if err != nil {
+			return fmt.Errorf("Invalid source subnet for processor %s: %s", procDef.Name, sourceCIDR)
+		}
+		_, target, err := net.ParseCIDR(targetCIDR)
+		if err != nil {
+			return fmt.Errorf("Invalid target subnet for processor %s: %s", procDef.Name, targetCIDR)
+		}
+		if len(source.IP) != len(target.IP) {
+			return fmt.Errorf("Subnets %s and %s of processor %s are not the same address family", sourceCIDR,
+				targetCIDR, procDef.Name)
+		}
+		sourceBits, _ := source.Mask.Size()
+		targetBits, _ := target.Mask.Size()
+		if targetBits > sourceBits {
+			return fmt.Errorf("Target subnet %s of processor %s is smaller than its source subnet %s", targetCIDR,
+				procDef.Name, sourceCIDR)
+		}
+
+		documentation := false
+		for _, subnet := range documentationSubnets {
+			bits, _ := subnet.Mask.Size()
+			documentation = documentation || (subnet.Contains(target.IP) && targetBits >= bits)
+		}
+		if !documentation {
+			return fmt.Errorf("Target subnet %s of processor %s is not inside a documentation range (192.0.2.0/24, "+
+				"198.51.100.0/24, 203.0.113.0/24, 2001:db8::/32)", targetCIDR, procDef.Name)
+		}
+	}
+	return nil
+}
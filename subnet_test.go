@@ -0,0 +1,120 @@
+package gonymizer
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorSubnetIP(t *testing.T) {
+	cmap := &ColumnMapper{
+		Processors: []ProcessorDefinition{
+			{
+				Name: "FakeSubnetIP",
+				Subnets: map[string]string{
+					"10.9.8.0/24": "192.0.2.0/24",
+					"10.1.2.0/24": "198.51.100.0/24",
+					"fd00:1::/48": "2001:db8:1::/48",
+				},
+			},
+		},
+	}
+
+	delete(AlphaNumericMap, subnetIPv4MapKey)
+	for input, expected := range map[string]string{
+		"10.9.8.7":         "192.0.2.7",
+		"10.1.2.3":         "198.51.100.3",
+		"10.1.2.3/24":      "198.51.100.3/24",
+		"10.1.2.0/16":      "198.51.100.0/24",
+		"10.1.2.128/25":    "198.51.100.128/25",
+		"fd00:1::/32":      "2001:db8:1::/48",
+		"fd00:1:0:5::abcd": "2001:db8:1:5::abcd",
+	} {
+		output, err := ProcessorSubnetIP(cmap, input)
+		require.Nil(t, err)
+		require.Equal(t, expected, output, input)
+	}
+
+	// Addresses outside of the configured subnets keep their /24 or /64 together
+	first, err := ProcessorSubnetIP(cmap, "172.16.5.1")
+	require.Nil(t, err)
+	second, err := ProcessorSubnetIP(cmap, "172.16.5.200")
+	require.Nil(t, err)
+	require.Equal(t, first[:len(first)-1]+"200", second)
+	require.True(t, documentationSubnets[0].Contains(net.ParseIP(first)) ||
+		documentationSubnets[1].Contains(net.ParseIP(first)) || documentationSubnets[2].Contains(net.ParseIP(first)))
+
+	// Only three source /24s fit in the IPv4 documentation ranges without merging their hosts
+	_, err = ProcessorSubnetIP(cmap, "172.16.6.1")
+	require.Nil(t, err)
+	_, err = ProcessorSubnetIP(cmap, "172.16.7.1")
+	require.Nil(t, err)
+	_, err = ProcessorSubnetIP(cmap, "172.16.8.1")
+	require.NotNil(t, err)
+	_, err = ProcessorSubnetIP(cmap, "172.16.5.2")
+	require.Nil(t, err)
+
+	output, err := ProcessorSubnetIP(cmap, "2600:1f18:aaaa:bbbb::10")
+	require.Nil(t, err)
+	require.True(t, documentationSubnets[3].Contains(net.ParseIP(output)))
+	require.Equal(t, "::10", output[len(output)-4:])
+
+	_, err = ProcessorSubnetIP(cmap, "not an ip")
+	require.NotNil(t, err)
+}
+
+func TestValidateSubnets(t *testing.T) {
+	procDef := ProcessorDefinition{Name: "FakeSubnetIP", Subnets: map[string]string{"10.0.0.0/25": "203.0.113.128/25"}}
+	require.Nil(t, procDef.validateSubnets())
+
+	// Hosts of a source larger than its target would share addresses
+	procDef.Subnets = map[string]string{"10.0.0.0/16": "192.0.2.0/24"}
+	require.NotNil(t, procDef.validateSubnets())
+
+	procDef.Subnets = map[string]string{"10.0.0.0/8": "192.0.0.0/16"}
+	require.NotNil(t, procDef.validateSubnets())
+
+	procDef.Subnets = map[string]string{"10.0.0.0/8": "2001:db8::/64"}
+	require.NotNil(t, procDef.validateSubnets())
+
+	procDef.Subnets = map[string]string{"10.0.0.0": "192.0.2.0/24"}
+	require.NotNil(t, procDef.validateSubnets())
+}
+
+func TestProcessorIPAddress(t *testing.T) {
+	var cmap ColumnMapper
+
+	for i := 0; i < 100; i++ {
+		output, err := ProcessorIPAddress(&cmap, "203.0.113.77")
+		require.Nil(t, err)
+		require.Regexp(t, `^203\.0\.113\.[0-9]+$`, output)
+		require.NotEqual(t, "203.0.113.0", output)
+		require.NotEqual(t, "203.0.113.255", output)
+	}
+
+	output, err := ProcessorIPAddress(&cmap, "2600:1f18:aaaa:bbbb:1:2:3:4")
+	require.Nil(t, err)
+	require.True(t, mustParseCIDR("2600:1f18:aaaa:bbbb::/64").Contains(net.ParseIP(output)))
+
+	// inet values keep their suffix, cidr network addresses stay network addresses
+	output, err = ProcessorIPAddress(&cmap, "10.1.2.3/8")
+	require.Nil(t, err)
+	require.Regexp(t, `^10\.1\.2\.[0-9]+/8$`, output)
+
+	cmap.Processors = []ProcessorDefinition{{Name: "FakeIPAddress", PrefixLength: 16, IPv6PrefixLength: 48}}
+	output, err = ProcessorIPAddress(&cmap, "10.1.0.0/24")
+	require.Nil(t, err)
+	require.Regexp(t, `^10\.1\.[0-9]+\.0/24$`, output)
+
+	output, err = ProcessorIPAddress(&cmap, "fd00:1:2:3::1")
+	require.Nil(t, err)
+	require.True(t, mustParseCIDR("fd00:1:2::/48").Contains(net.ParseIP(output)))
+
+	_, err = ProcessorIPAddress(&cmap, "not an ip")
+	require.NotNil(t, err)
+
+	require.Nil(t, cmap.Processors[0].validateIPPrefixLengths())
+	require.NotNil(t, ProcessorDefinition{Name: "FakeIPAddress", PrefixLength: 33}.validateIPPrefixLengths())
+	require.NotNil(t, ProcessorDefinition{Name: "FakeIPAddress", IPv6PrefixLength: 129}.validateIPPrefixLengths())
+}
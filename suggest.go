@@ -0,0 +1,215 @@
+package gonymizer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// maxSuggestionRows is the number of rows of every table that are sampled by SuggestProcessors.
+	maxSuggestionRows = 1000
+
+	// minSuggestionShare is the fraction of the sampled values that must match a PII pattern or value class for its
+	// processor to be suggested.
+	minSuggestionShare = 0.5
+
+	// columnNameConfidence is the confidence of a suggestion based on the column name alone.
+	columnNameConfidence = 0.5
+)
+
+// patternProcessors are the processors suggested for the values matching a PII pattern or the phone value class. Phone
+// numbers outside of the US and identifiers without a dedicated faker (including user registered PII patterns) are
+// scrambled so their format is kept.
+var patternProcessors = map[string]string{
+	"email":         "FakeEmailAddress",
+	"card_pan":      "AlphaNumericScrambler",
+	"iban":          "FakeIBAN",
+	"ssn_us":        "FakeSSN",
+	"ein_us":        "FakeEIN",
+	"nino_uk":       "AlphaNumericScrambler",
+	"phone_us":      "FakePhoneNumber",
+	"phone_uk":      "AlphaNumericScrambler",
+	"phone_e164":    "AlphaNumericScrambler",
+	"ipv4":          "FakeIPAddress",
+	"ipv6":          "FakeIPAddress",
+	ValueClassPhone: "FakePhoneNumber",
+}
+
+// columnNameProcessors are the processors suggested for columns by their name, in the order they are tried.
+var columnNameProcessors = []struct {
+	regex     *regexp.Regexp
+	processor string
+}{
+	{regexp.MustCompile(`(?i)^(first_?name|given_?name|fname)$`), "FakeFirstName"},
+	{regexp.MustCompile(`(?i)^(last_?name|surname|family_?name|lname)$`), "FakeLastName"},
+	{regexp.MustCompile(`(?i)^(full_?name|name|display_?name|contact_?name)$`), "FakeFullName"},
+	{regexp.MustCompile(`(?i)e_?mail`), "FakeEmailAddress"},
+	{regexp.MustCompile(`(?i)(phone|mobile|fax)`), "FakePhoneNumber"},
+	{regexp.MustCompile(`(?i)^(street|address|address_?line_?\d|street_?address)$`), "FakeStreetAddress"},
+	{regexp.MustCompile(`(?i)^city$`), "FakeCity"},
+	{regexp.MustCompile(`(?i)^(state|province)$`), "FakeState"},
+	{regexp.MustCompile(`(?i)(zip|postal_?code|post_?code)`), "FakeZip"},
+	{regexp.MustCompile(`(?i)(company|employer|organi[sz]ation)_?name`), "FakeCompanyName"},
+	{regexp.MustCompile(`(?i)^(industry|sector)$`), "FakeIndustry"},
+	{regexp.MustCompile(`(?i)(job_?title|occupation)`), "FakeJobTitle"},
+	{regexp.MustCompile(`(?i)^(user_?name|login|handle)$`), "FakeUsername"},
+	{regexp.MustCompile(`(?i)(ssn|social_?security)`), "FakeSSN"},
+	{regexp.MustCompile(`(?i)(birth|dob)`), "RandomDate"},
+	{regexp.MustCompile(`(?i)(ip_?addr|remote_?addr|client_?ip)`), "FakeIPAddress"},
+	{regexp.MustCompile(`(?i)user_?agent`), "FakeUserAgent"},
+	{regexp.MustCompile(`(?i)host_?name`), "FakeHostname"},
+}
+
+// ProcessorSuggestion is a suggested map file entry for a column that is not anonymized. Confidence (0 to 1) is the
+// fraction of the sampled values that match the pattern the processor was chosen for, or columnNameConfidence when the
+// processor was chosen by the column name alone.
+type ProcessorSuggestion struct {
+	Entry      ColumnMapper
+	Confidence float64
+	Reason     string
+	Sampled    int64
+}
+
+// SuggestionReport contains the suggested map file entries, sorted by table and column.
+type SuggestionReport struct {
+	Suggestions []ProcessorSuggestion
+}
+
+// columnSample counts the PII patterns (and the phone value class) matched by the sampled values of a column.
+type columnSample struct {
+	schema, table, column string
+	ordinal               int
+	sampled               int64
+	matches               map[string]int64
+}
+
+// SuggestProcessors samples the first maxSuggestionRows rows of every table of the dump file and suggests a processor
+// for every column that is not anonymized by the map file (mapper may be nil to consider every column). The values are
+// matched against the PII patterns (see MatchPIIPatterns) and the phone value class (see classifyValue), so a column
+// holding US phone numbers gets FakePhoneNumber while E.164 numbers are scrambled in their format. Columns whose values
+// do not match are suggested a processor by their name (first_name, city, ...).
+func SuggestProcessors(mapper *DBMapper, dumpFile string) (*SuggestionReport, error) {
+	samples := map[string]*columnSample{}
+	var order []string
+	rows := map[string]int{}
+	err := forEachDumpRow(dumpFile, func(state *LineState, values []string) error {
+		table := state.SchemaName + "." + state.TableName
+		if rows[table]++; rows[table] > maxSuggestionRows {
+			return nil
+		}
+		for i, columnName := range state.ColumnNames {
+			if mapper != nil {
+				if cmap := mapper.ColumnMapper(state.SchemaName, state.TableName, columnName); cmap != nil &&
+					anonymizes(*cmap) {
+					continue
+				}
+			}
+
+			key := table + "." + columnName
+			sample := samples[key]
+			if sample == nil {
+				sample = &columnSample{schema: state.SchemaName, table: state.TableName, column: columnName,
+					ordinal: i + 1, matches: map[string]int64{}}
+				samples[key] = sample
+				order = append(order, key)
+			}
+			if values[i] == "\\N" || len(values[i]) == 0 {
+				continue
+			}
+
+			value := unescapeCopyValue(values[i])
+			sample.sampled++
+			for _, pattern := range MatchPIIPatterns(value) {
+				sample.matches[pattern]++
+			}
+			if classifyValue(value) == ValueClassPhone {
+				sample.matches[ValueClassPhone]++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error("Unable to sample dump file: ", err)
+		return nil, err
+	}
+
+	report := new(SuggestionReport)
+	for _, key := range order {
+		if suggestion, ok := samples[key].suggest(); ok {
+			report.Suggestions = append(report.Suggestions, suggestion)
+		}
+	}
+	sort.SliceStable(report.Suggestions, func(i, j int) bool {
+		a, b := report.Suggestions[i].Entry, report.Suggestions[j].Entry
+		if a.TableSchema+"."+a.TableName != b.TableSchema+"."+b.TableName {
+			return a.TableSchema+"."+a.TableName < b.TableSchema+"."+b.TableName
+		}
+		return a.OrdinalPosition < b.OrdinalPosition
+	})
+	return report, nil
+}
+
+// suggest returns the processor suggestion for the sampled column. The PII pattern or value class matched by the most
+// values wins (PII patterns before value classes on a tie). Returns false if there is nothing to suggest.
+func (sample *columnSample) suggest() (ProcessorSuggestion, bool) {
+	suggestion := ProcessorSuggestion{
+		Entry: ColumnMapper{
+			TableSchema:     sample.schema,
+			TableName:       sample.table,
+			ColumnName:      sample.column,
+			OrdinalPosition: sample.ordinal,
+		},
+		Sampled: sample.sampled,
+	}
+
+	// Dates and UUIDs are too common in columns without personal data (created_at, primary keys) to be suggested by
+	// their values
+	var best string
+	for _, pattern := range append(piiPatternNames(), ValueClassPhone) {
+		if sample.matches[pattern] > sample.matches[best] {
+			best = pattern
+		}
+	}
+	processor := patternProcessors[best]
+	if len(processor) == 0 {
+		processor = "AlphaNumericScrambler"
+	}
+	byName := ""
+	for _, hint := range columnNameProcessors {
+		if hint.regex.MatchString(sample.column) {
+			byName = hint.processor
+			break
+		}
+	}
+
+	share := 0.0
+	if sample.sampled > 0 {
+		share = float64(sample.matches[best]) / float64(sample.sampled)
+	}
+	switch {
+	case len(best) > 0 && share >= minSuggestionShare:
+		suggestion.Entry.Processors = []ProcessorDefinition{{Name: processor}}
+		suggestion.Confidence = share
+		suggestion.Reason = fmt.Sprintf("%.0f%% of %d sampled values match %s", 100*share, sample.sampled, best)
+		if byName == processor {
+			suggestion.Reason += " and the column name agrees"
+		}
+	case len(byName) > 0:
+		suggestion.Entry.Processors = []ProcessorDefinition{{Name: byName}}
+		suggestion.Confidence = columnNameConfidence
+		suggestion.Reason = fmt.Sprintf("Column name %s", sample.column)
+	default:
+		return suggestion, false
+	}
+	suggestion.Entry.Comment = fmt.Sprintf("Suggested (confidence %.2f): %s", suggestion.Confidence,
+		suggestion.Reason)
+	return suggestion, true
+}
+
+// WriteSuggestionReport will save the suggestion report to filepath as JSON.
+func WriteSuggestionReport(report *SuggestionReport, filepath string) error {
+	return writeJSONFile(filepath, report)
+}
@@ -0,0 +1,46 @@
+package gonymizer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestProcessors(t *testing.T) {
+	dump := writeTestAuditFile(t,
+		"COPY public.contacts (id, first_name, mobile, phone, ssn, notes, created_at) FROM stdin;\n"+
+			"1\tJane\t+4915112345678\t(555) 867-5309\t123-45-6789\thello\t2020-01-01\n"+
+			"2\tJohn\t+4915112345679\t555-867-5310\t\\N\tjane@example.com\t2020-01-02\n"+
+			"3\tMary\t+4915112345670\t(555) 867-5311\t234-56-7890\tcall me\t2020-01-03\n\\.\n")
+	defer os.Remove(dump)
+
+	report, err := SuggestProcessors(nil, dump)
+	require.Nil(t, err)
+
+	suggestions := map[string]ProcessorSuggestion{}
+	for _, suggestion := range report.Suggestions {
+		suggestions[suggestion.Entry.ColumnName] = suggestion
+	}
+	require.Len(t, suggestions, 4)
+	require.Equal(t, "FakeFirstName", suggestions["first_name"].Entry.Processors[0].Name)
+	require.Equal(t, columnNameConfidence, suggestions["first_name"].Confidence)
+	require.Equal(t, "AlphaNumericScrambler", suggestions["mobile"].Entry.Processors[0].Name)
+	require.Equal(t, "100% of 3 sampled values match phone_e164", suggestions["mobile"].Reason)
+	require.Equal(t, "FakePhoneNumber", suggestions["phone"].Entry.Processors[0].Name)
+	require.Equal(t, "100% of 3 sampled values match phone_us and the column name agrees",
+		suggestions["phone"].Reason)
+	require.Equal(t, "FakeSSN", suggestions["ssn"].Entry.Processors[0].Name)
+	require.Equal(t, int64(2), suggestions["ssn"].Sampled)
+	require.Equal(t, 1.0, suggestions["ssn"].Confidence)
+	require.Equal(t, 5, suggestions["ssn"].Entry.OrdinalPosition)
+	require.Contains(t, suggestions["ssn"].Entry.Comment, "Suggested (confidence 1.00)")
+
+	// Columns anonymized by the map file are skipped
+	mapper := &DBMapper{ColumnMaps: []ColumnMapper{{TableSchema: "public", TableName: "contacts", ColumnName: "phone",
+		Processors: []ProcessorDefinition{{Name: "FakePhoneNumber"}}}}}
+	report, err = SuggestProcessors(mapper, dump)
+	require.Nil(t, err)
+	require.Len(t, report.Suggestions, 3)
+	require.Equal(t, "mobile", report.Suggestions[1].Entry.ColumnName)
+}
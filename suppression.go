@@ -0,0 +1,95 @@
+package gonymizer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxSuppressionAttempts is the number of fake values generated before giving up on finding one that is not on the
+// suppression list.
+const maxSuppressionAttempts = 100
+
+// suppressionList contains the normalized e-mail addresses and phone numbers of the loaded suppression (do not
+// contact) list. Fake e-mail addresses and phone numbers never use a value on the list.
+var suppressionList = map[string]bool{}
+
+// LoadSuppressionList will load a suppression (do not contact) list of e-mail addresses and phone numbers. The file
+// contains one entry per line, only the first column of CSV files is used. Empty lines and lines starting with # are
+// skipped. The entries are added to the entries that are already loaded.
+func LoadSuppressionList(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Error("Failure to open file: ", err)
+		log.Error("path: ", path)
+		return err
+	}
+	defer f.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry := normalizeSuppressionEntry(strings.Trim(strings.SplitN(line, ",", 2)[0], "\" "))
+		if len(entry) > 0 {
+			suppressionList[entry] = true
+			count++
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+
+	log.Infof("Loaded %d suppression list entries from: %s", count, path)
+	return nil
+}
+
+// normalizeSuppressionEntry returns the value in the form stored in the suppression list. E-mail addresses are lower
+// cased and phone numbers are reduced to their digits (without the North American country code). Values that are
+// neither return an empty string.
+func normalizeSuppressionEntry(value string) string {
+	if strings.Contains(value, "@") {
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, strings.SplitN(strings.ToLower(value), "x", 2)[0]) // drop extensions
+	if len(digits) == 11 && digits[0] == '1' {
+		digits = digits[1:]
+	}
+	if len(digits) < 7 {
+		return ""
+	}
+	return digits
+}
+
+// suppressed returns true if the value is on the suppression list.
+func suppressed(value string) bool {
+	if len(suppressionList) == 0 {
+		return false
+	}
+	entry := normalizeSuppressionEntry(value)
+	return len(entry) > 0 && suppressionList[entry]
+}
+
+// notSuppressed calls generate until it returns a value that is not on the suppression list.
+func notSuppressed(generate func() string) (string, error) {
+	for i := 0; i < maxSuppressionAttempts; i++ {
+		if value := generate(); !suppressed(value) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("Unable to generate a value that is not on the suppression list after %d attempts",
+		maxSuppressionAttempts)
+}
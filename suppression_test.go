@@ -0,0 +1,38 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSuppressionList(t *testing.T) {
+	defer func() { suppressionList = map[string]bool{} }()
+
+	require.Nil(t, LoadSuppressionList(TestSuppressionListFile))
+	require.Len(t, suppressionList, 3)
+	require.True(t, suppressed("jane.doe@example.com"))
+	require.True(t, suppressed("555.010.0199"))
+	require.True(t, suppressed("1-555-010-0100"))
+	require.False(t, suppressed("john@example.com"))
+	require.False(t, suppressed("555-010-0101"))
+
+	require.NotNil(t, LoadSuppressionList("testing/missing.csv"))
+}
+
+func TestNotSuppressed(t *testing.T) {
+	suppressionList = map[string]bool{"taken@example.com": true}
+	defer func() { suppressionList = map[string]bool{} }()
+
+	values := []string{"taken@example.com", "TAKEN@example.com", "free@example.com"}
+	value, err := notSuppressed(func() string {
+		value := values[0]
+		values = values[1:]
+		return value
+	})
+	require.Nil(t, err)
+	require.Equal(t, "free@example.com", value)
+
+	_, err = notSuppressed(func() string { return "taken@example.com" })
+	require.NotNil(t, err)
+}
@@ -0,0 +1,80 @@
+package gonymizer
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// timedOutCalls is the number of processor calls that timed out and are still running in the background (see
+// callProcessor).
+var timedOutCalls int32
+
+// ProcessorTimeoutError is returned when a processor takes longer than the Timeout of its processor definition.
+type ProcessorTimeoutError struct {
+	Processor string
+	Timeout   time.Duration
+}
+
+// Error returns the error message.
+func (e *ProcessorTimeoutError) Error() string {
+	return fmt.Sprintf("Processor %s did not finish within %s", e.Processor, e.Timeout)
+}
+
+// validateTimeout checks that the Timeout of the processor definition is valid.
+func (procDef ProcessorDefinition) validateTimeout() error {
+	if len(procDef.Timeout) > 0 {
+		if timeout, err := time.ParseDuration(procDef.Timeout); err != nil || timeout <= 0 {
+			return fmt.Errorf("Invalid Timeout for processor %s: %s", procDef.Name, procDef.Timeout)
+		}
+	}
+	return nil
+}
+
+// callProcessor will call pfunc for the input. If the processor definition has a Timeout and the call takes longer a
+// ProcessorTimeoutError is returned, which stops the run. Go has no way to stop the call, so it is left running in the
+// background. It still uses the state shared by the processors (the mapping store, the current row, the random number
+// generators, ...), so no run may start until it has finished (see checkTimedOutCalls).
+func callProcessor(cmap *ColumnMapper, procDef ProcessorDefinition, pfunc ProcessorFunc, input string) (string, error) {
+	if len(procDef.Timeout) == 0 {
+		return pfunc(cmap, input)
+	}
+	if err := procDef.validateTimeout(); err != nil {
+		return "", err
+	}
+	timeout, _ := time.ParseDuration(procDef.Timeout)
+
+	type result struct {
+		output string
+		err    error
+	}
+	done := make(chan result, 1)
+	finished := make(chan bool, 1)
+	go func() {
+		output, err := pfunc(cmap, input)
+		done <- result{output, err}
+		if <-finished {
+			atomic.AddInt32(&timedOutCalls, -1)
+		}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		close(finished)
+		return r.output, r.err
+	case <-timer.C:
+		atomic.AddInt32(&timedOutCalls, 1)
+		finished <- true
+		return "", &ProcessorTimeoutError{Processor: procDef.Name, Timeout: timeout}
+	}
+}
+
+// checkTimedOutCalls returns an error while a processor call that timed out is still running (see callProcessor).
+func checkTimedOutCalls() error {
+	if count := atomic.LoadInt32(&timedOutCalls); count > 0 {
+		return fmt.Errorf("%d processor call(s) that timed out are still running", count)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package gonymizer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorTimeout(t *testing.T) {
+	ProcessorCatalog["TestSlow"] = func(cmap *ColumnMapper, input string) (string, error) {
+		if input == "slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		return "fast", nil
+	}
+	defer delete(ProcessorCatalog, "TestSlow")
+
+	cmap := &ColumnMapper{Processors: []ProcessorDefinition{{Name: "TestSlow", Timeout: "20ms"}}}
+	output, err := processValue(cmap, "quick")
+	require.Nil(t, err)
+	require.Equal(t, "fast", output)
+	require.Nil(t, checkTimedOutCalls())
+
+	_, err = processValue(cmap, "slow")
+	require.IsType(t, &ProcessorTimeoutError{}, err)
+
+	// No run may start while the call that timed out is still running
+	require.NotNil(t, checkTimedOutCalls())
+	require.NotNil(t, ProcessDumpFileWithOptions(&DBMapper{}, TestDbFile, TestSeedDumpFile, "", "", false,
+		ProcessOptions{}))
+	for atomic.LoadInt32(&timedOutCalls) > 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Nil(t, checkTimedOutCalls())
+}
+
+func TestValidateTimeout(t *testing.T) {
+	mapper := &DBMapper{
+		DBName:     "pii_localtest",
+		ColumnMaps: []ColumnMapper{{Processors: []ProcessorDefinition{{Name: "ScrubString", Timeout: "1s"}}}},
+	}
+	require.Nil(t, mapper.Validate())
+
+	mapper.ColumnMaps[0].Processors[0].Timeout = "soon"
+	require.NotNil(t, mapper.Validate())
+}
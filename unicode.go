@@ -0,0 +1,119 @@
+package gonymizer
+
+import (
+	"fmt"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Modes of scrambling non-ASCII letters and digits (see ProcessorDefinition.UnicodeMode).
+const (
+	// unicodeScript replaces them with random letters and digits of the same script, case, and Unicode block
+	unicodeScript = "script"
+
+	// unicodeASCII replaces them with random ASCII letters (lower case for scripts without case) and digits
+	unicodeASCII = "ascii"
+
+	// unicodeKeep keeps them as they are
+	unicodeKeep = "keep"
+)
+
+// runeClass is the class of a non-ASCII letter or digit. Runes of the same class replace each other when scrambling.
+type runeClass struct {
+	script   string
+	category byte
+	block    rune
+}
+
+var (
+	// runeClasses caches the class of every rune that has been scrambled and runeClassCandidates the runes of every
+	// class. Both are guarded by runeClassMutex as processors may run in a goroutine of their own (see Timeout).
+	runeClasses         = map[rune]runeClass{}
+	runeClassCandidates = map[runeClass][]rune{}
+	runeClassMutex      sync.Mutex
+)
+
+// runeCategory returns u for upper case letters, l for lower case letters, o for other letters (of scripts without
+// case), d for digits, and 0 for every other rune.
+func runeCategory(r rune) byte {
+	switch {
+	case unicode.IsUpper(r):
+		return 'u'
+	case unicode.IsLower(r):
+		return 'l'
+	case unicode.IsLetter(r):
+		return 'o'
+	case unicode.IsDigit(r):
+		return 'd'
+	}
+	return 0
+}
+
+// runeScript returns the name of the script of the rune (see unicode.Scripts).
+func runeScript(r rune) string {
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
+
+// sameScriptRunes returns the runes that may replace the non-ASCII letter or digit: the runes of the same script and
+// category in the same block of 256 code points, so they also have the same UTF-8 length.
+func sameScriptRunes(r rune) []rune {
+	runeClassMutex.Lock()
+	defer runeClassMutex.Unlock()
+
+	class, ok := runeClasses[r]
+	if !ok {
+		class = runeClass{script: runeScript(r), category: runeCategory(r), block: r &^ 0xff}
+		runeClasses[r] = class
+	}
+	if candidates, ok := runeClassCandidates[class]; ok {
+		return candidates
+	}
+
+	var candidates []rune
+	for c := class.block; c < class.block+0x100; c++ {
+		if c < utf8.RuneSelf || runeCategory(c) != class.category || runeScript(c) != class.script {
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+	runeClassCandidates[class] = candidates
+	return candidates
+}
+
+// scrambleRune returns a random replacement of the non-ASCII letter or digit in the mode (default unicodeScript).
+// Other runes are returned as they are.
+func scrambleRune(r rune, mode string) rune {
+	category := runeCategory(r)
+	if category == 0 || mode == unicodeKeep {
+		return r
+	}
+	if mode == unicodeASCII {
+		switch category {
+		case 'u':
+			return rune(uppercaseSet[rng.Intn(uppercaseSetLen)])
+		case 'd':
+			return rune(numericSet[rng.Intn(numericSetLen)])
+		}
+		return rune(lowercaseSet[rng.Intn(lowercaseSetLen)])
+	}
+
+	// The candidates always contain r itself
+	candidates := sameScriptRunes(r)
+	return candidates[rng.Intn(len(candidates))]
+}
+
+// validateUnicodeMode checks the UnicodeMode of a processor definition.
+func (procDef ProcessorDefinition) validateUnicodeMode() error {
+	switch procDef.UnicodeMode {
+	case "", unicodeScript, unicodeASCII, unicodeKeep:
+		return nil
+	}
+	return fmt.Errorf("Expected UnicodeMode script, ascii, or keep for processor %s, got %q", procDef.Name,
+		procDef.UnicodeMode)
+}
@@ -0,0 +1,71 @@
+package gonymizer
+
+import (
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScrambleUnicode(t *testing.T) {
+	for _, input := range []string{
+		"Иван Петров", "José Müller-Öz", "王小明", "やまだ たろう", "김민준", "٣٤٥",
+	} {
+		output := scrambleString(input)
+		require.Equal(t, len(input), len(output), input)
+		require.True(t, utf8.ValidString(output))
+
+		in, out := []rune(input), []rune(output)
+		require.Equal(t, len(in), len(out))
+		changed := 0
+		for i := range in {
+			require.Equal(t, runeCategory(in[i]), runeCategory(out[i]), input)
+			if runeCategory(in[i]) == 0 {
+				require.Equal(t, in[i], out[i])
+				continue
+			}
+			if in[i] >= utf8.RuneSelf {
+				require.Equal(t, runeScript(in[i]), runeScript(out[i]), input)
+			}
+			if in[i] != out[i] {
+				changed++
+			}
+		}
+		require.True(t, changed > 0, input)
+	}
+
+	// Accented Latin letters stay accented Latin letters
+	output := []rune(scrambleString("é"))
+	require.True(t, output[0] >= 0xc0 && output[0] <= 0xff && unicode.IsLower(output[0]))
+
+	output = []rune(scrambleUnicode("Ölçer 王", unicodeASCII))
+	require.Regexp(t, "^[A-Z][a-z]{4} [a-z]$", string(output))
+	require.Regexp(t, "^Ö[a-z]ç[a-z]{2}$", scrambleUnicode("Ölçer", unicodeKeep))
+
+	// Invalid UTF-8 is kept
+	require.Equal(t, "\xff", scrambleString("\xff"))
+}
+
+func TestProcessorAlphaNumericScramblerUnicode(t *testing.T) {
+	cmap := ColumnMapper{Processors: []ProcessorDefinition{{Name: "AlphaNumericScrambler", PreserveRepeats: true}}}
+	output, err := ProcessorAlphaNumericScrambler(&cmap, "Анна")
+	require.Nil(t, err)
+	runes := []rune(output)
+	require.Len(t, runes, 4)
+	require.Equal(t, runes[1], runes[2])
+	require.NotEqual(t, runes[1], runes[3])
+	require.True(t, unicode.Is(unicode.Cyrillic, runes[0]) && unicode.IsUpper(runes[0]))
+
+	cmap.Processors[0].UnicodeMode = unicodeASCII
+	output, err = ProcessorAlphaNumericScrambler(&cmap, "Анна")
+	require.Nil(t, err)
+	require.Regexp(t, "^[A-Z][a-z]{3}$", output)
+	require.Equal(t, output[1], output[2])
+}
+
+func TestValidateUnicodeMode(t *testing.T) {
+	require.Nil(t, ProcessorDefinition{Name: "AlphaNumericScrambler"}.validateUnicodeMode())
+	require.Nil(t, ProcessorDefinition{Name: "AlphaNumericScrambler", UnicodeMode: "ascii"}.validateUnicodeMode())
+	require.NotNil(t, ProcessorDefinition{Name: "AlphaNumericScrambler", UnicodeMode: "latin"}.validateUnicodeMode())
+}
@@ -0,0 +1,110 @@
+package gonymizer
+
+import (
+	"net"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/icrowley/fake"
+)
+
+// urlMapKey is the key in the AlphaNumericMap used to store the consistent mapping of URL path segments and query
+// values.
+const urlMapKey = "url"
+
+// fakeURL replaces the host name (see fakeHostname), user info, path segments, query values, and fragment of the URL
+// with fake tokens while keeping the scheme, port, path depth, file extensions, and query keys, so the URL still
+// parses. IP address hosts are replaced with random IPv4 addresses. URLs of the safe domains (and their subdomains) are
+// kept as they are. URLs without a scheme (www.example.com/about) are supported and values that do not parse as a URL
+// are scrambled.
+//
+// Example:
+// "https://kqm.xvlq.com/ksqel/48213/xpqlzw.pdf?id=8142" =
+// fakeURL("https://www.acme.com/users/12345/resume.pdf?id=4711", nil)
+func fakeURL(input string, safeDomains []string) string {
+	trimmed := strings.TrimSpace(input)
+	schemeless := !strings.Contains(trimmed, "://")
+	if schemeless {
+		trimmed = "//" + trimmed
+	}
+	u, err := url.Parse(trimmed)
+	if err != nil || len(u.Host) == 0 {
+		return scrambleString(input)
+	}
+	if safeDomain(u.Hostname(), safeDomains) {
+		return input
+	}
+
+	host := fakeHostname(u.Hostname())
+	if net.ParseIP(u.Hostname()) != nil {
+		host = fake.IPv4()
+	}
+	if len(u.Port()) > 0 {
+		host = net.JoinHostPort(host, u.Port())
+	}
+	u.Host = host
+
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(scrambleString(u.User.Username()), scrambleString(password))
+		} else {
+			u.User = url.User(scrambleString(u.User.Username()))
+		}
+	}
+
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		if len(segment) > 0 {
+			segments[i] = urlToken(segment)
+		}
+	}
+	u.Path, u.RawPath = strings.Join(segments, "/"), ""
+
+	if len(u.RawQuery) > 0 {
+		pairs := strings.Split(u.RawQuery, "&")
+		for i, pair := range pairs {
+			if eq := strings.IndexByte(pair, '='); eq >= 0 {
+				value, err := url.QueryUnescape(pair[eq+1:])
+				if err != nil {
+					value = pair[eq+1:]
+				}
+				pairs[i] = pair[:eq+1] + url.QueryEscape(urlToken(value))
+			}
+		}
+		u.RawQuery = strings.Join(pairs, "&")
+	}
+	if len(u.Fragment) > 0 {
+		u.Fragment = urlToken(u.Fragment)
+	}
+
+	output := u.String()
+	if schemeless {
+		output = strings.TrimPrefix(output, "//")
+	}
+	return output
+}
+
+// urlToken returns the consistently mapped fake token of a URL path segment or query value. The file extension of the
+// segment is kept.
+func urlToken(segment string) string {
+	return mappedValue(urlMapKey, segment, func() string {
+		ext := path.Ext(segment)
+		if len(ext) > 6 || len(ext) == len(segment) {
+			ext = ""
+		}
+		return scrambleString(strings.TrimSuffix(segment, ext)) + ext
+	})
+}
+
+// safeDomain returns true if the host is one of the domains or a subdomain of one of them.
+func safeDomain(host string, domains []string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, domain := range domains {
+		domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package gonymizer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// userAgent contains the coarse, non-identifying parts of a user-agent string: the browser family and major version
+// and the operating system family and major version.
+type userAgent struct {
+	Browser      string
+	BrowserMajor string
+	OS           string
+	OSVersion    string
+	Mobile       bool
+}
+
+var (
+	uaEdgeRegex    = regexp.MustCompile(`Edg(?:e|A|iOS)?/(\d+)`)
+	uaOperaRegex   = regexp.MustCompile(`(?:OPR|Opera)/(\d+)`)
+	uaChromeRegex  = regexp.MustCompile(`(?:Chrome|CriOS)/(\d+)`)
+	uaFirefoxRegex = regexp.MustCompile(`(?:Firefox|FxiOS)/(\d+)`)
+	uaSafariRegex  = regexp.MustCompile(`Version/(\d+).*Safari/`)
+	uaMSIERegex    = regexp.MustCompile(`MSIE (\d+)`)
+	uaTridentRegex = regexp.MustCompile(`Trident/.*rv:(\d+)`)
+	uaProductRegex = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_.-]*)(?:/(\d+))?`)
+
+	uaWindowsRegex = regexp.MustCompile(`Windows NT (\d+\.\d+)`)
+	uaIOSRegex     = regexp.MustCompile(`(iPhone|iPad|iPod).*? OS (\d+)`)
+	uaMacRegex     = regexp.MustCompile(`Mac OS X (\d+)[_.](\d+)`)
+	uaAndroidRegex = regexp.MustCompile(`Android (\d+)`)
+)
+
+// parseUserAgent extracts the browser and operating system families (with major versions) from a user-agent string.
+// Everything else in the user-agent (device models, build numbers, minor versions, extension tokens) is dropped.
+func parseUserAgent(input string) userAgent {
+	var ua userAgent
+
+	ua.Mobile = strings.Contains(input, "Mobile")
+
+	// Order matters: Edge and Opera also identify as Chrome, and Chrome identifies as Safari
+	if m := uaEdgeRegex.FindStringSubmatch(input); m != nil {
+		ua.Browser, ua.BrowserMajor = "Edge", m[1]
+	} else if m := uaOperaRegex.FindStringSubmatch(input); m != nil {
+		ua.Browser, ua.BrowserMajor = "Opera", m[1]
+	} else if m := uaChromeRegex.FindStringSubmatch(input); m != nil {
+		ua.Browser, ua.BrowserMajor = "Chrome", m[1]
+	} else if m := uaFirefoxRegex.FindStringSubmatch(input); m != nil {
+		ua.Browser, ua.BrowserMajor = "Firefox", m[1]
+	} else if m := uaSafariRegex.FindStringSubmatch(input); m != nil {
+		ua.Browser, ua.BrowserMajor = "Safari", m[1]
+	} else if m := uaMSIERegex.FindStringSubmatch(input); m != nil {
+		ua.Browser, ua.BrowserMajor = "IE", m[1]
+	} else if m := uaTridentRegex.FindStringSubmatch(input); m != nil {
+		ua.Browser, ua.BrowserMajor = "IE", m[1]
+	}
+
+	if m := uaWindowsRegex.FindStringSubmatch(input); m != nil {
+		ua.OS, ua.OSVersion = "Windows", m[1]
+	} else if m := uaIOSRegex.FindStringSubmatch(input); m != nil {
+		ua.OS, ua.OSVersion = m[1], m[2]
+	} else if m := uaMacRegex.FindStringSubmatch(input); m != nil {
+		ua.OS, ua.OSVersion = "Mac", m[1]+"_"+m[2]
+	} else if m := uaAndroidRegex.FindStringSubmatch(input); m != nil {
+		ua.OS, ua.OSVersion = "Android", m[1]
+	} else if strings.Contains(input, "CrOS") {
+		ua.OS = "ChromeOS"
+	} else if strings.Contains(input, "Linux") || strings.Contains(input, "X11") {
+		ua.OS = "Linux"
+	}
+	return ua
+}
+
+// platform returns the generic platform section (the part between parentheses) of a user-agent string.
+func (ua userAgent) platform() string {
+	switch ua.OS {
+	case "Windows":
+		return fmt.Sprintf("Windows NT %s; Win64; x64", ua.OSVersion)
+	case "Mac":
+		return fmt.Sprintf("Macintosh; Intel Mac OS X %s", ua.OSVersion)
+	case "iPhone", "iPod":
+		return fmt.Sprintf("%s; CPU iPhone OS %s_0 like Mac OS X", ua.OS, ua.OSVersion)
+	case "iPad":
+		return fmt.Sprintf("iPad; CPU OS %s_0 like Mac OS X", ua.OSVersion)
+	case "Android":
+		// "K" is the generic device model used by user-agent reduction in Chromium
+		return fmt.Sprintf("Linux; Android %s; K", ua.OSVersion)
+	case "ChromeOS":
+		return "X11; CrOS x86_64"
+	default:
+		return "X11; Linux x86_64"
+	}
+}
+
+// String rebuilds a generic user-agent string for the browser and operating system. Only major versions are kept and
+// all minor versions are zeroed so the resulting string is shared by a large number of users.
+func (ua userAgent) String() string {
+	platform := ua.platform()
+	mobile := ""
+	if ua.Mobile {
+		mobile = "Mobile "
+	}
+	chromium := fmt.Sprintf("Mozilla/5.0 (%s) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s.0.0.0 %sSafari/537.36",
+		platform, ua.BrowserMajor, mobile)
+
+	switch ua.Browser {
+	case "Chrome":
+		return chromium
+	case "Edge":
+		return fmt.Sprintf("%s Edg/%s.0.0.0", chromium, ua.BrowserMajor)
+	case "Opera":
+		return fmt.Sprintf("%s OPR/%s.0.0.0", chromium, ua.BrowserMajor)
+	case "Firefox":
+		return fmt.Sprintf("Mozilla/5.0 (%s; rv:%s.0) Gecko/20100101 Firefox/%s.0", platform, ua.BrowserMajor,
+			ua.BrowserMajor)
+	case "Safari":
+		mobile = ""
+		if ua.Mobile {
+			mobile = "Mobile/15E148 "
+		}
+		return fmt.Sprintf("Mozilla/5.0 (%s) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/%s.0 %sSafari/604.1",
+			platform, ua.BrowserMajor, mobile)
+	case "IE":
+		if ua.BrowserMajor == "11" {
+			return fmt.Sprintf("Mozilla/5.0 (%s; Trident/7.0; rv:11.0) like Gecko", platform)
+		}
+		return fmt.Sprintf("Mozilla/5.0 (compatible; MSIE %s.0; %s)", ua.BrowserMajor, platform)
+	}
+	return ""
+}
+
+// anonymizeUserAgent returns a generic user-agent string that preserves the browser and OS families and their major
+// versions of the input. User-agents that are not from a known browser (bots, command line tools, libraries) are
+// reduced to their leading product token and major version, for example: "curl/7.64.1 (x86_64)" => "curl/7".
+func anonymizeUserAgent(input string) string {
+	ua := parseUserAgent(input)
+	if ua.Browser != "" {
+		return ua.String()
+	}
+
+	m := uaProductRegex.FindStringSubmatch(strings.TrimSpace(input))
+	if m == nil {
+		return ""
+	}
+	if m[2] == "" {
+		return m[1]
+	}
+	return m[1] + "/" + m[2]
+}
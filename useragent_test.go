@@ -0,0 +1,34 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymizeUserAgent(t *testing.T) {
+	var userAgents = map[string]string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.5845.188 " +
+			"Safari/537.36": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) " +
+			"Chrome/116.0.0.0 Safari/537.36",
+		"Mozilla/5.0 (Linux; Android 13; SM-S908U Build/TP1A.220624.014) AppleWebKit/537.36 (KHTML, like Gecko) " +
+			"Chrome/116.0.5845.163 Mobile Safari/537.36": "Mozilla/5.0 (Linux; Android 13; K) AppleWebKit/537.36 " +
+			"(KHTML, like Gecko) Chrome/116.0.0.0 Mobile Safari/537.36",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:109.0) Gecko/20100101 Firefox/117.0": "Mozilla/5.0 " +
+			"(Macintosh; Intel Mac OS X 10_15; rv:117.0) Gecko/20100101 Firefox/117.0",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 16_6_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) " +
+			"Version/16.6 Mobile/15E148 Safari/604.1": "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) " +
+			"AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 " +
+			"Safari/537.36 Edg/116.0.1938.81": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 " +
+			"(KHTML, like Gecko) Chrome/116.0.0.0 Safari/537.36 Edg/116.0.0.0",
+		"curl/7.64.1":         "curl/7",
+		"MyInternalTool":      "MyInternalTool",
+		"":                    "",
+		"(garbage) tokens 42": "",
+	}
+
+	for input, expected := range userAgents {
+		require.Equal(t, expected, anonymizeUserAgent(input))
+	}
+}
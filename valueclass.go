@@ -0,0 +1,99 @@
+package gonymizer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Value classes used by the ValueClass processor. The class of a value is the first one that matches, in this order.
+const (
+	ValueClassEmail   = "email"
+	ValueClassUUID    = "uuid"
+	ValueClassBoolean = "boolean"
+	ValueClassDate    = "date"
+	ValueClassNumber  = "number"
+	ValueClassPhone   = "phone"
+	ValueClassText    = "text"
+)
+
+var (
+	valueClasses = []string{
+		ValueClassEmail, ValueClassUUID, ValueClassBoolean, ValueClassDate, ValueClassNumber, ValueClassPhone,
+		ValueClassText,
+	}
+
+	valueDateRegex   = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}([ T]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}(:?\d{2})?)?)?$`)
+	valueNumberRegex = regexp.MustCompile(`^[+-]?\d+(\.\d+)?$`)
+	valuePhoneRegex  = regexp.MustCompile(`^\+?[\d\s().-]+(\s*(x|ext\.?)\s*\d+)?$`)
+)
+
+// classifyValue returns the class of a value: an e-mail address (the email PII pattern), UUID, boolean (true/false,
+// yes/no, on/off), ISO 8601 date (or timestamp), number (integer, decimal, or numeric ID), phone number (7 to 15 digits
+// with phone punctuation), or any other (free) text. A plain string of digits is a number, not a phone number.
+func classifyValue(value string) string {
+	trimmed := strings.TrimSpace(value)
+	switch strings.ToLower(trimmed) {
+	case "true", "false", "yes", "no", "on", "off":
+		return ValueClassBoolean
+	}
+
+	switch {
+	case matchesPIIPattern("email", trimmed):
+		return ValueClassEmail
+	case len(trimmed) == 36 && uuidParses(trimmed):
+		return ValueClassUUID
+	case valueDateRegex.MatchString(trimmed):
+		return ValueClassDate
+	case valueNumberRegex.MatchString(trimmed):
+		return ValueClassNumber
+	case valuePhoneRegex.MatchString(trimmed):
+		digits := len(phoneDigits(trimmed))
+		if digits >= 7 && digits <= 15 {
+			return ValueClassPhone
+		}
+	}
+	return ValueClassText
+}
+
+// phoneDigits returns the digits of a phone number without its extension.
+func phoneDigits(value string) string {
+	if i := strings.IndexAny(strings.ToLower(value), "xe"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, value)
+}
+
+// uuidParses returns true if value is a valid UUID.
+func uuidParses(value string) bool {
+	_, err := uuid.Parse(value)
+	return err == nil
+}
+
+// validateValueClasses returns an error if an inner processor of a ValueClass processor lists a class (in Keys) that
+// is neither a value class nor the name of a PII pattern.
+func (procDef ProcessorDefinition) validateValueClasses() error {
+	if procDef.Name != "ValueClass" {
+		return nil
+	}
+	for _, inner := range procDef.Processors {
+		for _, class := range inner.Keys {
+			known := findPIIPattern(class) != nil
+			for _, valueClass := range valueClasses {
+				known = known || class == valueClass
+			}
+			if !known {
+				return fmt.Errorf("Unknown value class %q for processor %s. Expected one of %s or a PII pattern (%s)",
+					class, inner.Name, strings.Join(valueClasses, ", "), strings.Join(piiPatternNames(), ", "))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package gonymizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyValue(t *testing.T) {
+	for value, class := range map[string]string{
+		"jane.doe@example.com":                 ValueClassEmail,
+		"5f0c8a2e-2c4b-4f3e-9d3a-1b2c3d4e5f60": ValueClassUUID,
+		"TRUE":                                 ValueClassBoolean,
+		"off":                                  ValueClassBoolean,
+		"42":                                   ValueClassNumber,
+		"-3.14":                                ValueClassNumber,
+		"8005550100":                           ValueClassNumber,
+		"(800) 555-0100":                       ValueClassPhone,
+		"+44 20 7946 0958":                     ValueClassPhone,
+		"800-555-0100 ext. 12":                 ValueClassPhone,
+		"2020-01-02":                           ValueClassDate,
+		"2020-01-02T15:04:05Z":                 ValueClassDate,
+		"Call me after 5pm":                    ValueClassText,
+		"not@an email":                         ValueClassText,
+	} {
+		require.Equal(t, class, classifyValue(value), value)
+	}
+}
+
+func TestProcessorValueClass(t *testing.T) {
+	cmap := &ColumnMapper{
+		Processors: []ProcessorDefinition{
+			{
+				Name: "ValueClass",
+				Processors: []ProcessorDefinition{
+					{Name: "ScrubString", Keys: []string{ValueClassEmail, ValueClassText}},
+					{Name: "RandomDigits", Keys: []string{ValueClassNumber}},
+					{Name: "RandomDigits", Keys: []string{"ssn_us"}},
+				},
+			},
+		},
+	}
+
+	output, err := ProcessorValueClass(cmap, "jane@example.com")
+	require.Nil(t, err)
+	require.Equal(t, "****************", output)
+
+	output, err = ProcessorValueClass(cmap, "12345")
+	require.Nil(t, err)
+	require.Len(t, output, 5)
+
+	// Text matching a PII pattern
+	output, err = ProcessorValueClass(cmap, "123-45-6789")
+	require.Nil(t, err)
+	require.Regexp(t, `^[\d-]+$`, output)
+	require.NotEqual(t, "123-45-6789", output)
+
+	// No processors for booleans
+	output, err = ProcessorValueClass(cmap, "true")
+	require.Nil(t, err)
+	require.Equal(t, "true", output)
+
+	require.Nil(t, cmap.Processors[0].validateValueClasses())
+	cmap.Processors[0].Processors[1].Keys = []string{"integer"}
+	require.NotNil(t, cmap.Processors[0].validateValueClasses())
+}
@@ -0,0 +1,243 @@
+package gonymizer
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// VaultKeyEnv is the environment variable the CLI reads the vault key (64 hex characters) from. The key is never
+// passed as a flag so it does not show up in process listings or shell history.
+const VaultKeyEnv = "GONYMIZER_VAULT_KEY"
+
+// vaultMagic is written at the start of every vault file and authenticated with every record.
+const vaultMagic = "GONYMIZER-VAULT-1\n"
+
+// vaultCheck is the first record of every vault. It is used to detect a wrong key before any token is added.
+const vaultCheck = "gonymizer vault"
+
+// tokenPrefix is the prefix of every token created by the Tokenize processor.
+const tokenPrefix = "tok_"
+
+// tokenEncoding is used to encode the random part of tokens.
+var tokenEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// tokenVault is the vault used by the Tokenize processor. It is opened for every call to ProcessDumpFileWithOptions
+// with a VaultFile.
+var tokenVault *Vault
+
+// vaultRecord is a single original value to token mapping stored in the vault. Key is the column (or parent column)
+// the value was tokenized for.
+type vaultRecord struct {
+	Key   string
+	Token string
+	Value string
+}
+
+// Vault is an encrypted, append-only store of the original values replaced by the Tokenize processor. Every record is
+// encrypted with AES-256-GCM so the vault can be stored apart from the processed dump file and only holders of the key
+// can re-identify a token (see Detokenize).
+type Vault struct {
+	mutex  sync.Mutex
+	path   string
+	file   *os.File
+	aead   cipher.AEAD
+	tokens map[string]vaultRecord
+	values map[string]string // Key + "\x00" + Value to token
+}
+
+// ParseVaultKey decodes a 256-bit vault key from hex.
+func ParseVaultKey(hexKey string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(hexKey))
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("Expected a vault key of 64 hex characters (256 bits)")
+	}
+	return key, nil
+}
+
+// OpenVault opens the vault found at path using key, creating it if it does not exist. Every record is read so
+// values keep the same token across runs.
+func OpenVault(path string, key []byte) (*Vault, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		log.Error("Unable to open vault: ", err)
+		return nil, err
+	}
+	v := &Vault{path: path, file: f, aead: aead, tokens: map[string]vaultRecord{}, values: map[string]string{}}
+
+	info, err := f.Stat()
+	if err == nil && info.Size() == 0 {
+		if _, err = f.WriteString(vaultMagic); err == nil {
+			err = v.append([]byte(vaultCheck))
+		}
+	} else if err == nil {
+		err = v.load()
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return v, nil
+}
+
+// load reads and decrypts every record of the vault.
+func (v *Vault) load() error {
+	reader := bufio.NewReader(v.file)
+	magic := make([]byte, len(vaultMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != vaultMagic {
+		return fmt.Errorf("%s is not a vault", v.path)
+	}
+
+	nonce := make([]byte, v.aead.NonceSize())
+	var length [4]byte
+	for n := 0; ; n++ {
+		if _, err := io.ReadFull(reader, nonce); err == io.EOF {
+			if n == 0 {
+				return fmt.Errorf("%s is not a vault", v.path)
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(reader, length[:]); err != nil {
+			return err
+		}
+		sealed := make([]byte, binary.BigEndian.Uint32(length[:]))
+		if _, err := io.ReadFull(reader, sealed); err != nil {
+			return err
+		}
+		data, err := v.aead.Open(nil, nonce, sealed, []byte(vaultMagic))
+		if err != nil {
+			if n == 0 {
+				return errors.New("Wrong vault key")
+			}
+			return fmt.Errorf("Record %d of vault %s has been modified", n, v.path)
+		}
+		if n == 0 {
+			continue
+		}
+
+		var record vaultRecord
+		if err = json.Unmarshal(data, &record); err != nil {
+			return err
+		}
+		v.tokens[record.Token] = record
+		v.values[record.Key+"\x00"+record.Value] = record.Token
+	}
+}
+
+// append encrypts data with a random nonce and appends it to the vault.
+func (v *Vault) append(data []byte) error {
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := v.aead.Seal(nil, nonce, data, []byte(vaultMagic))
+
+	record := make([]byte, 0, len(nonce)+4+len(sealed))
+	record = append(record, nonce...)
+	record = append(record, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(record[len(nonce):], uint32(len(sealed)))
+	record = append(record, sealed...)
+	_, err := v.file.Write(record)
+	return err
+}
+
+// Tokenize returns the token of value for key (the column), creating and storing a new random token the first time
+// the value is seen.
+func (v *Vault) Tokenize(key, value string) (string, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if token, ok := v.values[key+"\x00"+value]; ok {
+		return token, nil
+	}
+
+	random := make([]byte, 10)
+	var token string
+	for token == "" || len(v.tokens[token].Token) > 0 {
+		if _, err := rand.Read(random); err != nil {
+			return "", err
+		}
+		token = tokenPrefix + strings.ToLower(tokenEncoding.EncodeToString(random))
+	}
+
+	record := vaultRecord{Key: key, Token: token, Value: value}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	if err = v.append(data); err != nil {
+		return "", err
+	}
+	v.tokens[token] = record
+	v.values[key+"\x00"+value] = token
+	return token, nil
+}
+
+// Detokenize returns the original value of token. A user and reason are required and every request is appended to the
+// audit log of the vault (the vault path followed by .audit.log) before the value is returned. The value itself is
+// never written to the audit log.
+func (v *Vault) Detokenize(token, user, reason string) (string, error) {
+	if len(strings.TrimSpace(user)) == 0 || len(strings.TrimSpace(reason)) == 0 {
+		return "", errors.New("A user and reason are required to detokenize a value")
+	}
+
+	v.mutex.Lock()
+	record, ok := v.tokens[token]
+	v.mutex.Unlock()
+
+	entry, err := json.Marshal(map[string]interface{}{
+		"Time":   time.Now().UTC().Format(time.RFC3339),
+		"User":   user,
+		"Reason": reason,
+		"Token":  token,
+		"Key":    record.Key,
+		"Found":  ok,
+	})
+	if err != nil {
+		return "", err
+	}
+	auditLog, err := os.OpenFile(v.path+".audit.log", os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer auditLog.Close()
+	if _, err = auditLog.Write(append(entry, '\n')); err != nil {
+		return "", err
+	}
+
+	if !ok {
+		return "", fmt.Errorf("Token %s was not found in the vault", token)
+	}
+	log.Warnf("%s detokenized %s (%s): %s", user, token, record.Key, reason)
+	return record.Value, nil
+}
+
+// Close closes the vault file.
+func (v *Vault) Close() error {
+	return v.file.Close()
+}
@@ -0,0 +1,87 @@
+package gonymizer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVault(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gonymizer-vault")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "vault")
+
+	key, err := ParseVaultKey(strings.Repeat("ab", 32))
+	require.Nil(t, err)
+	_, err = ParseVaultKey("abcd")
+	require.NotNil(t, err)
+
+	vault, err := OpenVault(path, key)
+	require.Nil(t, err)
+	token, err := vault.Tokenize("public.users.email", "jane@example.com")
+	require.Nil(t, err)
+	require.Regexp(t, `^tok_[a-z2-7]{16}$`, token)
+	again, err := vault.Tokenize("public.users.email", "jane@example.com")
+	require.Nil(t, err)
+	require.Equal(t, token, again)
+	other, err := vault.Tokenize("public.users.name", "jane@example.com")
+	require.Nil(t, err)
+	require.NotEqual(t, token, other)
+	require.Nil(t, vault.Close())
+
+	// Tokens are kept across runs
+	vault, err = OpenVault(path, key)
+	require.Nil(t, err)
+	again, err = vault.Tokenize("public.users.email", "jane@example.com")
+	require.Nil(t, err)
+	require.Equal(t, token, again)
+
+	_, err = vault.Detokenize(token, "support", "")
+	require.NotNil(t, err)
+	value, err := vault.Detokenize(token, "support", "TICKET-1")
+	require.Nil(t, err)
+	require.Equal(t, "jane@example.com", value)
+	_, err = vault.Detokenize("tok_missing", "support", "TICKET-1")
+	require.NotNil(t, err)
+	require.Nil(t, vault.Close())
+
+	auditLog, err := ioutil.ReadFile(path + ".audit.log")
+	require.Nil(t, err)
+	require.Equal(t, 2, strings.Count(string(auditLog), "TICKET-1"))
+	require.NotContains(t, string(auditLog), "jane@example.com")
+
+	// The vault is encrypted
+	data, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	require.NotContains(t, string(data), "jane@example.com")
+	_, err = OpenVault(path, []byte(strings.Repeat("x", 32)))
+	require.NotNil(t, err)
+}
+
+func TestProcessorTokenize(t *testing.T) {
+	cmap := &ColumnMapper{TableSchema: "public", TableName: "users", ColumnName: "email"}
+	_, err := ProcessorTokenize(cmap, "jane@example.com")
+	require.NotNil(t, err)
+
+	dir, err := ioutil.TempDir("", "gonymizer-vault")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	tokenVault, err = OpenVault(filepath.Join(dir, "vault"), []byte(strings.Repeat("k", 32)))
+	require.Nil(t, err)
+	defer func() {
+		tokenVault.Close()
+		tokenVault = nil
+	}()
+
+	token, err := ProcessorTokenize(cmap, "jane@example.com")
+	require.Nil(t, err)
+	child := &ColumnMapper{ParentSchema: "public", ParentTable: "users", ParentColumn: "email"}
+	childToken, err := ProcessorTokenize(child, "jane@example.com")
+	require.Nil(t, err)
+	require.Equal(t, token, childToken)
+}
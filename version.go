@@ -5,7 +5,7 @@ import "time"
 const (
 	buildTimeStamp = 1564593815
 	buildNumber    = 10
-	buildVersion   = "1.2.0"
+	buildVersion   = "2.0.0"
 )
 
 // BuildDate will return the current unix time as the build date time for the application.
@@ -0,0 +1,117 @@
+package gonymizer
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/pprof"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// watchdog logs the progress of ProcessDumpFileWithOptions every HeartbeatInterval and detects when no line has been
+// processed for StallTimeout (e.g. a processor stuck in a loop or blocked I/O). A stall is logged together with the
+// stack of every goroutine and reported to ProcessOptions.OnStall. A nil watchdog does nothing.
+type watchdog struct {
+	lines int64 // number of lines processed, updated atomically
+	table atomic.Value
+
+	heartbeat    time.Duration
+	stallTimeout time.Duration
+	onStall      func(error)
+	lastTable    string
+	stop         chan struct{}
+}
+
+// newWatchdog returns a running watchdog for the options or nil if both the heartbeat and stall detection are disabled.
+func newWatchdog(opts ProcessOptions) *watchdog {
+	if opts.HeartbeatInterval <= 0 && opts.StallTimeout <= 0 {
+		return nil
+	}
+
+	w := &watchdog{
+		heartbeat:    opts.HeartbeatInterval,
+		stallTimeout: opts.StallTimeout,
+		onStall:      opts.OnStall,
+		stop:         make(chan struct{}),
+	}
+	w.table.Store("")
+
+	interval := w.heartbeat
+	if w.stallTimeout > 0 && (interval <= 0 || w.stallTimeout/10 < interval) {
+		interval = w.stallTimeout / 10
+	}
+	go w.run(interval)
+	return w
+}
+
+// tick records that a line of the table in state has been processed.
+func (w *watchdog) tick(state *LineState) {
+	if w == nil {
+		return
+	}
+	atomic.AddInt64(&w.lines, 1)
+	if state.TableName != w.lastTable {
+		w.lastTable = state.TableName
+		w.table.Store(fmt.Sprintf("%s.%s", state.SchemaName, state.TableName))
+	}
+}
+
+// close stops the watchdog.
+func (w *watchdog) close() {
+	if w != nil {
+		close(w.stop)
+	}
+}
+
+// run checks the progress every interval until the watchdog is closed.
+func (w *watchdog) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var (
+		started    = time.Now()
+		lastBeat   = started
+		lastChange = started
+		lastLines  int64
+		stalled    bool
+	)
+	for {
+		select {
+		case <-w.stop:
+			return
+		case now := <-ticker.C:
+			lines := atomic.LoadInt64(&w.lines)
+			if lines != lastLines {
+				lastLines, lastChange, stalled = lines, now, false
+			}
+
+			if w.heartbeat > 0 && now.Sub(lastBeat) >= w.heartbeat {
+				log.Infof("Heartbeat: %d lines processed (%.0f lines/s), current table: %s", lines,
+					float64(lines)/now.Sub(started).Seconds(), w.table.Load())
+				lastBeat = now
+			}
+
+			if w.stallTimeout > 0 && !stalled && now.Sub(lastChange) >= w.stallTimeout {
+				stalled = true
+				err := fmt.Errorf("No progress for %s after %d lines, current table: %s",
+					now.Sub(lastChange).Round(time.Second), lines, w.table.Load())
+				log.Error(err)
+				log.Error("Goroutines:\n", goroutineDump())
+				if w.onStall != nil {
+					w.onStall(err)
+				}
+			}
+		}
+	}
+}
+
+// goroutineDump returns the stack traces of all goroutines.
+func goroutineDump() string {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+		return err.Error()
+	}
+	return buf.String()
+}
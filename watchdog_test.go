@@ -0,0 +1,34 @@
+package gonymizer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchdog(t *testing.T) {
+	require.Nil(t, newWatchdog(ProcessOptions{}))
+
+	stalls := make(chan error, 1)
+	w := newWatchdog(ProcessOptions{
+		StallTimeout: 50 * time.Millisecond,
+		OnStall:      func(err error) { stalls <- err },
+	})
+	defer w.close()
+
+	// Keep making progress for longer than the stall timeout
+	state := &LineState{SchemaName: "public", TableName: "events"}
+	for i := 0; i < 10; i++ {
+		w.tick(state)
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Len(t, stalls, 0)
+
+	select {
+	case err := <-stalls:
+		require.Contains(t, err.Error(), "public.events")
+	case <-time.After(time.Second):
+		t.Fatal("Expected a stall")
+	}
+}